@@ -0,0 +1,236 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package analysis measures the level of PCM audio streams: the sample peak,
+the RMS level and the integrated loudness of ITU-R BS.1770.
+
+A Meter is an io.Writer, so it can measure a stream on its own or the
+output of a Resampler, written to it directly or through an io.MultiWriter.
+*/
+package analysis
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/zaf/resample/wav"
+)
+
+const (
+	// Sample formats, with the values of the resample package formats.
+	// Samples are little-endian.
+	F32 = 0 // 32-bit floating point PCM
+	F64 = 1 // 64-bit floating point PCM
+	I32 = 2 // 32-bit signed linear PCM
+	I16 = 3 // 16-bit signed linear PCM
+)
+
+// Meter measures the peak, RMS level and loudness of the samples written
+// to it. Loudness is measured as specified by ITU-R BS.1770 and EBU R128:
+// the mean square of the K weighted channels is computed over 400 ms blocks
+// overlapping by 75%, and averaged over the blocks above the absolute gate
+// of -70 LUFS and the relative gate 10 LU below their loudness.
+type Meter struct {
+	format   int
+	size     int // sample size in bytes
+	channels int
+	frames   int64       // frames measured
+	peak     float64     // largest absolute sample
+	squares  float64     // sum of the squared samples
+	weights  []float64   // channel weights, 0 for LFE and 1.41 for surround channels
+	filters  [][2]biquad // K weighting per channel
+	partial  []byte      // bytes of an incomplete frame
+	step     int         // frames per 100 ms step
+	n        int         // frames in the current step
+	sum      float64     // weighted squares of the current step
+	steps    []float64   // mean squares of the last steps
+	blocks   []float64   // mean squares of the 400 ms blocks
+}
+
+// NewMeter returns a Meter of samples of format with channels channels at
+// rate. The channels are weighted for loudness following the default WAV
+// speaker layout of their number, see SetChannelMask.
+func NewMeter(format, channels int, rate float64) (*Meter, error) {
+	m := &Meter{format: format, channels: channels, step: int(math.Round(rate / 10))}
+	switch format {
+	case F64:
+		m.size = 8
+	case F32, I32:
+		m.size = 4
+	case I16:
+		m.size = 2
+	default:
+		return nil, errors.New("invalid format setting")
+	}
+	if channels <= 0 {
+		return nil, errors.New("invalid channels number")
+	}
+	if m.step <= 0 {
+		return nil, errors.New("invalid sampling rate")
+	}
+	for c := 0; c < channels; c++ {
+		m.filters = append(m.filters, kWeighting(rate))
+	}
+	m.SetChannelMask(0)
+	return m, nil
+}
+
+// SetChannelMask sets the speaker layout of the channels, as a WAV channel
+// mask, used to weight them for loudness: low frequency channels are left
+// out and surround ones count 1.5 dB more. A mask of 0 is the default
+// layout. It should be set before writing.
+func (m *Meter) SetChannelMask(mask uint32) {
+	if mask == 0 {
+		mask = wav.DefaultChannelMask(m.channels)
+	}
+	m.weights = m.weights[:0]
+	for _, p := range wav.ChannelPositions(mask, m.channels) {
+		w := 1.0
+		switch p {
+		case wav.LowFrequency:
+			w = 0
+		case wav.BackLeft, wav.BackRight, wav.SideLeft, wav.SideRight:
+			w = 1.41
+		}
+		m.weights = append(m.weights, w)
+	}
+}
+
+// Write measures the samples in p. Frames may span writes.
+func (m *Meter) Write(p []byte) (int, error) {
+	n := len(p)
+	frame := m.size * m.channels
+	if len(m.partial) > 0 {
+		p = append(m.partial, p...)
+	}
+	end := len(p) - len(p)%frame
+	m.partial = append([]byte(nil), p[end:]...)
+	for i := 0; i < end; i += frame {
+		for c := 0; c < m.channels; c++ {
+			x := m.sample(p[i+m.size*c:])
+			m.peak = math.Max(m.peak, math.Abs(x))
+			m.squares += x * x
+			x = m.filters[c][0].filter(x)
+			x = m.filters[c][1].filter(x)
+			m.sum += m.weights[c] * x * x
+		}
+		m.frames++
+		if m.n++; m.n == m.step {
+			m.addStep(m.sum / float64(m.step))
+			m.n, m.sum = 0, 0
+		}
+	}
+	return n, nil
+}
+
+// sample decodes the sample at the start of p to the [-1, 1) range.
+func (m *Meter) sample(p []byte) float64 {
+	switch m.format {
+	case F64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(p))
+	case F32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+	case I32:
+		return float64(int32(binary.LittleEndian.Uint32(p))) / (1 << 31)
+	}
+	return float64(int16(binary.LittleEndian.Uint16(p))) / (1 << 15)
+}
+
+// addStep adds a 100 ms step, completing a block once four are measured.
+func (m *Meter) addStep(ms float64) {
+	if m.steps = append(m.steps, ms); len(m.steps) > 4 {
+		m.steps = m.steps[1:]
+	}
+	if len(m.steps) == 4 {
+		m.blocks = append(m.blocks, (m.steps[0]+m.steps[1]+m.steps[2]+m.steps[3])/4)
+	}
+}
+
+// Frames returns the number of frames measured.
+func (m *Meter) Frames() int64 {
+	return m.frames
+}
+
+// Peak returns the largest absolute sample value in dBFS, -Inf for silence.
+func (m *Meter) Peak() float64 {
+	return 20 * math.Log10(m.peak)
+}
+
+// RMS returns the RMS level of all the samples in dBFS, -Inf for silence.
+// A full scale sine is at -3 dBFS.
+func (m *Meter) RMS() float64 {
+	if m.frames == 0 {
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(m.squares/float64(m.frames*int64(m.channels)))
+}
+
+// Loudness returns the integrated loudness in LUFS, or -Inf when the
+// stream is shorter than 400 ms or below the absolute gate.
+func (m *Meter) Loudness() float64 {
+	lufs := func(ms float64) float64 {
+		return -0.691 + 10*math.Log10(ms)
+	}
+	gate := func(threshold float64) float64 {
+		var sum float64
+		var n int
+		for _, b := range m.blocks {
+			if lufs(b) > threshold {
+				sum += b
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+	abs := gate(-70)
+	if abs == 0 {
+		return math.Inf(-1)
+	}
+	return lufs(gate(lufs(abs) - 10))
+}
+
+// biquad is a second order IIR filter section.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64 // transposed direct form II state
+}
+
+func (f *biquad) filter(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// kWeighting returns the two filter sections of the ITU-R BS.1770 K
+// weighting at rate: a high shelf modelling the head followed by a high
+// pass. The coefficients are derived for any rate as in libebur128.
+func kWeighting(rate float64) [2]biquad {
+	k := math.Tan(math.Pi * 1681.974450955533 / rate)
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	k = math.Tan(math.Pi * 38.13547087602444 / rate)
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := biquad{b0: 1, b1: -2, b2: 1, a1: 2 * (k*k - 1) / a0, a2: (1 - k/q + k*k) / a0}
+	return [2]biquad{shelf, highPass}
+}
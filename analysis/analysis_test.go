@@ -0,0 +1,57 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+	"github.com/zaf/resample/wav"
+)
+
+func TestMeter(t *testing.T) {
+	m, err := NewMeter(I16, 2, 48000)
+	if err != nil {
+		t.Fatal("NewMeter failed:", err)
+	}
+	// 2 seconds of a stereo 1 kHz sine with a peak of -20 dBFS. Frames
+	// split across writes are measured whole.
+	p, _ := testsignal.Encode(testsignal.Sine(1000, 0.1, 48000, 2*48000), 2, testsignal.I16)
+	m.Write(p[:1001])
+	m.Write(p[1001:])
+	if m.Frames() != 2*48000 {
+		t.Errorf("Measured %d frames", m.Frames())
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Peak", m.Peak(), -20},
+		{"RMS", m.RMS(), -23.01},
+		// A 1 kHz sine at -20 dBFS in both channels measures -20 LUFS.
+		{"Loudness", m.Loudness(), -20},
+	} {
+		if math.Abs(tc.got-tc.want) > 0.1 {
+			t.Errorf("%s: %.2f, expected %.2f", tc.name, tc.got, tc.want)
+		}
+	}
+	if m, _ = NewMeter(I16, 1, 48000); !math.IsInf(m.Loudness(), -1) || !math.IsInf(m.Peak(), -1) || !math.IsInf(m.RMS(), -1) {
+		t.Error("Levels of an empty stream are not -Inf")
+	}
+	// Low frequency channels are left out of the loudness.
+	m.SetChannelMask(wav.LowFrequency)
+	m.Write(p)
+	if !math.IsInf(m.Loudness(), -1) || m.Peak() > -19.9 {
+		t.Errorf("LFE channel measured at %.2f LUFS", m.Loudness())
+	}
+	if _, err = NewMeter(8, 1, 48000); err == nil {
+		t.Error("No error for an invalid format")
+	}
+}
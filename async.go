@@ -0,0 +1,156 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by Write of an asynchronous Resampler when
+// queue Writes are already waiting. The data isn't written.
+var ErrQueueFull = errors.New("write queue is full")
+
+// WithAsync makes Write asynchronous, for real-time capture threads that
+// must not wait for the destination. Write copies the data to a queue of
+// up to queue Writes and returns at once, failing with ErrQueueFull when
+// the queue is full. A goroutine resamples the queued data and writes the
+// output. Its first error is sent to Errors and returned by the following
+// Writes and by Wait, Reset and Close, the data queued after it being
+// dropped. Wait waits for the queue to be written, Reset and Close drain it
+// first. Other methods may only be called once Wait returned.
+func WithAsync(queue int) Option {
+	return func(o *options) {
+		o.queue = queue
+	}
+}
+
+// Wait blocks until the data queued by Write of an asynchronous Resampler
+// is resampled and written, and returns the first error of the background
+// Writes. Without WithAsync it returns nil.
+func (r *Resampler) Wait() error {
+	if r.async == nil {
+		return nil
+	}
+	r.async.wg.Wait()
+	return r.async.failed()
+}
+
+// Errors returns a channel receiving the first error of the background
+// Writes of an asynchronous Resampler, closed by Close. Without WithAsync
+// it returns nil.
+func (r *Resampler) Errors() <-chan error {
+	if r.async == nil {
+		return nil
+	}
+	return r.async.errs
+}
+
+// async runs the Writes of an asynchronous Resampler in a goroutine.
+type async struct {
+	queue chan []byte
+	free  chan []byte // buffers of written data, for reuse
+	errs  chan error
+	wg    sync.WaitGroup // queued Writes not done
+	done  chan struct{}  // closed when the goroutine returns
+	mu    sync.Mutex
+	err   error // first error of a background Write
+}
+
+func newAsync(r *Resampler, queue int) *async {
+	a := &async{
+		queue: make(chan []byte, queue),
+		free:  make(chan []byte, queue+1),
+		errs:  make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+	go a.run(r)
+	return a
+}
+
+// run resamples the queued data until the queue is closed.
+func (a *async) run(r *Resampler) {
+	defer close(a.done)
+	for p := range a.queue {
+		if a.failed() == nil {
+			if _, err := r.writeSync(p); err != nil {
+				a.fail(err)
+			}
+		}
+		select {
+		case a.free <- p[:0]:
+		default:
+		}
+		a.wg.Done()
+	}
+}
+
+// write queues a copy of p.
+func (a *async) write(r *Resampler, p []byte) (int, error) {
+	if err := a.failed(); err != nil {
+		return 0, err
+	}
+	if frames, err := r.checkWrite(p); frames == 0 {
+		return 0, err
+	}
+	var b []byte
+	select {
+	case b = <-a.free:
+	default:
+	}
+	b = append(b, p...)
+	a.wg.Add(1)
+	select {
+	case a.queue <- b:
+		return len(p), nil
+	default:
+		a.wg.Done()
+		return 0, ErrQueueFull
+	}
+}
+
+// fail records the first error of a background Write.
+func (a *async) fail(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = err
+		// An error of before a Reset may not have been received.
+		select {
+		case a.errs <- err:
+		default:
+		}
+	}
+}
+
+// failed returns the first error of a background Write.
+func (a *async) failed() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// reset waits for the queue to be written and returns and clears the
+// first error.
+func (a *async) reset() error {
+	a.wg.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// stop writes the queue and stops the goroutine.
+func (a *async) stop() error {
+	close(a.queue)
+	<-a.done
+	close(a.errs)
+	return a.failed()
+}
@@ -0,0 +1,96 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// gatedWriter blocks its Writes until gate is closed.
+type gatedWriter struct {
+	gate chan struct{}
+	bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	return w.Buffer.Write(p)
+}
+
+// failWriter fails every Write.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWithAsync(t *testing.T) {
+	if _, err := New(io.Discard, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithAsync(-1)); err == nil {
+		t.Error("No error for a negative queue length")
+	}
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.25, 8000, 1000), 1, testsignal.I16)
+	w := &gatedWriter{gate: make(chan struct{})}
+	res, err := New(w, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithAsync(2), WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	// The goroutine blocks on the first Write, the queue then fills.
+	var written int
+	for ; written < 4; written++ {
+		if _, err = res.Write(in); err != nil {
+			break
+		}
+	}
+	if err != ErrQueueFull || written < 2 {
+		t.Errorf("%d Writes queued, error %v", written, err)
+	}
+	close(w.gate)
+	if err = res.Wait(); err != nil {
+		t.Error("Wait failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Error("Close failed:", err)
+	}
+	var want bytes.Buffer
+	res, _ = New(&want, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithDither(DitherNone))
+	for i := 0; i < written; i++ {
+		res.Write(in)
+	}
+	res.Close()
+	if !bytes.Equal(w.Bytes(), want.Bytes()) {
+		t.Errorf("Asynchronous output of %d bytes, expecting %d", w.Len(), want.Len())
+	}
+
+	res, err = New(failWriter{}, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithAsync(4))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(in); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	errs := res.Errors()
+	if err = <-errs; err == nil || err.Error() != "write failed" {
+		t.Errorf("Expecting: write failed got: %v", err)
+	}
+	if err = res.Wait(); err == nil {
+		t.Error("Wait didn't return the error")
+	}
+	if _, err = res.Write(in); err == nil {
+		t.Error("Write after an error didn't fail")
+	}
+	res.Close()
+	if _, ok := <-errs; ok {
+		t.Error("Errors isn't closed")
+	}
+}
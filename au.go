@@ -0,0 +1,60 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+
+	"github.com/zaf/resample/au"
+)
+
+// NewFromAU reads the Sun AU header from src and returns a Resampler
+// configured with the channels, sample encoding and sampling rate found in it.
+// The big-endian sample data is converted to little-endian, resampled to
+// outRate and written to dst. The Resampler must be closed to flush its
+// remaining output.
+func NewFromAU(dst io.Writer, src io.Reader, outRate float64, opts ...Option) (*Resampler, error) {
+	h, err := au.ReadHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	inFormat, err := auFormat(h.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	data := src
+	if h.DataSize != au.UnknownSize {
+		data = io.LimitReader(src, h.DataSize)
+	}
+	size := au.SampleSize(h.Encoding)
+	if size > 1 {
+		data = &byteSwapReader{r: data, size: size}
+	}
+	return newFromReader(dst, data, float64(h.SampleRate), outRate, h.Channels, inFormat, size*h.Channels, opts)
+}
+
+// auFormat maps an AU encoding to the matching Resampler format.
+func auFormat(encoding int) (int, error) {
+	switch encoding {
+	case au.MuLaw:
+		return MuLaw, nil
+	case au.ALaw:
+		return ALaw, nil
+	case au.Linear16:
+		return I16, nil
+	case au.Linear32:
+		return I32, nil
+	case au.Float:
+		return F32, nil
+	case au.Double:
+		return F64, nil
+	}
+	return 0, errors.New("unsupported AU encoding")
+}
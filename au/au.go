@@ -0,0 +1,213 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package au implements reading and writing of Sun/NeXT AU (.au, .snd) headers.
+
+AU sample data is big-endian. ReadHeader leaves the reader positioned at
+the first byte of sample data, Writer takes little-endian samples and
+stores them in big-endian byte order.
+*/
+package au
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// Encodings
+	MuLaw    = 1  // 8-bit G.711 µ-law, the default AU encoding
+	Linear8  = 2  // 8-bit signed linear PCM
+	Linear16 = 3  // 16-bit signed linear PCM
+	Linear24 = 4  // 24-bit signed linear PCM
+	Linear32 = 5  // 32-bit signed linear PCM
+	Float    = 6  // 32-bit IEEE floating point
+	Double   = 7  // 64-bit IEEE floating point
+	ALaw     = 27 // 8-bit G.711 A-law
+
+	headerSize = 24
+	// UnknownSize is the data size of streams whose length was not known when written.
+	UnknownSize = math.MaxUint32
+)
+
+var (
+	// ErrNotAU is returned when the input is not an AU stream.
+	ErrNotAU = errors.New("au: not an AU file")
+	// ErrBadHeader is returned for malformed headers.
+	ErrBadHeader = errors.New("au: malformed header")
+)
+
+// Header holds the parsed header of an AU file.
+type Header struct {
+	Encoding   int    // sample encoding
+	SampleRate int    // sampling rate in Hz
+	Channels   int    // number of interleaved channels
+	DataSize   int64  // size of the sample data in bytes, UnknownSize if not set
+	Annotation []byte // free form annotation following the header
+}
+
+// SampleSize returns the size in bytes of a sample of the given encoding,
+// or 0 for unsupported encodings.
+func SampleSize(encoding int) int {
+	switch encoding {
+	case MuLaw, ALaw, Linear8:
+		return 1
+	case Linear16:
+		return 2
+	case Linear24:
+		return 3
+	case Linear32, Float:
+		return 4
+	case Double:
+		return 8
+	}
+	return 0
+}
+
+// ReadHeader parses an AU header from r. On success r is positioned at
+// the beginning of the sample data.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var b [headerSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotAU
+		}
+		return nil, err
+	}
+	if string(b[0:4]) != ".snd" {
+		return nil, ErrNotAU
+	}
+	offset := binary.BigEndian.Uint32(b[4:])
+	h := Header{
+		DataSize:   int64(binary.BigEndian.Uint32(b[8:])),
+		Encoding:   int(binary.BigEndian.Uint32(b[12:])),
+		SampleRate: int(binary.BigEndian.Uint32(b[16:])),
+		Channels:   int(binary.BigEndian.Uint32(b[20:])),
+	}
+	if offset < headerSize || h.SampleRate == 0 || h.Channels == 0 {
+		return nil, ErrBadHeader
+	}
+	if offset > headerSize {
+		h.Annotation = make([]byte, offset-headerSize)
+		if _, err := io.ReadFull(r, h.Annotation); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return &h, nil
+}
+
+// Writer writes sample data to an AU file.
+type Writer struct {
+	w       io.Writer
+	seeker  io.WriteSeeker // set when the data size can be filled in on Close
+	start   int64          // offset of the file in the seeker
+	size    int            // sample size in bytes
+	partial []byte         // bytes of an incomplete sample
+	written int64          // sample bytes written
+	closed  bool
+}
+
+// NewWriter writes an AU header for h to w and returns a Writer for the
+// sample data. An Encoding of zero selects µ-law. The data size is filled
+// in on Close when w is an io.WriteSeeker, otherwise it is left unknown.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	if w == nil {
+		return nil, errors.New("io.Writer is nil")
+	}
+	if h.Encoding == 0 {
+		h.Encoding = MuLaw
+	}
+	size := SampleSize(h.Encoding)
+	if size == 0 || h.SampleRate <= 0 || h.Channels <= 0 {
+		return nil, ErrBadHeader
+	}
+	// The annotation is at least 4 bytes, NUL terminated and padded.
+	annotation := append(append([]byte{}, h.Annotation...), 0)
+	for len(annotation) < 4 || len(annotation)%8 != 0 {
+		annotation = append(annotation, 0)
+	}
+	b := make([]byte, headerSize, headerSize+len(annotation))
+	copy(b, ".snd")
+	binary.BigEndian.PutUint32(b[4:], uint32(headerSize+len(annotation)))
+	binary.BigEndian.PutUint32(b[8:], UnknownSize)
+	binary.BigEndian.PutUint32(b[12:], uint32(h.Encoding))
+	binary.BigEndian.PutUint32(b[16:], uint32(h.SampleRate))
+	binary.BigEndian.PutUint32(b[20:], uint32(h.Channels))
+	b = append(b, annotation...)
+	wr := &Writer{w: w, size: size}
+	if s, ok := w.(io.WriteSeeker); ok {
+		if off, err := s.Seek(0, io.SeekCurrent); err == nil {
+			wr.seeker = s
+			wr.start = off
+		}
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return wr, nil
+}
+
+// Write writes little-endian samples, converting them to big-endian.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("au: write on closed Writer")
+	}
+	if w.size == 1 {
+		n, err := w.w.Write(p)
+		w.written += int64(n)
+		return n, err
+	}
+	data := append(w.partial, p...)
+	whole := len(data) - len(data)%w.size
+	out := make([]byte, whole)
+	for i := 0; i < whole; i += w.size {
+		for j := 0; j < w.size; j++ {
+			out[i+j] = data[i+w.size-1-j]
+		}
+	}
+	w.partial = append(w.partial[:0], data[whole:]...)
+	n, err := w.w.Write(out)
+	w.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close fills in the data size when the destination is seekable.
+// Bytes of an incomplete trailing sample are dropped. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.seeker == nil || w.written >= UnknownSize {
+		return nil
+	}
+	end, err := w.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = w.seeker.Seek(w.start+8, io.SeekStart); err != nil {
+		return err
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(w.written))
+	if _, err = w.seeker.Write(b[:]); err != nil {
+		return err
+	}
+	_, err = w.seeker.Seek(end, io.SeekStart)
+	return err
+}
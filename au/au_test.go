@@ -0,0 +1,94 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package au
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Encoding: Linear16, SampleRate: 8000, Channels: 2, Annotation: []byte("test")})
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	// Samples split across writes must still be swapped as a whole.
+	w.Write([]byte{1, 2, 3})
+	w.Write([]byte{4, 5, 6, 7})
+	w.Close()
+	h, err := ReadHeader(&buf)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.Encoding != Linear16 || h.SampleRate != 8000 || h.Channels != 2 || h.DataSize != UnknownSize {
+		t.Errorf("Unexpected header: %+v", h)
+	}
+	if !bytes.HasPrefix(h.Annotation, []byte("test\x00")) {
+		t.Errorf("Annotation: %q", h.Annotation)
+	}
+	if len(h.Annotation)%8 != 0 {
+		t.Errorf("Annotation size: %d, must be padded", len(h.Annotation))
+	}
+	if data, _ := io.ReadAll(&buf); !bytes.Equal(data, []byte{2, 1, 4, 3, 6, 5}) {
+		t.Errorf("Sample data: %v", data)
+	}
+}
+
+func TestWriteSeeker(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.au"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Default encoding is µ-law
+	w, err := NewWriter(f, Header{SampleRate: 8000, Channels: 1})
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	w.Write(make([]byte, 160))
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	f.Seek(0, io.SeekStart)
+	h, err := ReadHeader(f)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.Encoding != MuLaw || h.DataSize != 160 {
+		t.Errorf("Unexpected header: %+v", h)
+	}
+}
+
+var BadHeaderTest = []struct {
+	name string
+	data []byte
+	err  error
+}{
+	{"empty", []byte{}, ErrNotAU},
+	{"not au", []byte("RIFF\x00\x00\x00\x18\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x1f\x40\x00\x00\x00\x01"), ErrNotAU},
+	{"bad offset", []byte(".snd\x00\x00\x00\x10\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x1f\x40\x00\x00\x00\x01"), ErrBadHeader},
+	{"no channels", []byte(".snd\x00\x00\x00\x18\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x1f\x40\x00\x00\x00\x00"), ErrBadHeader},
+	{"truncated annotation", []byte(".snd\x00\x00\x00\x20\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x1f\x40\x00\x00\x00\x01"), io.ErrUnexpectedEOF},
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	for _, tc := range BadHeaderTest {
+		_, err := ReadHeader(bytes.NewReader(tc.data))
+		if err != tc.err {
+			t.Errorf("%s: error: %v, expecting: %v", tc.name, err, tc.err)
+		}
+	}
+	if _, err := NewWriter(io.Discard, Header{Encoding: 100, SampleRate: 8000, Channels: 1}); err != ErrBadHeader {
+		t.Errorf("NewWriter error: %v, expecting: %v", err, ErrBadHeader)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zaf/resample/au"
+)
+
+func TestNewFromAU(t *testing.T) {
+	// 8 kHz µ-law to 16 kHz 16-bit linear, written back as AU.
+	var in bytes.Buffer
+	w, err := au.NewWriter(&in, au.Header{SampleRate: 8000, Channels: 1})
+	if err != nil {
+		t.Fatal("Failed to create AU writer:", err)
+	}
+	w.Write(bytes.Repeat([]byte{0xff, 0x80, 0x00}, 800))
+	var out bytes.Buffer
+	aw, err := au.NewWriter(&out, au.Header{Encoding: au.Linear16, SampleRate: 16000, Channels: 1})
+	if err != nil {
+		t.Fatal("Failed to create AU writer:", err)
+	}
+	res, err := NewFromAU(aw, &in, 16000.0, WithOutFormat(I16))
+	if err != nil {
+		t.Fatal("NewFromAU failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to close Resampler:", err)
+	}
+	aw.Close()
+	h, err := au.ReadHeader(&out)
+	if err != nil {
+		t.Fatal("Failed to parse output:", err)
+	}
+	if h.Encoding != au.Linear16 || out.Len() != 2400*2*2 {
+		t.Errorf("Unexpected output, encoding: %d, size: %d", h.Encoding, out.Len())
+	}
+}
@@ -0,0 +1,71 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+// Resampling libraries.
+const (
+	BackendSoxr       = 0 // libsoxr, the default where it's available
+	BackendSamplerate = 1 // libsamplerate, needs the `samplerate' build tag
+	BackendFIR        = 2 // polyphase FIR filter in Go
+)
+
+// backend is a resampling library. It takes and returns interleaved
+// little-endian samples in the F32, F64, I32 or I16 formats chosen when it
+// is created.
+type backend interface {
+	// process resamples frames of input data p and returns the output
+	// available so far. The output is only valid until the next call.
+	process(p []byte, frames int) ([]byte, error)
+	// flush returns the remaining output at the end of the input, as much
+	// as fits in the output buffer per call, until it returns none.
+	flush() ([]byte, error)
+	// reserve allocates the output buffer for process calls of up to
+	// frames input frames.
+	reserve(frames int)
+	// delay returns the number of output frames buffered by the library,
+	// or 0 when it can't tell.
+	delay() float64
+	// clips returns the number of output samples clipped by the library
+	// to the range of an integer format.
+	clips() int64
+	// setRatio changes the output to input rate ratio of the stream.
+	setRatio(ratio float64) error
+	// reset discards the buffered data.
+	reset() error
+	// close frees the library state.
+	close()
+}
+
+// flushFrames is the number of output frames a flush call returns at most
+// before an output buffer is allocated.
+const flushFrames = 4096 * 16
+
+// growBuffer returns buf resized to n bytes, reallocated only when its
+// capacity is too small.
+func growBuffer(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	return buf[:n]
+}
+
+// WithBackend selects the resampling library, BackendSoxr,
+// BackendSamplerate or BackendFIR. libsamplerate and the FIR backend have
+// no dither of their own, TPDF dither of integer output is then done in
+// Go, and they ignore WithThreads. The FIR backend designs its filter from
+// the quality setting, or uses the one of WithFIR or WithFIRSpec, which
+// isn't redesigned by SetRate. Built without cgo for a system purego can't
+// load libsoxr on, such as WebAssembly or Windows, the package has no
+// libsoxr and the FIR backend is the default, as it is when purego doesn't
+// find libsoxr.
+func WithBackend(b int) Option {
+	return func(o *options) {
+		o.backend = b
+	}
+}
@@ -0,0 +1,72 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// testBackend resamples a second of 8 kHz mono F64 input to 16 kHz with b
+// and checks the number of output frames.
+func testBackend(t *testing.T, b backend) {
+	t.Helper()
+	in := testsignal.Sine(440, 0.5, 8000, 8000)
+	p := float64Bytes(in)
+	var frames int
+	for i := 0; i < len(in); i += 1000 {
+		out, err := b.process(p[8*i:8*(i+1000)], 1000)
+		if err != nil {
+			t.Fatal("process failed:", err)
+		}
+		frames += len(out) / 8
+	}
+	out, err := b.flush()
+	if err != nil {
+		t.Fatal("flush failed:", err)
+	}
+	if frames += len(out) / 8; math.Abs(float64(frames-16000)) > 100 {
+		t.Errorf("%d frames out, expecting about 16000", frames)
+	}
+	if d := b.delay(); d < 0 {
+		t.Errorf("Negative delay %g", d)
+	}
+	if err = b.setRatio(0); err == nil {
+		t.Error("Invalid ratio didn't return an error")
+	}
+	if err = b.reset(); err != nil {
+		t.Error("reset failed:", err)
+	}
+	b.close()
+}
+
+func TestSoxrBackend(t *testing.T) {
+	if defaultBackend() != BackendSoxr {
+		t.Skip("libsoxr isn't available")
+	}
+	b, err := newSoxr(8000, 16000, 1, F64, F64, MediumQ, 1, false, false, soxrFlags{})
+	if err != nil {
+		t.Fatal("Failed to create the backend:", err)
+	}
+	testBackend(t, b)
+	// SOXR_NO_DITHER io and SOXR_NOSMALLINTOPT runtime flags
+	if b, err = newSoxr(8000, 16000, 1, F64, F64, MediumQ, 1, false, false, soxrFlags{io: 8, runtime: 8}); err != nil {
+		t.Fatal("Failed to create the backend with extra flags:", err)
+	}
+	testBackend(t, b)
+}
+
+func TestWithBackend(t *testing.T) {
+	if _, err := New(io.Discard, 8000, 16000, 1, I16, I16, MediumQ, WithBackend(7)); err == nil {
+		t.Error("Invalid backend didn't return an error")
+	}
+}
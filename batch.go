@@ -0,0 +1,104 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Job is an in-memory clip of RAW PCM data resampled by ResampleBuffers,
+// with the parameters of New. Output and Err are set once it's done.
+type Job struct {
+	Input      []byte
+	InputRate  float64
+	OutputRate float64
+	Channels   int
+	InFormat   int
+	OutFormat  int
+	Quality    int
+	Options    []Option
+
+	Output []byte // resampled data
+	Err    error  // error of the job
+}
+
+// ResampleBuffers resamples jobs concurrently, such as a library of clips
+// pre-rendered at several rates, with workers goroutines, or one per CPU
+// when workers is 0 or less. The Resampler of each job is single threaded
+// unless its Options say otherwise. Once ctx is done the jobs in progress
+// stop and the remaining ones aren't started, their Err being the context
+// error. ResampleBuffers returns when all jobs are done, with the error
+// of the first failed job or nil.
+func ResampleBuffers(ctx context.Context, jobs []Job, workers int) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	next := make(chan *Job)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(jobs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range next {
+				j.Output, j.Err = resampleJob(ctx, j)
+			}
+		}()
+	}
+	for i := range jobs {
+		if err := ctx.Err(); err != nil {
+			jobs[i].Output, jobs[i].Err = nil, err
+			continue
+		}
+		next <- &jobs[i]
+	}
+	close(next)
+	wg.Wait()
+	for _, j := range jobs {
+		if j.Err != nil {
+			return j.Err
+		}
+	}
+	return nil
+}
+
+// resampleJob resamples the input of j, chunkFrames at a time so that it
+// stops early once ctx is done.
+func resampleJob(ctx context.Context, j *Job) ([]byte, error) {
+	var out bytes.Buffer
+	opts := append([]Option{WithThreads(1)}, j.Options...)
+	r, err := New(&out, j.InputRate, j.OutputRate, j.Channels, j.InFormat, j.OutFormat, j.Quality, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out.Grow(int(float64(len(j.Input)/r.inFrameSize)*j.OutputRate/j.InputRate) * r.outFrameSize * r.outChannels / r.channels)
+	chunk := chunkFrames * r.inFrameSize * r.channels
+	for p := j.Input; len(p) > 0; {
+		if err = ctx.Err(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		n := len(p)
+		// Keep the last Write long enough to give output.
+		if n >= 2*chunk {
+			n = chunk
+		}
+		if _, err = r.Write(p[:n]); err != nil {
+			r.Close()
+			return nil, err
+		}
+		p = p[n:]
+	}
+	if err = r.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestResampleBuffers(t *testing.T) {
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.25, 16000, 20000), 1, testsignal.I16)
+	rates := []float64{8000, 16000, 22050, 44100, 48000}
+	jobs := make([]Job, len(rates))
+	for i, rate := range rates {
+		jobs[i] = Job{Input: in, InputRate: 16000, OutputRate: rate, Channels: 1, InFormat: I16, OutFormat: I16, Quality: MediumQ, Options: []Option{WithDither(DitherNone)}}
+	}
+	jobs = append(jobs, Job{Input: in, InputRate: 16000, OutputRate: 8000, Channels: 0, InFormat: I16, OutFormat: I16, Quality: MediumQ})
+	if err := ResampleBuffers(context.Background(), jobs, 2); err == nil || err.Error() != "invalid channels number" {
+		t.Errorf("Expecting: invalid channels number got: %v", err)
+	}
+	for i, rate := range rates {
+		var want bytes.Buffer
+		res, err := New(&want, 16000, rate, 1, I16, I16, MediumQ, WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		res.Write(in)
+		res.Close()
+		if jobs[i].Err != nil || !bytes.Equal(jobs[i].Output, want.Bytes()) {
+			t.Errorf("Job at %g Hz: error %v, output of %d bytes, expecting %d", rate, jobs[i].Err, len(jobs[i].Output), want.Len())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs = jobs[:len(rates)]
+	if err := ResampleBuffers(ctx, jobs, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expecting: context canceled got: %v", err)
+	}
+	for i, j := range jobs {
+		if !errors.Is(j.Err, context.Canceled) || j.Output != nil {
+			t.Errorf("Job %d of a canceled context: error %v, output of %d bytes", i, j.Err, len(j.Output))
+		}
+	}
+}
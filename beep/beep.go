@@ -0,0 +1,191 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package beep adapts the resample package to github.com/gopxl/beep.
+
+Resample is a drop-in replacement of beep.Resample that resamples a
+Streamer with libsoxr, and ResampleSeeker keeps a StreamSeeker seekable:
+
+	s, err := beep.Resample(resample.HighQ, format.SampleRate, sr, streamer)
+	if err != nil {
+		return err
+	}
+	speaker.Play(s)
+*/
+package beep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	gobeep "github.com/gopxl/beep/v2"
+	"github.com/zaf/resample"
+)
+
+// chunk is the number of source frames resampled at a time.
+const chunk = 512
+
+// frameSize is the size in bytes of a stereo F64 frame.
+const frameSize = 16
+
+// Streamer is a beep.Streamer playing a source Streamer at another sample rate.
+type Streamer struct {
+	src     gobeep.Streamer
+	quality int
+	old     gobeep.SampleRate
+	new     gobeep.SampleRate
+	opts    []resample.Option
+	r       *resample.Resampler
+	in      [][2]float64 // source frames
+	pending []byte       // source data too short to produce an output frame
+	out     bytes.Buffer // resampled data not streamed yet
+	done    bool         // the source is drained and the resampler flushed
+	err     error
+	pos     int // output frames streamed
+}
+
+// Resample returns a Streamer that plays s, sampled at old, at the new rate
+// with libsoxr. quality is one of the resample quality settings, options of
+// resample.New may follow. Unlike beep.Resample the rates can't be changed
+// once streaming.
+func Resample(quality int, old, new gobeep.SampleRate, s gobeep.Streamer, opts ...resample.Option) (*Streamer, error) {
+	if s == nil {
+		return nil, errors.New("streamer is nil")
+	}
+	st := &Streamer{src: s, quality: quality, old: old, new: new, opts: opts, in: make([][2]float64, chunk)}
+	if err := st.reset(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// reset starts a new resampler, dropping the buffered data.
+func (s *Streamer) reset() error {
+	if s.r != nil {
+		s.r.Close()
+	}
+	s.out.Reset()
+	s.pending = s.pending[:0]
+	s.done = false
+	var err error
+	s.r, err = resample.New(&s.out, float64(s.old), float64(s.new), 2, resample.F64, resample.F64, s.quality, s.opts...)
+	return err
+}
+
+// Stream fills samples with resampled frames of the source. It returns
+// false once the source is drained and all its frames were streamed.
+func (s *Streamer) Stream(samples [][2]float64) (int, bool) {
+	for s.out.Len() < len(samples)*frameSize && !s.done {
+		s.fill()
+	}
+	n := s.out.Len() / frameSize
+	if n > len(samples) {
+		n = len(samples)
+	}
+	b := s.out.Next(n * frameSize)
+	for i := range samples[:n] {
+		samples[i][0] = math.Float64frombits(binary.LittleEndian.Uint64(b[16*i:]))
+		samples[i][1] = math.Float64frombits(binary.LittleEndian.Uint64(b[16*i+8:]))
+	}
+	s.pos += n
+	return n, n > 0 || !s.done
+}
+
+// fill resamples the next chunk of the source. When the source is drained
+// the resampler is flushed.
+func (s *Streamer) fill() {
+	n, ok := s.src.Stream(s.in)
+	for _, f := range s.in[:n] {
+		s.pending = binary.LittleEndian.AppendUint64(s.pending, math.Float64bits(f[0]))
+		s.pending = binary.LittleEndian.AppendUint64(s.pending, math.Float64bits(f[1]))
+	}
+	if float64(len(s.pending)/frameSize)*float64(s.new)/float64(s.old) >= 1 {
+		if _, err := s.r.Write(s.pending); err != nil {
+			s.fail(err)
+			return
+		}
+		s.pending = s.pending[:0]
+	}
+	if !ok || n == 0 {
+		// The remaining input is shorter than an output frame and dropped.
+		if err := s.r.Close(); err != nil {
+			s.fail(err)
+			return
+		}
+		s.r = nil
+		s.err = s.src.Err()
+		s.done = true
+	}
+}
+
+// fail stops streaming with err.
+func (s *Streamer) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.done = true
+}
+
+// Err returns the error of the source or of the resampling, if any.
+func (s *Streamer) Err() error {
+	return s.err
+}
+
+// Ratio returns the ratio of the output and source sample rates.
+func (s *Streamer) Ratio() float64 {
+	return float64(s.new) / float64(s.old)
+}
+
+// StreamSeeker is a Streamer of a seekable source, itself seekable in
+// output frames.
+type StreamSeeker struct {
+	*Streamer
+	src gobeep.StreamSeeker
+}
+
+// ResampleSeeker is like Resample for a source that implements
+// beep.StreamSeeker. The returned StreamSeeker reports its length and
+// position, and seeks, in frames at the new rate.
+func ResampleSeeker(quality int, old, new gobeep.SampleRate, s gobeep.StreamSeeker, opts ...resample.Option) (*StreamSeeker, error) {
+	st, err := Resample(quality, old, new, s, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamSeeker{Streamer: st, src: s}, nil
+}
+
+// Len returns the length of the source in output frames.
+func (s *StreamSeeker) Len() int {
+	return int(math.Round(float64(s.src.Len()) * s.Ratio()))
+}
+
+// Position returns the number of output frames streamed since the start,
+// or since the last Seek.
+func (s *StreamSeeker) Position() int {
+	return s.pos
+}
+
+// Seek moves to output frame p, seeking the source to the nearest frame.
+// The resampler starts afresh from there.
+func (s *StreamSeeker) Seek(p int) error {
+	if p < 0 || p > s.Len() {
+		return errors.New("seek position out of range")
+	}
+	if err := s.src.Seek(int(math.Round(float64(p) / s.Ratio()))); err != nil {
+		return err
+	}
+	s.err = nil
+	if err := s.reset(); err != nil {
+		return err
+	}
+	s.pos = p
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package beep
+
+import (
+	"testing"
+
+	gobeep "github.com/gopxl/beep/v2"
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+)
+
+// buffer returns a stereo buffer at rate of the samples of x, inverted in
+// the right channel.
+func buffer(x []float64, rate gobeep.SampleRate) *gobeep.Buffer {
+	buf := gobeep.NewBuffer(gobeep.Format{SampleRate: rate, NumChannels: 2, Precision: 4})
+	i := 0
+	buf.Append(gobeep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if i == len(x) {
+			return 0, false
+		}
+		m := 0
+		for ; m < len(samples) && i < len(x); m, i = m+1, i+1 {
+			samples[m] = [2]float64{x[i], -x[i]}
+		}
+		return m, true
+	}))
+	return buf
+}
+
+// drain streams s to the end and returns its frames.
+func drain(s gobeep.Streamer) [][2]float64 {
+	var all [][2]float64
+	buf := make([][2]float64, 300)
+	for {
+		n, ok := s.Stream(buf)
+		all = append(all, buf[:n]...)
+		if !ok {
+			return all
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	src := buffer(testsignal.Sine(440, 0.5, 22050, 22050), 22050)
+	s, err := Resample(resample.HighQ, 22050, 44100, src.Streamer(0, src.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := drain(s)
+	if err = s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) < 44000 || len(out) > 44200 {
+		t.Errorf("streamed %d frames, expected about 44100", len(out))
+	}
+	for i := 1000; i < 1010; i++ {
+		if out[i][0] != -out[i][1] {
+			t.Fatalf("frame %d: channels %v are not opposite", i, out[i])
+		}
+	}
+	if n, ok := s.Stream(make([][2]float64, 10)); n != 0 || ok {
+		t.Errorf("drained streamer returned %d, %v", n, ok)
+	}
+	if _, err = Resample(resample.HighQ, 22050, 44100, nil); err == nil {
+		t.Error("nil streamer accepted")
+	}
+}
+
+func TestResampleSeeker(t *testing.T) {
+	src := buffer(testsignal.Sine(440, 0.5, 8000, 8000), 8000)
+	s, err := ResampleSeeker(resample.MediumQ, 8000, 16000, src.Streamer(0, src.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ gobeep.StreamSeeker = s
+	if s.Len() != 16000 {
+		t.Errorf("Len is %d, expected 16000", s.Len())
+	}
+	n, _ := s.Stream(make([][2]float64, 1000))
+	if n != 1000 || s.Position() != 1000 {
+		t.Errorf("streamed %d frames, position %d, expected 1000", n, s.Position())
+	}
+	if err = s.Seek(8000); err != nil {
+		t.Fatal(err)
+	}
+	if s.Position() != 8000 {
+		t.Errorf("position %d after Seek, expected 8000", s.Position())
+	}
+	rest := drain(s)
+	if len(rest) < 7900 || len(rest) > 8100 {
+		t.Errorf("streamed %d frames after Seek, expected about 8000", len(rest))
+	}
+	if err = s.Seek(-1); err == nil {
+		t.Error("negative position accepted")
+	}
+}
@@ -0,0 +1,54 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestBuildWithoutCgo builds the package for the systems without libsoxr,
+// where the FIR backend is the default.
+func TestBuildWithoutCgo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross-compiling in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	for _, target := range [][2]string{{"js", "wasm"}, {"wasip1", "wasm"}, {"windows", "amd64"}} {
+		cmd := exec.Command(goTool, "vet", ".")
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS="+target[0], "GOARCH="+target[1])
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s/%s build failed: %v\n%s", target[0], target[1], err, out)
+		}
+	}
+}
+
+// TestBuildLoadSoxr builds the package with cgo and the loadsoxr tag
+// without pkg-config, which libsoxr is then not looked up with.
+func TestBuildLoadSoxr(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	if out, err := exec.Command(goTool, "env", "CGO_ENABLED").Output(); err != nil || string(out) != "1\n" {
+		t.Skip("cgo is disabled")
+	}
+	cmd := exec.Command(goTool, "vet", "-tags", "loadsoxr", ".")
+	cmd.Env = append(os.Environ(), "PKG_CONFIG=resample-no-pkg-config")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("loadsoxr build failed: %v\n%s", err, out)
+	}
+}
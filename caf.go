@@ -0,0 +1,63 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+
+	"github.com/zaf/resample/caf"
+)
+
+// NewFromCAF reads the CAF header from src and returns a Resampler
+// configured with the channels, sample format and sampling rate found in it.
+// The audio data is then resampled to outRate and written to dst. Big-endian
+// samples are converted to little-endian. The Resampler must be closed to
+// flush its remaining output.
+func NewFromCAF(dst io.Writer, src io.Reader, outRate float64, opts ...Option) (*Resampler, error) {
+	h, err := caf.ReadHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	inFormat, err := cafFormat(h.Format)
+	if err != nil {
+		return nil, err
+	}
+	data := src
+	if h.DataSize >= 0 {
+		data = io.LimitReader(src, h.DataSize)
+	}
+	size := h.BitsPerChannel / 8
+	if size > 1 && !h.LittleEndian() {
+		data = &byteSwapReader{r: data, size: size}
+	}
+	return newFromReader(dst, data, h.SampleRate, outRate, h.Channels, inFormat, size*h.Channels, opts)
+}
+
+// cafFormat maps a CAF audio description to the matching Resampler format.
+func cafFormat(f caf.Format) (int, error) {
+	if f.FramesPerPacket != 1 || f.BytesPerPacket != f.Channels*f.BitsPerChannel/8 {
+		return 0, errors.New("unsupported CAF packet layout")
+	}
+	switch {
+	case f.FormatID == caf.LinearPCM && !f.Float() && f.BitsPerChannel == 16:
+		return I16, nil
+	case f.FormatID == caf.LinearPCM && !f.Float() && f.BitsPerChannel == 32:
+		return I32, nil
+	case f.FormatID == caf.LinearPCM && f.Float() && f.BitsPerChannel == 32:
+		return F32, nil
+	case f.FormatID == caf.LinearPCM && f.Float() && f.BitsPerChannel == 64:
+		return F64, nil
+	case f.FormatID == caf.MuLaw && f.BitsPerChannel == 8:
+		return MuLaw, nil
+	case f.FormatID == caf.ALaw && f.BitsPerChannel == 8:
+		return ALaw, nil
+	}
+	return 0, errors.New("unsupported CAF sample format")
+}
@@ -0,0 +1,254 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package caf implements reading and writing of Apple Core Audio Format headers.
+
+ReadHeader consumes everything up to the start of the audio data, leaving
+the reader positioned at the first byte of sample data. Writer produces
+files with a single data chunk.
+*/
+package caf
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// Format identifiers
+	LinearPCM = "lpcm" // Linear PCM
+	MuLaw     = "ulaw" // G.711 µ-law
+	ALaw      = "alaw" // G.711 A-law
+
+	// Linear PCM format flags
+	FlagFloat        = 1 << 0 // samples are floating point
+	FlagLittleEndian = 1 << 1 // samples are little-endian
+
+	fileHeaderSize = 8
+	descSize       = 32
+	// dataSizeOffset is the file offset of the data chunk size written by Writer.
+	dataSizeOffset = fileHeaderSize + 12 + descSize + 4
+)
+
+var (
+	// ErrNotCAF is returned when the input is not a CAF stream.
+	ErrNotCAF = errors.New("caf: not a CAF file")
+	// ErrNoDesc is returned when the first chunk is not an audio description.
+	ErrNoDesc = errors.New("caf: missing desc chunk")
+	// ErrBadDesc is returned for malformed audio description chunks.
+	ErrBadDesc = errors.New("caf: malformed desc chunk")
+)
+
+// Format describes the audio data as defined in the desc chunk.
+type Format struct {
+	SampleRate      float64 // sampling rate in Hz
+	FormatID        string  // format identifier, e.g. LinearPCM
+	Flags           uint32  // format flags
+	BytesPerPacket  int     // bytes per packet, 0 for variable sized packets
+	FramesPerPacket int     // frames per packet
+	Channels        int     // number of interleaved channels
+	BitsPerChannel  int     // bits per sample
+}
+
+// Float reports whether linear PCM samples are floating point.
+func (f Format) Float() bool {
+	return f.Flags&FlagFloat != 0
+}
+
+// LittleEndian reports whether samples are stored in little-endian byte order.
+func (f Format) LittleEndian() bool {
+	return f.Flags&FlagLittleEndian != 0
+}
+
+// Header holds the parsed header of a CAF file.
+type Header struct {
+	Format
+	DataSize int64 // size of the audio data in bytes, -1 if it extends to the end of the file
+}
+
+// ReadHeader parses a CAF header from r. On success r is positioned at
+// the beginning of the audio data. Chunks other than desc and data are skipped.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var b [fileHeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotCAF
+		}
+		return nil, err
+	}
+	if string(b[0:4]) != "caff" || binary.BigEndian.Uint16(b[4:6]) != 1 {
+		return nil, ErrNotCAF
+	}
+	var h Header
+	id, size, err := readChunkHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != "desc" {
+		return nil, ErrNoDesc
+	}
+	if err = readDesc(r, size, &h.Format); err != nil {
+		return nil, err
+	}
+	for {
+		if id, size, err = readChunkHeader(r); err != nil {
+			return nil, err
+		}
+		if id != "data" {
+			if size < 0 {
+				return nil, errors.New("caf: invalid chunk size")
+			}
+			if err = skip(r, size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// The data starts with the edit count.
+		if size >= 0 && size < 4 {
+			return nil, errors.New("caf: invalid data chunk size")
+		}
+		if err = skip(r, 4); err != nil {
+			return nil, err
+		}
+		h.DataSize = size
+		if size > 0 {
+			h.DataSize -= 4
+		}
+		return &h, nil
+	}
+}
+
+// readChunkHeader reads a chunk type and its size.
+func readChunkHeader(r io.Reader) (string, int64, error) {
+	var b [12]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", 0, err
+	}
+	return string(b[0:4]), int64(binary.BigEndian.Uint64(b[4:12])), nil
+}
+
+// readDesc parses a desc chunk payload of the given size into f.
+func readDesc(r io.Reader, size int64, f *Format) error {
+	if size != descSize {
+		return ErrBadDesc
+	}
+	var b [descSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	f.SampleRate = math.Float64frombits(binary.BigEndian.Uint64(b[0:8]))
+	f.FormatID = string(b[8:12])
+	f.Flags = binary.BigEndian.Uint32(b[12:16])
+	f.BytesPerPacket = int(binary.BigEndian.Uint32(b[16:20]))
+	f.FramesPerPacket = int(binary.BigEndian.Uint32(b[20:24]))
+	f.Channels = int(binary.BigEndian.Uint32(b[24:28]))
+	f.BitsPerChannel = int(binary.BigEndian.Uint32(b[28:32]))
+	if !(f.SampleRate > 0) || f.Channels == 0 {
+		return ErrBadDesc
+	}
+	return nil
+}
+
+// skip discards n bytes from r, seeking when possible.
+func skip(r io.Reader, n int64) error {
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Writer writes audio data to a CAF file.
+type Writer struct {
+	w      io.Writer
+	size   int64 // audio bytes written
+	closed bool
+}
+
+// NewWriter writes a CAF header describing f to w and returns a Writer
+// for the audio data. The data chunk size is left undetermined, as allowed
+// for the last chunk of a file, unless w is an io.WriteSeeker in which case
+// Close fills it in.
+func NewWriter(w io.Writer, f Format) (*Writer, error) {
+	if w == nil {
+		return nil, errors.New("io.Writer is nil")
+	}
+	if len(f.FormatID) != 4 || !(f.SampleRate > 0) || f.Channels <= 0 {
+		return nil, ErrBadDesc
+	}
+	b := make([]byte, dataSizeOffset+8+4)
+	copy(b, "caff")
+	binary.BigEndian.PutUint16(b[4:], 1) // version
+	copy(b[8:], "desc")
+	binary.BigEndian.PutUint64(b[12:], descSize)
+	d := b[20:]
+	binary.BigEndian.PutUint64(d[0:], math.Float64bits(f.SampleRate))
+	copy(d[8:], f.FormatID)
+	binary.BigEndian.PutUint32(d[12:], f.Flags)
+	binary.BigEndian.PutUint32(d[16:], uint32(f.BytesPerPacket))
+	binary.BigEndian.PutUint32(d[20:], uint32(f.FramesPerPacket))
+	binary.BigEndian.PutUint32(d[24:], uint32(f.Channels))
+	binary.BigEndian.PutUint32(d[28:], uint32(f.BitsPerChannel))
+	copy(b[dataSizeOffset-4:], "data")
+	binary.BigEndian.PutUint64(b[dataSizeOffset:], math.MaxUint64) // -1, unknown size
+	// Edit count is zero.
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Write writes audio data.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("caf: write on closed Writer")
+	}
+	n, err := w.w.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close sets the data chunk size when the underlying writer is seekable.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	s, ok := w.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	end, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = s.Seek(dataSizeOffset, io.SeekStart); err != nil {
+		return err
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(w.size+4))
+	if _, err = s.Write(b[:]); err != nil {
+		return err
+	}
+	_, err = s.Seek(end, io.SeekStart)
+	return err
+}
@@ -0,0 +1,108 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package caf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testFormat = Format{
+	SampleRate:      44100,
+	FormatID:        LinearPCM,
+	Flags:           FlagLittleEndian,
+	BytesPerPacket:  4,
+	FramesPerPacket: 1,
+	Channels:        2,
+	BitsPerChannel:  16,
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testFormat)
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	audio := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	if _, err = w.Write(audio); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	h, err := ReadHeader(&buf)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.Format != testFormat {
+		t.Errorf("Format: %+v, expecting: %+v", h.Format, testFormat)
+	}
+	if h.DataSize != -1 {
+		t.Errorf("Data size: %d, expecting: -1", h.DataSize)
+	}
+	if rest, _ := io.ReadAll(&buf); !bytes.Equal(rest, audio) {
+		t.Errorf("Audio data: %v, expecting: %v", rest, audio)
+	}
+}
+
+func TestWriteSeeker(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.caf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, testFormat)
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	if _, err = w.Write(make([]byte, 400)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	// Trailing chunks may follow once the data size is known.
+	f.Write([]byte("free\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00"))
+	f.Seek(0, io.SeekStart)
+	h, err := ReadHeader(f)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.DataSize != 400 {
+		t.Errorf("Data size: %d, expecting: 400", h.DataSize)
+	}
+}
+
+var BadHeaderTest = []struct {
+	name string
+	data []byte
+	err  error
+}{
+	{"empty", []byte{}, ErrNotCAF},
+	{"not caf", []byte("RIFF\x00\x01\x00\x00"), ErrNotCAF},
+	{"bad version", []byte("caff\x00\x02\x00\x00"), ErrNotCAF},
+	{"truncated", []byte("caff\x00\x01\x00\x00desc"), io.ErrUnexpectedEOF},
+	{"no desc", []byte("caff\x00\x01\x00\x00data\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00"), ErrNoDesc},
+	{"short desc", []byte("caff\x00\x01\x00\x00desc\x00\x00\x00\x00\x00\x00\x00\x10"), ErrBadDesc},
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	for _, tc := range BadHeaderTest {
+		_, err := ReadHeader(bytes.NewReader(tc.data))
+		if err != tc.err {
+			t.Errorf("%s: error: %v, expecting: %v", tc.name, err, tc.err)
+		}
+	}
+	if _, err := NewWriter(io.Discard, Format{FormatID: "lpcm"}); err != ErrBadDesc {
+		t.Errorf("NewWriter error: %v, expecting: %v", err, ErrBadDesc)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/zaf/resample/caf"
+)
+
+func TestNewFromCAF(t *testing.T) {
+	// Big-endian 16-bit mono ramp
+	var in bytes.Buffer
+	w, err := caf.NewWriter(&in, caf.Format{
+		SampleRate:      16000,
+		FormatID:        caf.LinearPCM,
+		BytesPerPacket:  2,
+		FramesPerPacket: 1,
+		Channels:        1,
+		BitsPerChannel:  16,
+	})
+	if err != nil {
+		t.Fatal("Failed to create CAF writer:", err)
+	}
+	for i := 0; i < 8000; i++ {
+		binary.Write(w, binary.BigEndian, int16(i))
+	}
+	var out bytes.Buffer
+	res, err := NewFromCAF(&out, &in, 16000.0)
+	if err != nil {
+		t.Fatal("NewFromCAF failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to close Resampler:", err)
+	}
+	if out.Len() != 16000 {
+		t.Fatalf("Output size: %d, expecting: 16000", out.Len())
+	}
+	// Same rate conversion keeps the little-endian samples close to the input.
+	if s := int16(binary.LittleEndian.Uint16(out.Bytes()[8000:])); s < 3900 || s > 4100 {
+		t.Errorf("Unexpected sample value: %d", s)
+	}
+}
+
+func TestByteSwapReader(t *testing.T) {
+	r := &byteSwapReader{r: bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7}), size: 2}
+	buf := make([]byte, 3)
+	var got []byte
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	if !bytes.Equal(got, []byte{2, 1, 4, 3, 6, 5}) {
+		t.Errorf("Swapped data: %v", got)
+	}
+}
@@ -0,0 +1,315 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// chunkFrames is the number of RAW frames resampled at a time.
+const chunkFrames = 4096
+
+// maxRate is the highest sample rate accepted, and maxRatio the highest
+// ratio of the output to the input rate, or of the input to the output
+// rate, which the buffers of a conversion grow with.
+const (
+	maxRate  = 768000
+	maxRatio = 256
+)
+
+// conversion holds the query parameters of a request.
+type conversion struct {
+	outRate   float64
+	inRate    float64 // RAW input only
+	channels  int     // RAW input only
+	inFormat  int     // RAW input only
+	outFormat int     // -1 for the input format
+	quality   int
+	wav       bool // RAW input converted to WAV
+}
+
+// parseQuery returns the conversion described by the query parameters q.
+func parseQuery(q url.Values) (*conversion, error) {
+	c := &conversion{outFormat: -1, inFormat: resample.I16}
+	var err error
+	if c.outRate, err = parseRate(q, "or"); err != nil {
+		return nil, err
+	}
+	if q.Has("ir") {
+		if c.inRate, err = parseRate(q, "ir"); err != nil {
+			return nil, err
+		}
+		if err = checkRatio(c.inRate, c.outRate); err != nil {
+			return nil, err
+		}
+	}
+	if s := q.Get("ch"); s != "" {
+		if c.channels, err = strconv.Atoi(s); err != nil || c.channels <= 0 {
+			return nil, fmt.Errorf("invalid channels %s", s)
+		}
+	}
+	if s := q.Get("if"); s != "" {
+		if c.inFormat, err = resample.ParseFormat(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := q.Get("of"); s != "" {
+		if c.outFormat, err = resample.ParseFormat(s); err != nil {
+			return nil, err
+		}
+	}
+	s := q.Get("q")
+	if s == "" {
+		s = *quality
+	}
+	if c.quality, err = resample.ParseQuality(s); err != nil {
+		return nil, err
+	}
+	if s := q.Get("wav"); s != "" {
+		if c.wav, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("invalid wav %s", s)
+		}
+	}
+	return c, nil
+}
+
+// parseRate returns the sample rate of the query parameter key.
+func parseRate(q url.Values, key string) (float64, error) {
+	s := q.Get(key)
+	if s == "" {
+		return 0, fmt.Errorf("missing %s", key)
+	}
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(rate > 0) || rate > maxRate {
+		return 0, fmt.Errorf("invalid %s %s", key, s)
+	}
+	return rate, nil
+}
+
+// checkRatio returns an error when the input and output rates are too far
+// apart.
+func checkRatio(inRate, outRate float64) error {
+	if outRate > inRate*maxRatio || inRate > outRate*maxRatio {
+		return fmt.Errorf("ratio of %g to %g Hz too large", inRate, outRate)
+	}
+	return nil
+}
+
+// handleResample converts the audio of the request body and streams the
+// result in the response.
+func handleResample(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := parseQuery(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body := io.Reader(req.Body)
+	if *maxSize > 0 {
+		body = http.MaxBytesReader(w, req.Body, *maxSize)
+	}
+	in := bufio.NewReader(body)
+	rc := http.NewResponseController(w)
+	// Stream the response while reading the body. HTTP/2 always does.
+	rc.EnableFullDuplex()
+	out := &streamWriter{w: w, rc: rc}
+	start := time.Now()
+	defer func() { observeConversion("resample", start, err) }()
+	var r *resample.Resampler
+	if magic, _ := in.Peek(4); string(magic) == "RIFF" || string(magic) == "riff" {
+		w.Header().Set("Content-Type", "audio/wav")
+		r, err = convertWAV(out, in, c)
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if c.wav {
+			w.Header().Set("Content-Type", "audio/wav")
+		}
+		r, err = convertRaw(out, in, c)
+	}
+	if r != nil {
+		addFrames(r)
+	}
+	if err != nil {
+		if !out.started {
+			status := http.StatusBadRequest
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		log.Printf("%s %s: %s", req.RemoteAddr, req.URL, err)
+		// The status is sent, drop the connection so the client sees
+		// the response is truncated.
+		panic(http.ErrAbortHandler)
+	}
+	debugf("%s %s: %d bytes in %s", req.RemoteAddr, req.URL, out.n, time.Since(start).Round(time.Millisecond))
+}
+
+// convertWAV resamples the WAV file read from in to a WAV file in the
+// same container, with the channel mask and the metadata chunks of its
+// header, written to out. It returns the closed Resampler.
+func convertWAV(out io.Writer, in io.Reader, c *conversion) (*resample.Resampler, error) {
+	h, err := wav.ReadHeader(in)
+	if err != nil {
+		return nil, err
+	}
+	inFormat, err := resample.WAVSampleFormat(h.Format)
+	if err != nil {
+		return nil, err
+	}
+	if h.SampleRate > maxRate {
+		return nil, fmt.Errorf("invalid WAV sample rate %d", h.SampleRate)
+	}
+	if err = checkRatio(float64(h.SampleRate), c.outRate); err != nil {
+		return nil, err
+	}
+	outFormat := c.outFormat
+	if outFormat < 0 {
+		outFormat = inFormat
+	}
+	data := io.LimitReader(in, h.DataSize)
+	frameSize := h.BlockAlign
+	if h.Tag == wav.FormatIMAADPCM {
+		if data, err = wav.NewIMAReader(data, h.Format); err != nil {
+			return nil, err
+		}
+		frameSize = 2 * h.Channels
+	}
+	f, err := resample.WAVFormat(outFormat, h.Channels, c.outRate)
+	if err != nil {
+		return nil, err
+	}
+	f.ChannelMask = h.ChannelMask
+	// The Resampler checks the conversion before the header is sent.
+	dst := &laterWriter{}
+	r, err := resample.New(dst, float64(h.SampleRate), c.outRate, h.Channels, inFormat, outFormat, c.quality, resample.WithThreads(*threads))
+	if err != nil {
+		return nil, err
+	}
+	newWriter := wav.NewWriter
+	if h.W64 {
+		newWriter = wav.NewW64Writer
+	}
+	ww, err := newWriter(out, f, resample.WAVMetadata(h.Chunks, c.outRate/float64(h.SampleRate))...)
+	if err != nil {
+		dst.w = io.Discard
+		r.Close()
+		return nil, err
+	}
+	dst.w = ww
+	if err = resampleData(r, data, frameSize, c.outRate/float64(h.SampleRate)); err != nil {
+		return r, err
+	}
+	return r, ww.Close()
+}
+
+// convertRaw resamples the RAW data read from in to out, in a WAV file
+// when c.wav is set. It returns the closed Resampler.
+func convertRaw(out io.Writer, in io.Reader, c *conversion) (*resample.Resampler, error) {
+	if c.inRate == 0 || c.channels == 0 {
+		return nil, errors.New("RAW input needs ir and ch")
+	}
+	outFormat := c.outFormat
+	if outFormat < 0 {
+		outFormat = c.inFormat
+	}
+	// The Resampler checks the conversion before a WAV header is sent.
+	dst := &laterWriter{w: out}
+	r, err := resample.New(dst, c.inRate, c.outRate, c.channels, c.inFormat, outFormat, c.quality, resample.WithThreads(*threads))
+	if err != nil {
+		return nil, err
+	}
+	var ww *wav.Writer
+	if c.wav {
+		f, err := resample.WAVFormat(outFormat, c.channels, c.outRate)
+		if err == nil {
+			ww, err = wav.NewWriter(out, f)
+		}
+		if err != nil {
+			dst.w = io.Discard
+			r.Close()
+			return nil, err
+		}
+		dst.w = ww
+	}
+	if err = resampleData(r, in, c.channels*resample.FormatSize(c.inFormat), c.outRate/c.inRate); err != nil || ww == nil {
+		return r, err
+	}
+	return r, ww.Close()
+}
+
+// resampleData passes the frames of frameSize bytes read from in through
+// r, resampling by ratio, and closes r.
+func resampleData(r *resample.Resampler, in io.Reader, frameSize int, ratio float64) error {
+	buf := make([]byte, chunkFrames*frameSize)
+	for {
+		n, err := io.ReadFull(in, buf)
+		n -= n % frameSize
+		// The end of the data may be too short for an output frame.
+		if n > 0 && int(float64(n/frameSize)*ratio) > 0 {
+			if _, e := r.Write(buf[:n]); e != nil {
+				r.Close()
+				return e
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return r.Close()
+		}
+		if err != nil {
+			r.Close()
+			return err
+		}
+	}
+}
+
+// laterWriter writes to w, set once the Resampler writing to it is
+// created.
+type laterWriter struct {
+	w io.Writer
+}
+
+func (l *laterWriter) Write(p []byte) (int, error) {
+	return l.w.Write(p)
+}
+
+// streamWriter writes the response, flushing every write so the output
+// reaches the client as it is produced.
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool  // the response status was sent
+	n       int64 // bytes written
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.started = true
+	n, err := s.w.Write(p)
+	s.n += int64(n)
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	return n, err
+}
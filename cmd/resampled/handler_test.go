@@ -0,0 +1,111 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+	"github.com/zaf/resample/wav"
+)
+
+// post sends body to the /resample endpoint of srv with query.
+func post(t *testing.T, srv *httptest.Server, query string, body []byte) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/resample?"+query, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("POST failed:", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("Reading the response failed:", err)
+	}
+	return resp, data
+}
+
+func TestHandleResample(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handleResample))
+	defer srv.Close()
+	x := testsignal.Sine(440, 0.3, 8000, 8000)
+	mono, _ := testsignal.Encode(x, 1, testsignal.I16)
+	stereo, _ := testsignal.Encode(x, 2, testsignal.I16)
+
+	var in bytes.Buffer
+	ww, err := wav.NewWriter(&in, wav.Format{Tag: wav.FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 16})
+	if err != nil {
+		t.Fatal("Failed to create the WAV input:", err)
+	}
+	ww.Write(mono)
+	ww.Close()
+	resp, data := post(t, srv, "or=16000", in.Bytes())
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "audio/wav" {
+		t.Fatalf("WAV response %s of %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	r := bytes.NewReader(data)
+	h, err := wav.ReadHeader(r)
+	if err != nil {
+		t.Fatal("Failed to read the WAV output:", err)
+	}
+	if h.SampleRate != 16000 || h.Channels != 1 || h.BitsPerSample != 16 {
+		t.Errorf("WAV output of %d Hz, %d channels, %d bits", h.SampleRate, h.Channels, h.BitsPerSample)
+	}
+	if n := r.Len() / 2; math.Abs(float64(n-16000)) > 100 {
+		t.Errorf("%d WAV output frames, expected about 16000", n)
+	}
+
+	resp, data = post(t, srv, "or=16000&ir=8000&ch=2&of=f32", stereo)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("RAW response %s of %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if n := len(data) / 8; math.Abs(float64(n-16000)) > 100 {
+		t.Errorf("%d RAW output frames, expected about 16000", n)
+	}
+
+	for _, query := range []string{
+		"ir=8000&ch=1",
+		"or=16000",
+		"or=16000&ir=8000&ch=1&if=i8",
+		"or=16000&ir=8000&ch=1&q=best",
+		// Rejected by the Resampler, before a WAV header is sent.
+		"or=16000&ir=8000&ch=2000&wav=true",
+		// Rates and ratios the buffers would grow too large for.
+		"or=1e9&ir=8000&ch=1",
+		"or=16000&ir=800000&ch=1",
+		"or=NaN&ir=8000&ch=1",
+		"or=768000&ir=2000&ch=1",
+		"or=100&ir=48000&ch=1",
+	} {
+		if resp, data = post(t, srv, query, mono); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: response %s", query, resp.Status)
+		}
+		if strings.HasPrefix(string(data), "RIFF") {
+			t.Errorf("%s: WAV header sent with the error", query)
+		}
+	}
+	// The rate of a WAV header is checked too.
+	if resp, _ = post(t, srv, "or=30", in.Bytes()); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("WAV ratio too large: response %s", resp.Status)
+	}
+
+	resp, err = http.Get(srv.URL + "/resample?or=16000")
+	if err != nil {
+		t.Fatal("GET failed:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET response %s", resp.Status)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+// The program serves resampling over HTTP, for systems that can't link
+// the library. POST a WAV file or RAW PCM data to /resample and the
+// converted audio is streamed back while the request body is read.
+// Usage: resampled [flags]
+//
+// The query parameters describe the conversion:
+//
+//	or   output sample rate, required
+//	ir   input sample rate of RAW data
+//	ch   channels of RAW data
+//	if   sample format of RAW data: i16, i32, i24in32, f32, f64, ulaw or alaw (default i16)
+//	of   output sample format (default same as the input)
+//	q    quality: quick, low, medium, high, vhigh, sincbest, sincmedium or sincfastest (default -q)
+//	wav  true to wrap RAW output in a WAV header
+//
+// Rates above 768 kHz, and output rates more than 256 times higher or lower
+// than the input rate, are rejected.
+//
+// WAV input, recognized by its header, gives WAV output with the metadata
+// chunks of the input. RAW data is little-endian. As the response is
+// streamed, its WAV header holds the placeholder sizes of streamed WAV
+// data, and errors found once the response started abort the connection.
+//
+// /stream takes the same parameters for RAW data over a WebSocket, for
+// live audio such as the microphone of a browser speech recognition front
+// end. Each binary message, holding whole frames, is resampled at once and
+// answered with a binary message of its output, empty while the filter
+// fills at the start, so the latency is the one of the filter. A text
+// message "end" flushes the remaining output in a last binary message,
+// then the server closes the connection, as it does after a message larger
+// than -max-message. Browsers of other origins than the server's are
+// accepted when listed by -allow-origin.
+//
+// /metrics exposes Prometheus metrics: the requests by endpoint and status,
+// the conversions and WebSockets in progress, the frames resampled, the
+// output samples clipped, and the duration of the conversions of /resample
+// and of the messages of /stream, by whether they failed.
+//
+// Example:
+//
+//	curl --data-binary @in.wav -o out.wav 'http://localhost:8080/resample?or=16000'
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zaf/resample"
+)
+
+var (
+	addr        = flag.String("addr", ":8080", "Address to listen on")
+	quality     = flag.String("q", "high", "Default resampling quality: quick, low, medium, high, vhigh, or sincbest, sincmedium and sincfastest emulating libsamplerate")
+	maxSize     = flag.Int64("max-size", 256<<20, "Maximum request body size in bytes, 0 for no limit")
+	maxMessage  = flag.Int64("max-message", 1<<20, "Maximum WebSocket message size in bytes, 0 for no limit")
+	threads     = flag.Int("threads", 1, "Number of resampler threads per request")
+	verbose     = flag.Bool("v", false, "Log every request")
+	allowOrigin = flag.String("allow-origin", "", "Comma separated origins allowed to open WebSockets besides the server's, * for all")
+)
+
+func main() {
+	flag.Parse()
+	if _, err := resample.ParseQuality(*quality); err != nil {
+		log.Fatal(err)
+	}
+	if *threads < 1 {
+		log.Fatal("Invalid number of threads")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/resample", instrument("resample", handleResample))
+	mux.Handle("/stream", instrument("stream", handleStream))
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
+
+// debugf logs a message when -v is set.
+func debugf(format string, v ...interface{}) {
+	if *verbose {
+		log.Printf(format, v...)
+	}
+}
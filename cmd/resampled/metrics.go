@@ -0,0 +1,92 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zaf/resample"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "resampled_requests_total",
+		Help: "Requests by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+	activeStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "resampled_active_streams",
+		Help: "Conversions and WebSockets in progress, by endpoint.",
+	}, []string{"endpoint"})
+	framesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "resampled_frames_total",
+		Help: "Frames resampled, by direction: in or out.",
+	}, []string{"direction"})
+	clipsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "resampled_clipped_samples_total",
+		Help: "Output samples clipped to the range of integer formats.",
+	})
+	conversionSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "resampled_conversion_seconds",
+		Help:    "Duration of the requests of /resample and of the messages of /stream, by status: ok or error.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 4, 10),
+	}, []string{"endpoint", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, activeStreams, framesTotal, clipsTotal, conversionSeconds)
+}
+
+// instrument counts the requests of the endpoint handled by h and the
+// ones in progress.
+func instrument(endpoint string, h http.HandlerFunc) http.Handler {
+	labels := prometheus.Labels{"endpoint": endpoint}
+	return promhttp.InstrumentHandlerInFlight(activeStreams.With(labels),
+		promhttp.InstrumentHandlerCounter(requestsTotal.MustCurryWith(labels), h))
+}
+
+// observeConversion adds the duration of a conversion of the endpoint
+// since start, which ended with err, to the metrics.
+func observeConversion(endpoint string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	conversionSeconds.WithLabelValues(endpoint, status).Observe(time.Since(start).Seconds())
+}
+
+// addFrames adds the frames resampled by r and its clipped samples to the
+// metrics.
+func addFrames(r *resample.Resampler) {
+	in, _ := r.InputPosition()
+	out, _ := r.OutputPosition()
+	framesTotal.WithLabelValues("in").Add(float64(in))
+	framesTotal.WithLabelValues("out").Add(float64(out))
+	clipsTotal.Add(float64(r.Clips()))
+}
+
+// frameCount holds the counts of a Resampler of a stream already added to
+// the metrics, which grow with each of its messages.
+type frameCount struct {
+	in, out, clips int64
+}
+
+// add adds the frames resampled by r and its clipped samples since the
+// previous call to the metrics.
+func (c *frameCount) add(r *resample.Resampler) {
+	in, _ := r.InputPosition()
+	out, _ := r.OutputPosition()
+	clips := r.Clips()
+	framesTotal.WithLabelValues("in").Add(float64(in - c.in))
+	framesTotal.WithLabelValues("out").Add(float64(out - c.out))
+	clipsTotal.Add(float64(clips - c.clips))
+	c.in, c.out, c.clips = in, out, clips
+}
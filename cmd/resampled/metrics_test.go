@@ -0,0 +1,69 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/resample", instrument("resample", handleResample))
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	in := testutil.ToFloat64(framesTotal.WithLabelValues("in"))
+	out := testutil.ToFloat64(framesTotal.WithLabelValues("out"))
+	ok := testutil.ToFloat64(requestsTotal.WithLabelValues("resample", "200"))
+	bad := testutil.ToFloat64(requestsTotal.WithLabelValues("resample", "400"))
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.3, 8000, 8000), 1, testsignal.I16)
+	post(t, srv, "or=16000&ir=8000&ch=1", p)
+	post(t, srv, "or=16000&ir=8000&ch=2000", p)
+	if n := testutil.ToFloat64(framesTotal.WithLabelValues("in")) - in; n != 8000 {
+		t.Errorf("%g input frames counted, expected 8000", n)
+	}
+	if n := testutil.ToFloat64(framesTotal.WithLabelValues("out")) - out; n < 15900 || n > 16100 {
+		t.Errorf("%g output frames counted, expected about 16000", n)
+	}
+	if n := testutil.ToFloat64(requestsTotal.WithLabelValues("resample", "200")) - ok; n != 1 {
+		t.Errorf("%g requests of status 200 counted, expected 1", n)
+	}
+	if n := testutil.ToFloat64(requestsTotal.WithLabelValues("resample", "400")) - bad; n != 1 {
+		t.Errorf("%g requests of status 400 counted, expected 1", n)
+	}
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal("GET failed:", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("Reading the metrics failed:", err)
+	}
+	for _, metric := range []string{
+		`resampled_conversion_seconds_count{endpoint="resample",status="ok"}`,
+		`resampled_conversion_seconds_count{endpoint="resample",status="error"}`,
+		`resampled_active_streams{endpoint="resample"} 0`,
+		`resampled_clipped_samples_total`,
+	} {
+		if !strings.Contains(string(data), metric) {
+			t.Errorf("%s missing from the metrics", metric)
+		}
+	}
+}
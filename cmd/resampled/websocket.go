@@ -0,0 +1,166 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zaf/resample"
+)
+
+// writeTimeout bounds the time spent sending a message to a client.
+const writeTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{CheckOrigin: checkOrigin}
+
+// checkOrigin accepts the requests of the same origin and those of the
+// origins of -allow-origin.
+func checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range strings.Split(*allowOrigin, ",") {
+		if o = strings.TrimSpace(o); o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, req.Host)
+}
+
+// handleStream resamples the binary messages of RAW PCM of a WebSocket
+// and sends back the output of each one as a binary message, empty while
+// the filter fills. A text message "end" flushes the resampler, whose
+// remaining output is sent as a last binary message before the server
+// closes the connection.
+func handleStream(w http.ResponseWriter, req *http.Request) {
+	c, err := parseQuery(req.URL.Query())
+	if err == nil && (c.inRate == 0 || c.channels == 0) {
+		err = errors.New("RAW input needs ir and ch")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return // Upgrade replied with the error
+	}
+	defer conn.Close()
+	// A message is read whole into memory.
+	if *maxMessage > 0 {
+		conn.SetReadLimit(*maxMessage)
+	}
+	s, err := newStream(c)
+	if err != nil {
+		closeWith(conn, websocket.CloseInternalServerErr, err)
+		return
+	}
+	defer s.r.Close()
+	start := time.Now()
+	var messages int
+	var count frameCount
+	for {
+		kind, p, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				debugf("%s %s: %s", req.RemoteAddr, req.URL, err)
+			}
+			return
+		}
+		var out []byte
+		msgStart := time.Now()
+		switch {
+		case kind == websocket.TextMessage && string(p) == "end":
+			out, err = s.end()
+		case kind == websocket.TextMessage:
+			err = errors.New("unknown command " + string(p))
+		default:
+			out, err = s.write(p)
+		}
+		count.add(s.r)
+		observeConversion("stream", msgStart, err)
+		if err != nil {
+			closeWith(conn, websocket.CloseUnsupportedData, err)
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err = conn.WriteMessage(websocket.BinaryMessage, out); err != nil {
+			debugf("%s %s: %s", req.RemoteAddr, req.URL, err)
+			return
+		}
+		messages++
+		if kind == websocket.TextMessage {
+			debugf("%s %s: %d messages in %s", req.RemoteAddr, req.URL, messages, time.Since(start).Round(time.Millisecond))
+			closeWith(conn, websocket.CloseNormalClosure, nil)
+			return
+		}
+	}
+}
+
+// closeWith sends a close message with code and the text of err.
+func closeWith(conn *websocket.Conn, code int, err error) {
+	var text string
+	if err != nil {
+		text = err.Error()
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(writeTimeout))
+}
+
+// stream resamples the messages of a WebSocket.
+type stream struct {
+	r         *resample.Resampler
+	out       bytes.Buffer // output of the current message
+	pending   []byte       // input too short for an output frame
+	frameSize int
+	ratio     float64
+}
+
+// newStream returns a stream doing the conversion c.
+func newStream(c *conversion) (*stream, error) {
+	outFormat := c.outFormat
+	if outFormat < 0 {
+		outFormat = c.inFormat
+	}
+	s := &stream{frameSize: c.channels * resample.FormatSize(c.inFormat), ratio: c.outRate / c.inRate}
+	var err error
+	s.r, err = resample.New(&s.out, c.inRate, c.outRate, c.channels, c.inFormat, outFormat, c.quality, resample.WithThreads(*threads))
+	return s, err
+}
+
+// write resamples the data of a message and returns the output.
+func (s *stream) write(p []byte) ([]byte, error) {
+	if len(p)%s.frameSize != 0 {
+		return nil, errors.New("message with an incomplete frame")
+	}
+	s.out.Reset()
+	s.pending = append(s.pending, p...)
+	// Keep messages too short for an output frame for the next one.
+	if float64(len(s.pending)/s.frameSize)*s.ratio < 1 {
+		return nil, nil
+	}
+	_, err := s.r.Write(s.pending)
+	s.pending = s.pending[:0]
+	return s.out.Bytes(), err
+}
+
+// end flushes the resampler and returns the remaining output.
+func (s *stream) end() ([]byte, error) {
+	s.out.Reset()
+	// The pending input is shorter than an output frame and dropped.
+	err := s.r.Close()
+	return s.out.Bytes(), err
+}
@@ -0,0 +1,106 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestHandleStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handleStream))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?or=16000&ir=8000&ch=1", nil)
+	if err != nil {
+		t.Fatal("Dial failed:", err)
+	}
+	defer conn.Close()
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.3, 8000, 8000), 1, testsignal.I16)
+	var frames int
+	for p := in; len(p) > 0; p = p[1600:] {
+		if err = conn.WriteMessage(websocket.BinaryMessage, p[:1600]); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if _, out, err := conn.ReadMessage(); err != nil {
+			t.Fatal("Read failed:", err)
+		} else {
+			frames += len(out) / 2
+		}
+	}
+	if err = conn.WriteMessage(websocket.TextMessage, []byte("end")); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	kind, out, err := conn.ReadMessage()
+	if err != nil || kind != websocket.BinaryMessage {
+		t.Fatalf("Last message of type %d: %v", kind, err)
+	}
+	if frames += len(out) / 2; math.Abs(float64(frames-16000)) > 100 {
+		t.Errorf("%d frames out, expected about 16000", frames)
+	}
+	if _, _, err = conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Stream ended with %v, expected a normal closure", err)
+	}
+
+	// Unknown commands and incomplete frames close the stream.
+	for _, msg := range []struct {
+		kind int
+		p    []byte
+	}{
+		{websocket.TextMessage, []byte("flush")},
+		{websocket.BinaryMessage, []byte{1, 2, 3}},
+	} {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?or=16000&ir=8000&ch=1", nil)
+		if err != nil {
+			t.Fatal("Dial failed:", err)
+		}
+		conn.WriteMessage(msg.kind, msg.p)
+		if _, _, err = conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseUnsupportedData) {
+			t.Errorf("Message %q closed the stream with %v", msg.p, err)
+		}
+		conn.Close()
+	}
+
+	// A message over -max-message closes the stream.
+	defer func(n int64) { *maxMessage = n }(*maxMessage)
+	*maxMessage = 1000
+	if conn, _, err = websocket.DefaultDialer.Dial(url+"?or=16000&ir=8000&ch=1", nil); err != nil {
+		t.Fatal("Dial failed:", err)
+	}
+	conn.WriteMessage(websocket.BinaryMessage, in[:1002])
+	if _, _, err = conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("Message too large closed the stream with %v", err)
+	}
+	conn.Close()
+
+	for _, query := range []string{
+		"ir=8000&ch=1",
+		"or=16000",
+		"or=16000&ch=1",
+		"or=16000&ir=8000&ch=1&of=x",
+		"or=1e9&ir=8000&ch=1",
+		"or=16000&ir=1e6&ch=1",
+		"or=16000&ir=20&ch=1",
+	} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+"?"+query, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: error %v", query, err)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,222 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zaf/resample"
+)
+
+// audioExts are the file extensions converted in recursive mode.
+var audioExts = map[string]bool{".wav": true, ".w64": true, ".raw": true, ".pcm": true}
+
+// job is a single conversion. Several inputs are concatenated.
+type job struct {
+	inputs []string
+	output string
+}
+
+// name returns the input file names of the job for messages.
+func (j job) name() string {
+	return strings.Join(j.inputs, "+")
+}
+
+// sources returns the inputs read by the job, repeated -loop times.
+func (j job) sources() []string {
+	var names []string
+	for i := 0; i < *loop; i++ {
+		names = append(names, j.inputs...)
+	}
+	return names
+}
+
+// jobList returns the conversions requested by the command line arguments:
+// either input files concatenated to one output file, or input files
+// followed by an output directory. Inputs containing glob patterns are
+// expanded.
+func jobList(args []string) ([]job, error) {
+	if *outTemplate != "" {
+		return templateJobs(args)
+	}
+	files, err := expandInputs(args[:len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	dst := args[len(args)-1]
+	if *recursive {
+		return walkJobs(files, dst)
+	}
+	if !isDir(dst) {
+		if strings.HasSuffix(dst, string(filepath.Separator)) {
+			return nil, fmt.Errorf("output directory %s doesn't exist", dst)
+		}
+		return []job{{files, dst}}, nil
+	}
+	jobs := make([]job, len(files))
+	for i, f := range files {
+		if f == "-" {
+			return nil, errors.New("standard input can't be converted into a directory")
+		}
+		jobs[i] = job{[]string{f}, filepath.Join(dst, filepath.Base(f))}
+	}
+	return jobs, nil
+}
+
+// runJobs runs the conversion of jobs on -j parallel workers, each
+// reporting its progress to p, and passes the results to done, called
+// by one worker at a time. Dry runs are done in order.
+func runJobs(jobs []job, p *progress, run func(job, *result, *progress) error, done func(job, *result, error)) {
+	n := *workers
+	if n > len(jobs) {
+		n = len(jobs)
+	}
+	if n <= 1 || *dry {
+		for _, j := range jobs {
+			var r result
+			done(j, &r, run(j, &r, p))
+		}
+		return
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan job)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(p *progress) {
+			defer wg.Done()
+			for j := range queue {
+				var r result
+				err := run(j, &r, p)
+				mu.Lock()
+				done(j, &r, err)
+				mu.Unlock()
+			}
+		}(p.worker())
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// templateJobs returns a conversion for every input when output names
+// come from the -o template. Their output is named once the input is opened.
+func templateJobs(args []string) ([]job, error) {
+	files, err := expandInputs(args)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []job
+	if *recursive {
+		if jobs, err = walkJobs(files, ""); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, f := range files {
+			jobs = append(jobs, job{inputs: []string{f}})
+		}
+	}
+	for i := range jobs {
+		jobs[i].output = ""
+	}
+	return jobs, nil
+}
+
+// expandInputs expands the glob patterns of inputs.
+func expandInputs(inputs []string) ([]string, error) {
+	var files []string
+	for _, in := range inputs {
+		if in == "-" {
+			files = append(files, in)
+			continue
+		}
+		matches, err := filepath.Glob(in)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in, err)
+		}
+		if matches == nil {
+			// Not a pattern, or nothing matching. Let opening it report the error.
+			matches = []string{in}
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// outputName returns the output file name of j, expanding the -o template
+// when set. The template fields are {dir}, {name} and {ext}, the directory,
+// name without extension and extension of the input, and {rate}, {format}
+// and {channels} of the output.
+func outputName(j job, in *input, s *settings) string {
+	if *outTemplate == "" {
+		return j.output
+	}
+	src := j.inputs[0]
+	ext := filepath.Ext(src)
+	name := strings.TrimSuffix(filepath.Base(src), ext)
+	if src == "-" {
+		name, ext = "stdin", ""
+	}
+	return strings.NewReplacer(
+		"{dir}", filepath.Dir(src),
+		"{name}", name,
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{rate}", strconv.FormatFloat(s.rate, 'f', -1, 64),
+		"{format}", resample.FormatName(s.format),
+		"{channels}", strconv.Itoa(s.channels),
+	).Replace(*outTemplate)
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// walkJobs returns a conversion for every audio file found under the input
+// directories, mirroring their structure under the dst directory. Input
+// files are converted into dst directly.
+func walkJobs(inputs []string, dst string) ([]job, error) {
+	var jobs []job
+	for _, in := range inputs {
+		if in == "-" {
+			return nil, errors.New("standard input can't be used in recursive mode")
+		}
+		if !isDir(in) {
+			jobs = append(jobs, job{[]string{in}, filepath.Join(dst, filepath.Base(in))})
+			continue
+		}
+		err := filepath.WalkDir(in, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !audioExts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			rel, err := filepath.Rel(in, path)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job{[]string{path}, filepath.Join(dst, rel)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
@@ -0,0 +1,91 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zaf/resample"
+)
+
+func TestJobList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.wav", "b.wav", "sub/c.wav", "sub/notes.txt"} {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0o755)
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	a, b, c := filepath.Join(dir, "a.wav"), filepath.Join(dir, "b.wav"), filepath.Join(dir, "sub", "c.wav")
+
+	for _, tc := range []struct {
+		name      string
+		args      []string
+		recursive bool
+		template  string
+		jobs      []job
+	}{
+		{"concatenation", []string{a, b, "out.wav"}, false, "", []job{{[]string{a, b}, "out.wav"}}},
+		{"glob", []string{filepath.Join(dir, "*.wav"), "out.wav"}, false, "", []job{{[]string{a, b}, "out.wav"}}},
+		{"directory", []string{a, b, out}, false, "", []job{{[]string{a}, filepath.Join(out, "a.wav")}, {[]string{b}, filepath.Join(out, "b.wav")}}},
+		{"recursive", []string{dir, out}, true, "", []job{
+			{[]string{a}, filepath.Join(out, "a.wav")},
+			{[]string{b}, filepath.Join(out, "b.wav")},
+			{[]string{c}, filepath.Join(out, "sub", "c.wav")},
+		}},
+		{"template", []string{a, b}, false, "{name}.raw", []job{{inputs: []string{a}}, {inputs: []string{b}}}},
+	} {
+		setFlag(t, recursive, tc.recursive)
+		setFlag(t, outTemplate, tc.template)
+		jobs, err := jobList(tc.args)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(jobs, tc.jobs) {
+			t.Errorf("%s: jobs %v, expected %v", tc.name, jobs, tc.jobs)
+		}
+	}
+
+	setFlag(t, outTemplate, "")
+	setFlag(t, recursive, false)
+	for _, args := range [][]string{
+		{"-", out},
+		{a, filepath.Join(dir, "missing") + string(filepath.Separator)},
+	} {
+		if _, err := jobList(args); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+	setFlag(t, recursive, true)
+	if _, err := jobList([]string{"-", out}); err == nil {
+		t.Error("Recursive standard input: no error")
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	s := &settings{format: resample.F32, rate: 16000, channels: 1}
+	j := job{[]string{filepath.Join("in", "song.wav")}, "out.wav"}
+	if name := outputName(j, nil, s); name != "out.wav" {
+		t.Errorf("Output name %s without a template", name)
+	}
+	setFlag(t, outTemplate, "{dir}/{name}-{rate}hz-{format}-{channels}ch.{ext}")
+	if name, want := outputName(j, nil, s), "in/song-16000hz-f32-1ch.wav"; name != want {
+		t.Errorf("Output name %s, expected %s", name, want)
+	}
+	j.inputs[0] = "-"
+	if name := outputName(j, nil, s); name != "./stdin-16000hz-f32-1ch." {
+		t.Errorf("Output name of standard input %s", name)
+	}
+}
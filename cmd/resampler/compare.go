@@ -0,0 +1,199 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/zaf/resample"
+)
+
+// alignFrames is the number of frames from the start of the files used
+// to find their time offset.
+const alignFrames = 1 << 18
+
+// comparison holds the result of -compare.
+type comparison struct {
+	Reference stream  `json:"reference"`
+	Test      stream  `json:"test"`
+	Offset    int64   `json:"offset"`    // frames the test file is delayed by
+	Compared  int64   `json:"compared"`  // frames compared
+	SNR       float64 `json:"snr"`       // dB, +Inf for identical signals
+	RMSDiff   float64 `json:"rms_diff"`  // dBFS
+	PeakDiff  float64 `json:"peak_diff"` // dBFS
+}
+
+// compareFiles time-aligns the test file to the reference file and prints
+// the signal to noise ratio of the test file and the level of their difference.
+func compareFiles(refName, testName string) error {
+	ref, refIn, err := readSamples(refName)
+	if err != nil {
+		return err
+	}
+	test, testIn, err := readSamples(testName)
+	if err != nil {
+		return err
+	}
+	if refIn.rate != testIn.rate || refIn.channels != testIn.channels {
+		return usageError(fmt.Errorf("%s (%g Hz %d channels) can't be compared to %s (%g Hz %d channels)",
+			testName, testIn.rate, testIn.channels, refName, refIn.rate, refIn.channels))
+	}
+	c := comparison{
+		Reference: stream{File: refName, Rate: refIn.rate, Channels: refIn.channels, Format: resample.FormatName(refIn.format), Frames: int64(len(ref) / refIn.channels)},
+		Test:      stream{File: testName, Rate: testIn.rate, Channels: testIn.channels, Format: resample.FormatName(testIn.format), Frames: int64(len(test) / testIn.channels)},
+	}
+	channels := refIn.channels
+	c.Offset = alignment(ref, test, channels, int(refIn.rate))
+	refStart, testStart := int64(0), c.Offset
+	if c.Offset < 0 {
+		refStart, testStart = -c.Offset, 0
+	}
+	c.Compared = c.Reference.Frames - refStart
+	if n := c.Test.Frames - testStart; n < c.Compared {
+		c.Compared = n
+	}
+	if c.Compared <= 0 {
+		return errors.New("the files don't overlap")
+	}
+	var signal, noise, peak float64
+	ref = ref[refStart*int64(channels) : (refStart+c.Compared)*int64(channels)]
+	test = test[testStart*int64(channels):]
+	for i, v := range ref {
+		d := test[i] - v
+		signal += v * v
+		noise += d * d
+		peak = math.Max(peak, math.Abs(d))
+	}
+	c.SNR = 10 * math.Log10(signal/noise)
+	c.RMSDiff = 10 * math.Log10(noise/float64(len(ref)))
+	c.PeakDiff = 20 * math.Log10(peak)
+	if *jsonOut {
+		// JSON has no infinities, identical signals are reported with null levels.
+		b, _ := json.Marshal(struct {
+			comparison
+			SNR      *float64 `json:"snr"`
+			RMSDiff  *float64 `json:"rms_diff"`
+			PeakDiff *float64 `json:"peak_diff"`
+		}{c, finite(c.SNR), finite(c.RMSDiff), finite(c.PeakDiff)})
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("Reference: %s (%g Hz %s %d channels, %d frames)\n", refName, c.Reference.Rate, c.Reference.Format, channels, c.Reference.Frames)
+	fmt.Printf("Test:      %s (%g Hz %s %d channels, %d frames)\n", testName, c.Test.Rate, c.Test.Format, channels, c.Test.Frames)
+	fmt.Printf("Offset:    %d frames (%.3f ms)\n", c.Offset, 1000*float64(c.Offset)/refIn.rate)
+	fmt.Printf("Compared:  %d frames\n", c.Compared)
+	fmt.Printf("SNR:       %.2f dB\n", c.SNR)
+	fmt.Printf("RMS diff:  %.2f dBFS\n", c.RMSDiff)
+	fmt.Printf("Peak diff: %.2f dBFS\n", c.PeakDiff)
+	return nil
+}
+
+// finite returns a pointer to v, or nil if v is infinite or NaN.
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+// alignment returns the delay in frames of test relative to ref, up to a
+// second either way, found as the peak of the cross-correlation of the
+// start of their channel sums.
+func alignment(ref, test []float64, channels, maxLag int) int64 {
+	refLen, testLen := len(ref)/channels, len(test)/channels
+	if refLen > alignFrames {
+		refLen = alignFrames
+	}
+	if testLen > alignFrames {
+		testLen = alignFrames
+	}
+	n := nextPow2(refLen + testLen)
+	r, t := make([]complex128, n), make([]complex128, n)
+	for i := 0; i < refLen*channels; i++ {
+		r[i/channels] += complex(ref[i], 0)
+	}
+	for i := 0; i < testLen*channels; i++ {
+		t[i/channels] += complex(test[i], 0)
+	}
+	fft(r, false)
+	fft(t, false)
+	for i := range t {
+		t[i] *= complex(real(r[i]), -imag(r[i]))
+	}
+	fft(t, true)
+	best, lag := math.Inf(-1), 0
+	for k := -maxLag; k <= maxLag; k++ {
+		if k <= -refLen || k >= testLen {
+			continue
+		}
+		if v := real(t[(k+n)%n]); v > best {
+			best, lag = v, k
+		}
+	}
+	return int64(lag)
+}
+
+// readSamples reads the whole sample data of name, or of standard input
+// when name is "-", as float64 values in the [-1, 1) range.
+func readSamples(name string) ([]float64, *input, error) {
+	in, err := openInput(name, &progress{})
+	if err != nil {
+		return nil, nil, inputError(err)
+	}
+	defer in.Close()
+	order, err := byteOrder("ie", *inEndian)
+	if err != nil {
+		return nil, nil, usageError(err)
+	}
+	if in.header != nil {
+		order = binary.LittleEndian
+	}
+	p, err := io.ReadAll(in.data)
+	if err != nil {
+		return nil, nil, inputError(err)
+	}
+	p = p[:len(p)-len(p)%in.frameSize()]
+	s, err := decodeSamples(p, in.format, order)
+	if err != nil {
+		return nil, nil, usageError(fmt.Errorf("%s: %w", name, err))
+	}
+	return s, in, nil
+}
+
+// decodeSamples decodes samples of format in the given byte order to
+// float64 values in the [-1, 1) range.
+func decodeSamples(p []byte, format int, order binary.ByteOrder) ([]float64, error) {
+	s := make([]float64, len(p)/resample.FormatSize(format))
+	switch format {
+	case resample.I16:
+		for i := range s {
+			s[i] = float64(int16(order.Uint16(p[2*i:]))) / (1 << 15)
+		}
+	case resample.I32:
+		for i := range s {
+			s[i] = float64(int32(order.Uint32(p[4*i:]))) / (1 << 31)
+		}
+	case resample.F32:
+		for i := range s {
+			s[i] = float64(math.Float32frombits(order.Uint32(p[4*i:])))
+		}
+	case resample.F64:
+		for i := range s {
+			s[i] = math.Float64frombits(order.Uint64(p[8*i:]))
+		}
+	default:
+		return nil, fmt.Errorf("%s samples can't be analyzed", resample.FormatName(format))
+	}
+	return s, nil
+}
@@ -0,0 +1,74 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestCompareFiles(t *testing.T) {
+	ref := testsignal.Sine(1000, 0.5, 8000, 8000)
+	// The test file is 10 frames late and 1% quieter: its difference to
+	// the reference is 40 dB below it.
+	test := make([]float64, 10, 8010)
+	for _, v := range ref {
+		test = append(test, 0.99*v)
+	}
+	refName := writeWAV(t, "ref.wav", ref, 8000, 2, testsignal.F32)
+	testName := writeWAV(t, "test.wav", test, 8000, 2, testsignal.F32)
+
+	setFlag(t, jsonOut, true)
+	out, err := captureStdout(t, func() error { return compareFiles(refName, testName) })
+	if err != nil {
+		t.Fatal("compareFiles failed:", err)
+	}
+	var c struct {
+		comparison
+		SNR      *float64 `json:"snr"`
+		RMSDiff  *float64 `json:"rms_diff"`
+		PeakDiff *float64 `json:"peak_diff"`
+	}
+	if err = json.Unmarshal([]byte(out), &c); err != nil || c.SNR == nil || c.RMSDiff == nil || c.PeakDiff == nil {
+		t.Fatalf("Invalid JSON %q: %v", out, err)
+	}
+	if c.Offset != 10 || c.Compared != 8000 || c.Reference.Frames != 8000 || c.Test.Frames != 8010 || c.Test.Channels != 2 {
+		t.Errorf("Offset %d, %d frames compared of %d and %d", c.Offset, c.Compared, c.Reference.Frames, c.Test.Frames)
+	}
+	for _, v := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"SNR", *c.SNR, 40},
+		{"RMS diff", *c.RMSDiff, 20 * math.Log10(0.01*0.5/math.Sqrt2)},
+		{"Peak diff", *c.PeakDiff, 20 * math.Log10(0.01*0.5)},
+	} {
+		if math.Abs(v.got-v.want) > 0.05 {
+			t.Errorf("%s %.3f dB, expecting %.3f dB", v.name, v.got, v.want)
+		}
+	}
+
+	// Identical files have no difference.
+	setFlag(t, jsonOut, false)
+	if out, err = captureStdout(t, func() error { return compareFiles(refName, refName) }); err != nil {
+		t.Fatal("compareFiles failed:", err)
+	}
+	if !strings.Contains(out, "Offset:    0 frames") || !strings.Contains(out, "SNR:       +Inf dB") {
+		t.Errorf("Comparison of a file to itself:\n%s", out)
+	}
+
+	other := writeWAV(t, "other.wav", ref, 16000, 2, testsignal.F32)
+	if err = compareFiles(refName, other); exitCode(err) != exitUsage {
+		t.Errorf("Comparison of different rates returned %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zaf/resample"
+)
+
+// openInputs opens the named inputs as a single input reading the sample
+// data of each of them in turn. All inputs must have the parameters of
+// the first one.
+func openInputs(names []string, p *progress) (*input, error) {
+	first, err := openInput(names[0], p)
+	if err != nil || len(names) == 1 {
+		return first, err
+	}
+	c := &concatReader{cur: first, names: names[1:], p: p}
+	in := *first
+	in.data, in.file, in.closer = c, nil, c
+	return &in, nil
+}
+
+// concatReader reads the sample data of several inputs, opening each one
+// when the previous is exhausted.
+type concatReader struct {
+	cur   *input   // input being read, nil after the last one
+	names []string // inputs left to open
+	p     *progress
+}
+
+func (c *concatReader) Read(b []byte) (int, error) {
+	for c.cur != nil {
+		n, err := c.cur.data.Read(b)
+		if err != io.EOF {
+			return n, err
+		}
+		if err = c.next(); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, io.EOF
+}
+
+// next closes the current input and opens the following one.
+func (c *concatReader) next() error {
+	prev := c.cur
+	prev.Close()
+	c.cur = nil
+	if len(c.names) == 0 {
+		return nil
+	}
+	name := c.names[0]
+	c.names = c.names[1:]
+	in, err := openInput(name, c.p)
+	if err != nil {
+		return err
+	}
+	c.cur = in
+	if in.rate != prev.rate || in.channels != prev.channels || in.format != prev.format {
+		return usageError(fmt.Errorf("%s: %g Hz %s %d channels can't be joined to %g Hz %s %d channels", name, in.rate, resample.FormatName(in.format), in.channels,
+			prev.rate, resample.FormatName(prev.format), prev.channels))
+	}
+	return nil
+}
+
+// Close closes the input being read.
+func (c *concatReader) Close() error {
+	if c.cur == nil {
+		return nil
+	}
+	err := c.cur.Close()
+	c.cur = nil
+	return err
+}
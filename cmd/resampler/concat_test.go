@@ -0,0 +1,73 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestConcat(t *testing.T) {
+	setFlag(t, or, 16000)
+	setFlag(t, quiet, true)
+	x := testsignal.Sine(440, 0.5, 8000, 12000)
+	whole := writeWAV(t, "whole.wav", x, 8000, 2, testsignal.F32)
+	parts := []string{
+		writeWAV(t, "a.wav", x[:3001], 8000, 2, testsignal.F32),
+		writeWAV(t, "b.wav", x[3001:3002], 8000, 2, testsignal.F32),
+		writeWAV(t, "c.wav", x[3002:], 8000, 2, testsignal.F32),
+	}
+	dir := t.TempDir()
+
+	// The joined inputs convert as one: the output has the length of
+	// the conversion of the whole signal, without gaps at the joins.
+	var r, rw result
+	out := filepath.Join(dir, "joined.wav")
+	if err := convertFile(job{parts, out}, &r, &progress{}); err != nil {
+		t.Fatal("convertFile failed:", err)
+	}
+	if err := convertFile(job{[]string{whole}, filepath.Join(dir, "whole.wav")}, &rw, &progress{}); err != nil {
+		t.Fatal("convertFile failed:", err)
+	}
+	h, joined := readWAV(t, out)
+	_, want := readWAV(t, filepath.Join(dir, "whole.wav"))
+	if r.Input.Frames != 12000 || h.Frames() != r.Output.Frames || r.Output.Frames != rw.Output.Frames || r.Output.Frames < 23990 || r.Output.Frames > 24010 {
+		t.Errorf("%d frames in, %d frames out, %d in the file, %d for the whole input", r.Input.Frames, r.Output.Frames, h.Frames(), rw.Output.Frames)
+	}
+	if !bytes.Equal(joined, want) {
+		t.Error("The joined output differs from the conversion of the whole input")
+	}
+
+	// Inputs of other rates, channels or formats are rejected, without
+	// writing the output.
+	for _, tc := range []struct {
+		name   string
+		second string
+	}{
+		{"rate", writeWAV(t, "rate.wav", x, 16000, 2, testsignal.F32)},
+		{"channels", writeWAV(t, "mono.wav", x, 8000, 1, testsignal.F32)},
+		{"format", writeWAV(t, "i16.wav", x, 8000, 2, testsignal.I16)},
+	} {
+		out := filepath.Join(dir, tc.name+".wav")
+		err := convertFile(job{[]string{parts[0], tc.second}, out}, &result{}, &progress{})
+		if code := exitCode(err); code != exitUsage {
+			t.Errorf("Mismatched %s: error %v, exit code %d", tc.name, err, code)
+		}
+		if _, err = os.Stat(out); !os.IsNotExist(err) {
+			t.Errorf("Mismatched %s: output written: %v", tc.name, err)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(files) != 0 {
+		t.Errorf("Temporary files left: %q", files)
+	}
+}
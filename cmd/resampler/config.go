@@ -0,0 +1,132 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// loadConfig sets the flags that were not given on the command line from
+// the config file name. Each line holds a flag name, an equals sign and
+// its value: a number, true or false, or a string, bare or in double or
+// single quotes. Comments start with #. There are no tables or arrays.
+func loadConfig(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		key, value, err := parseConfigLine(sc.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if key == "" || cmdFlags[key] {
+			continue
+		}
+		fl := flag.Lookup(key)
+		if fl == nil || key == "config" {
+			return fmt.Errorf("%s:%d: unknown setting %s", name, line, key)
+		}
+		if err = flag.Set(key, value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value for %s: %w", name, line, key, err)
+		}
+	}
+	return sc.Err()
+}
+
+// presets are the settings of -preset, as flag names and values.
+var presets = map[string][][2]string{
+	// 8 kHz mono for VoIP, padded to whole 20 ms packets.
+	"telephony":      {{"or", "8000"}, {"mono", "true"}, {"iof", "i16"}, {"q", "medium"}, {"pad", "20ms"}},
+	"telephony-ulaw": {{"or", "8000"}, {"mono", "true"}, {"iof", "ulaw"}, {"q", "medium"}, {"pad", "20ms"}},
+	"telephony-alaw": {{"or", "8000"}, {"mono", "true"}, {"iof", "alaw"}, {"q", "medium"}, {"pad", "20ms"}},
+}
+
+// applyPreset sets the flags of the preset name that were not given on
+// the command line or in the config file.
+func applyPreset(name string) error {
+	preset, ok := presets[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for n := range presets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown preset %s, use one of %s", name, strings.Join(names, ", "))
+	}
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, kv := range preset {
+		if set[kv[0]] || kv[0] == "mono" && (set["stereo"] || set["remix"]) {
+			continue
+		}
+		if err := flag.Set(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseConfigLine returns the key and value of a config file line, or an
+// empty key for blank and comment lines.
+func parseConfigLine(line string) (string, string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", "", nil
+	}
+	if line[0] == '[' {
+		return "", "", errors.New("tables are not supported")
+	}
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", errors.New("missing =")
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if key == "" {
+		return "", "", errors.New("missing setting name")
+	}
+	var rest string
+	switch {
+	case strings.HasPrefix(value, `"`):
+		q, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return "", "", errors.New("unterminated string")
+		}
+		rest = value[len(q):]
+		value, _ = strconv.Unquote(q)
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("unterminated string")
+		}
+		rest = value[end+2:]
+		value = value[1 : end+1]
+	default:
+		value, _, _ = strings.Cut(value, "#")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "", "", errors.New("missing value")
+		}
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return "", "", fmt.Errorf("unexpected %q after the value", rest)
+	}
+	return key, value, nil
+}
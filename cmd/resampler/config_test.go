@@ -0,0 +1,112 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+)
+
+func TestParseConfigLine(t *testing.T) {
+	for _, tc := range []struct {
+		line, key, value string
+	}{
+		{"", "", ""},
+		{"   ", "", ""},
+		{"# comment", "", ""},
+		{"or = 16000", "or", "16000"},
+		{"  q=vhigh  ", "q", "vhigh"},
+		{"mono = true # downmix", "mono", "true"},
+		{`o = "{name}#{rate}.wav"`, "o", "{name}#{rate}.wav"},
+		{`o = "a \"b\""  # quoted`, "o", `a "b"`},
+		{"o = '{dir}/{name}.raw'", "o", "{dir}/{name}.raw"},
+		{"pad = '' ", "pad", ""},
+		{"gain = -3=x", "gain", "-3=x"},
+	} {
+		key, value, err := parseConfigLine(tc.line)
+		if err != nil || key != tc.key || value != tc.value {
+			t.Errorf("%q: %q = %q, %v", tc.line, key, value, err)
+		}
+	}
+	for _, line := range []string{
+		"[section]",
+		"or",
+		"= 16000",
+		"or =",
+		"or = # nothing",
+		`o = "unterminated`,
+		"o = 'unterminated",
+		`o = "a" b`,
+		"o = 'a' b",
+	} {
+		if _, _, err := parseConfigLine(line); err == nil {
+			t.Errorf("%q: no error", line)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	setFlag(t, &cmdFlags, map[string]bool{"or": true})
+	setFlag(t, or, 44100)
+	setFlag(t, ch, 2)
+	setFlag(t, ir, 44100)
+	setFlag(t, quality, "high")
+	setFlag(t, mono, false)
+	setFlag(t, outFormat, "")
+	setFlag(t, pad, "")
+	flag.Set("or", "44100")
+	name := filepath.Join(t.TempDir(), "resampler.conf")
+	conf := "# speech\nor = 16000\nch = 1\nir = 8000\nq = 'low'\n"
+	if err := os.WriteFile(name, []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(name); err != nil {
+		t.Fatal("loadConfig failed:", err)
+	}
+	if *or != 44100 || *ch != 1 || *ir != 8000 || *quality != "low" {
+		t.Errorf("Settings -or %d -ch %d -ir %d -q %s", *or, *ch, *ir, *quality)
+	}
+	// The config file overrides the preset.
+	if err := applyPreset("telephony"); err != nil {
+		t.Fatal("applyPreset failed:", err)
+	}
+	if *or != 44100 || *quality != "low" || !*mono || *outFormat != "i16" || *pad != "20ms" {
+		t.Errorf("Preset settings -or %d -q %s -mono %t -iof %s -pad %s", *or, *quality, *mono, *outFormat, *pad)
+	}
+	if err := applyPreset("radio"); err == nil {
+		t.Error("Unknown preset: no error")
+	}
+
+	// Only the raw input flags of the command line are checked against the
+	// WAV header.
+	in := &input{rate: 48000, channels: 2, format: resample.I16}
+	var r result
+	checkHeaderFlags(in, &r)
+	if len(r.Warnings) != 0 {
+		t.Errorf("Warnings of flags of the config file: %v", r.Warnings)
+	}
+	cmdFlags["ch"] = true
+	checkHeaderFlags(in, &r)
+	if len(r.Warnings) != 1 {
+		t.Errorf("Warnings of -ch: %v", r.Warnings)
+	}
+
+	for _, conf := range []string{"config = other.conf\n", "nosuchflag = 1\n", "ir = x\n", "[table]\n"} {
+		if err := os.WriteFile(name, []byte(conf), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadConfig(name); err == nil {
+			t.Errorf("%q: no error", conf)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// shapedDither maps the noise shaped -dither values to their setting.
+var shapedDither = map[string]int{
+	"light":     resample.DitherShapedLight,
+	"shaped":    resample.DitherShaped,
+	"fweighted": resample.DitherShapedF,
+}
+
+// settings are the output parameters resolved for an input.
+type settings struct {
+	format   int               // output format
+	rate     float64           // output sampling rate
+	quality  int               // resampling quality
+	channels int               // output channels
+	mask     uint32            // output channel mask
+	opts     []resample.Option // Resampler options
+}
+
+// resolve returns the output settings for in.
+func resolve(in *input) (*settings, error) {
+	s := &settings{format: resample.I16, rate: float64(*or), channels: in.channels, opts: []resample.Option{resample.WithThreads(*threads)}}
+	// Only WAV input sets the output format, RAW input was always
+	// converted to I16.
+	if in.header != nil {
+		s.format = in.format
+	}
+	if *convertOnly {
+		s.rate = in.rate
+	}
+	if *outFormat != "" {
+		format, err := resample.ParseFormat(*outFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output format: %w", err)
+		}
+		s.format = format
+	}
+	q, err := resample.ParseQuality(*quality)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quality: %w", err)
+	}
+	s.quality = q
+	if in.header != nil {
+		s.mask = in.header.ChannelMask
+	}
+	if *gain != 0 {
+		s.opts = append(s.opts, resample.WithGain(*gain))
+	}
+	if *determinism {
+		s.opts = append(s.opts, resample.WithDitherSeed(0))
+	}
+	inOrder, err := byteOrder("ie", *inEndian)
+	if err != nil {
+		return nil, err
+	}
+	outOrder, err := byteOrder("oe", *outEndian)
+	if err != nil {
+		return nil, err
+	}
+	if in.header != nil && inOrder == binary.BigEndian {
+		return nil, errors.New("-ie big only applies to RAW input, WAV files are little-endian")
+	}
+	s.opts = append(s.opts, resample.WithByteOrder(inOrder, outOrder))
+	switch strings.ToLower(*dither) {
+	case "tpdf":
+	case "none":
+		s.opts = append(s.opts, resample.WithDither(resample.DitherNone))
+	case "shaped", "light", "fweighted":
+		if s.format != resample.I16 {
+			return nil, errors.New("-dither " + *dither + " needs i16 output")
+		}
+		s.opts = append(s.opts, resample.WithDither(shapedDither[strings.ToLower(*dither)]))
+	default:
+		return nil, errors.New("-dither must be none, tpdf, shaped, light or fweighted")
+	}
+	mix, err := channelMix(in)
+	if err != nil {
+		return nil, err
+	}
+	if mix != nil {
+		s.channels, s.mask = len(mix.Matrix), mix.OutMask
+		s.opts = append(s.opts, resample.WithMix(*mix))
+	}
+	return s, nil
+}
+
+// sourceRate returns the rate the input is resampled from, its sampling
+// rate scaled by -speed. A faster speed shortens the output and raises
+// its pitch.
+func sourceRate(in *input) float64 {
+	return in.rate * *speed
+}
+
+// byteOrder parses the value of an endianness flag.
+func byteOrder(name, value string) (binary.ByteOrder, error) {
+	switch strings.ToLower(value) {
+	case "little", "le":
+		return binary.LittleEndian, nil
+	case "big", "be":
+		return binary.BigEndian, nil
+	}
+	return nil, fmt.Errorf("-%s must be big or little", name)
+}
+
+// dryRun prints the conversion done by j without writing anything.
+func dryRun(j job, r *result, _ *progress) error {
+	inputFile, outputFile := j.name(), j.output
+	r.Input.File = inputFile
+	input, err := openInputs(j.sources(), &progress{})
+	if err != nil {
+		return inputError(err)
+	}
+	defer input.Close()
+	r.setInput(input)
+	s, err := resolve(input)
+	if err != nil {
+		return usageError(err)
+	}
+	outputFile = outputName(j, input, s)
+	r.setOutput(outputFile, s)
+	container := "raw"
+	if isWAVOutput(outputFile) {
+		container = "wav"
+	}
+	kind := "raw"
+	if input.header != nil {
+		kind = "wav"
+	}
+	if *skipExisting && upToDate(j.inputs, firstOutput(outputFile)) {
+		r.Skipped = true
+		if !*jsonOut {
+			fmt.Printf("%s -> %s: up to date, skipped\n", inputFile, outputFile)
+		}
+		return nil
+	}
+	if _, err := os.Stat(firstOutput(outputFile)); err == nil && outputFile != "-" {
+		if !*force && !*skipExisting {
+			return outputError(fmt.Errorf("%s already exists, use -f to overwrite it", outputFile))
+		}
+		container += ", overwriting"
+	}
+	if *jsonOut {
+		return nil
+	}
+	if *segment != "" {
+		outputFile = segmentName(outputFile, 1) + "..."
+		container += ", " + *segment + " segments"
+	}
+	fmt.Printf("%s (%s %g Hz %s %d channels) -> %s (%s %g Hz %s %d channels)\n", inputFile, kind, input.rate, resample.FormatName(input.format), input.channels,
+		outputFile, container, s.rate, resample.FormatName(s.format), s.channels)
+	return nil
+}
+
+// convertFile resamples the inputs of j to its output file, recording
+// the conversion in r and reporting the reads to p.
+func convertFile(j job, r *result, p *progress) error {
+	inputFile, outputFile := j.name(), j.output
+	r.Input.File = inputFile
+	sources := j.sources()
+	if (*norm || *loudTarget != "") && slices.Contains(sources, "-") {
+		// The input is read twice, the standard input only once.
+		spool, err := spoolStdin()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(spool)
+		for i := range sources {
+			if sources[i] == "-" {
+				sources[i] = spool
+			}
+		}
+	}
+	// Open input file (WAV or RAW PCM)
+	input, err := openInputs(sources, p)
+	if err != nil {
+		return inputError(err)
+	}
+	defer input.Close()
+	r.setInput(input)
+	if input.header != nil {
+		checkHeaderFlags(input, r)
+		logHeader(inputFile, input.header)
+	}
+	if err = trimInput(input); err != nil {
+		return inputError(err)
+	}
+	s, err := resolve(input)
+	if err != nil {
+		return usageError(err)
+	}
+	outputFile = outputName(j, input, s)
+	r.setOutput(outputFile, s)
+	if *skipExisting && upToDate(j.inputs, firstOutput(outputFile)) {
+		r.Skipped = true
+		debugf("%s: %s is up to date, skipped", inputFile, outputFile)
+		return nil
+	}
+	if *norm {
+		if err = normalize(inputFile, sources, s); err != nil {
+			return err
+		}
+	}
+	if *loudTarget != "" {
+		if err = loudnorm(inputFile, sources, s, r); err != nil {
+			return err
+		}
+	}
+	if *recursive || *outTemplate != "" {
+		if err = os.MkdirAll(filepath.Dir(outputFile), 0o755); err != nil {
+			return outputError(err)
+		}
+	}
+	var meta []wav.Chunk
+	if *copyMeta && input.header != nil {
+		meta = resample.WAVMetadata(input.header.Chunks, s.rate/sourceRate(input))
+	}
+	output, err := createOutput(outputFile, s.format, s.channels, s.rate, s.mask, meta)
+	if err != nil {
+		return outputError(err)
+	}
+	var dst io.Writer = output
+	if *maxDuration != "" {
+		d, err := parseTime(*maxDuration)
+		if err != nil {
+			output.discard()
+			return usageError(fmt.Errorf("-duration: %w", err))
+		}
+		dst = &limitWriter{w: dst, n: timeFrames(d, s.rate) * int64(s.channels*resample.FormatSize(s.format))}
+	}
+	var padSize int64
+	if *pad != "" {
+		d, err := parseTime(*pad)
+		if err != nil {
+			output.discard()
+			return usageError(fmt.Errorf("-pad: %w", err))
+		}
+		if padSize = timeFrames(d, s.rate) * int64(s.channels*resample.FormatSize(s.format)); padSize <= 0 {
+			output.discard()
+			return usageError(errors.New("-pad is shorter than a frame"))
+		}
+	}
+	var trimmer *silenceTrimmer
+	if *trimSilence {
+		if trimmer, err = newSilenceTrimmer(dst, s, s.rate); err != nil {
+			output.discard()
+			return usageError(err)
+		}
+		dst = trimmer
+	}
+	// Create a Resampler
+	res, err := resample.New(dst, sourceRate(input), s.rate, input.channels, input.format, s.format, s.quality, s.opts...)
+	if err != nil {
+		output.discard()
+		return err
+	}
+
+	debugf("%s: %g Hz %s %d channels -> %s: %g Hz %s %d channels, quality %s", inputFile, input.rate, resample.FormatName(input.format), input.channels,
+		outputFile, s.rate, resample.FormatName(s.format), s.channels, qualityToStr(s.quality))
+	if *speed != 1 {
+		debugf("%s: speed %g, resampling from %g Hz", inputFile, *speed, sourceRate(input))
+	}
+
+	// Read input and pass it to the Resampler in chunks
+	stats := &chunkLogger{w: res, out: output, inFrame: input.frameSize(), outFrame: s.channels * resample.FormatSize(s.format)}
+	err = resample.CopyFrames(stats, inputReader{input.data}, input.frameSize())
+	// Close the Resampler and the output file. Clsoing the Resampler will flush any remaining data to the output file.
+	// If the Resampler is not closed before the output file, any remaining data will be lost.
+	if e := res.Close(); err == nil {
+		err = e
+	}
+	if err == nil && trimmer != nil {
+		err = trimmer.flush()
+	}
+	if err == nil && padSize > 0 {
+		err = writeSilence(output, s.format, (padSize-output.n%padSize)%padSize)
+	}
+	if err != nil {
+		output.discard()
+		return err
+	}
+	if *copyMeta && input.header != nil && output.wav != nil && len(j.sources()) == 1 {
+		// Metadata may follow the sample data, errors only mean there is none.
+		readChunks := wav.ReadChunks
+		if input.header.W64 {
+			readChunks = wav.ReadW64Chunks
+		}
+		if _, e := io.CopyN(io.Discard, input.rest, input.header.DataPad()); e == nil {
+			trailer, _ := readChunks(input.rest)
+			output.wav.Trailer = resample.WAVMetadata(trailer, s.rate/sourceRate(input))
+		}
+	}
+	if err = output.Close(); err == nil {
+		err = checkDigest(output, r)
+	}
+	if err == nil {
+		err = output.commit()
+	}
+	if err != nil {
+		output.discard()
+		return outputError(err)
+	}
+	r.Input.Frames = stats.framesIn
+	r.Output.Frames = output.n / int64(stats.outFrame)
+	r.Duration = float64(r.Output.Frames) / s.rate
+	if output.meter != nil {
+		r.Output.Peak = finite(20 * math.Log10(output.meter.peak))
+		r.Output.RMS = finite(output.meter.rms())
+		dc := output.meter.dc()
+		r.Output.DC = &dc
+		r.Output.Clips = output.meter.clips
+	}
+	debugf("%s: %d frames in, %d frames out", inputFile, r.Input.Frames, r.Output.Frames)
+	return nil
+}
+
+// firstOutput returns the name of the first file written to the output
+// name, its first segment with -segment.
+func firstOutput(name string) string {
+	if *segment != "" {
+		return segmentName(name, 1)
+	}
+	return name
+}
+
+// upToDate reports whether the output file exists and was modified after
+// all the inputs. Standard input and output are never up to date.
+func upToDate(inputs []string, output string) bool {
+	if output == "-" {
+		return false
+	}
+	fi, err := os.Stat(output)
+	if err != nil {
+		return false
+	}
+	for _, name := range inputs {
+		if name == "-" {
+			return false
+		}
+		in, err := os.Stat(name)
+		if err != nil || in.ModTime().After(fi.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// chunkLogger logs the frames going through every Write to the Resampler
+// when -v is set.
+type chunkLogger struct {
+	w        io.Writer
+	out      *output
+	inFrame  int   // input frame size
+	outFrame int   // output frame size
+	chunks   int   // number of writes
+	framesIn int64 // frames written
+	outBytes int64 // output at the end of the last write
+}
+
+func (c *chunkLogger) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.chunks++
+	c.framesIn += int64(n / c.inFrame)
+	debugf("chunk %d: %d frames in, %d frames out", c.chunks, n/c.inFrame, (c.out.n-c.outBytes)/int64(c.outFrame))
+	c.outBytes = c.out.n
+	return n, err
+}
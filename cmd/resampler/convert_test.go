@@ -0,0 +1,39 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+func TestResolveFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		in     *input
+		iof    string
+		format int
+	}{
+		{"RAW", &input{rate: 8000, channels: 1, format: resample.F32}, "", resample.I16},
+		{"RAW -iof", &input{rate: 8000, channels: 1, format: resample.F32}, "f64", resample.F64},
+		{"WAV", &input{rate: 8000, channels: 1, format: resample.F32, header: &wav.Header{}}, "", resample.F32},
+		{"WAV -iof", &input{rate: 8000, channels: 1, format: resample.F32, header: &wav.Header{}}, "i32", resample.I32},
+	} {
+		setFlag(t, outFormat, tc.iof)
+		s, err := resolve(tc.in)
+		if err != nil {
+			t.Fatalf("%s: resolve failed: %v", tc.name, err)
+		}
+		if s.format != tc.format {
+			t.Errorf("%s: output format %d, expecting %d", tc.name, s.format, tc.format)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// Exit codes. When several conversions fail the code of the first failure is used.
+const (
+	exitFailure = 1 // resampling failed
+	exitUsage   = 2 // invalid arguments
+	exitInput   = 3 // input can't be read or decoded
+	exitOutput  = 4 // output can't be written
+)
+
+// exitError is an error carrying the exit code it causes.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns err with an exit code, unless it is nil or already has one.
+func withCode(code int, err error) error {
+	var e *exitError
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	return &exitError{code, err}
+}
+
+func usageError(err error) error  { return withCode(exitUsage, err) }
+func inputError(err error) error  { return withCode(exitInput, err) }
+func outputError(err error) error { return withCode(exitOutput, err) }
+
+// exitCode returns the exit code caused by err.
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitFailure
+}
+
+// inputReader reads from r, its errors other than the end of the input
+// being input errors.
+type inputReader struct {
+	r io.Reader
+}
+
+func (i inputReader) Read(p []byte) (int, error) {
+	n, err := i.r.Read(p)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		err = inputError(err)
+	}
+	return n, err
+}
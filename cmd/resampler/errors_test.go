@@ -0,0 +1,58 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	err := errors.New("failure")
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{err, exitFailure},
+		{usageError(err), exitUsage},
+		{inputError(err), exitInput},
+		{outputError(err), exitOutput},
+		{fmt.Errorf("file: %w", inputError(err)), exitInput},
+		// The first code given is kept.
+		{outputError(inputError(err)), exitInput},
+	} {
+		if code := exitCode(tc.err); code != tc.code {
+			t.Errorf("%v: exit code %d, expected %d", tc.err, code, tc.code)
+		}
+	}
+	if usageError(nil) != nil {
+		t.Error("usageError(nil) isn't nil")
+	}
+
+	// Read errors of an inputReader are input errors, the end of the input isn't.
+	r := inputReader{&errReader{err}}
+	if _, err := r.Read(make([]byte, 1)); exitCode(err) != exitInput {
+		t.Errorf("Read error %v, exit code %d", err, exitCode(err))
+	}
+	r = inputReader{&errReader{io.EOF}}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read at the end returned %v", err)
+	}
+}
+
+// errReader fails every Read with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,192 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/zaf/resample/caf"
+	"github.com/zaf/resample/wav"
+)
+
+// fileInfo describes an audio file for -info.
+type fileInfo struct {
+	File          string   `json:"file"`
+	Container     string   `json:"container"`
+	Format        string   `json:"format"`
+	SampleRate    int      `json:"sample_rate"`
+	Channels      int      `json:"channels"`
+	Layout        []string `json:"layout,omitempty"`
+	BitsPerSample int      `json:"bits_per_sample"`
+	BlockAlign    int      `json:"block_align"`
+	DataSize      int64    `json:"data_size"`
+	Frames        int64    `json:"frames"`   // -1 if unknown
+	Duration      float64  `json:"duration"` // seconds, -1 if unknown
+	Chunks        []string `json:"chunks"`
+}
+
+// printInfo prints the header of the WAV, Wave64 or CAF file name.
+func printInfo(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return inputError(err)
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var info *fileInfo
+	if magic, _ := br.Peek(4); string(magic) == "caff" {
+		info, err = cafInfo(br)
+	} else {
+		info, err = wavInfo(br)
+	}
+	if err != nil {
+		return inputError(err)
+	}
+	info.File = name
+	if *jsonOut {
+		b, _ := json.Marshal(info)
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("File:        %s\n", info.File)
+	fmt.Printf("Container:   %s\n", info.Container)
+	fmt.Printf("Format:      %s\n", info.Format)
+	fmt.Printf("Sample rate: %d Hz\n", info.SampleRate)
+	fmt.Printf("Channels:    %d\n", info.Channels)
+	if info.Layout != nil {
+		fmt.Printf("Layout:      %s\n", strings.Join(info.Layout, " "))
+	}
+	fmt.Printf("Bit depth:   %d\n", info.BitsPerSample)
+	fmt.Printf("Block align: %d\n", info.BlockAlign)
+	if info.Frames < 0 {
+		fmt.Println("Duration:    unknown (streamed file)")
+	} else {
+		fmt.Printf("Duration:    %.3f s (%d frames)\n", info.Duration, info.Frames)
+	}
+	chunks := strings.Join(info.Chunks, ", ")
+	if chunks == "" {
+		chunks = "none"
+	}
+	fmt.Printf("Chunks:      %s\n", chunks)
+	return nil
+}
+
+// wavInfo reads the header of a WAV or Wave64 file from r.
+func wavInfo(r io.Reader) (*fileInfo, error) {
+	h, err := wav.ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	info := &fileInfo{
+		Container:     "WAV",
+		Format:        tagName(h.Tag),
+		SampleRate:    h.SampleRate,
+		Channels:      h.Channels,
+		BitsPerSample: h.BitsPerSample,
+		BlockAlign:    h.BlockAlign,
+		DataSize:      h.DataSize,
+		Frames:        -1,
+		Duration:      -1,
+		Chunks:        []string{},
+	}
+	if h.W64 {
+		info.Container = "Wave64"
+	}
+	if h.ChannelMask != 0 {
+		for _, p := range h.Positions() {
+			info.Layout = append(info.Layout, wav.PositionName(p))
+		}
+	}
+	if h.DataSize < math.MaxUint32 || h.W64 && h.DataSize < math.MaxInt64 {
+		info.Frames = h.Frames()
+		if h.Tag == wav.FormatIMAADPCM {
+			info.Frames *= int64(h.SamplesPerBlock())
+		}
+		if h.SampleRate > 0 {
+			info.Duration = float64(info.Frames) / float64(h.SampleRate)
+		}
+	}
+	for _, c := range h.Chunks {
+		info.Chunks = append(info.Chunks, fmt.Sprintf("%s (%d bytes)", strings.TrimSpace(c.ID), len(c.Data)))
+	}
+	return info, nil
+}
+
+// cafInfo reads the header of a CAF file from r. The chunks other than
+// the audio description and data aren't kept by the caf package.
+func cafInfo(r io.Reader) (*fileInfo, error) {
+	h, err := caf.ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	info := &fileInfo{
+		Container:     "CAF",
+		Format:        cafFormatName(h.Format),
+		SampleRate:    int(h.SampleRate),
+		Channels:      h.Channels,
+		BitsPerSample: h.BitsPerChannel,
+		BlockAlign:    h.BytesPerPacket,
+		DataSize:      h.DataSize,
+		Frames:        -1,
+		Duration:      -1,
+		Chunks:        []string{},
+	}
+	if h.DataSize >= 0 && h.BytesPerPacket > 0 {
+		info.Frames = h.DataSize / int64(h.BytesPerPacket) * int64(h.FramesPerPacket)
+		if h.SampleRate > 0 {
+			info.Duration = float64(info.Frames) / h.SampleRate
+		}
+	}
+	return info, nil
+}
+
+// cafFormatName returns a description of a CAF audio format.
+func cafFormatName(f caf.Format) string {
+	var name string
+	switch f.FormatID {
+	case caf.LinearPCM:
+		name = "PCM"
+		if f.Float() {
+			name = "IEEE float"
+		}
+		if f.BitsPerChannel > 8 && !f.LittleEndian() {
+			name += ", big-endian"
+		}
+	case caf.MuLaw:
+		name = "µ-law"
+	case caf.ALaw:
+		name = "A-law"
+	default:
+		name = fmt.Sprintf("unknown (%q)", f.FormatID)
+	}
+	return name
+}
+
+// tagName returns a description of a WAV format tag.
+func tagName(tag uint16) string {
+	switch tag {
+	case wav.FormatPCM:
+		return "PCM"
+	case wav.FormatFloat:
+		return "IEEE float"
+	case wav.FormatALaw:
+		return "A-law"
+	case wav.FormatMuLaw:
+		return "µ-law"
+	case wav.FormatIMAADPCM:
+		return "IMA ADPCM"
+	}
+	return fmt.Sprintf("unknown (%#x)", tag)
+}
@@ -0,0 +1,130 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zaf/resample/caf"
+	"github.com/zaf/resample/wav"
+)
+
+// writeFixture writes the header made by newWriter and size bytes of
+// sample data to the file name of the test's temporary directory.
+func writeFixture(t *testing.T, name string, size int, newWriter func(io.Writer) (io.WriteCloser, error)) string {
+	t.Helper()
+	name = filepath.Join(t.TempDir(), name)
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := newWriter(f)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if _, err = w.Write(make([]byte, size)); err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return name
+}
+
+func TestPrintInfo(t *testing.T) {
+	stereo := wav.Format{Tag: wav.FormatPCM, Channels: 2, SampleRate: 16000, BitsPerSample: 16}
+	surround := wav.Format{Tag: wav.FormatFloat, Channels: 6, SampleRate: 48000, BitsPerSample: 32, ChannelMask: wav.DefaultChannelMask(6)}
+	list := wav.Chunk{ID: "LIST", Data: []byte("INFOISFT\x04\x00\x00\x00test")}
+	for _, tc := range []struct {
+		name string
+		want fileInfo
+		text []string // lines of the text output
+	}{
+		{
+			"../../testing/piano-16k-16-2.wav",
+			fileInfo{Container: "WAV", Format: "PCM", SampleRate: 16000, Channels: 2, BitsPerSample: 16, BlockAlign: 4, DataSize: 100904 * 4, Frames: 100904, Duration: 100904.0 / 16000, Chunks: []string{}},
+			[]string{"Container:   WAV", "Duration:    6.306 s (100904 frames)", "Chunks:      none"},
+		},
+		{
+			writeFixture(t, "stereo.wav", 32000, func(w io.Writer) (io.WriteCloser, error) { return wav.NewWriter(w, stereo, list) }),
+			fileInfo{Container: "WAV", Format: "PCM", SampleRate: 16000, Channels: 2, BitsPerSample: 16, BlockAlign: 4, DataSize: 32000, Frames: 8000, Duration: 0.5, Chunks: []string{"LIST (16 bytes)"}},
+			[]string{"Container:   WAV", "Format:      PCM", "Sample rate: 16000 Hz", "Channels:    2", "Bit depth:   16", "Block align: 4", "Duration:    0.500 s (8000 frames)", "Chunks:      LIST (16 bytes)"},
+		},
+		{
+			writeFixture(t, "surround.w64", 48000*24, func(w io.Writer) (io.WriteCloser, error) { return wav.NewW64Writer(w, surround) }),
+			fileInfo{Container: "Wave64", Format: "IEEE float", SampleRate: 48000, Channels: 6, Layout: []string{"FL", "FR", "FC", "LFE", "BL", "BR"}, BitsPerSample: 32, BlockAlign: 24, DataSize: 48000 * 24, Frames: 48000, Duration: 1, Chunks: []string{}},
+			[]string{"Container:   Wave64", "Format:      IEEE float", "Channels:    6", "Layout:      FL FR FC LFE BL BR", "Duration:    1.000 s (48000 frames)", "Chunks:      none"},
+		},
+		{
+			// Streamed WAV data of unknown size.
+			writeFixture(t, "streamed.wav", 400, func(w io.Writer) (io.WriteCloser, error) { return wav.NewWriter(struct{ io.Writer }{w}, stereo) }),
+			fileInfo{Container: "WAV", Format: "PCM", SampleRate: 16000, Channels: 2, BitsPerSample: 16, BlockAlign: 4, DataSize: 0xFFFFFFFF, Frames: -1, Duration: -1, Chunks: []string{}},
+			[]string{"Duration:    unknown (streamed file)"},
+		},
+		{
+			writeFixture(t, "mono.caf", 88200, func(w io.Writer) (io.WriteCloser, error) {
+				return caf.NewWriter(w, caf.Format{SampleRate: 44100, FormatID: caf.LinearPCM, BytesPerPacket: 2, FramesPerPacket: 1, Channels: 1, BitsPerChannel: 16})
+			}),
+			fileInfo{Container: "CAF", Format: "PCM, big-endian", SampleRate: 44100, Channels: 1, BitsPerSample: 16, BlockAlign: 2, DataSize: 88200, Frames: 44100, Duration: 1, Chunks: []string{}},
+			[]string{"Container:   CAF", "Format:      PCM, big-endian", "Sample rate: 44100 Hz", "Channels:    1", "Bit depth:   16", "Block align: 2", "Duration:    1.000 s (44100 frames)", "Chunks:      none"},
+		},
+		{
+			writeFixture(t, "float.caf", 8000*8, func(w io.Writer) (io.WriteCloser, error) {
+				return caf.NewWriter(w, caf.Format{SampleRate: 8000, FormatID: caf.LinearPCM, Flags: caf.FlagFloat | caf.FlagLittleEndian, BytesPerPacket: 8, FramesPerPacket: 1, Channels: 2, BitsPerChannel: 32})
+			}),
+			fileInfo{Container: "CAF", Format: "IEEE float", SampleRate: 8000, Channels: 2, BitsPerSample: 32, BlockAlign: 8, DataSize: 8000 * 8, Frames: 8000, Duration: 1, Chunks: []string{}},
+			[]string{"Format:      IEEE float", "Duration:    1.000 s (8000 frames)"},
+		},
+	} {
+		setFlag(t, jsonOut, true)
+		out, err := captureStdout(t, func() error { return printInfo(tc.name) })
+		if err != nil {
+			t.Fatalf("%s: printInfo failed: %v", tc.name, err)
+		}
+		var got fileInfo
+		if err = json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", tc.name, out, err)
+		}
+		tc.want.File = tc.name
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s:\n%+v\nexpecting\n%+v", tc.name, got, tc.want)
+		}
+
+		setFlag(t, jsonOut, false)
+		if out, err = captureStdout(t, func() error { return printInfo(tc.name) }); err != nil {
+			t.Fatalf("%s: printInfo failed: %v", tc.name, err)
+		}
+		lines := strings.Split(out, "\n")
+		if lines[0] != "File:        "+tc.name {
+			t.Errorf("%s: first line %q", tc.name, lines[0])
+		}
+		for _, l := range tc.text {
+			if !strings.Contains(out, l+"\n") {
+				t.Errorf("%s: missing %q in\n%s", tc.name, l, out)
+			}
+		}
+	}
+
+	// Other files are input errors.
+	raw := filepath.Join(t.TempDir(), "noise.raw")
+	os.WriteFile(raw, bytes.Repeat([]byte{1}, 100), 0o644)
+	for _, name := range []string{raw, filepath.Join(t.TempDir(), "missing.wav")} {
+		if err := printInfo(name); exitCode(err) != exitInput {
+			t.Errorf("%s: printInfo returned %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// input is an opened source of PCM sample data.
+type input struct {
+	data     io.Reader   // sample data
+	file     *os.File    // underlying file
+	rate     float64     // sampling rate
+	channels int         // number of channels
+	format   int         // resample format of the sample data
+	header   *wav.Header // WAV header, nil for raw input
+	rest     io.Reader   // what follows the data chunk of WAV input
+	prog     *progress   // progress report of the reads
+	closer   io.Closer   // closes the input instead of file when set
+}
+
+// openInput opens name, or standard input when name is "-", and reads its
+// WAV header if it has one. The input parameters of raw PCM files are
+// taken from the flags. Reading is reported to p.
+func openInput(name string, p *progress) (*input, error) {
+	if name == "-" {
+		p.startFile("stdin", -1)
+		in, err := newInput(&progressReader{os.Stdin, p})
+		if err != nil {
+			return nil, err
+		}
+		in.prog = p
+		return in, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	var size int64 = -1
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		size = fi.Size()
+	}
+	p.startFile(name, size)
+	in, err := newInput(&progressReader{f, p})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	in.file = f
+	in.prog = p
+	return in, nil
+}
+
+// newInput sniffs r for a WAV or Wave64 header.
+func newInput(r io.Reader) (*input, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	if string(magic) != "RIFF" && string(magic) != "riff" {
+		format, err := resample.ParseFormat(*inFormat)
+		if err != nil {
+			return nil, usageError(err)
+		}
+		if *ch < 1 {
+			return nil, usageError(errors.New("invalid channel number"))
+		}
+		if *ir <= 0 {
+			return nil, usageError(errors.New("invalid input sample rate"))
+		}
+		return &input{data: br, rate: float64(*ir), channels: *ch, format: format}, nil
+	}
+	h, err := wav.ReadHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	format, err := resample.WAVSampleFormat(h.Format)
+	if err != nil {
+		return nil, err
+	}
+	in := &input{
+		data:     io.LimitReader(br, h.DataSize),
+		rate:     float64(h.SampleRate),
+		channels: h.Channels,
+		format:   format,
+		header:   h,
+		rest:     br,
+	}
+	if h.Tag == wav.FormatIMAADPCM {
+		if in.data, err = wav.NewIMAReader(in.data, h.Format); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// Close completes the progress report of the input and closes its file.
+func (in *input) Close() error {
+	if in.closer != nil {
+		return in.closer.Close()
+	}
+	in.prog.endFile()
+	if in.file == nil {
+		return nil
+	}
+	return in.file.Close()
+}
+
+// frameSize returns the size in bytes of a frame of decoded input data.
+func (in *input) frameSize() int {
+	return in.channels * resample.FormatSize(in.format)
+}
@@ -0,0 +1,62 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/analysis"
+)
+
+// parseLoudness parses a loudness target in LUFS, with or without the unit.
+func parseLoudness(v string) (float64, error) {
+	s := strings.TrimSpace(v)
+	if len(s) > 4 && strings.EqualFold(s[len(s)-4:], "lufs") {
+		s = strings.TrimSpace(s[:len(s)-4])
+	}
+	l, err := strconv.ParseFloat(s, 64)
+	if err != nil || l >= 0 || math.IsInf(l, 0) {
+		return 0, fmt.Errorf("invalid loudness %q, use a negative level in LUFS such as -16LUFS", v)
+	}
+	return l, nil
+}
+
+// loudnorm adds to s the gain bringing the integrated loudness of the
+// output of the inputs to the -loudnorm target. The loudness is measured
+// by resampling the whole input in a first pass.
+func loudnorm(name string, inputs []string, s *settings, r *result) error {
+	target, err := parseLoudness(*loudTarget)
+	if err != nil {
+		return usageError(err)
+	}
+	m, err := analysis.NewMeter(analysis.F64, s.channels, s.rate)
+	if err != nil {
+		return err
+	}
+	m.SetChannelMask(s.mask)
+	if err = firstPass(inputs, s, m); err != nil {
+		return err
+	}
+	level := m.Loudness()
+	if math.IsInf(level, -1) {
+		debugf("%s: silent input, not normalized", name)
+		return nil
+	}
+	g := target - level
+	debugf("%s: integrated loudness %.1f LUFS, normalizing to %.1f LUFS", name, level, target)
+	if m.Peak()+g > 0 {
+		r.warn("%s: output peak at %.2f dBFS after loudness normalization, it will clip", name, m.Peak()+g)
+	}
+	s.opts = append(s.opts, resample.WithGain(*gain+g))
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample/analysis"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestParseLoudness(t *testing.T) {
+	for v, want := range map[string]float64{"-16": -16, "-23LUFS": -23, " -14.5 lufs ": -14.5} {
+		if l, err := parseLoudness(v); err != nil || l != want {
+			t.Errorf("%q: %g, %v", v, l, err)
+		}
+	}
+	for _, v := range []string{"", "LUFS", "0", "3LUFS", "-inf", "loud"} {
+		if _, err := parseLoudness(v); err == nil {
+			t.Errorf("Invalid loudness %q accepted", v)
+		}
+	}
+}
+
+func TestLoudnorm(t *testing.T) {
+	in := writeWAV(t, "in.wav", testsignal.PinkNoise(0.1, 3*44100, 1), 44100, 2, testsignal.F32)
+	setFlag(t, or, 48000)
+	setFlag(t, quiet, true)
+	for _, tc := range []struct {
+		target string
+		lufs   float64
+		clips  bool
+	}{
+		{"-23LUFS", -23, false},
+		{"-16", -16, false},
+		{"-3LUFS", -3, true},
+	} {
+		setFlag(t, loudTarget, tc.target)
+		out := filepath.Join(t.TempDir(), "out.wav")
+		var r result
+		if err := convertFile(job{[]string{in}, out}, &r, &progress{}); err != nil {
+			t.Fatalf("%s: convertFile failed: %v", tc.target, err)
+		}
+		h, samples := readWAV(t, out)
+		if h.SampleRate != 48000 || h.Channels != 2 {
+			t.Fatalf("%s: output %d Hz, %d channels", tc.target, h.SampleRate, h.Channels)
+		}
+		m, err := analysis.NewMeter(analysis.F32, h.Channels, float64(h.SampleRate))
+		if err != nil {
+			t.Fatal("NewMeter failed:", err)
+		}
+		m.Write(samples)
+		if l := m.Loudness(); math.Abs(l-tc.lufs) > 0.1 {
+			t.Errorf("%s: output loudness %.2f LUFS", tc.target, l)
+		}
+		if clips := len(r.Warnings) > 0; clips != tc.clips {
+			t.Errorf("%s: warnings %q", tc.target, r.Warnings)
+		}
+	}
+
+	// Silence isn't normalized.
+	setFlag(t, loudTarget, "-23LUFS")
+	in = writeWAV(t, "silence.wav", make([]float64, 44100), 44100, 2, testsignal.F32)
+	out := filepath.Join(t.TempDir(), "out.wav")
+	var r result
+	if err := convertFile(job{[]string{in}, out}, &r, &progress{}); err != nil {
+		t.Fatal("Silence: convertFile failed:", err)
+	}
+	_, samples := readWAV(t, out)
+	for i, b := range samples {
+		if b != 0 {
+			t.Fatalf("Silence: byte %d of the output is %d", i, b)
+		}
+	}
+	if len(samples) == 0 || len(r.Warnings) > 0 {
+		t.Errorf("Silence: %d bytes of output, warnings %q", len(samples), r.Warnings)
+	}
+}
@@ -8,101 +8,449 @@
 
 // The program takes as input a WAV or RAW PCM sound file
 // and resamples it to the desired sampling rate.
-// The output is RAW PCM data.
+// The output is RAW PCM data, or a WAV file when the -wav flag is
+// set or the output file name has a .wav extension.
 // Usage: goresample [flags] input_file output_file
 //
-// Example: go run main.go -ir 16000 -or 8000 ../../testing/piano-16k-16-2.wav 8k.raw
-
+// -convert-only keeps the sampling rate of each input instead of -or, to
+// only change the sample format, channels or gain. An -or equal to the
+// input rate does the same for a single rate.
+//
+// RAW PCM data is little-endian unless -ie or -oe are set to big.
+//
+// Use - as the input or output file name to read from standard input or
+// write to standard output.
+//
+// Several input files given with an output file are concatenated into
+// it, without gaps. They must have the same sampling rate, channels and
+// sample format.
+//
+// Batch mode: goresample [flags] input_file... output_dir
+// Several input files, or glob patterns, convert each file to a file of
+// the same name in the output directory. With -r input directories are
+// walked and the audio files (.wav, .w64, .raw and .pcm) found in them are
+// converted, mirroring the directory structure under the output directory.
+//
+// With -o all the arguments are inputs and output file names are made from
+// a template. {dir}, {name} and {ext} are replaced by the directory, the
+// name without extension and the extension of the input file, {rate},
+// {format} and {channels} by the output parameters. Missing directories
+// are created.
+//
+// -j converts several files in parallel, each with its own resampler using
+// a single thread unless -threads is set. The progress then shows the
+// files done and running and the total of the batch.
+//
+// Watch mode: goresample [flags] -watch input_dir output_dir
+// The audio files arriving in the input directory, and in its
+// subdirectories with -r, are converted to the output directory, or to
+// the -o template without output directory, once they stop growing: their
+// size and modification time have to stay the same for a second after the
+// last change notified by the system. Watching goes on until the program
+// is interrupted.
+// Files already there when watching starts are converted too, -skip-existing
+// leaves those with an up to date output alone.
+//
+// Existing output files are only replaced when -f is set, and only once
+// the new conversion has succeeded. -skip-existing skips the inputs whose
+// output exists and was modified after them, and replaces older outputs,
+// so an interrupted batch can be run again.
+//
+// -info prints the header fields, duration and chunks of the WAV, Wave64
+// or CAF files given as arguments without converting them.
+//
+// -compare takes a reference file and a test file of the same sampling
+// rate and channels, aligns them in time and prints the signal to noise
+// ratio of the test file and the RMS and peak level of their difference.
+//
+// -json prints a line of JSON per conversion on standard output, holding
+// the input and output parameters and frame counts, the output duration,
+// warnings and errors.
+//
+// Exit codes: 0 success, 1 resampling error, 2 invalid arguments, 3 input
+// read or decoding error, 4 output write error. When several conversions
+// fail the code of the first failure is used.
+//
+// Progress is displayed on standard error when it is a terminal, unless
+// -quiet is set. -v logs the conversion settings, the WAV header fields
+// and per chunk statistics instead. -n prints the conversions that would be
+// done, with the input and output parameters, and exits.
+//
+// -mono and -stereo mix the input channels using the speaker layout of WAV
+// files, -remix takes an explicit matrix: "0.5,0.5" mixes stereo to mono,
+// "0,1;1,0" swaps the channels of a stereo file. -gain applies a gain in dB,
+// computed in double precision before the output is quantized. -norm
+// resamples the input twice, first measuring the output peak and then
+// scaling it to the -peak level. Standard input is first copied to a
+// temporary file for it.
+// -loudnorm does the same with the integrated loudness of EBU R128, the
+// ITU-R BS.1770 measure in LUFS, bringing it to a target such as -16LUFS
+// for podcasts or -23LUFS for broadcast. It warns when the gain makes the
+// output clip.
+//
+// -ss and -t convert an excerpt of the input, skipping its start and
+// stopping after a duration measured in input time. -loop repeats the
+// inputs without gaps, -ss and -t then apply to the looped input, and
+// -duration caps the output duration.
+//
+// -speed plays the input faster or slower by resampling it as if it had
+// been recorded at its rate times the speed, keeping the -or output rate:
+// the tempo and the pitch change together. -speed 0.96 undoes the 25/24
+// PAL speedup of film audio, small variations around 1 augment speech
+// recognition training data.
+//
+// -trim-silence strips the leading and trailing silence of the output,
+// where all channels stay below the -silence-threshold level, leaving
+// -silence-keep of it around the sound.
+//
+// -segment splits the output into files of a fixed duration, the last one
+// holding what remains, numbered before the extension: out-001.wav,
+// out-002.wav... The conversion runs across the segments without gaps.
+//
+// -verify prints the digest of the output sample data, headers excluded,
+// in the format of sha256sum, so pipelines can detect nondeterministic or
+// truncated conversions. Given as sha256:digest the conversion fails when
+// the output doesn't match, and its file is deleted. With -json the
+// digest is in the output object instead.
+//
+// -deterministic makes the output byte identical across runs, for
+// reproducible dataset builds and golden files: the resampler runs single
+// threaded and the dither uses a fixed random seed.
+//
+// -stats prints a line of JSON at the end of the run with the number of
+// conversions, failed and skipped ones, the input and output frames and
+// output duration, the peak and RMS level of the output in dBFS, its DC
+// offset as the mean sample value, the number of clipped output samples
+// and the processing speed in seconds of output per second. The level is
+// not measured for G.711 output. It is printed on standard error when the
+// output goes to standard output.
+//
+// -play also sends the output to an audio player, to audition the result
+// while it is converted. Built with the oto tag, the command plays mono and
+// stereo output on the default audio device itself. Otherwise, and for the
+// output oto can't play, -play depends on an external tool: the first of
+// ffplay, paplay, aplay or the sox play command found in the PATH, or the
+// -player command, which reads a WAV stream from its standard input.
+//
+// -spectrogram saves a PNG image of the spectrum of the output over time,
+// with the frequency rising from 0 to half the output rate up the image
+// and levels from -120 dBFS, black, to 0 dBFS, white. It needs a single
+// output.
+//
+// -config reads settings from a file holding a flag name, an equals sign and
+// its value per line, so conversion profiles can be shared:
+//
+//	# 16 kHz mono for speech recognition
+//	or = 16000
+//	iof = "i16"
+//	mono = true
+//	q = "vhigh"
+//	o = "{dir}/16k/{name}.wav"
+//
+// Values are numbers, true or false, or strings, quoted when they hold a #
+// that isn't a comment. This is a flat key = value format, not TOML or
+// YAML: tables, arrays and multi-line strings aren't supported. Flags given
+// on the command line override the file.
+//
+// -preset applies a set of flags that the command line and the config file
+// override. telephony converts to 8 kHz mono i16 at medium quality, with
+// the output padded by -pad to whole 20 ms packets; telephony-ulaw and
+// telephony-alaw write G.711 µ-law and A-law instead.
+//
+// -copy-meta copies the metadata chunks of WAV inputs, such as bext, LIST,
+// cue, iXML or axml, to WAV outputs, cue points and the bext time reference
+// rescaled to the output rate. Only the metadata of the first input is
+// kept when inputs are concatenated.
+//
+// The sampling rate, channels and sample format of WAV files are read from
+// their header, the -ir, -ch and -if flags only describe RAW input. The
+// output keeps the sample format of WAV input unless -iof is set, RAW input
+// is converted to i16 by default whatever its -if.
+//
+// Example: go run main.go -or 8000 ../../testing/piano-16k-16-2.wav 8k.raw
 package main
 
 import (
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"math"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
 )
 
-const wavHeader = 44
-
 var (
-	inFormat  = flag.String("if", "i16", "PCM input format")
-	outFormat = flag.String("iof", "i16", "PCM output format")
-	ch        = flag.Int("ch", 2, "Number of channels")
-	ir        = flag.Int("ir", 44100, "Input sample rate")
-	or        = flag.Int("or", 0, "Output sample rate")
+	inFormat     = flag.String("if", "i16", "PCM input format")
+	outFormat    = flag.String("iof", "", "PCM output format (default i16 for RAW input, the format of WAV input)")
+	quality      = flag.String("q", "high", "Resampling quality: quick, low, medium, high, vhigh, or sincbest, sincmedium and sincfastest emulating libsamplerate")
+	inEndian     = flag.String("ie", "little", "Byte order of RAW input: big or little")
+	outEndian    = flag.String("oe", "little", "Byte order of RAW output: big or little")
+	ch           = flag.Int("ch", 2, "Number of channels")
+	ir           = flag.Int("ir", 44100, "Input sample rate")
+	or           = flag.Int("or", 0, "Output sample rate")
+	convertOnly  = flag.Bool("convert-only", false, "Keep the sample rate of the inputs, only converting the format, channels or gain")
+	wavOut       = flag.Bool("wav", false, "Write a WAV file (default when the output file ends in .wav)")
+	recursive    = flag.Bool("r", false, "Convert the audio files found in the input directories recursively")
+	quiet        = flag.Bool("quiet", false, "Don't display progress")
+	threads      = flag.Int("threads", 0, "Number of resampler threads (default one per CPU, one with -j)")
+	workers      = flag.Int("j", 1, "Number of files converted in parallel")
+	watchDir     = flag.String("watch", "", "Convert the audio files arriving in this directory, the only argument is the output directory")
+	verbose      = flag.Bool("v", false, "Log the conversion settings and statistics")
+	outTemplate  = flag.String("o", "", "Output file name template, e.g. {dir}/{name}-{rate}hz.{ext}, all arguments are then inputs")
+	preset       = flag.String("preset", "", "Apply a settings preset: telephony, telephony-ulaw or telephony-alaw")
+	pad          = flag.String("pad", "", "Pad the output with silence to a multiple of this duration, in the same formats as -ss")
+	determinism  = flag.Bool("deterministic", false, "Resample single threaded with a fixed dither seed, so the same input always gives the same output")
+	stats        = flag.Bool("stats", false, "Print the totals of the run as JSON at the end: frames, output peak and RMS level, DC offset, clipped samples and speed")
+	verify       = flag.String("verify", "", "Print a digest of the output samples: md5, sha1 or sha256, optionally followed by :digest to check it")
+	config       = flag.String("config", "", "Read settings from this file of key = value lines, flags given on the command line take precedence")
+	info         = flag.Bool("info", false, "Print the header of the WAV, Wave64 or CAF files given as arguments")
+	compare      = flag.Bool("compare", false, "Print the SNR of the second file given as argument against the first one")
+	jsonOut      = flag.Bool("json", false, "Print a JSON result object per conversion on standard output")
+	force        = flag.Bool("f", false, "Overwrite existing output files")
+	skipExisting = flag.Bool("skip-existing", false, "Skip inputs whose output is newer than them, replace older outputs")
+	dry          = flag.Bool("n", false, "Print the conversions that would be done without writing anything")
+	mono         = flag.Bool("mono", false, "Downmix to mono")
+	stereo       = flag.Bool("stereo", false, "Mix to stereo")
+	dither       = flag.String("dither", "tpdf", "Dither of integer output: none, tpdf, or noise shaped for i16 output: light, shaped or fweighted")
+	gain         = flag.Float64("gain", 0, "Gain in dB applied before quantization to the output format")
+	start        = flag.String("ss", "", "Skip the start of the input, in seconds, as a duration (1m30s) or as [hh:]mm:ss[.frac]")
+	length       = flag.String("t", "", "Stop after this much input, in the same formats as -ss")
+	maxDuration  = flag.String("duration", "", "Cap the output to this duration, in the same formats as -ss")
+	speed        = flag.Float64("speed", 1, "Speed factor, changing the tempo and pitch of the output")
+	loop         = flag.Int("loop", 1, "Read the inputs this many times in a row")
+	norm         = flag.Bool("norm", false, "Normalize the output to the -peak level, reading the input twice (standard input is copied to a temporary file)")
+	peak         = flag.Float64("peak", -1, "Target peak level of -norm in dBFS")
+	loudTarget   = flag.String("loudnorm", "", "Normalize the integrated loudness to this EBU R128 target, such as -16LUFS")
+	play         = flag.Bool("play", false, "Also play the output on the default audio device")
+	playCmd      = flag.String("player", "", "Playback command of -play reading a WAV stream from standard input, instead of the audio device of the oto build tag (default ffplay, paplay, aplay or sox, the first found)")
+	specOut      = flag.String("spectrogram", "", "Save a spectrogram of the output to this PNG file")
+	trimSilence  = flag.Bool("trim-silence", false, "Strip the leading and trailing silence of the output")
+	silenceLevel = flag.Float64("silence-threshold", -60, "Level in dBFS below which -trim-silence considers the output silent")
+	silenceKeep  = flag.String("silence-keep", "0", "Silence left before and after the sound by -trim-silence, in the same formats as -ss")
+	segment      = flag.String("segment", "", "Split the output into files of this duration, in the same formats as -ss, numbered from 001")
+	copyMeta     = flag.Bool("copy-meta", false, "Copy the metadata chunks of WAV inputs, such as bext, LIST and cue, to WAV outputs")
+	remix        = flag.String("remix", "", "Channel mixing matrix: input channel gains separated by commas, one row per output channel separated by semicolons")
 )
 
-func strToFormat(format string) (int, error) {
-	switch strings.ToLower(format) {
-	case "i16":
-		return resample.I16, nil
-	case "i32":
-		return resample.I32, nil
-	case "f32":
-		return resample.F32, nil
-	case "f64":
-		return resample.F64, nil
-	}
-	return 0, fmt.Errorf("unknown format %s", format)
+func qualityToStr(quality int) string {
+	switch quality {
+	case resample.Quick:
+		return "quick"
+	case resample.LowQ:
+		return "low"
+	case resample.MediumQ:
+		return "medium"
+	case resample.HighQ:
+		return "high"
+	case resample.VeryHighQ:
+		return "very high"
+	case resample.SincBestQ:
+		return "sinc best"
+	case resample.SincMediumQ:
+		return "sinc medium"
+	case resample.SincFastestQ:
+		return "sinc fastest"
+	}
+	return "unknown"
+}
+
+// debugf logs a message when -v is set.
+func debugf(format string, v ...interface{}) {
+	if *verbose {
+		log.Printf(format, v...)
+	}
+}
+
+// logHeader logs the fields of a WAV header when -v is set.
+func logHeader(name string, h *wav.Header) {
+	if !*verbose {
+		return
+	}
+	ids := make([]string, len(h.Chunks))
+	for i, c := range h.Chunks {
+		ids[i] = strings.TrimSpace(c.ID)
+	}
+	log.Printf("%s: format tag %#x, %d channels, %d Hz, %d bits, block align %d, channel mask %#x, %d data bytes, W64 %t, chunks %v",
+		name, h.Tag, h.Channels, h.SampleRate, h.BitsPerSample, h.BlockAlign, h.ChannelMask, h.DataSize, h.W64, ids)
+}
+
+// cmdFlags holds the names of the flags given on the command line, before
+// the config file and the preset set others.
+var cmdFlags = map[string]bool{}
+
+// checkHeaderFlags warns about raw input flags given on the command line
+// that are overridden by the WAV header of in.
+func checkHeaderFlags(in *input, r *result) {
+	for _, name := range []string{"ir", "ch", "if"} {
+		if !cmdFlags[name] {
+			continue
+		}
+		var header string
+		switch name {
+		case "ir":
+			if float64(*ir) != in.rate {
+				header = fmt.Sprint(in.rate)
+			}
+		case "ch":
+			if *ch != in.channels {
+				header = fmt.Sprint(in.channels)
+			}
+		case "if":
+			if format, err := resample.ParseFormat(*inFormat); err != nil || format != in.format {
+				header = resample.FormatName(in.format)
+			}
+		}
+		if header != "" {
+			r.warn("Ignoring -%s %s, the WAV header specifies %s", name, flag.Lookup(name).Value, header)
+		}
+	}
+}
+
+// fatal logs v and exits with code.
+func fatal(code int, v ...interface{}) {
+	log.Println(v...)
+	os.Exit(code)
 }
 
 func main() {
 	flag.Parse()
-	inFrmt, err := strToFormat(*inFormat)
-	if err != nil {
-		log.Fatalf("Invalid input format : %s", err)
+	flag.Visit(func(f *flag.Flag) {
+		cmdFlags[f.Name] = true
+	})
+	if *config != "" {
+		if err := loadConfig(*config); err != nil {
+			fatal(exitUsage, err)
+		}
 	}
-	outFrmt, err := strToFormat(*outFormat)
-	if err != nil {
-		log.Fatalf("Invalid output format : %s", err)
+	if *preset != "" {
+		if err := applyPreset(*preset); err != nil {
+			fatal(exitUsage, err)
+		}
 	}
-	if *ch < 1 {
-		log.Fatalln("Invalid channel number")
+	if *info {
+		if flag.NArg() < 1 {
+			fatal(exitUsage, "No files given")
+		}
+		code := 0
+		for _, name := range flag.Args() {
+			if err := printInfo(name); err != nil {
+				log.Printf("%s: %s", name, err)
+				if code == 0 {
+					code = exitCode(err)
+				}
+			}
+		}
+		os.Exit(code)
 	}
-	if *ir <= 0 || *or <= 0 {
-		log.Fatalln("Invalid input or output sample rate")
+	if *compare {
+		if flag.NArg() != 2 {
+			fatal(exitUsage, "-compare needs a reference and a test file")
+		}
+		if err := compareFiles(flag.Arg(0), flag.Arg(1)); err != nil {
+			fatal(exitCode(err), err)
+		}
+		return
 	}
-	if flag.NArg() < 2 {
-		log.Fatalln("No input or output files given")
+	if *convertOnly && *or != 0 {
+		fatal(exitUsage, "-convert-only keeps the input rate, -or can't be set")
 	}
-	inputFile := flag.Arg(0)
-	outputFile := flag.Arg(1)
-
-	// Open input file (WAV or RAW PCM)
-	input, err := os.Open(inputFile)
-	if err != nil {
-		log.Fatalln(err)
+	if *or <= 0 && !*convertOnly {
+		fatal(exitUsage, "Invalid output sample rate")
 	}
-	defer input.Close()
-	output, err := os.Create(outputFile)
-	if err != nil {
-		log.Fatalln(err)
+	if *copyMeta && (*start != "" || *length != "" || *segment != "") {
+		fatal(exitUsage, "-copy-meta can't be used with -ss, -t or -segment")
 	}
-	// Create a Resampler
-	res, err := resample.New(output, float64(*ir), float64(*or), *ch, inFrmt, outFrmt, resample.HighQ)
-	if err != nil {
-		output.Close()
-		os.Remove(outputFile)
-		log.Fatalln(err)
+	if *workers < 1 {
+		fatal(exitUsage, "-j must be at least 1")
 	}
-	// Skip WAV file header in order to pass only the PCM data to the Resampler
-	if strings.ToLower(filepath.Ext(inputFile)) == ".wav" {
-		input.Seek(wavHeader, 0)
+	if *speed <= 0 || math.IsInf(*speed, 0) || math.IsNaN(*speed) {
+		fatal(exitUsage, "-speed must be a positive number")
 	}
-
-	// Read input and pass it to the Resampler in chunks
-	_, err = io.Copy(res, input)
-	// Close the Resampler and the output file. Clsoing the Resampler will flush any remaining data to the output file.
-	// If the Resampler is not closed before the output file, any remaining data will be lost.
-	res.Close()
-	output.Close()
+	if *verify != "" {
+		if _, _, err := parseVerify(*verify); err != nil {
+			fatal(exitUsage, err)
+		}
+	}
+	if *loudTarget != "" {
+		if *norm {
+			fatal(exitUsage, "-norm and -loudnorm can't be used together")
+		}
+		if _, err := parseLoudness(*loudTarget); err != nil {
+			fatal(exitUsage, err)
+		}
+	}
+	if *loop < 1 {
+		fatal(exitUsage, "-loop must be at least 1")
+	}
+	if *determinism {
+		if *threads > 1 {
+			fatal(exitUsage, "-deterministic resamples single threaded, -threads can't be set")
+		}
+		*threads = 1
+	}
+	if *workers > 1 {
+		if *play {
+			fatal(exitUsage, "-play can't be used with -j")
+		}
+		if *threads == 0 {
+			*threads = 1
+		}
+	}
+	if *watchDir != "" {
+		if err := watch(*watchDir, flag.Args()); err != nil {
+			fatal(exitCode(err), err)
+		}
+		return
+	}
+	if flag.NArg() < 2 && (*outTemplate == "" || flag.NArg() < 1) {
+		fatal(exitUsage, "No input or output files given")
+	}
+	jobs, err := jobList(flag.Args())
 	if err != nil {
-		os.Remove(outputFile)
-		log.Fatalln(err)
+		fatal(exitUsage, err)
+	}
+	for _, j := range jobs {
+		if *jsonOut && j.output == "-" {
+			fatal(exitUsage, "-json can't be used when writing to standard output")
+		}
+		if *segment != "" && j.output == "-" {
+			fatal(exitUsage, "-segment can't be used when writing to standard output")
+		}
+		for _, in := range j.inputs {
+			if *loop > 1 && in == "-" {
+				fatal(exitUsage, "-loop needs a file input, streams can't be read twice")
+			}
+		}
+	}
+	if *stats && *dry {
+		fatal(exitUsage, "-stats can't be used with -n")
+	}
+	if *specOut != "" && len(jobs) > 1 {
+		fatal(exitUsage, "-spectrogram needs a single output")
+	}
+	run, prog := convertFile, &progress{}
+	if *dry {
+		run = dryRun
+	} else {
+		prog = newProgress(jobs)
+	}
+	var t tally
+	started := time.Now()
+	runJobs(jobs, prog, run, t.add)
+	if *stats {
+		printStats(&t, jobs, started)
+	}
+	if t.skipped > 0 && !*dry && !*jsonOut {
+		log.Printf("%d of %d conversions skipped, their output is up to date", t.skipped, len(jobs))
+	}
+	if t.failed > 0 {
+		if len(jobs) > 1 {
+			log.Printf("%d of %d conversions failed", t.failed, len(jobs))
+		}
+		os.Exit(t.code)
 	}
 }
@@ -0,0 +1,97 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+	"github.com/zaf/resample/wav"
+)
+
+// setFlag sets the flag variable p to v for the duration of the test.
+func setFlag[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// writeWAV writes the samples of x to the WAV file name of the test's
+// temporary directory, repeated in channels channels of format at rate,
+// and returns its path.
+func writeWAV(t *testing.T, name string, x []float64, rate, channels, format int) string {
+	t.Helper()
+	p, err := testsignal.Encode(x, channels, format)
+	if err != nil {
+		t.Fatal("Encode failed:", err)
+	}
+	f, err := resample.WAVFormat(format, channels, float64(rate))
+	if err != nil {
+		t.Fatal("WAVFormat failed:", err)
+	}
+	name = filepath.Join(t.TempDir(), name)
+	file, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w, err := wav.NewWriter(file, f)
+	if err != nil {
+		t.Fatal("Failed to create the WAV file:", err)
+	}
+	if _, err = w.Write(p); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	return name
+}
+
+// readWAV returns the header and the sample data of the WAV file name.
+func readWAV(t *testing.T, name string) (*wav.Header, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(data)
+	h, err := wav.ReadHeader(r)
+	if err != nil {
+		t.Fatalf("%s isn't WAV: %v", name, err)
+	}
+	samples := data[len(data)-r.Len():]
+	if h.DataSize < int64(len(samples)) {
+		samples = samples[:h.DataSize]
+	}
+	return h, samples
+}
+
+// captureStdout returns what fn prints on standard output.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	err = fn()
+	os.Stdout = stdout
+	f.Seek(0, io.SeekStart)
+	out, _ := io.ReadAll(f)
+	return string(out), err
+}
@@ -0,0 +1,95 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/zaf/resample"
+)
+
+// normalize adds to s the gain bringing the peak of the output of the
+// inputs of the job name to the -peak level. The peak is measured by
+// resampling the whole input in a first pass, so intersample peaks created
+// by resampling are accounted for.
+func normalize(name string, inputs []string, s *settings) error {
+	pm := &peakMeter{}
+	if err := firstPass(inputs, s, pm); err != nil {
+		return err
+	}
+	if pm.peak == 0 {
+		debugf("%s: silent input, not normalized", name)
+		return nil
+	}
+	level := 20 * math.Log10(pm.peak)
+	debugf("%s: output peak %.2f dBFS, normalizing to %.2f dBFS", name, level, *peak)
+	s.opts = append(s.opts, resample.WithGain(*gain+*peak-level))
+	return nil
+}
+
+// firstPass writes the output of the input files as F64 samples to w,
+// for the measurements of -norm and -loudnorm. The files are opened a
+// second time for it.
+func firstPass(files []string, s *settings, w io.Writer) error {
+	measure, err := openInputs(files, &progress{})
+	if err != nil {
+		return inputError(err)
+	}
+	defer measure.Close()
+	if err = trimInput(measure); err != nil {
+		return inputError(err)
+	}
+	res, err := resample.New(w, sourceRate(measure), s.rate, measure.channels, measure.format, resample.F64, s.quality, s.opts...)
+	if err != nil {
+		return err
+	}
+	err = resample.CopyFrames(res, inputReader{measure.data}, measure.frameSize())
+	if e := res.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// peakMeter records the largest absolute value of the F64 samples written to it.
+type peakMeter struct {
+	peak float64
+}
+
+func (pm *peakMeter) Write(p []byte) (int, error) {
+	for i := 0; i+8 <= len(p); i += 8 {
+		if v := math.Abs(math.Float64frombits(binary.LittleEndian.Uint64(p[i:]))); v > pm.peak {
+			pm.peak = v
+		}
+	}
+	return len(p), nil
+}
+
+// spoolStdin copies the standard input to a temporary file, so that the
+// conversions measuring their input first can read it twice, and returns
+// its name. The caller removes the file.
+func spoolStdin() (string, error) {
+	f, err := os.CreateTemp("", "resampler-*.stdin")
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(f, os.Stdin); err != nil {
+		err = inputError(err)
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
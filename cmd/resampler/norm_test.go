@@ -0,0 +1,76 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestFirstPass(t *testing.T) {
+	setFlag(t, start, "")
+	setFlag(t, length, "")
+	setFlag(t, inFormat, "i16")
+	setFlag(t, ch, 1)
+	setFlag(t, ir, 8000)
+	// A second of a 440 Hz sine at half scale, as raw I16.
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 8000, 8000), 1, testsignal.I16)
+	name := filepath.Join(t.TempDir(), "in.raw")
+	if err := os.WriteFile(name, p, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &settings{format: resample.I16, rate: 16000, quality: resample.MediumQ, channels: 1}
+	pm := &peakMeter{}
+	if err := firstPass([]string{name}, s, pm); err != nil {
+		t.Fatal("firstPass failed:", err)
+	}
+	if math.Abs(pm.peak-0.5) > 0.01 {
+		t.Errorf("Peak %g, expected 0.5", pm.peak)
+	}
+	if err := normalize(name, []string{name}, s); err != nil || len(s.opts) != 1 {
+		t.Errorf("normalize returned %v, %d options", err, len(s.opts))
+	}
+
+	err := firstPass([]string{filepath.Join(t.TempDir(), "missing.raw")}, s, pm)
+	if code := exitCode(err); code != exitInput {
+		t.Errorf("Missing input: error %v, exit code %d", err, code)
+	}
+	setFlag(t, start, "x")
+	if code := exitCode(firstPass([]string{name}, s, pm)); code != exitUsage {
+		t.Errorf("Invalid -ss: exit code %d", code)
+	}
+}
+
+func TestSpoolStdin(t *testing.T) {
+	in := []byte("sample data")
+	name := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(name, in, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	setFlag(t, &os.Stdin, f)
+	spool, err := spoolStdin()
+	if err != nil {
+		t.Fatal("spoolStdin failed:", err)
+	}
+	defer os.Remove(spool)
+	if p, err := os.ReadFile(spool); err != nil || !bytes.Equal(p, in) {
+		t.Errorf("Spooled %q, %v", p, err)
+	}
+}
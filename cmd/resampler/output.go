@@ -0,0 +1,272 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// output is the destination of the resampled data, either a RAW PCM file
+// or a WAV file.
+type output struct {
+	w       io.Writer    // where sample data goes
+	file    *os.File     // underlying file
+	wav     *wav.Writer  // WAV writer, nil for raw output
+	format  wav.Format   // format of the data
+	meta    []wav.Chunk  // chunks written after the WAV header
+	play    *player      // playback of the output, nil without -play
+	spec    *spectrogram // spectrogram of the output, nil without -spectrogram
+	hash    hash.Hash    // digest of the sample data, nil without -verify
+	meter   *levelMeter  // level of the output, nil without -stats
+	n       int64        // bytes of sample data written
+	name    string       // output file name, the file is renamed to it on commit
+	segSize int64        // bytes per segment, 0 without -segment
+	segN    int64        // bytes written to the current segment
+	index   int          // number of the current segment
+	done    []string     // segments written
+}
+
+// isWAVOutput reports whether the output file should be a WAV file.
+func isWAVOutput(name string) bool {
+	return *wavOut || name != "-" && strings.ToLower(filepath.Ext(name)) == ".wav"
+}
+
+// createOutput creates the output file name, or uses standard output when
+// name is "-", for data of the given format, channels and sampling rate.
+// WAV output starts with the meta chunks.
+// Data is written to a temporary file next to name, which only replaces
+// it on commit. An existing file is not overwritten unless -f is set, or
+// -skip-existing when it is older than the input. With -segment the output
+// is split into files named by segmentName, each committed once full.
+func createOutput(name string, format, channels int, rate float64, mask uint32, meta []wav.Chunk) (*output, error) {
+	order, _ := byteOrder("oe", *outEndian)
+	bigEndian := order == binary.BigEndian
+	wf, err := resample.WAVFormat(format, channels, rate)
+	if err != nil {
+		return nil, err
+	}
+	wf.ChannelMask = mask
+	if isWAVOutput(name) && bigEndian {
+		return nil, usageError(errors.New("-oe big only applies to RAW output, WAV files are little-endian"))
+	}
+	out := &output{name: name, format: wf, meta: meta}
+	if *segment != "" {
+		d, err := parseTime(*segment)
+		if err != nil {
+			return nil, usageError(fmt.Errorf("-segment: %w", err))
+		}
+		if out.segSize = timeFrames(d, rate) * int64(wf.BlockAlign); out.segSize <= 0 {
+			return nil, usageError(errors.New("-segment is shorter than a frame"))
+		}
+	}
+	if err = out.open(); err != nil {
+		return nil, err
+	}
+	if *play {
+		if bigEndian {
+			out.discard()
+			return nil, usageError(errors.New("-play can't be used with -oe big"))
+		}
+		if out.play, err = startPlayer(format, wf); err != nil {
+			out.discard()
+			return nil, err
+		}
+	}
+	if *specOut != "" {
+		if out.spec, err = newSpectrogram(*specOut, format, channels, order); err != nil {
+			out.discard()
+			return nil, err
+		}
+	}
+	if *stats {
+		out.meter = newLevelMeter(format, order)
+	}
+	if *verify != "" {
+		newHash, _, err := parseVerify(*verify)
+		if err != nil {
+			out.discard()
+			return nil, usageError(err)
+		}
+		out.hash = newHash()
+	}
+	return out, nil
+}
+
+// segmentName returns the name of the segment numbered index of the output
+// name, the number being added before the extension: out-001.wav.
+func segmentName(name string, index int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(name, ext), index, ext)
+}
+
+// open creates the temporary file of the output, or of its next segment.
+func (out *output) open() error {
+	name := out.name
+	if out.segSize > 0 {
+		out.index++
+		name = segmentName(out.name, out.index)
+	}
+	f := os.Stdout
+	if name != "-" {
+		if _, err := os.Stat(name); err == nil && !*force && !*skipExisting {
+			return fmt.Errorf("%s already exists, use -f to overwrite it", name)
+		}
+		var err error
+		if f, err = os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp"); err != nil {
+			return err
+		}
+	}
+	out.file, out.w, out.wav, out.segN = f, f, nil, 0
+	if isWAVOutput(out.name) {
+		var err error
+		if out.wav, err = wav.NewWriter(f, out.format, out.meta...); err != nil {
+			out.file.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		out.w = out.wav
+	}
+	return nil
+}
+
+func (out *output) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		b := p
+		if out.segSize > 0 {
+			if out.segN == out.segSize {
+				if err := out.next(); err != nil {
+					return n, outputError(err)
+				}
+			}
+			if room := out.segSize - out.segN; int64(len(b)) > room {
+				b = b[:room]
+			}
+		}
+		m, err := out.w.Write(b)
+		n += m
+		out.n += int64(m)
+		out.segN += int64(m)
+		if out.play != nil {
+			out.play.Write(b[:m])
+		}
+		if out.spec != nil {
+			out.spec.Write(b[:m])
+		}
+		if out.hash != nil {
+			out.hash.Write(b[:m])
+		}
+		if out.meter != nil {
+			out.meter.Write(b[:m])
+		}
+		if err != nil {
+			return n, outputError(err)
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// next commits the full segment and starts the following one.
+func (out *output) next() error {
+	err := out.closeFile()
+	if err == nil {
+		err = out.commit()
+	}
+	if err != nil {
+		return err
+	}
+	out.done = append(out.done, segmentName(out.name, out.index))
+	debugf("%s written", segmentName(out.name, out.index))
+	return out.open()
+}
+
+// closeFile completes the WAV header, if any, and closes the output file.
+func (out *output) closeFile() error {
+	var err error
+	if out.wav != nil {
+		err = out.wav.Close()
+	}
+	if out.file == os.Stdout {
+		return err
+	}
+	if e := out.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Close completes the WAV header, if any, and closes the output file.
+// With -play it waits for the playback to end, with -spectrogram it saves
+// the image.
+func (out *output) Close() error {
+	err := out.closeFile()
+	if out.spec != nil {
+		if e := out.spec.Close(); err == nil {
+			err = e
+		}
+		out.spec = nil
+	}
+	if out.play != nil {
+		if e := out.play.Close(); e != nil {
+			log.Printf("Playback failed: %s", e)
+		}
+		out.play = nil
+	}
+	return err
+}
+
+// commit moves the closed output file to its name.
+func (out *output) commit() error {
+	if out.file == os.Stdout {
+		return nil
+	}
+	name := out.name
+	if out.segSize > 0 {
+		name = segmentName(out.name, out.index)
+	}
+	tmp := out.file.Name()
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// discard closes and deletes the output of a failed conversion, leaving
+// any previous file of the same name untouched. The segments already
+// written are deleted.
+func (out *output) discard() {
+	if out.play != nil {
+		out.play.Kill()
+		out.play = nil
+	}
+	out.spec = nil
+	out.Close()
+	if out.file != os.Stdout {
+		os.Remove(out.file.Name())
+	}
+	for _, name := range out.done {
+		os.Remove(name)
+	}
+}
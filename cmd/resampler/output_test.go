@@ -0,0 +1,133 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestSegmentName(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		index int
+		want  string
+	}{
+		{"out.wav", 1, "out-001.wav"},
+		{"dir/take.2.raw", 12, "dir/take.2-012.raw"},
+		{"out", 3, "out-003"},
+		{"out.WAV", 1000, "out-1000.WAV"},
+	} {
+		if got := segmentName(tc.name, tc.index); got != tc.want {
+			t.Errorf("segmentName(%q, %d) = %q, expecting %q", tc.name, tc.index, got, tc.want)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	setFlag(t, segment, "0.5")
+	// Half a second of 8 kHz mono i16 is 8000 bytes per segment.
+	for _, tc := range []struct {
+		size  int
+		sizes []int
+	}{
+		{20000, []int{8000, 8000, 4000}},
+		{16000, []int{8000, 8000}}, // no empty segment after a full one
+		{100, []int{100}},
+	} {
+		name := filepath.Join(t.TempDir(), "out.raw")
+		out, err := createOutput(name, resample.I16, 1, 8000, 0, nil)
+		if err != nil {
+			t.Fatal("createOutput failed:", err)
+		}
+		data := make([]byte, tc.size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		// Writes straddle the segment boundaries.
+		for p := data; len(p) > 0; {
+			n := min(3000, len(p))
+			if m, err := out.Write(p[:n]); m != n || err != nil {
+				t.Fatalf("Write returned %d, %v", m, err)
+			}
+			p = p[n:]
+		}
+		if err = out.Close(); err == nil {
+			err = out.commit()
+		}
+		if err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		var joined []byte
+		for i, size := range tc.sizes {
+			p, err := os.ReadFile(segmentName(name, i+1))
+			if err != nil || len(p) != size {
+				t.Errorf("%d bytes: segment %d holds %d bytes, expecting %d: %v", tc.size, i+1, len(p), size, err)
+			}
+			joined = append(joined, p...)
+		}
+		if !bytes.Equal(joined, data) {
+			t.Errorf("%d bytes: the segments don't hold the data written", tc.size)
+		}
+		if _, err = os.Stat(segmentName(name, len(tc.sizes)+1)); !os.IsNotExist(err) {
+			t.Errorf("%d bytes: extra segment %d: %v", tc.size, len(tc.sizes)+1, err)
+		}
+		if _, err = os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%d bytes: unsegmented output written: %v", tc.size, err)
+		}
+	}
+
+	// A failed conversion deletes the segments already written.
+	name := filepath.Join(t.TempDir(), "out.raw")
+	out, err := createOutput(name, resample.I16, 1, 8000, 0, nil)
+	if err != nil {
+		t.Fatal("createOutput failed:", err)
+	}
+	out.Write(make([]byte, 10000))
+	out.discard()
+	if files, _ := filepath.Glob(filepath.Join(filepath.Dir(name), "*")); len(files) != 0 {
+		t.Errorf("Discarded output left %q", files)
+	}
+}
+
+func TestSegmentedConversion(t *testing.T) {
+	// 2.5 seconds resampled to 16 kHz make two WAV segments of a second
+	// and one of half a second.
+	in := writeWAV(t, "in.wav", testsignal.Sine(440, 0.5, 8000, 20000), 8000, 2, testsignal.I16)
+	setFlag(t, or, 16000)
+	setFlag(t, quiet, true)
+	setFlag(t, segment, "1s")
+	name := filepath.Join(t.TempDir(), "out.wav")
+	var r result
+	if err := convertFile(job{[]string{in}, name}, &r, &progress{}); err != nil {
+		t.Fatal("convertFile failed:", err)
+	}
+	var frames int64
+	for i := 1; i <= 3; i++ {
+		h, samples := readWAV(t, segmentName(name, i))
+		if h.SampleRate != 16000 || h.Channels != 2 || h.DataSize != int64(len(samples)) {
+			t.Errorf("Segment %d: %d Hz, %d channels, %d of %d bytes", i, h.SampleRate, h.Channels, len(samples), h.DataSize)
+		}
+		if i < 3 && h.Frames() != 16000 {
+			t.Errorf("Segment %d: %d frames, expecting 16000", i, h.Frames())
+		}
+		frames += h.Frames()
+	}
+	if frames != r.Output.Frames || frames < 39990 || frames > 40010 {
+		t.Errorf("%d frames in the segments, %d written", frames, r.Output.Frames)
+	}
+	if _, err := os.Stat(segmentName(name, 4)); !os.IsNotExist(err) {
+		t.Error("Extra segment:", err)
+	}
+}
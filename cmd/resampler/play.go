@@ -0,0 +1,118 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/zaf/resample/wav"
+)
+
+// players are the playback commands tried in order by -play, each reading
+// a WAV stream from its standard input.
+var players = [][]string{
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", "-i", "-"},
+	{"paplay"},
+	{"aplay", "-q", "-"},
+	{"play", "-q", "-t", "wav", "-"},
+}
+
+// player sends a copy of the output to an audio playback command, or to
+// the audio device when built with the oto tag.
+type player struct {
+	w     io.Writer    // the stream played
+	close func() error // ends the stream and waits for the playback to end
+	kill  func()       // stops the playback at once, nil to let it end
+	err   error
+}
+
+// playerCommand returns the playback command set by -player, or the first
+// of players found in the PATH.
+func playerCommand() ([]string, error) {
+	if *playCmd != "" {
+		return strings.Fields(*playCmd), nil
+	}
+	for _, p := range players {
+		if _, err := exec.LookPath(p[0]); err == nil {
+			return p, nil
+		}
+	}
+	return nil, errors.New("no audio player found, install ffplay, paplay, aplay or sox, or set -player")
+}
+
+// startPlayer starts the playback of audio of format, stored as f. Without
+// -player the audio device is tried first, then the playback commands.
+func startPlayer(format int, f wav.Format) (*player, error) {
+	if *playCmd == "" {
+		p, err := startDevice(format, f)
+		if p != nil {
+			debugf("Playing on the audio device")
+			return p, nil
+		}
+		if err != nil {
+			debugf("Can't play on the audio device: %s", err)
+		}
+	}
+	args, err := playerCommand()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &player{
+		close: func() error {
+			stdin.Close()
+			return cmd.Wait()
+		},
+		kill: func() { cmd.Process.Kill() },
+	}
+	// The pipe can't be seeked, the header keeps its placeholder sizes.
+	if p.w, err = wav.NewWriter(stdin, f); err != nil {
+		p.Close()
+		return nil, err
+	}
+	debugf("Playing with %s", strings.Join(args, " "))
+	return p, nil
+}
+
+// Write sends p to the player. Playback errors don't stop the conversion,
+// the first one is logged and the player is then left alone.
+func (p *player) Write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	if _, p.err = p.w.Write(b); p.err != nil {
+		log.Printf("Playback stopped: %s", p.err)
+	}
+}
+
+// Close ends the stream and waits for the player to finish playing it.
+func (p *player) Close() error {
+	return p.close()
+}
+
+// Kill stops the playback without waiting for the end of the stream.
+func (p *player) Kill() {
+	if p.kill != nil {
+		p.kill()
+	}
+	p.close()
+}
@@ -0,0 +1,50 @@
+//go:build oto
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"io"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// startDevice plays audio of format on the default audio device through
+// oto, which plays mono and stereo only, at the rate of the first output
+// played.
+func startDevice(format int, f wav.Format) (*player, error) {
+	rate := float64(f.SampleRate)
+	// The rates match, the Resampler only converts the samples for oto.
+	pl, err := resample.NewOtoPlayer(rate, rate, f.Channels, format, resample.Quick)
+	if err != nil {
+		return nil, err
+	}
+	return &player{w: &frameWriter{w: pl, size: f.BlockAlign}, close: pl.Close}, nil
+}
+
+// frameWriter passes whole frames to w, which rejects incomplete ones,
+// and keeps the rest for the next write.
+type frameWriter struct {
+	w    io.Writer
+	size int
+	buf  []byte
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	fw.buf = append(fw.buf, p...)
+	n := len(fw.buf) - len(fw.buf)%fw.size
+	if n == 0 {
+		return len(p), nil
+	}
+	_, err := fw.w.Write(fw.buf[:n])
+	fw.buf = fw.buf[:copy(fw.buf, fw.buf[n:])]
+	return len(p), err
+}
@@ -0,0 +1,19 @@
+//go:build !oto
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import "github.com/zaf/resample/wav"
+
+// startDevice returns no player, playing on the audio device needs the oto
+// build tag.
+func startDevice(format int, f wav.Format) (*player, error) {
+	return nil, nil
+}
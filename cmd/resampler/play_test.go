@@ -0,0 +1,62 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+func TestPlayer(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("playback commands need a Unix system")
+	}
+	played := filepath.Join(t.TempDir(), "played.wav")
+	setFlag(t, playCmd, "cp /dev/stdin "+played)
+	f, _ := resample.WAVFormat(resample.I16, 2, 8000)
+	p, err := startPlayer(resample.I16, f)
+	if err != nil {
+		t.Fatal("startPlayer failed:", err)
+	}
+	samples := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	p.Write(samples)
+	if err = p.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	data, err := os.ReadFile(played)
+	if err != nil {
+		t.Fatal("Nothing played:", err)
+	}
+	r := bytes.NewReader(data)
+	h, err := wav.ReadHeader(r)
+	if err != nil {
+		t.Fatal("Played stream isn't WAV:", err)
+	}
+	if h.SampleRate != 8000 || h.Channels != 2 || !bytes.Equal(data[len(data)-r.Len():], samples) {
+		t.Errorf("Played %d Hz, %d channels, samples %v", h.SampleRate, h.Channels, data[len(data)-r.Len():])
+	}
+
+	// Killed before the end of the stream.
+	setFlag(t, playCmd, "sleep 10")
+	if p, err = startPlayer(resample.I16, f); err != nil {
+		t.Fatal("startPlayer failed:", err)
+	}
+	p.Kill()
+
+	setFlag(t, playCmd, "resampler-no-such-player")
+	if _, err = startPlayer(resample.I16, f); err == nil {
+		t.Error("Missing player didn't return an error")
+	}
+}
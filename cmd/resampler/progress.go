@@ -0,0 +1,205 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often the progress line is redrawn.
+const progressInterval = 200 * time.Millisecond
+
+// progress reports the conversion progress on standard error. Parallel
+// workers each report through a progress returned by worker, which adds
+// to the total of the batch.
+type progress struct {
+	mu        sync.Mutex // guards the batch totals shared by workers
+	batch     *progress  // report of the batch of a worker, nil otherwise
+	running   int        // files being read by workers
+	finished  int        // files read by workers
+	enabled   bool
+	w         io.Writer // where the report is drawn
+	count     int       // number of files
+	total     int64     // size of all input files, 0 if unknown
+	done      int64     // bytes of the finished files
+	start     time.Time // start of the batch
+	index     int       // current file number
+	name      string    // current file name
+	fileSize  int64     // size of the current file, -1 if unknown
+	fileDone  int64     // bytes read from the current file
+	fileStart time.Time
+	drawn     time.Time // last time the line was drawn
+	started   bool      // the current file was opened
+}
+
+// newProgress returns a progress report for jobs. It is only enabled when
+// standard error is a terminal and neither -quiet nor -v are set.
+func newProgress(jobs []job) *progress {
+	p := &progress{start: time.Now(), w: os.Stderr}
+	for _, j := range jobs {
+		p.count += len(j.sources())
+	}
+	if *quiet || *verbose {
+		return p
+	}
+	if fi, err := os.Stderr.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return p
+	}
+	p.enabled = true
+	for _, j := range jobs {
+		for _, in := range j.sources() {
+			if fi, err := os.Stat(in); err == nil {
+				p.total += fi.Size()
+			}
+		}
+	}
+	return p
+}
+
+// worker returns the progress report of a parallel worker.
+func (p *progress) worker() *progress {
+	return &progress{batch: p}
+}
+
+// startFile begins reporting on a file size bytes long, or of unknown size if negative.
+func (p *progress) startFile(name string, size int64) {
+	if b := p.batch; b != nil {
+		b.mu.Lock()
+		b.running++
+		b.mu.Unlock()
+	}
+	p.index++
+	p.started = true
+	p.name = name
+	p.fileSize = size
+	p.fileDone = 0
+	p.fileStart = time.Now()
+	p.drawn = time.Time{}
+}
+
+// endFile completes the report of the current file.
+func (p *progress) endFile() {
+	if !p.started {
+		return
+	}
+	p.started = false
+	if b := p.batch; b != nil {
+		b.mu.Lock()
+		if p.fileSize >= 0 {
+			b.done += p.fileSize - p.fileDone
+		}
+		b.running--
+		b.finished++
+		if b.enabled {
+			b.drawBatch()
+			if b.finished == b.count {
+				fmt.Fprintln(b.w)
+			}
+		}
+		b.mu.Unlock()
+		return
+	}
+	if p.fileSize >= 0 {
+		p.fileDone = p.fileSize
+	}
+	if p.enabled {
+		p.draw()
+		if p.index == p.count {
+			fmt.Fprintln(p.w)
+		}
+	}
+	p.done += p.fileDone
+	p.fileDone = 0
+}
+
+// add records n more bytes read from the current file.
+func (p *progress) add(n int) {
+	p.fileDone += int64(n)
+	if b := p.batch; b != nil {
+		b.mu.Lock()
+		b.done += int64(n)
+		if b.enabled && time.Since(b.drawn) >= progressInterval {
+			b.drawBatch()
+		}
+		b.mu.Unlock()
+		return
+	}
+	if p.enabled && time.Since(p.drawn) >= progressInterval {
+		p.draw()
+	}
+}
+
+func (p *progress) draw() {
+	p.drawn = time.Now()
+	elapsed := time.Since(p.fileStart).Seconds()
+	line := fmt.Sprintf("[%d/%d] %s %s", p.index, p.count, p.name, byteSize(p.fileDone))
+	if elapsed > 0 {
+		line += fmt.Sprintf(" %s/s", byteSize(int64(float64(p.fileDone)/elapsed)))
+	}
+	if p.fileSize > 0 {
+		line += fmt.Sprintf(" %3.0f%% ETA %s", 100*float64(p.fileDone)/float64(p.fileSize), eta(p.fileDone, p.fileSize, elapsed))
+	}
+	if p.count > 1 && p.total > 0 {
+		done := p.done + p.fileDone
+		line += fmt.Sprintf(" | total %3.0f%% ETA %s", 100*float64(done)/float64(p.total), eta(done, p.total, time.Since(p.start).Seconds()))
+	}
+	fmt.Fprintf(p.w, "\r%s\x1b[K", line)
+}
+
+// drawBatch draws the progress of the files read by parallel workers.
+func (p *progress) drawBatch() {
+	p.drawn = time.Now()
+	elapsed := time.Since(p.start).Seconds()
+	line := fmt.Sprintf("[%d/%d] %d running %s", p.finished, p.count, p.running, byteSize(p.done))
+	if elapsed > 0 {
+		line += fmt.Sprintf(" %s/s", byteSize(int64(float64(p.done)/elapsed)))
+	}
+	if p.total > 0 {
+		line += fmt.Sprintf(" | total %3.0f%% ETA %s", 100*float64(p.done)/float64(p.total), eta(p.done, p.total, elapsed))
+	}
+	fmt.Fprintf(p.w, "\r%s\x1b[K", line)
+}
+
+// eta estimates the time left to process size bytes when done took elapsed seconds.
+func eta(done, size int64, elapsed float64) string {
+	if done == 0 || done >= size {
+		return "0:00"
+	}
+	left := time.Duration(elapsed * float64(size-done) / float64(done) * float64(time.Second))
+	return fmt.Sprintf("%d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+}
+
+// byteSize formats a number of bytes for display.
+func byteSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// progressReader counts the bytes read through it.
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.add(n)
+	return n, err
+}
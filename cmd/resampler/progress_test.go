@@ -0,0 +1,116 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// progressLines returns the lines drawn in buf, each replacing the last.
+func progressLines(buf *bytes.Buffer) []string {
+	var lines []string
+	for _, l := range strings.Split(buf.String(), "\r")[1:] {
+		lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(l, "\n"), "\x1b[K"))
+	}
+	return lines
+}
+
+// checkLines reports the lines missing the substrings of want.
+func checkLines(t *testing.T, lines []string, want [][]string) {
+	t.Helper()
+	if len(lines) != len(want) {
+		t.Fatalf("%d lines drawn, expecting %d: %q", len(lines), len(want), lines)
+	}
+	for i, parts := range want {
+		for _, s := range parts {
+			if !strings.Contains(lines[i], s) {
+				t.Errorf("Line %d %q, expecting %q", i+1, lines[i], s)
+			}
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progress{enabled: true, w: &buf, count: 2, total: 3000, start: time.Now()}
+	p.startFile("a.wav", 1000)
+	p.add(500)
+	p.add(500) // within progressInterval of the last line
+	p.endFile()
+	p.startFile("b.wav", 2000)
+	p.add(1000)
+	p.endFile()
+	checkLines(t, progressLines(&buf), [][]string{
+		{"[1/2] a.wav 500B", " 50% ETA ", "| total  17% ETA "},
+		{"[1/2] a.wav 1000B", "100% ETA 0:00", "| total  33% ETA "},
+		{"[2/2] b.wav 1000B", " 50% ETA ", "| total  67% ETA "},
+		{"[2/2] b.wav 2.0KB", "100% ETA 0:00", "| total 100% ETA 0:00"},
+	})
+	if !strings.HasSuffix(buf.String(), "\x1b[K\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("The report doesn't end with a single new line: %q", buf.String())
+	}
+
+	// Files of unknown size only show the bytes read, a single file no total.
+	buf.Reset()
+	p = &progress{enabled: true, w: &buf, count: 1, start: time.Now()}
+	p.startFile("stdin", -1)
+	p.add(2048)
+	p.endFile()
+	for _, l := range progressLines(&buf) {
+		if !strings.HasPrefix(l, "[1/1] stdin 2.0KB") || strings.Contains(l, "%") {
+			t.Errorf("Line %q", l)
+		}
+	}
+
+	// Nothing is drawn when disabled.
+	buf.Reset()
+	p = &progress{w: &buf, count: 1, start: time.Now()}
+	p.startFile("a.wav", 1000)
+	p.add(1000)
+	p.endFile()
+	if buf.Len() != 0 {
+		t.Errorf("Disabled progress drew %q", buf.String())
+	}
+}
+
+func TestBatchProgress(t *testing.T) {
+	var buf bytes.Buffer
+	b := &progress{enabled: true, w: &buf, count: 2, total: 3000, start: time.Now()}
+	w1, w2 := b.worker(), b.worker()
+	w1.startFile("a.wav", 1000)
+	w2.startFile("b.wav", 2000)
+	w1.add(400)
+	w1.endFile()
+	w2.endFile()
+	checkLines(t, progressLines(&buf), [][]string{
+		{"[0/2] 2 running 400B", "| total  13% ETA "},
+		{"[1/2] 1 running 1000B", "| total  33% ETA "},
+		{"[2/2] 0 running 2.9KB", "| total 100% ETA 0:00"},
+	})
+	if !strings.HasSuffix(buf.String(), "\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("The report doesn't end with a single new line: %q", buf.String())
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0B", 1023: "1023B", 1536: "1.5KB", 5 << 20: "5.0MB", 3 << 30: "3.0GB"} {
+		if got := byteSize(n); got != want {
+			t.Errorf("byteSize(%d) = %q, expecting %q", n, got, want)
+		}
+	}
+	if got := eta(500, 1000, 10); got != "0:10" {
+		t.Errorf("eta(500, 1000, 10) = %q", got)
+	}
+	if got := eta(0, 1000, 10); got != "0:00" {
+		t.Errorf("eta(0, 1000, 10) = %q", got)
+	}
+}
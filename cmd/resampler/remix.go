@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/wav"
+)
+
+// channelMix returns the channel mix requested by the -mono, -stereo and
+// -remix flags for in, or nil when the channels are left as they are.
+func channelMix(in *input) (*resample.Mix, error) {
+	set := 0
+	for _, b := range []bool{*mono, *stereo, *remix != ""} {
+		if b {
+			set++
+		}
+	}
+	switch {
+	case set > 1:
+		return nil, errors.New("only one of -mono, -stereo and -remix can be used")
+	case *remix != "":
+		m, err := parseRemix(*remix, in.channels)
+		if err != nil {
+			return nil, err
+		}
+		return &resample.Mix{Matrix: m}, nil
+	case *mono:
+		return layoutMix(in, wav.FrontCenter)
+	case *stereo:
+		return layoutMix(in, wav.FrontLeft|wav.FrontRight)
+	}
+	return nil, nil
+}
+
+// layoutMix returns the Mix converting in to the outMask speaker layout.
+// Inputs without a known layout are averaged to every output channel.
+func layoutMix(in *input, outMask uint32) (*resample.Mix, error) {
+	f := wav.Format{Channels: in.channels}
+	if in.header != nil {
+		f = in.header.Format
+	}
+	if f.Mask() == outMask {
+		return nil, nil
+	}
+	outChannels := bits.OnesCount32(outMask)
+	for _, p := range f.Positions() {
+		if p == 0 {
+			m := make([][]float64, outChannels)
+			for o := range m {
+				m[o] = make([]float64, in.channels)
+				for i := range m[o] {
+					m[o][i] = 1 / float64(in.channels)
+				}
+			}
+			return &resample.Mix{Matrix: m}, nil
+		}
+	}
+	m, err := resample.Downmix(f.Mask(), outMask)
+	if err != nil {
+		return nil, err
+	}
+	// Mono and stereo files don't need a channel mask.
+	m.OutMask = 0
+	return &m, nil
+}
+
+// parseRemix parses a mixing matrix given as rows of comma separated
+// input channel gains, one row per output channel, separated by semicolons.
+func parseRemix(s string, channels int) ([][]float64, error) {
+	var m [][]float64
+	for _, row := range strings.Split(s, ";") {
+		gains := strings.Split(row, ",")
+		if len(gains) != channels {
+			return nil, fmt.Errorf("-remix row %q doesn't have %d gains", row, channels)
+		}
+		r := make([]float64, channels)
+		for i, g := range gains {
+			v, err := strconv.ParseFloat(strings.TrimSpace(g), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -remix gain %q", g)
+			}
+			r[i] = v
+		}
+		m = append(m, r)
+	}
+	return m, nil
+}
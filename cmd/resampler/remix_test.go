@@ -0,0 +1,36 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRemix(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		channels int
+		m        [][]float64
+	}{
+		{"0.5,0.5", 2, [][]float64{{0.5, 0.5}}},
+		{"1,0; 0,1 ;0.5, 0.5", 2, [][]float64{{1, 0}, {0, 1}, {0.5, 0.5}}},
+		{"1;1", 1, [][]float64{{1}, {1}}},
+		{"-1,1e-1,2", 3, [][]float64{{-1, 0.1, 2}}},
+	} {
+		if m, err := parseRemix(tc.s, tc.channels); err != nil || !reflect.DeepEqual(m, tc.m) {
+			t.Errorf("%q of %d channels: %v, %v", tc.s, tc.channels, m, err)
+		}
+	}
+	for _, s := range []string{"", "1", "1,0,0", "1,0;1", "1,x", "1,0;"} {
+		if _, err := parseRemix(s, 2); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/zaf/resample"
+)
+
+// result describes a conversion for the -json output.
+type result struct {
+	Input    stream   `json:"input"`
+	Output   stream   `json:"output"`
+	Duration float64  `json:"duration"`          // seconds of output
+	Skipped  bool     `json:"skipped,omitempty"` // the output was up to date
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+// stream holds the parameters of an input or output.
+type stream struct {
+	File     string   `json:"file"`
+	Rate     float64  `json:"rate,omitempty"`
+	Channels int      `json:"channels,omitempty"`
+	Format   string   `json:"format,omitempty"`
+	Frames   int64    `json:"frames"`
+	Digest   string   `json:"digest,omitempty"` // of the output samples, with -verify
+	Peak     *float64 `json:"peak,omitempty"`   // dBFS of the output samples, with -stats
+	RMS      *float64 `json:"rms,omitempty"`    // dBFS of the output samples, with -stats
+	DC       *float64 `json:"dc,omitempty"`     // mean output sample value, with -stats
+	Clips    int64    `json:"clips,omitempty"`  // clipped output samples, with -stats
+}
+
+// warn logs a warning and records it in the result.
+func (r *result) warn(format string, v ...interface{}) {
+	log.Printf(format, v...)
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, v...))
+}
+
+// setInput records the parameters of in.
+func (r *result) setInput(in *input) {
+	r.Input.Rate, r.Input.Channels, r.Input.Format = in.rate, in.channels, resample.FormatName(in.format)
+}
+
+// setOutput records the output settings s.
+func (r *result) setOutput(name string, s *settings) {
+	r.Output.File = name
+	r.Output.Rate, r.Output.Channels, r.Output.Format = s.rate, s.channels, resample.FormatName(s.format)
+}
+
+// print writes the result as a line of JSON to standard output.
+func (r *result) print(err error) {
+	if err != nil {
+		r.Error = err.Error()
+		r.ExitCode = exitCode(err)
+	}
+	b, _ := json.Marshal(r)
+	os.Stdout.Write(append(b, '\n'))
+}
+
+// tally counts the results of conversions.
+type tally struct {
+	failed    int
+	skipped   int
+	code      int // exit code of the first failure
+	inFrames  int64
+	outFrames int64
+	duration  float64 // seconds of output
+	peak      float64 // largest output sample, with -stats
+	sum       float64 // sum of the output samples, with -stats
+	sumSq     float64 // sum of the squared output samples, with -stats
+	samples   int64   // output samples measured, with -stats
+	clips     int64   // clipped output samples, with -stats
+}
+
+// add logs the error of the conversion j, if any, prints its result with
+// -json and counts it.
+func (t *tally) add(j job, r *result, err error) {
+	if err != nil {
+		log.Printf("%s: %s", j.name(), err)
+		if t.failed++; t.code == 0 {
+			t.code = exitCode(err)
+		}
+	}
+	if r.Skipped {
+		t.skipped++
+	}
+	t.inFrames += r.Input.Frames
+	t.outFrames += r.Output.Frames
+	t.duration += r.Duration
+	if r.Output.Peak != nil {
+		t.peak = math.Max(t.peak, math.Pow(10, *r.Output.Peak/20))
+	}
+	if r.Output.DC != nil {
+		n := r.Output.Frames * int64(r.Output.Channels)
+		t.samples += n
+		t.sum += *r.Output.DC * float64(n)
+		if r.Output.RMS != nil {
+			t.sumSq += math.Pow(10, *r.Output.RMS/10) * float64(n)
+		}
+	}
+	t.clips += r.Output.Clips
+	if *jsonOut {
+		r.print(err)
+	} else if err == nil && r.Output.Digest != "" {
+		if r.Output.File == "-" {
+			log.Printf("%s  -", r.Output.Digest)
+		} else {
+			fmt.Printf("%s  %s\n", r.Output.Digest, r.Output.File)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/zaf/resample"
+)
+
+// silenceTrimmer strips the leading and trailing silence of the output
+// written through it, frames whose samples all stay below the threshold.
+// Up to keep bytes of silence are left before and after the sound. Silent
+// runs are held back until louder frames follow them, or dropped by flush.
+// Writes must hold whole frames.
+type silenceTrimmer struct {
+	w         io.Writer
+	format    int
+	channels  int
+	order     binary.ByteOrder
+	frame     int     // frame size in bytes
+	threshold float64 // linear level of silence
+	keep      int     // bytes of silence kept around the sound
+	started   bool    // sound was found
+	held      []byte  // silent frames following the last loud one
+}
+
+// newSilenceTrimmer returns a silenceTrimmer writing to w the output of
+// settings s at rate, configured by the -silence flags.
+func newSilenceTrimmer(w io.Writer, s *settings, rate float64) (*silenceTrimmer, error) {
+	order, err := byteOrder("oe", *outEndian)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = decodeSamples(nil, s.format, order); err != nil {
+		return nil, fmt.Errorf("-trim-silence: %w", err)
+	}
+	d, err := parseTime(*silenceKeep)
+	if err != nil {
+		return nil, fmt.Errorf("-silence-keep: %w", err)
+	}
+	t := &silenceTrimmer{
+		w:         w,
+		format:    s.format,
+		channels:  s.channels,
+		order:     order,
+		frame:     s.channels * resample.FormatSize(s.format),
+		threshold: math.Pow(10, *silenceLevel/20),
+	}
+	t.keep = int(timeFrames(d, rate)) * t.frame
+	return t, nil
+}
+
+func (t *silenceTrimmer) Write(p []byte) (int, error) {
+	v, _ := decodeSamples(p, t.format, t.order)
+	var out []byte
+	for i := 0; i+t.frame <= len(p); i += t.frame {
+		if !t.loud(v[i/t.frame*t.channels:][:t.channels]) {
+			t.held = append(t.held, p[i:i+t.frame]...)
+			continue
+		}
+		if !t.started && len(t.held) > t.keep {
+			t.held = t.held[len(t.held)-t.keep:]
+		}
+		t.started = true
+		out = append(append(out, t.held...), p[i:i+t.frame]...)
+		t.held = t.held[:0]
+	}
+	if !t.started && len(t.held) > t.keep {
+		t.held = t.held[:copy(t.held, t.held[len(t.held)-t.keep:])]
+	}
+	if len(out) > 0 {
+		if _, err := t.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// loud reports whether a sample of the frame reaches the threshold.
+func (t *silenceTrimmer) loud(frame []float64) bool {
+	for _, x := range frame {
+		if math.Abs(x) >= t.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// flush writes the silence kept after the sound and drops the rest.
+func (t *silenceTrimmer) flush() error {
+	if !t.started || len(t.held) == 0 {
+		return nil
+	}
+	n := len(t.held)
+	if n > t.keep {
+		n = t.keep
+	}
+	_, err := t.w.Write(t.held[:n])
+	t.held = nil
+	return err
+}
+
+// writeSilence writes n bytes of silence of the given format to w.
+func writeSilence(w io.Writer, format int, n int64) error {
+	return resample.WriteSilence(w, format, 1, n/int64(resample.FormatSize(format)))
+}
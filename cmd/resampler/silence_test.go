@@ -0,0 +1,72 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/zaf/resample"
+)
+
+func TestSilenceTrimmer(t *testing.T) {
+	setFlag(t, silenceLevel, -20)
+	setFlag(t, silenceKeep, "0.02")
+	// Frames of I16 mono at 100 Hz: silence below -20 dBFS, sound above.
+	const quiet, loud = 1000, 8000
+	var frames []int16
+	for _, run := range []struct {
+		n     int
+		level int16
+	}{{5, quiet}, {3, loud}, {4, 0}, {2, -loud}, {6, quiet}} {
+		for i := 0; i < run.n; i++ {
+			frames = append(frames, run.level)
+		}
+	}
+	p := make([]byte, 2*len(frames))
+	for i, v := range frames {
+		binary.LittleEndian.PutUint16(p[2*i:], uint16(v))
+	}
+	s := &settings{format: resample.I16, channels: 1}
+
+	for _, chunk := range []int{1, 3, len(frames)} {
+		var out bytes.Buffer
+		tr, err := newSilenceTrimmer(&out, s, 100)
+		if err != nil {
+			t.Fatal("Failed to create the trimmer:", err)
+		}
+		for b := p; len(b) > 0; b = b[min(len(b), 2*chunk):] {
+			if n, err := tr.Write(b[:min(len(b), 2*chunk)]); err != nil || n != min(len(b), 2*chunk) {
+				t.Fatalf("Write returned %d, %v", n, err)
+			}
+		}
+		if err = tr.flush(); err != nil {
+			t.Fatal("flush failed:", err)
+		}
+		// Two frames of silence are kept before and after the sound.
+		if want := p[2*3 : 2*16]; !reflect.DeepEqual(out.Bytes(), want) {
+			t.Errorf("Writes of %d frames: output %v, expected %v", chunk, out.Bytes(), want)
+		}
+	}
+
+	var out bytes.Buffer
+	tr, _ := newSilenceTrimmer(&out, s, 100)
+	tr.Write(p[:2*5])
+	tr.flush()
+	if out.Len() != 0 {
+		t.Errorf("%d bytes out of silence", out.Len())
+	}
+
+	setFlag(t, silenceKeep, "x")
+	if _, err := newSilenceTrimmer(&out, s, 100); err == nil {
+		t.Error("Invalid -silence-keep: no error")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/cmplx"
+	"os"
+
+	"github.com/zaf/resample"
+)
+
+const (
+	specSize  = 1024 // FFT size, the image has specSize/2 rows
+	specWidth = 1200 // maximum number of image columns
+	specFloor = -120 // level in dBFS drawn black
+)
+
+// spectrogram renders the spectrum of the output over time to a PNG image.
+// Columns are analysed with half overlapping Hann windows on the channel
+// average. Once 2*specWidth columns are filled pairs of columns are
+// merged, so long outputs use bounded memory.
+type spectrogram struct {
+	name     string
+	format   int
+	channels int
+	order    binary.ByteOrder
+	partial  []byte      // bytes of an incomplete frame
+	samples  []float64   // channel average not analysed yet
+	window   []float64   // Hann window
+	cols     [][]float64 // levels in dBFS per bin
+	merge    int         // analysed windows per column
+	analysed int         // windows merged into the last column
+}
+
+// newSpectrogram returns a spectrogram of output data of the given format
+// saved to name. Existing files are only replaced when -f is set.
+func newSpectrogram(name string, format, channels int, order binary.ByteOrder) (*spectrogram, error) {
+	if _, err := os.Stat(name); err == nil && !*force {
+		return nil, fmt.Errorf("%s already exists, use -f to overwrite it", name)
+	}
+	if _, err := decodeSamples(nil, format, order); err != nil {
+		return nil, usageError(fmt.Errorf("-spectrogram: %w", err))
+	}
+	s := &spectrogram{name: name, format: format, channels: channels, order: order, window: make([]float64, specSize), merge: 1}
+	for i := range s.window {
+		s.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/specSize)
+	}
+	return s, nil
+}
+
+// Write analyses the output data in p.
+func (s *spectrogram) Write(p []byte) {
+	frame := s.channels * resample.FormatSize(s.format)
+	if len(s.partial) > 0 {
+		p = append(s.partial, p...)
+	}
+	n := len(p) - len(p)%frame
+	s.partial = append([]byte(nil), p[n:]...)
+	v, _ := decodeSamples(p[:n], s.format, s.order)
+	for i := 0; i < len(v); i += s.channels {
+		var sum float64
+		for _, x := range v[i : i+s.channels] {
+			sum += x
+		}
+		s.samples = append(s.samples, sum/float64(s.channels))
+	}
+	for len(s.samples) >= specSize {
+		s.analyse(s.samples[:specSize])
+		s.samples = s.samples[:copy(s.samples, s.samples[specSize/2:])]
+	}
+}
+
+// analyse adds the spectrum of the window of samples x to the image.
+func (s *spectrogram) analyse(x []float64) {
+	buf := make([]complex128, specSize)
+	for i, v := range x {
+		buf[i] = complex(v*s.window[i], 0)
+	}
+	fft(buf, false)
+	if s.analysed == 0 || s.analysed == s.merge {
+		col := make([]float64, specSize/2)
+		for i := range col {
+			col[i] = math.Inf(-1)
+		}
+		s.cols = append(s.cols, col)
+		s.analysed = 0
+	}
+	col := s.cols[len(s.cols)-1]
+	for i := range col {
+		// A full scale sine has a magnitude of specSize/4 through the Hann window.
+		level := 20 * math.Log10(cmplx.Abs(buf[i])/(specSize/4))
+		col[i] = math.Max(col[i], level)
+	}
+	s.analysed++
+	if len(s.cols) == 2*specWidth && s.analysed == s.merge {
+		s.halve()
+	}
+}
+
+// halve merges pairs of columns, keeping the loudest level of each bin.
+func (s *spectrogram) halve() {
+	for i := 0; i < len(s.cols)/2; i++ {
+		a, b := s.cols[2*i], s.cols[2*i+1]
+		for j := range a {
+			a[j] = math.Max(a[j], b[j])
+		}
+		s.cols[i] = a
+	}
+	if len(s.cols)%2 != 0 {
+		s.cols[len(s.cols)/2] = s.cols[len(s.cols)-1]
+	}
+	s.cols = s.cols[:(len(s.cols)+1)/2]
+	s.merge *= 2
+	s.analysed = s.merge
+}
+
+// Close saves the image.
+func (s *spectrogram) Close() error {
+	if len(s.samples) > 0 || len(s.cols) == 0 {
+		s.analyse(append(s.samples, make([]float64, specSize-len(s.samples))...))
+	}
+	if len(s.cols) > specWidth {
+		s.halve()
+	}
+	img := image.NewRGBA(image.Rect(0, 0, len(s.cols), specSize/2))
+	for x, col := range s.cols {
+		for i, level := range col {
+			img.Set(x, specSize/2-1-i, heat((level-specFloor)/-specFloor))
+		}
+	}
+	f, err := os.Create(s.name)
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, img); err != nil {
+		f.Close()
+		os.Remove(s.name)
+		return err
+	}
+	return f.Close()
+}
+
+// heat maps v in [0, 1] to a black, blue, red, yellow and white color scale.
+func heat(v float64) color.RGBA {
+	ramp := func(a, b float64) uint8 {
+		return uint8(255 * math.Max(0, math.Min(1, (v-a)/(b-a))))
+	}
+	c := color.RGBA{R: ramp(0.25, 0.5), G: ramp(0.5, 0.75), B: ramp(0, 0.25), A: 255}
+	switch {
+	case v > 0.75:
+		c.B = ramp(0.75, 1)
+	case v > 0.25:
+		c.B = 255 - ramp(0.25, 0.5)
+	}
+	return c
+}
@@ -0,0 +1,88 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+)
+
+// renderSpectrogram returns the spectrogram of the samples of x, written
+// as stereo I16 data in odd sized chunks.
+func renderSpectrogram(t *testing.T, x []float64) image.Image {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "spec.png")
+	s, err := newSpectrogram(name, resample.I16, 2, binary.LittleEndian)
+	if err != nil {
+		t.Fatal("newSpectrogram failed:", err)
+	}
+	p, _ := testsignal.Encode(x, 2, testsignal.I16)
+	for len(p) > 0 {
+		n := min(len(p), 1001)
+		s.Write(p[:n])
+		p = p[n:]
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal("Invalid PNG:", err)
+	}
+	return img
+}
+
+func TestSpectrogram(t *testing.T) {
+	// 30 half overlapping windows and the rest of the samples.
+	img := renderSpectrogram(t, testsignal.Sine(1000, 0.5, 8000, 16000))
+	if b := img.Bounds(); b.Dx() != 31 || b.Dy() != specSize/2 {
+		t.Fatalf("Image of %dx%d, expecting 31x%d", b.Dx(), b.Dy(), specSize/2)
+	}
+	// 1 kHz is the bin 128 of 1024 at 8 kHz, counted from the bottom.
+	for _, x := range []int{1, 15, 29} {
+		row, best := -1, uint32(0)
+		for y := 0; y < specSize/2; y++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if r+g+b > best {
+				row, best = y, r+g+b
+			}
+		}
+		if row != specSize/2-1-128 {
+			t.Errorf("Column %d peaks at row %d, expecting %d", x, row, specSize/2-1-128)
+		}
+	}
+
+	// Long outputs are merged to at most specWidth columns.
+	img = renderSpectrogram(t, make([]float64, 3*specWidth*specSize/2))
+	if w := img.Bounds().Dx(); w > specWidth || w <= specWidth/2 {
+		t.Errorf("Image of %d columns, expecting up to %d", w, specWidth)
+	}
+
+	// Existing images are only replaced with -f.
+	name := filepath.Join(t.TempDir(), "spec.png")
+	os.WriteFile(name, nil, 0o644)
+	if _, err := newSpectrogram(name, resample.I16, 1, binary.LittleEndian); err == nil {
+		t.Error("Existing image overwritten without -f")
+	}
+	if _, err := newSpectrogram(filepath.Join(t.TempDir(), "ulaw.png"), resample.MuLaw, 1, binary.LittleEndian); exitCode(err) != exitUsage {
+		t.Errorf("Spectrogram of u-law output returned %v", err)
+	}
+}
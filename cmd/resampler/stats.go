@@ -0,0 +1,128 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"time"
+
+	"github.com/zaf/resample"
+)
+
+// levelMeter measures the peak and RMS level and the DC offset of the
+// output samples written to it and counts the clipped ones, integer samples
+// at full scale and float samples beyond it. Writes must hold whole samples.
+type levelMeter struct {
+	format int
+	order  binary.ByteOrder
+	peak   float64 // largest absolute sample value
+	clips  int64   // clipped samples
+	sum    float64 // sum of the samples
+	sumSq  float64 // sum of the squared samples
+	n      int64   // number of samples
+}
+
+// newLevelMeter returns a levelMeter of output data of the given format,
+// or nil if its samples can't be analyzed.
+func newLevelMeter(format int, order binary.ByteOrder) *levelMeter {
+	if _, err := decodeSamples(nil, format, order); err != nil {
+		return nil
+	}
+	return &levelMeter{format: format, order: order}
+}
+
+func (m *levelMeter) Write(p []byte) {
+	full := 1.0
+	switch m.format {
+	case resample.I16:
+		full = float64(math.MaxInt16) / (1 << 15)
+	case resample.I32:
+		full = float64(math.MaxInt32) / (1 << 31)
+	}
+	v, _ := decodeSamples(p, m.format, m.order)
+	m.n += int64(len(v))
+	for _, x := range v {
+		m.sum += x
+		m.sumSq += x * x
+		x = math.Abs(x)
+		if x > m.peak {
+			m.peak = x
+		}
+		if x > full || x == full && m.format != resample.F32 && m.format != resample.F64 {
+			m.clips++
+		}
+	}
+}
+
+// rms returns the RMS level of the samples in dBFS, -Inf for silence.
+func (m *levelMeter) rms() float64 {
+	if m.n == 0 {
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(m.sumSq/float64(m.n))
+}
+
+// dc returns the DC offset of the samples, their mean relative to full scale.
+func (m *levelMeter) dc() float64 {
+	if m.n == 0 {
+		return 0
+	}
+	return m.sum / float64(m.n)
+}
+
+// runStats are the totals of a run printed by -stats.
+type runStats struct {
+	Conversions  int      `json:"conversions"`
+	Failed       int      `json:"failed"`
+	Skipped      int      `json:"skipped"`
+	InputFrames  int64    `json:"input_frames"`
+	OutputFrames int64    `json:"output_frames"`
+	Duration     float64  `json:"duration"`        // seconds of output
+	Peak         *float64 `json:"peak"`            // dBFS, null when silent or not measured
+	RMS          *float64 `json:"rms"`             // dBFS, null when silent or not measured
+	DC           *float64 `json:"dc"`              // mean sample value, null when not measured
+	Clips        int64    `json:"clips"`           // clipped output samples
+	Elapsed      float64  `json:"elapsed"`         // seconds of processing
+	Speed        float64  `json:"speed,omitempty"` // seconds of output per second of processing
+}
+
+// printStats writes the statistics of the run started at start as a line
+// of JSON, on standard error when an output is written to standard output.
+func printStats(t *tally, jobs []job, start time.Time) {
+	s := runStats{
+		Conversions:  len(jobs),
+		Failed:       t.failed,
+		Skipped:      t.skipped,
+		InputFrames:  t.inFrames,
+		OutputFrames: t.outFrames,
+		Duration:     t.duration,
+		Peak:         finite(20 * math.Log10(t.peak)),
+		RMS:          finite(10 * math.Log10(t.sumSq/float64(t.samples))),
+		Clips:        t.clips,
+		Elapsed:      time.Since(start).Seconds(),
+	}
+	if t.samples > 0 {
+		dc := t.sum / float64(t.samples)
+		s.DC = &dc
+	}
+	if s.Elapsed > 0 {
+		s.Speed = s.Duration / s.Elapsed
+	}
+	w := os.Stdout
+	for _, j := range jobs {
+		if j.output == "-" {
+			w = os.Stderr
+		}
+	}
+	b, _ := json.Marshal(s)
+	w.Write(append(b, '\n'))
+}
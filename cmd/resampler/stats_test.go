@@ -0,0 +1,115 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zaf/resample"
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestLevelMeter(t *testing.T) {
+	// A 0.5 sine with a 0.1 DC offset: 0.6 peak, 0.1 mean and a mean
+	// square of 0.5²/2 + 0.1².
+	x := testsignal.Sine(1000, 0.5, 8000, 8000)
+	for i := range x {
+		x[i] += 0.1
+	}
+	p, _ := testsignal.Encode(x, 2, testsignal.F32)
+	m := newLevelMeter(resample.F32, binary.LittleEndian)
+	m.Write(p[:len(p)/2])
+	m.Write(p[len(p)/2:])
+	if m.n != 16000 || math.Abs(m.peak-0.6) > 1e-6 || m.clips != 0 {
+		t.Errorf("%d samples, peak %g, %d clips", m.n, m.peak, m.clips)
+	}
+	if rms := 10 * math.Log10(0.125+0.01); math.Abs(m.rms()-rms) > 1e-4 {
+		t.Errorf("RMS %g dBFS, expecting %g dBFS", m.rms(), rms)
+	}
+	if math.Abs(m.dc()-0.1) > 1e-6 {
+		t.Errorf("DC %g, expecting 0.1", m.dc())
+	}
+
+	// Integer samples at full scale are clipped, float ones beyond it.
+	for _, tc := range []struct {
+		format, clips int
+		x             []float64
+	}{
+		{testsignal.I16, 2, []float64{1, -1, 0.5, 0}},
+		{testsignal.F32, 1, []float64{1, -1.5, 0.5, 0}},
+	} {
+		p, _ = testsignal.Encode(tc.x, 1, tc.format)
+		m = newLevelMeter(tc.format, binary.LittleEndian)
+		if m.Write(p); m.clips != int64(tc.clips) {
+			t.Errorf("%s: %d clipped samples, expecting %d", resample.FormatName(tc.format), m.clips, tc.clips)
+		}
+	}
+	if m = newLevelMeter(resample.I16, binary.LittleEndian); m.rms() != math.Inf(-1) || m.dc() != 0 {
+		t.Errorf("Nothing written: RMS %g, DC %g", m.rms(), m.dc())
+	}
+}
+
+func TestStats(t *testing.T) {
+	sine := testsignal.Sine(1000, 0.5, 8000, 8000)
+	offset := make([]float64, len(sine))
+	for i, v := range sine {
+		offset[i] = v + 0.1
+	}
+	dir := t.TempDir()
+	jobs := []job{
+		{[]string{writeWAV(t, "sine.wav", sine, 8000, 2, testsignal.F32)}, filepath.Join(dir, "sine.wav")},
+		{[]string{writeWAV(t, "offset.wav", offset, 8000, 2, testsignal.F32)}, filepath.Join(dir, "offset.wav")},
+	}
+	// The rate is kept, but the FIR backend still filters the samples.
+	setFlag(t, convertOnly, true)
+	setFlag(t, stats, true)
+
+	var tl tally
+	var results []result
+	for _, j := range jobs {
+		var r result
+		err := convertFile(j, &r, &progress{})
+		if err != nil {
+			t.Fatal("convertFile failed:", err)
+		}
+		tl.add(j, &r, err)
+		results = append(results, r)
+	}
+	for i, want := range []struct{ peak, rms, dc float64 }{
+		{0.5, 0.125, 0},
+		{0.6, 0.135, 0.1},
+	} {
+		o := results[i].Output
+		if o.Peak == nil || o.RMS == nil || o.DC == nil {
+			t.Fatalf("%s: peak %v, RMS %v, DC %v", o.File, o.Peak, o.RMS, o.DC)
+		}
+		if math.Abs(*o.Peak-20*math.Log10(want.peak)) > 0.1 || math.Abs(*o.RMS-10*math.Log10(want.rms)) > 0.01 || math.Abs(*o.DC-want.dc) > 1e-5 {
+			t.Errorf("%s: peak %.4f dBFS, RMS %.4f dBFS, DC %g", o.File, *o.Peak, *o.RMS, *o.DC)
+		}
+	}
+
+	out, _ := captureStdout(t, func() error { printStats(&tl, jobs, time.Now()); return nil })
+	var s runStats
+	if err := json.Unmarshal([]byte(out), &s); err != nil || s.Peak == nil || s.RMS == nil || s.DC == nil {
+		t.Fatalf("Invalid JSON %q: %v", out, err)
+	}
+	if s.Conversions != 2 || s.Failed != 0 || s.InputFrames != 16000 || s.OutputFrames != 16000 || s.Duration != 2 || s.Clips != 0 {
+		t.Errorf("Totals %+v", s)
+	}
+	// The peak is the largest of the files, the RMS level and DC offset
+	// those of all their samples.
+	if math.Abs(*s.Peak-20*math.Log10(0.6)) > 0.1 || math.Abs(*s.RMS-10*math.Log10(0.13)) > 0.01 || math.Abs(*s.DC-0.05) > 1e-5 {
+		t.Errorf("Peak %.4f dBFS, RMS %.4f dBFS, DC %g", *s.Peak, *s.RMS, *s.DC)
+	}
+}
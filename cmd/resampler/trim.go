@@ -0,0 +1,96 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseTime parses a time given in seconds ("90.5"), as a Go duration
+// ("1m30s") or as [hh:]mm:ss[.frac].
+func parseTime(s string) (time.Duration, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil && v >= 0 {
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	var d float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i < len(parts)-1 && strings.Contains(p, ".")) {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		d = d*60 + v
+	}
+	return time.Duration(d * float64(time.Second)), nil
+}
+
+// timeFrames converts a duration to a number of frames at rate.
+func timeFrames(d time.Duration, rate float64) int64 {
+	return int64(math.Round(d.Seconds() * rate))
+}
+
+// trimInput applies the -ss and -t flags to the data of in, skipping the
+// start of the input and limiting what follows.
+func trimInput(in *input) error {
+	frame := int64(in.frameSize())
+	if *start != "" {
+		d, err := parseTime(*start)
+		if err != nil {
+			return usageError(fmt.Errorf("-ss: %w", err))
+		}
+		skip := timeFrames(d, in.rate) * frame
+		if n, err := io.CopyN(io.Discard, in.data, skip); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("-ss %s is past the end of the input (%d frames)", *start, n/frame)
+			}
+			return err
+		}
+	}
+	if *length != "" {
+		d, err := parseTime(*length)
+		if err != nil {
+			return usageError(fmt.Errorf("-t: %w", err))
+		}
+		in.data = io.LimitReader(in.data, timeFrames(d, in.rate)*frame)
+	}
+	return nil
+}
+
+// limitWriter passes the first n bytes written to it to w and drops the
+// rest, capping the output of the -duration flag.
+type limitWriter struct {
+	w io.Writer
+	n int64 // bytes left
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	b := p
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+	if len(b) > 0 {
+		n, err := l.w.Write(b)
+		l.n -= int64(n)
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
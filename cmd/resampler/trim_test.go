@@ -0,0 +1,89 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zaf/resample"
+)
+
+func TestParseTime(t *testing.T) {
+	for _, tc := range []struct {
+		s string
+		d time.Duration
+	}{
+		{"0", 0},
+		{"90.5", 90500 * time.Millisecond},
+		{"1m30s", 90 * time.Second},
+		{"250ms", 250 * time.Millisecond},
+		{"01:30", 90 * time.Second},
+		{"1:02:03.5", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+	} {
+		if d, err := parseTime(tc.s); err != nil || d != tc.d {
+			t.Errorf("%s: %s, %v, expected %s", tc.s, d, err, tc.d)
+		}
+	}
+	for _, s := range []string{"", "-1", "-1s", "abc", "1:2:3:4", "1.5:30", "1:-30", "1:x"} {
+		if _, err := parseTime(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestTrimInput(t *testing.T) {
+	// 10 frames of I16 stereo at 10 Hz, numbered.
+	var data []byte
+	for i := 0; i < 10; i++ {
+		data = append(data, byte(i), 0, byte(i), 0)
+	}
+	newInput := func() *input {
+		return &input{data: bytes.NewReader(data), rate: 10, channels: 2, format: resample.I16}
+	}
+	for _, tc := range []struct {
+		start, length string
+		first, frames int
+	}{
+		{"", "", 0, 10},
+		{"0.3", "", 3, 7},
+		{"", "500ms", 0, 5},
+		{"0:00.2", "0.4", 2, 4},
+		{"0.8", "1", 8, 2},
+	} {
+		setFlag(t, start, tc.start)
+		setFlag(t, length, tc.length)
+		in := newInput()
+		if err := trimInput(in); err != nil {
+			t.Errorf("-ss %q -t %q: %v", tc.start, tc.length, err)
+			continue
+		}
+		p, _ := io.ReadAll(in.data)
+		if len(p) != 4*tc.frames || tc.frames > 0 && int(p[0]) != tc.first {
+			t.Errorf("-ss %q -t %q: %d frames from %v, expected %d from %d", tc.start, tc.length, len(p)/4, p[:min(len(p), 1)], tc.frames, tc.first)
+		}
+	}
+
+	for _, tc := range []struct {
+		start, length string
+		code          int
+	}{
+		{"2", "", exitFailure},
+		{"x", "", exitUsage},
+		{"", "x", exitUsage},
+	} {
+		setFlag(t, start, tc.start)
+		setFlag(t, length, tc.length)
+		if err := trimInput(newInput()); err == nil || exitCode(err) != tc.code {
+			t.Errorf("-ss %q -t %q: error %v, exit code %d", tc.start, tc.length, err, exitCode(err))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// digests are the hash functions of -verify.
+var digests = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// parseVerify parses the value of the -verify flag, a hash function name
+// optionally followed by a colon and the expected digest in hex.
+func parseVerify(spec string) (func() hash.Hash, string, error) {
+	name, expected, _ := strings.Cut(spec, ":")
+	newHash, ok := digests[strings.ToLower(name)]
+	if !ok {
+		return nil, "", fmt.Errorf("-verify: unknown digest %s, use md5, sha1 or sha256", name)
+	}
+	expected = strings.ToLower(expected)
+	if _, err := hex.DecodeString(expected); err != nil || len(expected) != 0 && len(expected) != 2*newHash().Size() {
+		return nil, "", fmt.Errorf("-verify: invalid %s digest %q", name, expected)
+	}
+	return newHash, expected, nil
+}
+
+// checkDigest records the digest of the output samples in r and compares
+// it with the one expected by -verify.
+func checkDigest(out *output, r *result) error {
+	if out.hash == nil {
+		return nil
+	}
+	r.Output.Digest = hex.EncodeToString(out.hash.Sum(nil))
+	if _, expected, _ := parseVerify(*verify); expected != "" && expected != r.Output.Digest {
+		return fmt.Errorf("output digest %s doesn't match the expected %s", r.Output.Digest, expected)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestParseVerify(t *testing.T) {
+	for _, spec := range []string{"md5", "SHA1", "sha256", "md5:" + strings.Repeat("0", 32), "sha256:" + strings.Repeat("aB", 32)} {
+		if _, _, err := parseVerify(spec); err != nil {
+			t.Errorf("%s: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "crc32", "md5:" + strings.Repeat("0", 31), "sha1:" + strings.Repeat("0", 32), "sha256:" + strings.Repeat("x", 64)} {
+		if _, _, err := parseVerify(spec); err == nil {
+			t.Errorf("Invalid spec %q accepted", spec)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	in := writeWAV(t, "in.wav", testsignal.Sine(440, 0.5, 8000, 8000), 8000, 2, testsignal.F32)
+	dir := t.TempDir()
+	setFlag(t, or, 16000)
+	setFlag(t, quiet, true)
+	setFlag(t, verify, "sha256")
+
+	// The digest is of the output samples, printed in the format of sha256sum.
+	out := filepath.Join(dir, "out.wav")
+	var r result
+	err := convertFile(job{[]string{in}, out}, &r, &progress{})
+	if err != nil {
+		t.Fatal("convertFile failed:", err)
+	}
+	_, samples := readWAV(t, out)
+	sum := sha256.Sum256(samples)
+	digest := hex.EncodeToString(sum[:])
+	if r.Output.Digest != digest {
+		t.Errorf("Digest %s, expecting %s", r.Output.Digest, digest)
+	}
+	var tl tally
+	printed, _ := captureStdout(t, func() error { tl.add(job{[]string{in}, out}, &r, nil); return nil })
+	if printed != digest+"  "+out+"\n" || tl.code != 0 {
+		t.Errorf("Printed %q, exit code %d", printed, tl.code)
+	}
+
+	// The expected digest passes.
+	setFlag(t, verify, "sha256:"+strings.ToUpper(digest))
+	out = filepath.Join(dir, "match.wav")
+	r = result{}
+	if err = convertFile(job{[]string{in}, out}, &r, &progress{}); err != nil || r.Output.Digest != digest {
+		t.Errorf("Matching digest: %v, digest %s", err, r.Output.Digest)
+	}
+	if _, err = os.Stat(out); err != nil {
+		t.Error("Output missing:", err)
+	}
+
+	// Another digest fails the conversion with an output error and
+	// deletes the output.
+	setFlag(t, verify, "sha256:"+strings.Repeat("0", 64))
+	out = filepath.Join(dir, "mismatch.wav")
+	r = result{}
+	err = convertFile(job{[]string{in}, out}, &r, &progress{})
+	if err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Fatal("Mismatching digest returned", err)
+	}
+	tl = tally{}
+	printed, _ = captureStdout(t, func() error { tl.add(job{[]string{in}, out}, &r, err); return nil })
+	if printed != "" || tl.failed != 1 || tl.code != exitOutput {
+		t.Errorf("Printed %q, %d failed, exit code %d", printed, tl.failed, tl.code)
+	}
+	if _, err = os.Stat(out); !os.IsNotExist(err) {
+		t.Error("Output of the mismatch kept:", err)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettle is how long the watched directory has to be quiet before it
+// is scanned, and how often it is scanned while files are still changing.
+const watchSettle = time.Second
+
+// watchedFile is the state of an audio file found in the watched directory.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	done    bool // converted at this size and time
+}
+
+// watcher finds the files to convert in a watched directory.
+type watcher struct {
+	dir     string
+	dst     string                  // output directory, empty with -o
+	files   map[string]*watchedFile // audio files of the last scan
+	outputs map[string]bool         // files written by the conversions
+}
+
+// watch converts the audio files arriving in dir until the program is
+// interrupted. args holds the output directory, unless -o is set.
+func watch(dir string, args []string) error {
+	w := &watcher{dir: filepath.Clean(dir), files: map[string]*watchedFile{}, outputs: map[string]bool{}}
+	switch {
+	case *outTemplate != "" && len(args) != 0:
+		return usageError(errors.New("-watch with -o takes no arguments"))
+	case *outTemplate == "" && len(args) != 1:
+		return usageError(errors.New("-watch needs an output directory"))
+	case *outTemplate == "":
+		w.dst = filepath.Clean(args[0])
+		if !isDir(w.dst) {
+			return usageError(errors.New("output directory " + w.dst + " doesn't exist"))
+		}
+	}
+	if !isDir(w.dir) {
+		return usageError(errors.New(w.dir + " is not a directory"))
+	}
+	if *dry || *specOut != "" || *stats {
+		return usageError(errors.New("-n, -spectrogram and -stats can't be used with -watch"))
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	if err = w.add(fw, w.dir); err != nil {
+		return inputError(err)
+	}
+	debugf("Watching %s", w.dir)
+	// The files already there are scanned first.
+	settle := time.NewTimer(0)
+	for {
+		select {
+		case ev := <-fw.Events:
+			if ev.Has(fsnotify.Create) && *recursive && isDir(ev.Name) {
+				if err = w.add(fw, ev.Name); err != nil {
+					return inputError(err)
+				}
+			}
+			settle.Reset(watchSettle)
+		case err = <-fw.Errors:
+			return inputError(err)
+		case <-settle.C:
+			jobs, err := w.scan()
+			if err != nil {
+				return inputError(err)
+			}
+			if len(jobs) > 0 {
+				var t tally
+				runJobs(jobs, newProgress(jobs), convertFile, func(j job, r *result, err error) {
+					if r.Output.File != "" {
+						w.outputs[filepath.Clean(r.Output.File)] = true
+					}
+					t.add(j, r, err)
+				})
+			}
+			if w.pending() {
+				// Check again that the files found stopped changing.
+				settle.Reset(watchSettle)
+			}
+		}
+	}
+}
+
+// add watches dir for new and changed files, and its subdirectories in
+// recursive mode, except for the output directory.
+func (w *watcher) add(fw *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if path != dir && !*recursive || path == w.dst {
+			return fs.SkipDir
+		}
+		return fw.Add(path)
+	})
+}
+
+// pending reports whether files found by the last scan wait for their
+// size and modification time to settle.
+func (w *watcher) pending() bool {
+	for _, f := range w.files {
+		if !f.done {
+			return true
+		}
+	}
+	return false
+}
+
+// scan returns a conversion for each audio file whose size and modification
+// time didn't change since the previous scan and that wasn't converted yet.
+// Hidden files, such as the temporary outputs, and the files written by the
+// conversions are ignored.
+func (w *watcher) scan() ([]job, error) {
+	var jobs []job
+	seen := map[string]bool{}
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path != w.dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != w.dir && (!*recursive || path == w.dst) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || !audioExts[strings.ToLower(filepath.Ext(path))] || w.outputs[path] {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		seen[path] = true
+		f := w.files[path]
+		if f == nil || f.size != fi.Size() || !f.modTime.Equal(fi.ModTime()) {
+			// New or still growing, wait for the next scan.
+			w.files[path] = &watchedFile{size: fi.Size(), modTime: fi.ModTime()}
+			return nil
+		}
+		if f.done {
+			return nil
+		}
+		f.done = true
+		j := job{inputs: []string{path}}
+		if w.dst != "" {
+			rel, err := filepath.Rel(w.dir, path)
+			if err != nil {
+				return err
+			}
+			j.output = filepath.Join(w.dst, rel)
+		}
+		jobs = append(jobs, j)
+		return nil
+	})
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+		}
+	}
+	return jobs, err
+}
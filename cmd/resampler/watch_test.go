@@ -0,0 +1,122 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatcherScan(t *testing.T) {
+	dir, dst := t.TempDir(), t.TempDir()
+	write := func(name string, size int) string {
+		t.Helper()
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0o755)
+		if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	scan := func(w *watcher) []job {
+		t.Helper()
+		jobs, err := w.scan()
+		if err != nil {
+			t.Fatal("scan failed:", err)
+		}
+		return jobs
+	}
+	a := write("a.wav", 100)
+	write(".a.wav.tmp", 100)
+	write("notes.txt", 100)
+	write("sub/b.wav", 100)
+	out := write("out.wav", 100)
+
+	setFlag(t, recursive, false)
+	w := &watcher{dir: dir, dst: dst, files: map[string]*watchedFile{}, outputs: map[string]bool{out: true}}
+	if jobs := scan(w); len(jobs) != 0 || !w.pending() {
+		t.Fatalf("First scan: jobs %v, pending %t", jobs, w.pending())
+	}
+	// Converted once the size and modification time are the same as at the
+	// previous scan.
+	want := []job{{[]string{a}, filepath.Join(dst, "a.wav")}}
+	if jobs := scan(w); !reflect.DeepEqual(jobs, want) || w.pending() {
+		t.Fatalf("Second scan: jobs %v, pending %t", jobs, w.pending())
+	}
+	if jobs := scan(w); len(jobs) != 0 {
+		t.Fatalf("Third scan: jobs %v", jobs)
+	}
+	// A file growing again is converted again once it settles.
+	write("a.wav", 200)
+	if jobs := scan(w); len(jobs) != 0 || !w.pending() {
+		t.Fatalf("Scan of a growing file: jobs %v, pending %t", jobs, w.pending())
+	}
+	if jobs := scan(w); !reflect.DeepEqual(jobs, want) {
+		t.Fatalf("Scan of a settled file: jobs %v", jobs)
+	}
+
+	setFlag(t, recursive, true)
+	w = &watcher{dir: dir, dst: dst, files: map[string]*watchedFile{}, outputs: map[string]bool{}}
+	scan(w)
+	var names []string
+	for _, j := range scan(w) {
+		names = append(names, j.output)
+	}
+	sort.Strings(names)
+	if want := []string{filepath.Join(dst, "a.wav"), filepath.Join(dst, "out.wav"), filepath.Join(dst, "sub", "b.wav")}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Recursive outputs %v, expected %v", names, want)
+	}
+}
+
+func TestWatcherAdd(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"sub/deeper", "out"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0o755)
+	}
+	for _, tc := range []struct {
+		recursive bool
+		watched   []string
+	}{
+		{false, []string{dir}},
+		{true, []string{dir, filepath.Join(dir, "sub"), filepath.Join(dir, "sub", "deeper")}},
+	} {
+		setFlag(t, recursive, tc.recursive)
+		fw, err := fsnotify.NewWatcher()
+		if err != nil {
+			t.Skip("fsnotify isn't available:", err)
+		}
+		w := &watcher{dir: dir, dst: filepath.Join(dir, "out")}
+		if err = w.add(fw, dir); err != nil {
+			t.Fatal("add failed:", err)
+		}
+		watched := fw.WatchList()
+		sort.Strings(watched)
+		if !reflect.DeepEqual(watched, tc.watched) {
+			t.Errorf("Recursive %t: watching %v, expected %v", tc.recursive, watched, tc.watched)
+		}
+		// Files created in a watched directory are notified.
+		os.WriteFile(filepath.Join(dir, "new.wav"), nil, 0o644)
+		select {
+		case ev := <-fw.Events:
+			if ev.Name != filepath.Join(dir, "new.wav") {
+				t.Errorf("Event of %s", ev.Name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("No event of a new file")
+		}
+		os.Remove(filepath.Join(dir, "new.wav"))
+		fw.Close()
+	}
+}
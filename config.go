@@ -0,0 +1,216 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Config describes the conversion of a Resampler, the arguments of New.
+// It marshals to JSON with the formats and quality named, such as
+// {"in_rate":44100,"out_rate":48000,"channels":2,"in_format":"i16",
+// "out_format":"f32","quality":"high"}, so that it can be logged and
+// stored, and New makes an equivalent Resampler. Options aren't included.
+type Config struct {
+	InRate    float64
+	OutRate   float64
+	Channels  int
+	InFormat  int
+	OutFormat int
+	Quality   int
+}
+
+// configJSON is the JSON encoding of a Config.
+type configJSON struct {
+	InRate    float64 `json:"in_rate"`
+	OutRate   float64 `json:"out_rate"`
+	Channels  int     `json:"channels"`
+	InFormat  string  `json:"in_format"`
+	OutFormat string  `json:"out_format"`
+	Quality   string  `json:"quality"`
+}
+
+// formatNames are the names of the formats in the JSON encoding of a Config.
+var formatNames = map[int]string{
+	F32:     "f32",
+	F64:     "f64",
+	I32:     "i32",
+	I16:     "i16",
+	MuLaw:   "ulaw",
+	ALaw:    "alaw",
+	I24In32: "i24in32",
+}
+
+// qualityNames are the names of the quality settings in the JSON encoding
+// of a Config.
+var qualityNames = map[int]string{
+	Quick:        "quick",
+	LowQ:         "low",
+	MediumQ:      "medium",
+	HighQ:        "high",
+	VeryHighQ:    "vhigh",
+	SincBestQ:    "sincbest",
+	SincMediumQ:  "sincmedium",
+	SincFastestQ: "sincfastest",
+}
+
+// Config returns the configuration of the Resampler, at its current output
+// rate.
+func (r *Resampler) Config() Config {
+	return Config{
+		InRate:    r.inRate,
+		OutRate:   r.outRate,
+		Channels:  r.channels,
+		InFormat:  r.inFormat,
+		OutFormat: r.outFormat,
+		Quality:   r.quality,
+	}
+}
+
+// New returns a Resampler with the configuration c writing to writer, as
+// the package's New does.
+func (c Config) New(writer io.Writer, opts ...Option) (*Resampler, error) {
+	return New(writer, c.InRate, c.OutRate, c.Channels, c.InFormat, c.OutFormat, c.Quality, opts...)
+}
+
+// String returns a description of c such as
+// "44100 Hz i16 -> 48000 Hz f32, 2 channels, high quality".
+func (c Config) String() string {
+	return fmt.Sprintf("%s Hz %s -> %s Hz %s, %d channels, %s quality",
+		strconv.FormatFloat(c.InRate, 'f', -1, 64), name(formatNames, c.InFormat),
+		strconv.FormatFloat(c.OutRate, 'f', -1, 64), name(formatNames, c.OutFormat),
+		c.Channels, name(qualityNames, c.Quality))
+}
+
+// name returns the name of v, or v in decimal when it has none.
+func name(names map[int]string, v int) string {
+	if s, ok := names[v]; ok {
+		return s
+	}
+	return strconv.Itoa(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c Config) MarshalJSON() ([]byte, error) {
+	j := configJSON{InRate: c.InRate, OutRate: c.OutRate, Channels: c.Channels}
+	var ok bool
+	if j.InFormat, ok = formatNames[c.InFormat]; !ok {
+		return nil, fmt.Errorf("invalid input format %d", c.InFormat)
+	}
+	if j.OutFormat, ok = formatNames[c.OutFormat]; !ok {
+		return nil, fmt.Errorf("invalid output format %d", c.OutFormat)
+	}
+	if j.Quality, ok = qualityNames[c.Quality]; !ok {
+		return nil, fmt.Errorf("invalid quality setting %d", c.Quality)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var j configJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	inFormat, ok := lookup(formatNames, j.InFormat)
+	if !ok {
+		return fmt.Errorf("unknown input format %q", j.InFormat)
+	}
+	outFormat, ok := lookup(formatNames, j.OutFormat)
+	if !ok {
+		return fmt.Errorf("unknown output format %q", j.OutFormat)
+	}
+	quality, ok := lookup(qualityNames, j.Quality)
+	if !ok {
+		return fmt.Errorf("unknown quality %q", j.Quality)
+	}
+	*c = Config{
+		InRate:    j.InRate,
+		OutRate:   j.OutRate,
+		Channels:  j.Channels,
+		InFormat:  inFormat,
+		OutFormat: outFormat,
+		Quality:   quality,
+	}
+	return nil
+}
+
+// lookup returns the value named s in names.
+func lookup(names map[int]string, s string) (int, bool) {
+	for v, n := range names {
+		if n == s {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// ParseFormat returns the format named s, in any case: f32, f64, i32, i16,
+// ulaw, alaw or i24in32, the names of the JSON encoding of a Config.
+func ParseFormat(s string) (int, error) {
+	if format, ok := lookup(formatNames, strings.ToLower(s)); ok {
+		return format, nil
+	}
+	return 0, fmt.Errorf("unknown format %s", s)
+}
+
+// FormatName returns the name of format that ParseFormat accepts, or
+// "unknown".
+func FormatName(format int) string {
+	if s, ok := formatNames[format]; ok {
+		return s
+	}
+	return "unknown"
+}
+
+// ParseQuality returns the quality setting named s, in any case: quick,
+// low, medium, high, vhigh, sincbest, sincmedium or sincfastest, the names
+// of the JSON encoding of a Config.
+func ParseQuality(s string) (int, error) {
+	if quality, ok := lookup(qualityNames, strings.ToLower(s)); ok {
+		return quality, nil
+	}
+	return 0, fmt.Errorf("unknown quality %s", s)
+}
+
+// InRate returns the input sampling rate of the Resampler.
+func (r *Resampler) InRate() float64 {
+	return r.inRate
+}
+
+// OutRate returns the output sampling rate of the Resampler, or the last
+// one set by SetRate.
+func (r *Resampler) OutRate() float64 {
+	return r.outRate
+}
+
+// Channels returns the number of channels of the input data. With WithMix
+// the output has one per row of the mixing matrix instead.
+func (r *Resampler) Channels() int {
+	return r.channels
+}
+
+// InFormat returns the input format of the Resampler.
+func (r *Resampler) InFormat() int {
+	return r.inFormat
+}
+
+// OutFormat returns the output format of the Resampler.
+func (r *Resampler) OutFormat() int {
+	return r.outFormat
+}
+
+// Quality returns the quality setting the Resampler was created with.
+func (r *Resampler) Quality() int {
+	return r.quality
+}
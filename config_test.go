@@ -0,0 +1,109 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	res, err := New(io.Discard, 44100.0, 48000.0, 2, I16, F32, LowQ, WithVariableRate())
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	defer res.Close()
+	if res.InRate() != 44100 || res.OutRate() != 48000 {
+		t.Errorf("Rates %g and %g, expected 44100 and 48000", res.InRate(), res.OutRate())
+	}
+	if res.Channels() != 2 {
+		t.Errorf("%d channels, expected 2", res.Channels())
+	}
+	if res.InFormat() != I16 || res.OutFormat() != F32 {
+		t.Errorf("Formats %d and %d, expected %d and %d", res.InFormat(), res.OutFormat(), I16, F32)
+	}
+	if res.Quality() != LowQ {
+		t.Errorf("Quality %d, expected %d", res.Quality(), LowQ)
+	}
+	if err = res.SetRate(32000); err != nil {
+		t.Fatal("SetRate failed:", err)
+	}
+	if res.OutRate() != 32000 {
+		t.Errorf("Output rate %g after SetRate, expected 32000", res.OutRate())
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	res, err := New(io.Discard, 44100.0, 48000.0, 2, I24In32, F32, SincMediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	c := res.Config()
+	res.Close()
+	if s, want := c.String(), "44100 Hz i24in32 -> 48000 Hz f32, 2 channels, sincmedium quality"; s != want {
+		t.Errorf("String: %s, expected %s", s, want)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal("Marshal failed:", err)
+	}
+	if want := `{"in_rate":44100,"out_rate":48000,"channels":2,"in_format":"i24in32","out_format":"f32","quality":"sincmedium"}`; string(data) != want {
+		t.Errorf("JSON: %s, expected %s", data, want)
+	}
+	var c2 Config
+	if err = json.Unmarshal(data, &c2); err != nil {
+		t.Fatal("Unmarshal failed:", err)
+	}
+	if c2 != c {
+		t.Errorf("Unmarshaled %v, expected %v", c2, c)
+	}
+	if res, err = c2.New(io.Discard); err != nil {
+		t.Fatal("Failed to create a Resampler from a Config:", err)
+	}
+	if res.Config() != c {
+		t.Errorf("Resampler created with %v, expected %v", res.Config(), c)
+	}
+	res.Close()
+	if _, err = json.Marshal(Config{InRate: 8000, OutRate: 16000, Channels: 1, InFormat: 11}); err == nil {
+		t.Error("Invalid format marshaled")
+	}
+	if err = json.Unmarshal([]byte(`{"in_format":"i24","out_format":"i16","quality":"high"}`), &c2); err == nil {
+		t.Error("Unknown format unmarshaled")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for format, name := range formatNames {
+		if f, err := ParseFormat(strings.ToUpper(name)); err != nil || f != format {
+			t.Errorf("ParseFormat(%q) = %d, %v, expected %d", strings.ToUpper(name), f, err, format)
+		}
+		if s := FormatName(format); s != name {
+			t.Errorf("FormatName(%d) = %s, expected %s", format, s, name)
+		}
+		if size, _ := sizeOf(format); FormatSize(format) != size {
+			t.Errorf("FormatSize(%d) = %d, expected %d", format, FormatSize(format), size)
+		}
+	}
+	if _, err := ParseFormat("i24"); err == nil {
+		t.Error("Unknown format parsed")
+	}
+	if s := FormatName(11); s != "unknown" || FormatSize(11) != 0 {
+		t.Errorf("Invalid format named %s of size %d", s, FormatSize(11))
+	}
+	for quality, name := range qualityNames {
+		if q, err := ParseQuality(name); err != nil || q != quality {
+			t.Errorf("ParseQuality(%q) = %d, %v, expected %d", name, q, err, quality)
+		}
+	}
+	if _, err := ParseQuality("best"); err == nil {
+		t.Error("Unknown quality parsed")
+	}
+}
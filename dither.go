@@ -0,0 +1,133 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Dither settings for integer output.
+const (
+	DitherTPDF        = 0 // Triangular PDF dither, done by soxr
+	DitherNone        = 1 // No dither, samples are rounded
+	DitherShaped      = 2 // Noise shaped TPDF dither, I16 output only
+	DitherShapedLight = 3 // Lightly noise shaped TPDF dither, I16 output only
+	DitherShapedF     = 4 // F-weighted noise shaped TPDF dither, I16 output only
+)
+
+// shapeCoefs is the Lipshitz minimally audible error feedback filter,
+// designed for 44.1 kHz, moving quantization noise above 10 kHz.
+var shapeCoefs = []float64{2.033, -2.165, 1.959, -1.590, 0.6149}
+
+// Wannamaker's F-weighted error feedback filters, designed for 44.1 kHz
+// after the ear's threshold of hearing: the 3 tap one lowers the noise
+// in the midrange a little, the 9 tap one, like Shibata's curves, pushes
+// most of it above 15 kHz where the ear is least sensitive.
+var (
+	lightCoefs   = []float64{1.623, -0.982, 0.109}
+	fWeightCoefs = []float64{2.412, -3.370, 3.937, -4.174, 3.353, -2.205, 1.281, -0.569, 0.0847}
+)
+
+// shapeCurves are the error feedback filters of the noise shaped dithers.
+var shapeCurves = map[int][]float64{
+	DitherShaped:      shapeCoefs,
+	DitherShapedLight: lightCoefs,
+	DitherShapedF:     fWeightCoefs,
+}
+
+// shaper quantizes F64 samples to I16, I32 or I24In32 with TPDF dither, noise
+// shaped by an error feedback filter or not.
+type shaper struct {
+	channels int
+	bits     int         // output resolution, 16, 24 in 32-bit words or 32
+	coefs    []float64   // error feedback filter, nil for plain TPDF dither
+	errs     [][]float64 // recent quantization errors of each channel, newest first
+	seed     *int64      // random seed, nil for a time based one
+	rng      *rand.Rand
+	clips    int64 // samples clipped since the last reset
+}
+
+func newShaper(channels, bits int, coefs []float64, seed *int64) *shaper {
+	s := &shaper{channels: channels, bits: bits, coefs: coefs, seed: seed}
+	s.reset()
+	return s
+}
+
+// reset clears the error feedback and restarts the random sequence, which
+// repeats when seeded.
+func (s *shaper) reset() {
+	s.clips = 0
+	s.errs = make([][]float64, s.channels)
+	for c := range s.errs {
+		s.errs[c] = make([]float64, len(s.coefs))
+	}
+	seed := time.Now().UnixNano()
+	if s.seed != nil {
+		seed = *s.seed
+	}
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// quantize converts little-endian F64 samples to the output size.
+func (s *shaper) quantize(p []byte) []byte {
+	size, shift := 4, 32-s.bits
+	if s.bits == 16 {
+		size = 2
+	}
+	scale := math.Ldexp(1, s.bits-1)
+	out := make([]byte, len(p)/8*size)
+	for i := 0; i < len(p)/8; i++ {
+		e := s.errs[i%s.channels]
+		w := math.Float64frombits(binary.LittleEndian.Uint64(p[8*i:])) * scale
+		for k, c := range s.coefs {
+			w -= c * e[k]
+		}
+		y := math.Round(w + s.rng.Float64() - s.rng.Float64())
+		if y > scale-1 || y < -scale {
+			y = math.Max(-scale, math.Min(scale-1, y))
+			s.clips++
+		}
+		if len(e) > 0 {
+			copy(e[1:], e)
+			// Keep the feedback bounded when clipping.
+			e[0] = math.Max(-1, math.Min(1, y-w))
+		}
+		if size == 2 {
+			binary.LittleEndian.PutUint16(out[2*i:], uint16(int16(y)))
+		} else {
+			binary.LittleEndian.PutUint32(out[4*i:], uint32(int32(y)<<shift))
+		}
+	}
+	return out
+}
+
+// WithDither sets the dither applied when the output format is I16, I32 or
+// I24In32. The default is DitherTPDF. The noise shaped dithers, for I16
+// output at 44.1 or 48 kHz, trade a higher total noise level for less
+// audible noise: DitherShapedLight slightly, DitherShaped more and
+// DitherShapedF the most, for mastering 24-bit audio down to 16 bits for
+// distribution. soxr has no noise shaping of its own, they are done in Go.
+func WithDither(dither int) Option {
+	return func(o *options) {
+		o.dither = dither
+	}
+}
+
+// WithDitherSeed makes the dither reproducible, seeding its random numbers
+// with seed so that the same input always gives the same output. TPDF
+// dither is then done in Go instead of by soxr, whose random numbers can't
+// be seeded. Reset restarts the sequence.
+func WithDitherSeed(seed int64) Option {
+	return func(o *options) {
+		o.seed = &seed
+	}
+}
@@ -0,0 +1,105 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// bandEnergy returns the energy of x in the lower and upper quarters of the spectrum.
+func bandEnergy(x []float64) (low, high float64) {
+	n := len(x)
+	for k := 1; k < n/2; k++ {
+		var sum complex128
+		for i, v := range x {
+			sum += complex(v, 0) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/float64(n)))
+		}
+		e := real(sum)*real(sum) + imag(sum)*imag(sum)
+		switch {
+		case k < n/8:
+			low += e
+		case k >= 3*n/8:
+			high += e
+		}
+	}
+	return low, high
+}
+
+func TestShaper(t *testing.T) {
+	const n = 1024
+	x := testsignal.Sine(37, 0.3, n, n)
+	in, _ := testsignal.Encode(x, 1, testsignal.F64)
+	// Seeded, as the largest errors of the F-weighted curve depend on the
+	// random sequence.
+	seed := int64(1)
+	for d, coefs := range shapeCurves {
+		out := newShaper(1, 16, coefs, &seed).quantize(in)
+		if len(out) != 2*n {
+			t.Fatalf("Output size: %d, expecting: %d", len(out), 2*n)
+		}
+		noise := make([]float64, n)
+		for i := range noise {
+			noise[i] = float64(int16(binary.LittleEndian.Uint16(out[2*i:]))) - x[i]*(1<<15)
+			if math.Abs(noise[i]) > 16 {
+				t.Fatalf("Dither %d: sample %d off by %.1f", d, i, noise[i])
+			}
+		}
+		if low, high := bandEnergy(noise); low*10 > high {
+			t.Errorf("Dither %d: noise isn't shaped: low band %.0f, high band %.0f", d, low, high)
+		}
+	}
+}
+
+func TestWithDither(t *testing.T) {
+	if _, err := New(io.Discard, 8000.0, 16000.0, 1, I16, F32, MediumQ, WithDither(DitherShaped)); err == nil {
+		t.Error("Shaped dither to F32 didn't return an error")
+	}
+	if _, err := New(io.Discard, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithDither(7)); err == nil {
+		t.Error("Invalid dither didn't return an error")
+	}
+	for _, d := range []int{DitherTPDF, DitherNone, DitherShaped, DitherShapedLight, DitherShapedF} {
+		res, err := New(io.Discard, 8000.0, 16000.0, 2, I16, I16, MediumQ, WithDither(d))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if _, err = res.Write(make([]byte, 4096)); err != nil {
+			t.Error("Write failed:", err)
+		}
+		res.Close()
+	}
+}
+
+func TestWithDitherSeed(t *testing.T) {
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.25, 8000, 4096), 1, testsignal.I16)
+	for _, format := range []int{I16, I32, MuLaw} {
+		var out [2]bytes.Buffer
+		for i := range out {
+			res, err := New(&out[i], 8000.0, 16000.0, 1, I16, format, MediumQ, WithDitherSeed(42))
+			if err != nil {
+				t.Fatal("Failed to create a Resampler:", err)
+			}
+			if _, err = res.Write(in); err != nil {
+				t.Fatal("Write failed:", err)
+			}
+			if err = res.Close(); err != nil {
+				t.Fatal("Close failed:", err)
+			}
+		}
+		if out[0].Len() == 0 || !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+			t.Errorf("Format %d: seeded outputs differ", format)
+		}
+	}
+}
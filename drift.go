@@ -0,0 +1,76 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	maxDrift       = 1000  // largest output rate correction in ppm
+	driftGain      = 10000 // proportional correction in ppm at 100% fill error
+	driftSteps     = 20    // fill error integration gain in ppm per update
+	driftSmoothing = 0.1   // weight of each update in the smoothed fill level
+)
+
+// DriftCorrector keeps a playout buffer filled by a Resampler, such as the
+// buffer of an audio device in a network audio receiver, at a target fill
+// level. The output rate of the Resampler is adjusted within ±1000 ppm to
+// follow the drift between the clock of the sender and the one of the
+// device, from the fill levels measured by the application. The buffer is
+// the one the output of the Resampler goes to, for a jitter buffer feeding
+// the Resampler report 2*target-fill instead.
+type DriftCorrector struct {
+	r        *Resampler
+	rate     float64 // nominal output rate
+	target   float64 // fill level to keep in frames
+	level    float64 // smoothed fill level, negative before the first update
+	integral float64 // integrated correction in ppm
+	ppm      float64 // current correction in ppm
+}
+
+// NewDriftCorrector returns a DriftCorrector of the output rate of r, which
+// must be created with WithVariableRate, keeping the buffer it fills at
+// target frames.
+func NewDriftCorrector(r *Resampler, target int) (*DriftCorrector, error) {
+	if !r.variable {
+		return nil, errors.New("resampler created without WithVariableRate")
+	}
+	if target <= 0 {
+		return nil, errors.New("invalid target fill level")
+	}
+	return &DriftCorrector{r: r, rate: r.outRate, target: float64(target), level: -1}, nil
+}
+
+// Update takes the fill level of the buffer in frames, measured at regular
+// intervals such as every packet, and adjusts the output rate. The level
+// is smoothed over about ten updates, so network jitter doesn't move the
+// rate around.
+func (d *DriftCorrector) Update(fill int) error {
+	if d.level < 0 {
+		d.level = float64(fill)
+	}
+	d.level += driftSmoothing * (float64(fill) - d.level)
+	e := (d.level - d.target) / d.target
+	// An overfilled buffer needs fewer output frames, a lower rate.
+	d.integral = clamp(d.integral-driftSteps*e, maxDrift)
+	d.ppm = clamp(d.integral-driftGain*e, maxDrift)
+	return d.r.SetRate(d.rate * (1 + d.ppm/1e6))
+}
+
+// Correction returns the current correction of the output rate in ppm.
+func (d *DriftCorrector) Correction() float64 {
+	return d.ppm
+}
+
+// clamp limits v to the range from -limit to limit.
+func clamp(v, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, v))
+}
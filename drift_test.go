@@ -0,0 +1,60 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+func TestDriftCorrector(t *testing.T) {
+	r, err := New(io.Discard, 8000.0, 48000.0, 1, I16, I16, MediumQ, WithVariableRate())
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	defer r.Close()
+	d, err := NewDriftCorrector(r, 4800)
+	if err != nil {
+		t.Fatal("NewDriftCorrector failed:", err)
+	}
+	// The device plays 300 ppm faster than the sender sends. Every 20 ms
+	// packet adds its resampled frames to the buffer, the device takes its
+	// own 960 frames.
+	fill := 4800.0
+	for i := 0; i < 30000; i++ {
+		fill += 160*r.outRate/8000 - 960*(1+300e-6)
+		if err = d.Update(int(fill)); err != nil {
+			t.Fatal("Update failed:", err)
+		}
+	}
+	if math.Abs(fill-4800) > 50 || math.Abs(d.Correction()-300) > 10 {
+		t.Errorf("Buffer at %.0f frames with a correction of %.1f ppm", fill, d.Correction())
+	}
+	// Corrections are limited to 1000 ppm.
+	for i := 0; i < 1000; i++ {
+		d.Update(0)
+	}
+	if d.Correction() != 1000 || math.Abs(r.outRate-48048) > 1e-6 {
+		t.Errorf("Correction of %.1f ppm for an empty buffer, output at %g Hz", d.Correction(), r.outRate)
+	}
+	for _, rate := range []float64{0, -8000, math.NaN(), math.Inf(1)} {
+		if err = r.SetRate(rate); err == nil {
+			t.Errorf("SetRate(%g) didn't return an error", rate)
+		}
+	}
+	r2, _ := New(io.Discard, 8000.0, 48000.0, 1, I16, I16, MediumQ)
+	defer r2.Close()
+	if _, err = NewDriftCorrector(r2, 4800); err == nil {
+		t.Error("No error for a fixed rate Resampler")
+	}
+	if err = r2.SetRate(44100); err == nil {
+		t.Error("SetRate of a fixed rate Resampler didn't return an error")
+	}
+}
@@ -0,0 +1,163 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+// Filters applied in Go, as stages, to the input before resampling or to
+// the output after it. Their state is kept per channel and cleared by Reset.
+
+// Filter types.
+const (
+	HighPass = 0 // Attenuates frequencies below the cutoff
+	LowPass  = 1 // Attenuates frequencies above the cutoff
+)
+
+// Filter is a high-pass or low-pass filter, of the first order with a
+// slope of 6 dB per octave, or a second order Butterworth with 12 dB per
+// octave. At the cutoff frequency the level is 3 dB down.
+type Filter struct {
+	Type   int     // HighPass or LowPass
+	Cutoff float64 // cutoff frequency in Hz
+	Order  int     // 1 or 2
+}
+
+// WithPreFilter filters the input, after any mixing and gain, before it is
+// resampled. An 80 Hz high-pass removes rumble and handling noise from
+// voice recordings.
+func WithPreFilter(filters ...Filter) Option {
+	return func(o *options) {
+		o.preFilters = filters
+	}
+}
+
+// WithPostFilter filters the resampled output before it is converted to
+// the output format.
+func WithPostFilter(filters ...Filter) Option {
+	return func(o *options) {
+		o.postFilters = filters
+	}
+}
+
+// WithDCBlock removes the DC offset of the input with a one-pole high-pass
+// filter at cutoff Hz, before resampling. Cutoffs of 5 to 20 Hz remove the
+// offset without touching audible bass. The default, 0, leaves the input
+// unfiltered.
+func WithDCBlock(cutoff float64) Option {
+	return func(o *options) {
+		o.dcCutoff = cutoff
+	}
+}
+
+// dcBlockStage returns a stage filtering interleaved samples of channels
+// at rate with a one-pole high-pass at cutoff Hz, of unity gain at the
+// Nyquist frequency, and a function clearing its state.
+func dcBlockStage(cutoff, rate float64, channels int) (stage, func(), error) {
+	if cutoff <= 0 || cutoff >= rate/2 {
+		return nil, nil, errors.New("invalid DC block cutoff")
+	}
+	pole := math.Exp(-2 * math.Pi * cutoff / rate)
+	gain := (1 + pole) / 2
+	x1 := make([]float64, channels)
+	y1 := make([]float64, channels)
+	st := func(s []float64) []float64 {
+		for i := range s {
+			c := i % channels
+			x := s[i]
+			s[i] = gain*(x-x1[c]) + pole*y1[c]
+			x1[c], y1[c] = x, s[i]
+		}
+		return s
+	}
+	reset := func() {
+		for c := range x1 {
+			x1[c], y1[c] = 0, 0
+		}
+	}
+	return st, reset, nil
+}
+
+// biquad is a second order IIR filter section.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// newBiquad returns the coefficients of f at rate, computed as in the
+// Audio EQ Cookbook for the second order and with the bilinear transform
+// for the first.
+func newBiquad(f Filter, rate float64) (biquad, error) {
+	if f.Cutoff <= 0 || f.Cutoff >= rate/2 {
+		return biquad{}, errors.New("invalid filter cutoff")
+	}
+	if f.Type != HighPass && f.Type != LowPass {
+		return biquad{}, errors.New("invalid filter type")
+	}
+	var q biquad
+	switch f.Order {
+	case 1:
+		k := math.Tan(math.Pi * f.Cutoff / rate)
+		q.a1 = (k - 1) / (k + 1)
+		if f.Type == LowPass {
+			q.b0, q.b1 = k/(1+k), k/(1+k)
+		} else {
+			q.b0, q.b1 = 1/(1+k), -1/(1+k)
+		}
+	case 2:
+		w := 2 * math.Pi * f.Cutoff / rate
+		alpha := math.Sin(w) / math.Sqrt2
+		a0 := 1 + alpha
+		q.a1, q.a2 = -2*math.Cos(w)/a0, (1-alpha)/a0
+		if f.Type == LowPass {
+			q.b1 = (1 - math.Cos(w)) / a0
+			q.b0, q.b2 = q.b1/2, q.b1/2
+		} else {
+			q.b1 = -(1 + math.Cos(w)) / a0
+			q.b0, q.b2 = -q.b1/2, -q.b1/2
+		}
+	default:
+		return biquad{}, errors.New("invalid filter order")
+	}
+	return q, nil
+}
+
+// filterStage returns a stage applying filters to interleaved samples of
+// channels at rate, and a function clearing its state.
+func filterStage(filters []Filter, rate float64, channels int) (stage, func(), error) {
+	coefs := make([]biquad, len(filters))
+	for i, f := range filters {
+		var err error
+		if coefs[i], err = newBiquad(f, rate); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Transposed direct form II state of each section and channel.
+	z := make([][2]float64, len(filters)*channels)
+	st := func(s []float64) []float64 {
+		for i := range s {
+			c := i % channels
+			for k, q := range coefs {
+				z := &z[k*channels+c]
+				y := q.b0*s[i] + z[0]
+				z[0] = q.b1*s[i] - q.a1*y + z[1]
+				z[1] = q.b2*s[i] - q.a2*y
+				s[i] = y
+			}
+		}
+		return s
+	}
+	reset := func() {
+		for i := range z {
+			z[i] = [2]float64{}
+		}
+	}
+	return st, reset, nil
+}
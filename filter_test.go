@@ -0,0 +1,98 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// mean returns the average of the last n F64 samples of p.
+func mean(p []byte, n int) float64 {
+	s := toFloat64(p, F64)
+	var sum float64
+	for _, v := range s[len(s)-n:] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+func TestWithDCBlock(t *testing.T) {
+	in := testsignal.Sine(1000, 0.25, 8000, 16000)
+	for i := range in {
+		in[i] += 0.5
+	}
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithDCBlock(10))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	res.Close()
+	if m := mean(out.Bytes(), 16000); math.Abs(m) > 0.01 {
+		t.Errorf("DC offset of %f left", m)
+	}
+	for _, cutoff := range []float64{-1, 4000} {
+		if _, err = New(io.Discard, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithDCBlock(cutoff)); err == nil {
+			t.Errorf("No error for a cutoff of %g Hz", cutoff)
+		}
+	}
+}
+
+// sineRMS returns the RMS level of the last n samples of the output of
+// resampling a full scale sine of freq Hz from 8 to 16 kHz with opts.
+func sineRMS(t *testing.T, freq float64, n int, opts ...Option) float64 {
+	in := testsignal.Sine(freq, 1, 8000, 16000)
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, F64, I16, MediumQ, opts...)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	res.Close()
+	s := toFloat64(out.Bytes(), I16)
+	var sum float64
+	for _, v := range s[len(s)-n:] {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+func TestWithFilters(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		freq  float64
+		opt   Option
+		level float64 // expected attenuation in dB, warped by the bilinear transform
+	}{
+		{"first order high-pass", 100, WithPreFilter(Filter{HighPass, 100, 1}), -3},
+		{"second order high-pass", 20, WithPreFilter(Filter{HighPass, 80, 2}), -24},
+		{"second order low-pass", 2000, WithPostFilter(Filter{LowPass, 500, 2}), -25},
+		{"cascaded low-pass", 2000, WithPostFilter(Filter{LowPass, 1000, 2}, Filter{LowPass, 1000, 2}), -26},
+	} {
+		flat := sineRMS(t, tc.freq, 8000, WithDither(DitherNone))
+		rms := sineRMS(t, tc.freq, 8000, WithDither(DitherNone), tc.opt)
+		if level := 20 * math.Log10(rms/flat); math.Abs(level-tc.level) > 1 {
+			t.Errorf("%s: level %.1f dB, expected %.1f dB", tc.name, level, tc.level)
+		}
+	}
+	for _, f := range []Filter{{HighPass, 0, 2}, {LowPass, 4000, 1}, {2, 100, 1}, {HighPass, 100, 3}} {
+		if _, err := New(io.Discard, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithPreFilter(f)); err == nil {
+			t.Errorf("No error for filter %+v", f)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"math"
+
+	"github.com/zaf/resample/internal/simd"
+)
+
+// firPhases is the number of phases per input sample of the filters
+// designed by the FIR backend.
+const firPhases = 512
+
+// firMaxTaps caps the length, in input samples, of a designed filter.
+const firMaxTaps = 1 << 14
+
+// firSpec is the anti-aliasing filter of the FIR backend, given as
+// coefficients or as a cutoff and transition to design it from.
+type firSpec struct {
+	coeffs     []float64
+	phases     int
+	cutoff     float64
+	transition float64
+}
+
+// WithFIR sets the anti-aliasing filter of BackendFIR to coeffs, the
+// impulse response of a symmetric low-pass filter sampled phases times per
+// input sample, centered on its middle coefficient. Output samples between
+// the phases are interpolated linearly. The filter is applied at the input
+// rate, so that when downsampling it has to reject the frequencies above
+// the Nyquist frequency of the output, and it is scaled to unity gain at
+// DC.
+func WithFIR(coeffs []float64, phases int) Option {
+	return func(o *options) {
+		o.fir = &firSpec{coeffs: coeffs, phases: phases}
+	}
+}
+
+// WithFIRSpec sets the anti-aliasing filter of BackendFIR to a Kaiser
+// windowed sinc passing the frequencies up to cutoff Hz and rejecting
+// those above cutoff+transition Hz, by the stopband attenuation of the
+// quality setting. By default the passband of the quality ends below the
+// Nyquist frequency of the lower rate and the stopband starts at it.
+func WithFIRSpec(cutoff, transition float64) Option {
+	return func(o *options) {
+		o.fir = &firSpec{cutoff: cutoff, transition: transition}
+	}
+}
+
+// firQuality returns the passband end, as a fraction of the Nyquist
+// frequency of the lower rate, and the stopband attenuation in dB of the
+// filters designed for a quality setting.
+func firQuality(quality int) (passband, attenuation float64) {
+	switch {
+	case quality == SincBestQ:
+		return 0.931, 145
+	case quality == SincMediumQ:
+		return 0.832, 121
+	case quality == SincFastestQ:
+		return 0.663, 97
+	case quality == Quick:
+		return 0.7, 50
+	case quality == LowQ:
+		return 0.8, 96
+	case quality <= MediumQ:
+		return 0.88, 96
+	case quality <= HighQ:
+		return 0.913, 125
+	}
+	return 0.95, 175
+}
+
+// firBackend resamples in Go, interpolating the input with a polyphase
+// FIR filter at any ratio.
+type firBackend struct {
+	table     []float64 // filter coefficients by phase, see firTable
+	rowLen    int       // coefficients of a phase in table
+	center    float64   // index of the middle of the filter
+	phases    float64   // coefficients per input sample
+	radius    float64   // half the filter length in input samples
+	step      float64   // input frames per output frame
+	channels  int
+	inFormat  int         // format of the input data
+	outFormat int         // format of the output data
+	in        [][]float64 // buffered input frames, by channel
+	inStart   int64       // input frame of in[c][0]
+	inFrames  int64       // input frames passed
+	t         float64     // input frame of the next output frame
+	t0        float64     // input frame of the output frame n frames before t
+	n         int64       // output frames since t0
+	flushed   bool        // the end of the input was flushed
+	clipped   int64       // output samples clipped to an integer format
+}
+
+func newFIR(inRate, outRate float64, channels, inFormat, outFormat, quality int, spec *firSpec) (backend, error) {
+	var h []float64
+	var phases int
+	passband, attenuation := firQuality(quality)
+	if spec != nil && spec.coeffs != nil {
+		if spec.phases <= 0 {
+			return nil, errors.New("invalid FIR phases")
+		}
+		var sum float64
+		for _, v := range spec.coeffs {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, errors.New("invalid FIR coefficients")
+			}
+			sum += v
+		}
+		if sum == 0 {
+			return nil, errors.New("FIR coefficients have no gain at DC")
+		}
+		h, phases = append([]float64(nil), spec.coeffs...), spec.phases
+	} else {
+		nyquist := math.Min(inRate, outRate) / 2
+		cutoff, transition := passband*nyquist, (1-passband)*nyquist
+		if spec != nil {
+			cutoff, transition = spec.cutoff, spec.transition
+			if !(cutoff > 0) || !(transition > 0) || !(cutoff+transition/2 < inRate/2) {
+				return nil, errors.New("invalid FIR cutoff or transition")
+			}
+		}
+		var err error
+		if h, err = kaiserSinc((cutoff+transition/2)/inRate, transition/inRate, attenuation, firPhases); err != nil {
+			return nil, err
+		}
+		phases = firPhases
+	}
+	// Unity gain at DC of every phase.
+	var sum float64
+	for _, v := range h {
+		sum += v
+	}
+	for i := range h {
+		h[i] *= float64(phases) / sum
+	}
+	f := &firBackend{
+		center:    float64(len(h)-1) / 2,
+		phases:    float64(phases),
+		step:      inRate / outRate,
+		channels:  channels,
+		inFormat:  inFormat,
+		outFormat: outFormat,
+		in:        make([][]float64, channels),
+	}
+	f.table, f.rowLen = firTable(h, phases)
+	f.radius = f.center / f.phases
+	f.reset()
+	return f, nil
+}
+
+// firTable returns the coefficients of h sampled phases times per input
+// sample as phases+1 rows of rowLen coefficients, row r holding h[r],
+// h[r+phases], h[r+2*phases]... in reverse order, ending the row, and
+// zeros before them. The row phases is the row 0 shifted by one sample.
+// The last n coefficients of a row weight n contiguous input samples.
+func firTable(h []float64, phases int) ([]float64, int) {
+	rowLen := (len(h)+phases-1)/phases + 1
+	table := make([]float64, (phases+1)*rowLen)
+	for r := 0; r <= phases; r++ {
+		row := table[r*rowLen : (r+1)*rowLen]
+		for k := 0; r+k*phases < len(h); k++ {
+			row[rowLen-1-k] = h[r+k*phases]
+		}
+	}
+	return table, rowLen
+}
+
+// kaiserSinc returns a low-pass filter of cutoff and transition, in cycles
+// per input sample, with a stopband attenuation in dB, sampled phases
+// times per input sample.
+func kaiserSinc(cutoff, transition, attenuation float64, phases int) ([]float64, error) {
+	taps := math.Ceil((attenuation-7.95)/(14.36*transition)) + 1
+	if taps > firMaxTaps {
+		return nil, errors.New("FIR transition too narrow")
+	}
+	var beta float64
+	switch {
+	case attenuation > 50:
+		beta = 0.1102 * (attenuation - 8.7)
+	case attenuation > 21:
+		beta = 0.5842*math.Pow(attenuation-21, 0.4) + 0.07886*(attenuation-21)
+	}
+	n := int(taps) * phases
+	h := make([]float64, n+1)
+	half := float64(n) / 2
+	for i := range h {
+		x := (float64(i) - half) / float64(phases)
+		v := 2 * cutoff
+		if x != 0 {
+			v = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		r := (float64(i) - half) / half
+		h[i] = v * besselI0(beta*math.Sqrt(1-r*r)) / besselI0(beta)
+	}
+	return h, nil
+}
+
+// besselI0 returns the modified Bessel function of the first kind of
+// order 0 of x.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-16; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func (f *firBackend) process(p []byte, frames int) ([]byte, error) {
+	s := toFloat64(p, f.inFormat)
+	for ch := range f.in {
+		for i := ch; i < frames*f.channels; i += f.channels {
+			f.in[ch] = append(f.in[ch], s[i])
+		}
+	}
+	f.inFrames += int64(frames)
+	return f.output(f.interpolate(math.Inf(1))), nil
+}
+
+// flush returns all the remaining output on its first call after the
+// input ends.
+func (f *firBackend) flush() ([]byte, error) {
+	if f.flushed {
+		return nil, nil
+	}
+	f.flushed = true
+	// Silence after the input lets the last frames be interpolated.
+	for ch := range f.in {
+		f.in[ch] = append(f.in[ch], make([]float64, int(f.radius)+2)...)
+	}
+	return f.output(f.interpolate(float64(f.inFrames))), nil
+}
+
+// reserve does nothing, the buffers grow as needed.
+func (f *firBackend) reserve(frames int) {}
+
+// delay returns the output frames of the buffered input not yet
+// interpolated.
+func (f *firBackend) delay() float64 {
+	if f.flushed {
+		return 0
+	}
+	return math.Max(float64(f.inFrames)-f.t, 0) / f.step
+}
+
+func (f *firBackend) clips() int64 {
+	return f.clipped
+}
+
+func (f *firBackend) setRatio(ratio float64) error {
+	if !(ratio > 0) || math.IsInf(ratio, 0) {
+		return errors.New("invalid resampling ratio")
+	}
+	f.t0, f.n = f.t, 0
+	f.step = 1 / ratio
+	return nil
+}
+
+func (f *firBackend) reset() error {
+	// Silence before the input, as after it.
+	pad := int64(f.radius) + 1
+	for ch := range f.in {
+		f.in[ch] = append(f.in[ch][:0], make([]float64, pad)...)
+	}
+	f.inStart, f.inFrames = -pad, 0
+	f.t, f.t0, f.n = 0, 0, 0
+	f.flushed, f.clipped = false, 0
+	return nil
+}
+
+func (f *firBackend) close() {
+	f.in, f.table = nil, nil
+}
+
+// interpolate returns the output frames before input frame end that the
+// buffered input allows, and drops the input no longer needed.
+func (f *firBackend) interpolate(end float64) []float64 {
+	avail := f.inStart + int64(len(f.in[0]))
+	var out []float64
+	// Rounding errors of the step don't add an output frame at the end.
+	for f.t < end-1e-9 {
+		first := int64(math.Ceil(f.t - f.radius))
+		last := int64(math.Floor(f.t + f.radius))
+		if last >= avail {
+			break
+		}
+		// The input frames are weighted by the coefficients of a phase
+		// and of the next one, interpolated linearly at frac between them.
+		pos := math.Max(f.center+(f.t-float64(last))*f.phases, 0)
+		r := int(pos)
+		frac := pos - float64(r)
+		n := int(last - first + 1)
+		a := f.table[(r+1)*f.rowLen-n : (r+1)*f.rowLen]
+		b := f.table[(r+2)*f.rowLen-n : (r+2)*f.rowLen]
+		for _, in := range f.in {
+			va, vb := simd.Dot2(a, b, in[first-f.inStart:][:n])
+			out = append(out, va+frac*(vb-va))
+		}
+		f.n++
+		f.t = f.t0 + float64(f.n)*f.step
+	}
+	if drop := int64(math.Ceil(f.t-f.radius)) - f.inStart; drop > 0 {
+		drop = min(drop, avail-f.inStart)
+		for ch, in := range f.in {
+			f.in[ch] = in[:copy(in, in[drop:])]
+		}
+		f.inStart += drop
+	}
+	return out
+}
+
+// output converts interpolated samples to the output format.
+func (f *firBackend) output(out []float64) []byte {
+	f.clipped += countClips(out, f.outFormat)
+	return fromFloat64(out, f.outFormat)
+}
@@ -0,0 +1,156 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// firRMS resamples a second of a sine of freq Hz at 0.5 of full scale
+// with the FIR backend and returns the number of output frames and the
+// RMS of the output, without its first and last 10 ms.
+func firRMS(t *testing.T, inRate, outRate, freq float64, opts ...Option) (int, float64) {
+	t.Helper()
+	in := testsignal.Sine(freq, 0.5, inRate, int(inRate))
+	var out bytes.Buffer
+	res, err := New(&out, inRate, outRate, 1, F64, F64, HighQ, append(opts, WithBackend(BackendFIR))...)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	for p := float64Bytes(in); len(p) > 0; p = p[min(len(p), 8*1000):] {
+		if _, err = res.Write(p[:min(len(p), 8*1000)]); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	s := toFloat64(out.Bytes(), F64)
+	edge := int(outRate / 100)
+	var sum float64
+	for _, v := range s[edge : len(s)-edge] {
+		sum += v * v
+	}
+	return len(s), math.Sqrt(sum / float64(len(s)-2*edge))
+}
+
+func TestFIRBackend(t *testing.T) {
+	b, err := newFIR(8000, 16000, 1, F64, F64, MediumQ, nil)
+	if err != nil {
+		t.Fatal("Failed to create the backend:", err)
+	}
+	testBackend(t, b)
+	for _, tc := range []struct {
+		inRate, outRate, freq float64
+		rms                   float64
+	}{
+		{8000, 16000, 440, 0.5 / math.Sqrt2},
+		{44100, 48000, 10000, 0.5 / math.Sqrt2},
+		{48000, 44100, 1000, 0.5 / math.Sqrt2},
+		// Above the output Nyquist frequency, filtered out.
+		{48000, 8000, 6000, 0},
+		{48000, 44100, 23000, 0},
+	} {
+		frames, rms := firRMS(t, tc.inRate, tc.outRate, tc.freq)
+		if frames != int(tc.outRate) {
+			t.Errorf("%g to %g Hz: %d frames out, expecting %g", tc.inRate, tc.outRate, frames, tc.outRate)
+		}
+		if math.Abs(rms-tc.rms) > 1e-4 {
+			t.Errorf("%g to %g Hz: RMS of %g Hz is %g, expecting %g", tc.inRate, tc.outRate, tc.freq, rms, tc.rms)
+		}
+	}
+}
+
+func TestWithFIR(t *testing.T) {
+	// A triangle of two input samples interpolates linearly.
+	in := []float64{0.1, 0.5, -0.3, 0.2, 0.4, -0.1}
+	var out bytes.Buffer
+	res, err := New(&out, 8000, 16000, 1, F64, F64, HighQ, WithBackend(BackendFIR), WithFIR([]float64{1, 2, 1}, 2))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	s := toFloat64(out.Bytes(), F64)
+	if len(s) != 2*len(in) {
+		t.Fatalf("%d samples out, expecting %d", len(s), 2*len(in))
+	}
+	for i, v := range s {
+		want := in[i/2]
+		if i%2 == 1 {
+			next := 0.0
+			if i/2+1 < len(in) {
+				next = in[i/2+1]
+			}
+			want = (want + next) / 2
+		}
+		if math.Abs(v-want) > 1e-12 {
+			t.Errorf("Sample %d is %g, expecting %g", i, v, want)
+		}
+	}
+
+	// Passband to 1 kHz, stopband from 1.5 kHz.
+	if _, rms := firRMS(t, 8000, 16000, 440, WithFIRSpec(1000, 500)); math.Abs(rms-0.5/math.Sqrt2) > 1e-4 {
+		t.Errorf("RMS of 440 Hz is %g", rms)
+	}
+	if _, rms := firRMS(t, 8000, 16000, 2000, WithFIRSpec(1000, 500)); rms > 1e-4 {
+		t.Errorf("RMS of 2 kHz is %g", rms)
+	}
+
+	for _, opts := range [][]Option{
+		{WithBackend(BackendSamplerate), WithFIR([]float64{1}, 1)},
+		{WithBackend(BackendFIR), WithFIR([]float64{1, 2, 1}, 0)},
+		{WithBackend(BackendFIR), WithFIR([]float64{1, math.NaN(), 1}, 2)},
+		{WithBackend(BackendFIR), WithFIR([]float64{1, -2, 1}, 2)},
+		{WithBackend(BackendFIR), WithFIRSpec(4000, 500)},
+		{WithBackend(BackendFIR), WithFIRSpec(1000, 0)},
+		{WithBackend(BackendFIR), WithFIRSpec(1000, 0.01)},
+	} {
+		if _, err = New(io.Discard, 8000, 16000, 1, F64, F64, HighQ, opts...); err == nil {
+			t.Error("Invalid FIR filter didn't return an error")
+		}
+	}
+}
+
+func BenchmarkFIR(b *testing.B) {
+	for _, bc := range []struct {
+		name             string
+		inRate, outRate  float64
+		channels, format int
+	}{
+		{"44.1k-48k-2-I16", 44100, 48000, 2, I16},
+		{"48k-16k-1-F32", 48000, 16000, 1, F32},
+		{"8k-16k-1-I16", 8000, 16000, 1, I16},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			p, _ := testsignal.Encode(testsignal.Sine(440, 0.5, bc.inRate, int(bc.inRate)), bc.channels, bc.format)
+			res, err := New(io.Discard, bc.inRate, bc.outRate, bc.channels, bc.format, bc.format, HighQ, WithBackend(BackendFIR))
+			if err != nil {
+				b.Fatal("Failed to create a Resampler:", err)
+			}
+			b.SetBytes(int64(len(p)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = res.Write(p); err != nil {
+					b.Fatal("Write failed:", err)
+				}
+			}
+			res.Close()
+		})
+	}
+}
@@ -0,0 +1,93 @@
+//go:build flac
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+// NewFromFLAC decodes the FLAC stream src and returns a Resampler configured
+// with its channels and sampling rate. Streams of up to 16 bits per sample are
+// decoded to I16, deeper ones to I32. The decoded audio is resampled to outRate
+// and written to dst. The Resampler must be closed to flush its remaining output.
+func NewFromFLAC(dst io.Writer, src io.Reader, outRate float64, opts ...Option) (*Resampler, error) {
+	stream, err := flac.New(src)
+	if err != nil {
+		return nil, err
+	}
+	dec := &flacReader{
+		stream:   stream,
+		channels: int(stream.Info.NChannels),
+		bits:     int(stream.Info.BitsPerSample),
+	}
+	if dec.channels == 0 || dec.bits == 0 || dec.bits > 32 {
+		return nil, errors.New("unsupported FLAC stream parameters")
+	}
+	inFormat := I16
+	dec.size = 2
+	if dec.bits > 16 {
+		inFormat = I32
+		dec.size = 4
+	}
+	return newFromReader(dst, dec, float64(stream.Info.SampleRate), outRate, dec.channels, inFormat, dec.channels*dec.size, opts)
+}
+
+// flacReader decodes FLAC frames into interleaved little-endian PCM.
+type flacReader struct {
+	stream   *flac.Stream
+	channels int    // number of channels
+	bits     int    // bits per sample of the stream
+	size     int    // bytes per decoded sample
+	buf      []byte // decoded data not yet read
+}
+
+func (d *flacReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if err := d.decode(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// decode parses the next frame, left-justifying samples to the output sample size.
+func (d *flacReader) decode() error {
+	frame, err := d.stream.ParseNext()
+	if err != nil {
+		return err
+	}
+	if len(frame.Subframes) != d.channels {
+		return errors.New("FLAC frame channel count mismatch")
+	}
+	samples := len(frame.Subframes[0].Samples)
+	shift := uint(d.size*8 - d.bits)
+	buf := make([]byte, samples*d.channels*d.size)
+	i := 0
+	for s := 0; s < samples; s++ {
+		for _, sub := range frame.Subframes {
+			v := sub.Samples[s] << shift
+			if d.size == 2 {
+				binary.LittleEndian.PutUint16(buf[i:], uint16(v))
+			} else {
+				binary.LittleEndian.PutUint32(buf[i:], uint32(v))
+			}
+			i += d.size
+		}
+	}
+	d.buf = buf
+	return nil
+}
@@ -0,0 +1,139 @@
+//go:build flac
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+	"github.com/zaf/resample/testsignal"
+)
+
+// encodeFLAC returns a FLAC stream of a second of a 440 Hz sine at rate,
+// at half of the full scale of bits, in frames of verbatim subframes, and
+// its samples per channel.
+func encodeFLAC(t *testing.T, rate, channels, bits int) ([]byte, [][]int32) {
+	t.Helper()
+	x := testsignal.Sine(440, float64(int(1)<<(bits-2)), float64(rate), rate+channels)
+	samples := make([][]int32, channels)
+	for c := range samples {
+		samples[c] = make([]int32, rate)
+		for i := range samples[c] {
+			samples[c][i] = int32(x[i+c])
+		}
+	}
+	info := &meta.StreamInfo{
+		BlockSizeMin:  4096,
+		BlockSizeMax:  4096,
+		SampleRate:    uint32(rate),
+		NChannels:     uint8(channels),
+		BitsPerSample: uint8(bits),
+	}
+	var buf bytes.Buffer
+	enc, err := flac.NewEncoder(&buf, info)
+	if err != nil {
+		t.Fatal("Failed to create the FLAC encoder:", err)
+	}
+	layout := map[int]frame.Channels{1: frame.ChannelsMono, 2: frame.ChannelsLR}[channels]
+	for start := 0; start < rate; start += 4096 {
+		n := min(4096, rate-start)
+		f := &frame.Frame{Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(n),
+			SampleRate:        uint32(rate),
+			Channels:          layout,
+			BitsPerSample:     uint8(bits),
+		}}
+		for c := range samples {
+			f.Subframes = append(f.Subframes, &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   samples[c][start : start+n],
+				NSamples:  n,
+			})
+		}
+		if err = enc.WriteFrame(f); err != nil {
+			t.Fatal("Failed to encode a FLAC frame:", err)
+		}
+	}
+	if err = enc.Close(); err != nil {
+		t.Fatal("Failed to close the FLAC encoder:", err)
+	}
+	return buf.Bytes(), samples
+}
+
+func TestFLACReader(t *testing.T) {
+	for _, tc := range []struct {
+		channels, bits, size int
+	}{
+		{1, 16, 2},
+		{2, 16, 2},
+		{2, 12, 2},
+		{2, 24, 4},
+	} {
+		data, samples := encodeFLAC(t, 8000, tc.channels, tc.bits)
+		stream, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal("Failed to parse the FLAC stream:", err)
+		}
+		// Single byte reads cross the frames.
+		p, err := io.ReadAll(iotest.OneByteReader(&flacReader{stream: stream, channels: tc.channels, bits: tc.bits, size: tc.size}))
+		if err != nil {
+			t.Fatalf("%d bits: Read failed: %v", tc.bits, err)
+		}
+		if len(p) != 8000*tc.channels*tc.size {
+			t.Fatalf("%d bits, %d channels: %d bytes decoded", tc.bits, tc.channels, len(p))
+		}
+		shift := uint(8*tc.size - tc.bits)
+		for i := 0; i < 8000*tc.channels; i++ {
+			var v int32
+			if tc.size == 2 {
+				v = int32(int16(binary.LittleEndian.Uint16(p[2*i:])))
+			} else {
+				v = int32(binary.LittleEndian.Uint32(p[4*i:]))
+			}
+			if want := samples[i%tc.channels][i/tc.channels] << shift; v != want {
+				t.Fatalf("%d bits: sample %d is %d, expected %d", tc.bits, i, v, want)
+			}
+		}
+	}
+}
+
+func TestNewFromFLAC(t *testing.T) {
+	for _, tc := range []struct {
+		bits, size int
+	}{
+		{16, 2},
+		{24, 4},
+	} {
+		data, _ := encodeFLAC(t, 8000, 2, tc.bits)
+		var out bytes.Buffer
+		res, err := NewFromFLAC(&out, bytes.NewReader(data), 16000, WithDither(DitherNone))
+		if err != nil {
+			t.Fatalf("%d bits: NewFromFLAC failed: %v", tc.bits, err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatalf("%d bits: Close failed: %v", tc.bits, err)
+		}
+		if n := out.Len() / (2 * tc.size); math.Abs(float64(n-16000)) > 100 {
+			t.Errorf("%d bits: %d frames out, expected about 16000", tc.bits, n)
+		}
+	}
+	if _, err := NewFromFLAC(io.Discard, bytes.NewReader([]byte("RIFF0000WAVE")), 16000); err == nil {
+		t.Error("NewFromFLAC of a WAV file didn't return an error")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "encoding/binary"
+
+// G.711 companding. MuLaw and ALaw data is expanded to 16-bit linear PCM
+// before it is passed to soxr and compressed again on output.
+
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+var (
+	ulawTable [256]int16 // µ-law to linear
+	alawTable [256]int16 // A-law to linear
+)
+
+func init() {
+	for i := range ulawTable {
+		ulawTable[i] = ulawDecode(byte(i))
+		alawTable[i] = alawDecode(byte(i))
+	}
+}
+
+// ulawEncode compresses a 16-bit linear sample to µ-law.
+func ulawEncode(s int16) byte {
+	v := int(s)
+	var sign int
+	if v < 0 {
+		sign = 0x80
+		v = -v
+	}
+	if v > ulawClip {
+		v = ulawClip
+	}
+	v += ulawBias
+	exponent := 7
+	for mask := 0x4000; v&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (v >> (exponent + 3)) & 0x0f
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+// ulawDecode expands a µ-law sample to 16-bit linear.
+func ulawDecode(u byte) int16 {
+	u = ^u
+	exponent := int(u>>4) & 0x07
+	mantissa := int(u) & 0x0f
+	t := ((mantissa << 3) + ulawBias) << exponent
+	if u&0x80 != 0 {
+		return int16(ulawBias - t)
+	}
+	return int16(t - ulawBias)
+}
+
+// alawEncode compresses a 16-bit linear sample to A-law.
+func alawEncode(s int16) byte {
+	v := int(s) >> 3
+	mask := 0xd5
+	if v < 0 {
+		mask = 0x55
+		v = -v - 1
+	}
+	seg := 0
+	for end := 0x1f; seg < 8 && v > end; end = end<<1 | 1 {
+		seg++
+	}
+	if seg >= 8 {
+		return byte(0x7f ^ mask)
+	}
+	a := seg << 4
+	if seg < 2 {
+		a |= (v >> 1) & 0x0f
+	} else {
+		a |= (v >> seg) & 0x0f
+	}
+	return byte(a ^ mask)
+}
+
+// alawDecode expands an A-law sample to 16-bit linear.
+func alawDecode(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0f) << 4
+	switch seg := int(a&0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// expandG711 converts G.711 data in the given format to I16 PCM.
+func expandG711(p []byte, format int) []byte {
+	table := &ulawTable
+	if format == ALaw {
+		table = &alawTable
+	}
+	out := make([]byte, 2*len(p))
+	for i, v := range p {
+		binary.LittleEndian.PutUint16(out[2*i:], uint16(table[v]))
+	}
+	return out
+}
+
+// compressG711 converts I16 PCM to G.711 data in the given format.
+func compressG711(p []byte, format int) []byte {
+	encode := ulawEncode
+	if format == ALaw {
+		encode = alawEncode
+	}
+	out := make([]byte, len(p)/2)
+	for i := range out {
+		out[i] = encode(int16(binary.LittleEndian.Uint16(p[2*i:])))
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"testing"
+)
+
+var G711Test = []struct {
+	name    string
+	encode  func(int16) byte
+	decode  func(byte) int16
+	linear  int16
+	encoded byte
+	decoded int16
+}{
+	{"µ-law zero", ulawEncode, ulawDecode, 0, 0xff, 0},
+	{"µ-law max", ulawEncode, ulawDecode, 32767, 0x80, 32124},
+	{"µ-law min", ulawEncode, ulawDecode, -32768, 0x00, -32124},
+	{"A-law zero", alawEncode, alawDecode, 0, 0xd5, 8},
+	{"A-law max", alawEncode, alawDecode, 32767, 0xaa, 32256},
+	{"A-law min", alawEncode, alawDecode, -32768, 0x2a, -32256},
+}
+
+func TestG711(t *testing.T) {
+	for _, tc := range G711Test {
+		if e := tc.encode(tc.linear); e != tc.encoded {
+			t.Errorf("%s: encoded %d to %#x, expecting: %#x", tc.name, tc.linear, e, tc.encoded)
+		}
+		if d := tc.decode(tc.encoded); d != tc.decoded {
+			t.Errorf("%s: decoded %#x to %d, expecting: %d", tc.name, tc.encoded, d, tc.decoded)
+		}
+	}
+	// Every code word must survive a decode/encode round trip.
+	for i := 0; i < 256; i++ {
+		if d := ulawDecode(byte(i)); ulawDecode(ulawEncode(d)) != d {
+			t.Errorf("µ-law round trip failed for %#x", i)
+		}
+		if d := alawDecode(byte(i)); alawDecode(alawEncode(d)) != d {
+			t.Errorf("A-law round trip failed for %#x", i)
+		}
+	}
+}
+
+func TestG711Resampling(t *testing.T) {
+	for _, format := range []int{MuLaw, ALaw} {
+		var out bytes.Buffer
+		res, err := New(&out, 8000.0, 16000.0, 1, format, format, MediumQ)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		in := bytes.Repeat([]byte{0xff, 0xd5, 0x80, 0x2a}, 200)
+		if _, err = res.Write(in); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Failed to close Resampler:", err)
+		}
+		if out.Len() != 2*len(in) {
+			t.Errorf("Output size: %d, expecting: %d", out.Len(), 2*len(in))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "errors"
+
+// Conversion between interleaved frames, the layout of the Resampler input
+// and output, and planar data holding each channel in a block of its own,
+// as many decoders and encoders use. Samples are of any format and are
+// copied as they are, in their byte order.
+
+// Interleave writes to dst the frames made of the samples of the planes,
+// one per channel, of format, and returns the number of frames. The planes
+// must hold the same number of samples, dst room for all of them.
+func Interleave(dst []byte, planes [][]byte, format int) (int, error) {
+	size, err := sizeOf(format)
+	if err != nil {
+		return 0, err
+	}
+	if len(planes) == 0 {
+		return 0, errors.New("invalid channels number")
+	}
+	channels := len(planes)
+	frames := len(planes[0]) / size
+	for _, p := range planes {
+		if len(p) != len(planes[0]) || len(p)%size != 0 {
+			return 0, errors.New("planes of different or incomplete sizes")
+		}
+	}
+	if len(dst) < frames*channels*size {
+		return 0, errors.New("destination too short")
+	}
+	if frames == 0 {
+		return 0, nil
+	}
+	frame := channels * size
+	for c, p := range planes {
+		d := dst[c*size:]
+		for i := 0; i < frames; i++ {
+			copy(d[i*frame:i*frame+size], p[i*size:])
+		}
+	}
+	return frames, nil
+}
+
+// Deinterleave writes the samples of the frames in src, of format, to the
+// planes, one per channel, and returns the number of frames. Each plane
+// must have room for all the frames, trailing bytes of an incomplete frame
+// are ignored.
+func Deinterleave(planes [][]byte, src []byte, format int) (int, error) {
+	size, err := sizeOf(format)
+	if err != nil {
+		return 0, err
+	}
+	if len(planes) == 0 {
+		return 0, errors.New("invalid channels number")
+	}
+	frame := len(planes) * size
+	frames := len(src) / frame
+	for _, p := range planes {
+		if len(p) < frames*size {
+			return 0, errors.New("destination too short")
+		}
+	}
+	if frames == 0 {
+		return 0, nil
+	}
+	for c, p := range planes {
+		s := src[c*size:]
+		for i := 0; i < frames; i++ {
+			copy(p[i*size:(i+1)*size], s[i*frame:])
+		}
+	}
+	return frames, nil
+}
+
+// InterleaveInPlace turns the planar data in p, holding the samples of
+// channels channels of format one channel after the other, into frames,
+// without copying p.
+func InterleaveInPlace(p []byte, channels, format int) error {
+	size, err := checkPlanar(p, channels, format)
+	if err != nil {
+		return err
+	}
+	transpose(p, size, channels)
+	return nil
+}
+
+// DeinterleaveInPlace turns the frames in p, of channels channels of
+// format, into planar data holding the samples of one channel after the
+// other, without copying p.
+func DeinterleaveInPlace(p []byte, channels, format int) error {
+	size, err := checkPlanar(p, channels, format)
+	if err != nil {
+		return err
+	}
+	transpose(p, size, len(p)/size/channels)
+	return nil
+}
+
+// checkPlanar returns the sample size of format after checking that p
+// holds whole frames of channels channels.
+func checkPlanar(p []byte, channels, format int) (int, error) {
+	size, err := sizeOf(format)
+	if err != nil {
+		return 0, err
+	}
+	if channels <= 0 {
+		return 0, errors.New("invalid channels number")
+	}
+	if len(p)%(size*channels) != 0 {
+		return 0, errors.New("incomplete input frame data")
+	}
+	return size, nil
+}
+
+// transpose transposes in place the matrix of rows rows of samples of size
+// bytes in p, following the cycles of the permutation that moves sample k
+// to k*rows modulo n-1. A bitmap marks the samples moved.
+func transpose(p []byte, size, rows int) {
+	n := len(p) / size
+	if n < 3 || rows == 1 || rows == n {
+		return
+	}
+	moved := make([]uint64, (n+63)/64)
+	tmp, next := make([]byte, size), make([]byte, size)
+	for start := 1; start < n-1; start++ {
+		if moved[start/64]&(1<<(start%64)) != 0 {
+			continue
+		}
+		copy(tmp, p[start*size:(start+1)*size])
+		for k := start; ; {
+			k = k * rows % (n - 1)
+			copy(next, p[k*size:(k+1)*size])
+			copy(p[k*size:(k+1)*size], tmp)
+			moved[k/64] |= 1 << (k % 64)
+			tmp, next = next, tmp
+			if k == start {
+				break
+			}
+		}
+	}
+}
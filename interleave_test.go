@@ -0,0 +1,76 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestInterleave(t *testing.T) {
+	for _, tc := range []struct {
+		format, channels, frames int
+	}{
+		{I16, 2, 1000},
+		{F32, 3, 333},
+		{F64, 6, 17},
+		{MuLaw, 5, 1},
+		{I32, 1, 10},
+		{ALaw, 4, 0},
+	} {
+		size, _ := sizeOf(tc.format)
+		planes := make([][]byte, tc.channels)
+		var planar []byte
+		for c := range planes {
+			planes[c] = make([]byte, tc.frames*size)
+			rand.Read(planes[c])
+			planar = append(planar, planes[c]...)
+		}
+		frames := make([]byte, len(planar))
+		if n, err := Interleave(frames, planes, tc.format); err != nil || n != tc.frames {
+			t.Fatalf("Interleave: %d frames, %v", n, err)
+		}
+		for i := 0; i < tc.frames; i++ {
+			for c := range planes {
+				if s := frames[(i*tc.channels+c)*size:][:size]; !bytes.Equal(s, planes[c][i*size:][:size]) {
+					t.Fatalf("Format %d: sample %d of channel %d misplaced", tc.format, i, c)
+				}
+			}
+		}
+		split := make([][]byte, tc.channels)
+		for c := range split {
+			split[c] = make([]byte, tc.frames*size)
+		}
+		if n, err := Deinterleave(split, frames, tc.format); err != nil || n != tc.frames {
+			t.Fatalf("Deinterleave: %d frames, %v", n, err)
+		}
+		for c := range split {
+			if !bytes.Equal(split[c], planes[c]) {
+				t.Errorf("Format %d: channel %d differs after Deinterleave", tc.format, c)
+			}
+		}
+		p := append([]byte(nil), planar...)
+		if err := InterleaveInPlace(p, tc.channels, tc.format); err != nil || !bytes.Equal(p, frames) {
+			t.Errorf("Format %d: InterleaveInPlace differs from Interleave, %v", tc.format, err)
+		}
+		if err := DeinterleaveInPlace(p, tc.channels, tc.format); err != nil || !bytes.Equal(p, planar) {
+			t.Errorf("Format %d: DeinterleaveInPlace doesn't restore the planes, %v", tc.format, err)
+		}
+	}
+	if err := InterleaveInPlace(make([]byte, 6), 2, I16); err == nil {
+		t.Error("No error for incomplete frames")
+	}
+	if _, err := Interleave(make([]byte, 8), [][]byte{make([]byte, 4), make([]byte, 2)}, I16); err == nil {
+		t.Error("No error for planes of different sizes")
+	}
+	if _, err := Deinterleave([][]byte{make([]byte, 2), make([]byte, 2)}, make([]byte, 8), I16); err == nil {
+		t.Error("No error for short planes")
+	}
+}
@@ -0,0 +1,57 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package simd holds the inner loops of the FIR backend of the resample
+package and of its sample format conversions, with AVX2 and FMA assembly
+on amd64 and NEON assembly on arm64. The scalar loops here are used on the
+other systems, with the noasm build tag, on processors without the
+instructions, and for the ends of the slices the assembly doesn't cover.
+
+The assembly is kept out of the resample package, as Go assembly can't be
+part of a package using cgo.
+*/
+package simd
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// dot2Generic returns the dot products of a and b with x, all of the
+// same length.
+func dot2Generic(a, b, x []float64) (float64, float64) {
+	var sa, sb float64
+	b, x = b[:len(a)], x[:len(a)]
+	for i, v := range x {
+		sa += a[i] * v
+		sb += b[i] * v
+	}
+	return sa, sb
+}
+
+// i16ToFloat64Generic decodes the little-endian I16 samples of p to dst.
+func i16ToFloat64Generic(dst []float64, p []byte) {
+	for i := range dst {
+		dst[i] = float64(int16(binary.LittleEndian.Uint16(p[2*i:]))) / (1 << 15)
+	}
+}
+
+// f32ToFloat64Generic decodes the little-endian F32 samples of p to dst.
+func f32ToFloat64Generic(dst []float64, p []byte) {
+	for i := range dst {
+		dst[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(p[4*i:])))
+	}
+}
+
+// float64ToF32Generic encodes s as little-endian F32 samples to dst.
+func float64ToF32Generic(dst []byte, s []float64) {
+	for i, v := range s {
+		binary.LittleEndian.PutUint32(dst[4*i:], math.Float32bits(float32(v)))
+	}
+}
@@ -0,0 +1,70 @@
+//go:build !noasm
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+// useAVX2 selects the AVX2 and FMA kernels of simd_amd64.s.
+var useAVX2 = cpu.X86.HasAVX2 && cpu.X86.HasFMA
+
+// The kernels process 4 samples at a time and leave the rest to the
+// scalar loops.
+
+//go:noescape
+func dot2AVX2(a, b, x []float64) (float64, float64)
+
+//go:noescape
+func i16ToFloat64AVX2(dst []float64, p []byte)
+
+//go:noescape
+func f32ToFloat64AVX2(dst []float64, p []byte)
+
+//go:noescape
+func float64ToF32AVX2(dst []byte, s []float64)
+
+// Dot2 returns the dot products of a and b with x, all of the same
+// length.
+func Dot2(a, b, x []float64) (float64, float64) {
+	if !useAVX2 {
+		return dot2Generic(a, b, x)
+	}
+	return dot2AVX2(a, b[:len(a)], x[:len(a)])
+}
+
+// I16ToFloat64 decodes the little-endian I16 samples of p to dst.
+func I16ToFloat64(dst []float64, p []byte) {
+	n := 0
+	if useAVX2 {
+		n = len(dst) &^ 3
+		i16ToFloat64AVX2(dst[:n], p[:2*n])
+	}
+	i16ToFloat64Generic(dst[n:], p[2*n:])
+}
+
+// F32ToFloat64 decodes the little-endian F32 samples of p to dst.
+func F32ToFloat64(dst []float64, p []byte) {
+	n := 0
+	if useAVX2 {
+		n = len(dst) &^ 3
+		f32ToFloat64AVX2(dst[:n], p[:4*n])
+	}
+	f32ToFloat64Generic(dst[n:], p[4*n:])
+}
+
+// Float64ToF32 encodes s as little-endian F32 samples to dst.
+func Float64ToF32(dst []byte, s []float64) {
+	n := 0
+	if useAVX2 {
+		n = len(s) &^ 3
+		float64ToF32AVX2(dst[:4*n], s[:n])
+	}
+	float64ToF32Generic(dst[4*n:], s[n:])
+}
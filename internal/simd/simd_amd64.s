@@ -0,0 +1,139 @@
+//go:build !noasm
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+#include "textflag.h"
+
+// func dot2AVX2(a, b, x []float64) (float64, float64)
+TEXT ·dot2AVX2(SB), NOSPLIT, $0-88
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	MOVQ x_base+48(FP), DX
+	VXORPD Y0, Y0, Y0 // sums of a
+	VXORPD Y1, Y1, Y1 // sums of b
+	VXORPD Y2, Y2, Y2 // second sums of a
+	VXORPD Y3, Y3, Y3 // second sums of b
+	XORQ AX, AX
+	MOVQ CX, BX
+	ANDQ $-8, BX
+
+loop8:
+	CMPQ AX, BX
+	JAE  loop4init
+	VMOVUPD     (DX)(AX*8), Y4
+	VMOVUPD     32(DX)(AX*8), Y5
+	VFMADD231PD (SI)(AX*8), Y4, Y0
+	VFMADD231PD 32(SI)(AX*8), Y5, Y2
+	VFMADD231PD (DI)(AX*8), Y4, Y1
+	VFMADD231PD 32(DI)(AX*8), Y5, Y3
+	ADDQ        $8, AX
+	JMP         loop8
+
+loop4init:
+	VADDPD Y2, Y0, Y0
+	VADDPD Y3, Y1, Y1
+	MOVQ   CX, BX
+	ANDQ   $-4, BX
+
+loop4:
+	CMPQ AX, BX
+	JAE  reduce
+	VMOVUPD     (DX)(AX*8), Y4
+	VFMADD231PD (SI)(AX*8), Y4, Y0
+	VFMADD231PD (DI)(AX*8), Y4, Y1
+	ADDQ        $4, AX
+	JMP         loop4
+
+reduce:
+	VEXTRACTF128 $1, Y0, X2
+	VADDPD       X2, X0, X0
+	VHADDPD      X0, X0, X0
+	VEXTRACTF128 $1, Y1, X3
+	VADDPD       X3, X1, X1
+	VHADDPD      X1, X1, X1
+
+tail:
+	CMPQ AX, CX
+	JAE  done
+	VMOVSD      (DX)(AX*8), X4
+	VFMADD231SD (SI)(AX*8), X4, X0
+	VFMADD231SD (DI)(AX*8), X4, X1
+	INCQ        AX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, ret+72(FP)
+	MOVSD X1, ret1+80(FP)
+	RET
+
+// 1/32768, the scale of I16 samples
+DATA i16Scale<>+0(SB)/8, $0x3f00000000000000
+GLOBL i16Scale<>(SB), RODATA|NOPTR, $8
+
+// func i16ToFloat64AVX2(dst []float64, p []byte)
+TEXT ·i16ToFloat64AVX2(SB), NOSPLIT, $0-48
+	MOVQ         dst_base+0(FP), DI
+	MOVQ         dst_len+8(FP), CX
+	MOVQ         p_base+24(FP), SI
+	VBROADCASTSD i16Scale<>(SB), Y1
+	XORQ         AX, AX
+
+i16loop:
+	CMPQ      AX, CX
+	JAE       i16done
+	VPMOVSXWD (SI)(AX*2), X0
+	VCVTDQ2PD X0, Y0
+	VMULPD    Y1, Y0, Y0
+	VMOVUPD   Y0, (DI)(AX*8)
+	ADDQ      $4, AX
+	JMP       i16loop
+
+i16done:
+	VZEROUPPER
+	RET
+
+// func f32ToFloat64AVX2(dst []float64, p []byte)
+TEXT ·f32ToFloat64AVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ p_base+24(FP), SI
+	XORQ AX, AX
+
+f32loop:
+	CMPQ      AX, CX
+	JAE       f32done
+	VCVTPS2PD (SI)(AX*4), Y0
+	VMOVUPD   Y0, (DI)(AX*8)
+	ADDQ      $4, AX
+	JMP       f32loop
+
+f32done:
+	VZEROUPPER
+	RET
+
+// func float64ToF32AVX2(dst []byte, s []float64)
+TEXT ·float64ToF32AVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ s_base+24(FP), SI
+	MOVQ s_len+32(FP), CX
+	XORQ AX, AX
+
+tof32loop:
+	CMPQ       AX, CX
+	JAE        tof32done
+	VCVTPD2PSY (SI)(AX*8), X0
+	VMOVUPS    X0, (DI)(AX*4)
+	ADDQ       $4, AX
+	JMP        tof32loop
+
+tof32done:
+	VZEROUPPER
+	RET
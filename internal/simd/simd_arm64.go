@@ -0,0 +1,53 @@
+//go:build !noasm
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package simd
+
+// NEON is part of every arm64 processor. The kernels process 4 samples at
+// a time and leave the rest to the scalar loops.
+
+//go:noescape
+func dot2NEON(a, b, x []float64) (float64, float64)
+
+//go:noescape
+func i16ToFloat64NEON(dst []float64, p []byte)
+
+//go:noescape
+func f32ToFloat64NEON(dst []float64, p []byte)
+
+//go:noescape
+func float64ToF32NEON(dst []byte, s []float64)
+
+// Dot2 returns the dot products of a and b with x, all of the same
+// length.
+func Dot2(a, b, x []float64) (float64, float64) {
+	return dot2NEON(a, b[:len(a)], x[:len(a)])
+}
+
+// I16ToFloat64 decodes the little-endian I16 samples of p to dst.
+func I16ToFloat64(dst []float64, p []byte) {
+	n := len(dst) &^ 3
+	i16ToFloat64NEON(dst[:n], p[:2*n])
+	i16ToFloat64Generic(dst[n:], p[2*n:])
+}
+
+// F32ToFloat64 decodes the little-endian F32 samples of p to dst.
+func F32ToFloat64(dst []float64, p []byte) {
+	n := len(dst) &^ 3
+	f32ToFloat64NEON(dst[:n], p[:4*n])
+	f32ToFloat64Generic(dst[n:], p[4*n:])
+}
+
+// Float64ToF32 encodes s as little-endian F32 samples to dst.
+func Float64ToF32(dst []byte, s []float64) {
+	n := len(s) &^ 3
+	float64ToF32NEON(dst[:4*n], s[:n])
+	float64ToF32Generic(dst[4*n:], s[n:])
+}
@@ -0,0 +1,123 @@
+//go:build !noasm
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+#include "textflag.h"
+
+// func dot2NEON(a, b, x []float64) (float64, float64)
+TEXT ·dot2NEON(SB), NOSPLIT, $0-88
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R3
+	MOVD b_base+24(FP), R1
+	MOVD x_base+48(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16 // sums of a
+	VEOR V1.B16, V1.B16, V1.B16 // sums of b
+	VEOR V2.B16, V2.B16, V2.B16 // second sums of a
+	VEOR V3.B16, V3.B16, V3.B16 // second sums of b
+	LSR  $2, R3, R4
+	CBZ  R4, reduce
+
+loop4:
+	VLD1.P 32(R2), [V4.D2, V5.D2]
+	VLD1.P 32(R0), [V6.D2, V7.D2]
+	VLD1.P 32(R1), [V16.D2, V17.D2]
+	VFMLA  V4.D2, V6.D2, V0.D2
+	VFMLA  V5.D2, V7.D2, V2.D2
+	VFMLA  V4.D2, V16.D2, V1.D2
+	VFMLA  V5.D2, V17.D2, V3.D2
+	SUB    $1, R4
+	CBNZ   R4, loop4
+
+reduce:
+	// F0 and F1 are the low lanes of V0 and V1.
+	VFADD  V2.D2, V0.D2, V0.D2
+	VFADDP V0.D2, V0.D2, V0.D2
+	VFADD  V3.D2, V1.D2, V1.D2
+	VFADDP V1.D2, V1.D2, V1.D2
+	AND   $3, R3
+	CBZ   R3, done
+
+tail:
+	FMOVD.P 8(R2), F4
+	FMOVD.P 8(R0), F5
+	FMOVD.P 8(R1), F6
+	FMADDD  F4, F0, F5, F0
+	FMADDD  F4, F1, F6, F1
+	SUB     $1, R3
+	CBNZ    R3, tail
+
+done:
+	FMOVD F0, ret+72(FP)
+	FMOVD F1, ret1+80(FP)
+	RET
+
+// func i16ToFloat64NEON(dst []float64, p []byte)
+TEXT ·i16ToFloat64NEON(SB), NOSPLIT, $0-48
+	MOVD dst_base+0(FP), R0
+	MOVD dst_len+8(FP), R2
+	MOVD p_base+24(FP), R1
+	MOVD $0x3f00000000000000, R3 // 1/32768, the scale of I16 samples
+	VDUP R3, V3.D2
+	LSR  $2, R2
+	CBZ  R2, i16done
+
+i16loop:
+	// 4 samples widened to 32 then 64 bits.
+	FMOVD.P 8(R1), F0
+	VSXTL   V0.H4, V0.S4
+	VSXTL   V0.S2, V1.D2
+	VSXTL2  V0.S4, V2.D2
+	VSCVTF  V1.D2, V1.D2
+	VSCVTF  V2.D2, V2.D2
+	VFMUL   V3.D2, V1.D2, V1.D2
+	VFMUL   V3.D2, V2.D2, V2.D2
+	VST1.P  [V1.D2, V2.D2], 32(R0)
+	SUB     $1, R2
+	CBNZ    R2, i16loop
+
+i16done:
+	RET
+
+// func f32ToFloat64NEON(dst []float64, p []byte)
+TEXT ·f32ToFloat64NEON(SB), NOSPLIT, $0-48
+	MOVD dst_base+0(FP), R0
+	MOVD dst_len+8(FP), R2
+	MOVD p_base+24(FP), R1
+	LSR  $2, R2
+	CBZ  R2, f32done
+
+f32loop:
+	VLD1.P  16(R1), [V0.S4]
+	VFCVTL  V0.S2, V1.D2
+	VFCVTL2 V0.S4, V2.D2
+	VST1.P  [V1.D2, V2.D2], 32(R0)
+	SUB     $1, R2
+	CBNZ    R2, f32loop
+
+f32done:
+	RET
+
+// func float64ToF32NEON(dst []byte, s []float64)
+TEXT ·float64ToF32NEON(SB), NOSPLIT, $0-48
+	MOVD dst_base+0(FP), R0
+	MOVD s_base+24(FP), R1
+	MOVD s_len+32(FP), R2
+	LSR  $2, R2
+	CBZ  R2, tof32done
+
+tof32loop:
+	VLD1.P  32(R1), [V1.D2, V2.D2]
+	VFCVTN  V1.D2, V0.S2
+	VFCVTN2 V2.D2, V0.S4
+	VST1.P  [V0.S4], 16(R0)
+	SUB     $1, R2
+	CBNZ    R2, tof32loop
+
+tof32done:
+	RET
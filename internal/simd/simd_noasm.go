@@ -0,0 +1,32 @@
+//go:build noasm || !(amd64 || arm64)
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package simd
+
+// Dot2 returns the dot products of a and b with x, all of the same
+// length.
+func Dot2(a, b, x []float64) (float64, float64) {
+	return dot2Generic(a, b, x)
+}
+
+// I16ToFloat64 decodes the little-endian I16 samples of p to dst.
+func I16ToFloat64(dst []float64, p []byte) {
+	i16ToFloat64Generic(dst, p)
+}
+
+// F32ToFloat64 decodes the little-endian F32 samples of p to dst.
+func F32ToFloat64(dst []float64, p []byte) {
+	f32ToFloat64Generic(dst, p)
+}
+
+// Float64ToF32 encodes s as little-endian F32 samples to dst.
+func Float64ToF32(dst []byte, s []float64) {
+	float64ToF32Generic(dst, s)
+}
@@ -0,0 +1,89 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package simd
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDot2(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 70; n++ {
+		a, b, x := make([]float64, n), make([]float64, n), make([]float64, n)
+		for i := range x {
+			a[i], b[i], x[i] = rng.Float64()-0.5, rng.Float64()-0.5, rng.Float64()-0.5
+		}
+		sa, sb := Dot2(a, b, x)
+		wa, wb := dot2Generic(a, b, x)
+		if math.Abs(sa-wa) > 1e-12 || math.Abs(sb-wb) > 1e-12 {
+			t.Errorf("%d samples: %g, %g, expecting %g, %g", n, sa, sb, wa, wb)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		p := make([]byte, 4*n)
+		rng.Read(p)
+		// Every bit pattern of I16, F32 ones without NaNs.
+		for i := 0; i < n; i++ {
+			if p[4*i+3]&0x7f == 0x7f && p[4*i+2]&0x80 != 0 {
+				p[4*i+3] &^= 0x01
+			}
+		}
+		got, want := make([]float64, n), make([]float64, n)
+		I16ToFloat64(got[:n/2*2], p[:n/2*4])
+		i16ToFloat64Generic(want[:n/2*2], p[:n/2*4])
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%d I16 samples: sample %d is %g, expecting %g", n, i, got[i], want[i])
+			}
+		}
+		F32ToFloat64(got, p)
+		f32ToFloat64Generic(want, p)
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%d F32 samples: sample %d is %g, expecting %g", n, i, got[i], want[i])
+			}
+		}
+		s := make([]float64, n)
+		for i := range s {
+			s[i] = (rng.Float64() - 0.5) * 3
+		}
+		enc, wantEnc := make([]byte, 4*n), make([]byte, 4*n)
+		Float64ToF32(enc, s)
+		float64ToF32Generic(wantEnc, s)
+		if !bytes.Equal(enc, wantEnc) {
+			t.Fatalf("%d F64 samples: F32 encoding %v, expecting %v", n, enc, wantEnc)
+		}
+	}
+}
+
+func BenchmarkDot2(b *testing.B) {
+	a, c, x := make([]float64, 256), make([]float64, 256), make([]float64, 256)
+	for i := range x {
+		a[i], c[i], x[i] = float64(i), float64(-i), 1
+	}
+	b.SetBytes(3 * 8 * 256)
+	for i := 0; i < b.N; i++ {
+		Dot2(a, c, x)
+	}
+}
+
+func BenchmarkI16ToFloat64(b *testing.B) {
+	p, s := make([]byte, 2*4096), make([]float64, 4096)
+	b.SetBytes(int64(len(p)))
+	for i := 0; i < b.N; i++ {
+		I16ToFloat64(s, p)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+// WithLimiter softly limits the resampled output, after any post filters,
+// so that the peaks created by upsampling or a gain boost are rounded off
+// instead of hard clipped by the output format. Samples below threshold
+// dBFS pass unchanged, louder ones are compressed smoothly towards full
+// scale, which they never reach. Limiting works on each sample without
+// lookahead, adding no delay. The default, 0, doesn't limit.
+func WithLimiter(threshold float64) Option {
+	return func(o *options) {
+		o.limit = threshold
+	}
+}
+
+// limiterStage returns a stage limiting samples above threshold dBFS.
+func limiterStage(threshold float64) (stage, error) {
+	if threshold >= 0 || math.IsNaN(threshold) || math.IsInf(threshold, -1) {
+		return nil, errors.New("invalid limiter threshold")
+	}
+	return softLimitStage(math.Pow(10, threshold/20), 1), nil
+}
+
+// softLimitStage returns a stage compressing samples above knee smoothly
+// towards ceiling.
+func softLimitStage(knee, ceiling float64) stage {
+	room := ceiling - knee
+	return func(s []float64) []float64 {
+		for i, v := range s {
+			if a := math.Abs(v); a > knee {
+				// tanh keeps the slope continuous at the knee.
+				s[i] = math.Copysign(knee+room*math.Tanh((a-knee)/room), v)
+			}
+		}
+		return s
+	}
+}
@@ -0,0 +1,44 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestWithLimiter(t *testing.T) {
+	in := testsignal.Sine(440, 1.5, 8000, 8000)
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithLimiter(-3))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	res.Close()
+	var peak float64
+	knee := math.Pow(10, -3.0/20)
+	for _, v := range toFloat64(out.Bytes(), F64) {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	if peak >= 1 || peak <= knee {
+		t.Errorf("Peak of %f after limiting", peak)
+	}
+	for _, threshold := range []float64{1, math.Inf(-1)} {
+		if _, err = New(io.Discard, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithLimiter(threshold)); err == nil {
+			t.Errorf("No error for a threshold of %g dB", threshold)
+		}
+	}
+}
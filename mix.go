@@ -0,0 +1,181 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/zaf/resample/wav"
+)
+
+// Mix is a channel mixing matrix applied to the input before resampling.
+// Matrix[o][i] is the gain of input channel i in output channel o.
+// OutMask optionally records the speaker layout of the output channels
+// as a wav channel mask.
+type Mix struct {
+	Matrix  [][]float64
+	OutMask uint32
+}
+
+// Route sends an input speaker position to an output one with a gain.
+// Positions are the wav channel mask bits, e.g. wav.FrontCenter.
+type Route struct {
+	From, To uint32
+	Gain     float64
+}
+
+// MixByRole returns the Mix between the channel layouts described by the
+// inMask and outMask wav channel masks made of routes. Gains of routes
+// sharing both positions add up.
+func MixByRole(inMask, outMask uint32, routes ...Route) (Mix, error) {
+	in := wav.ChannelPositions(inMask, bits.OnesCount32(inMask))
+	out := wav.ChannelPositions(outMask, bits.OnesCount32(outMask))
+	if len(in) == 0 || len(out) == 0 {
+		return Mix{}, errors.New("empty channel layout")
+	}
+	m := Mix{Matrix: make([][]float64, len(out)), OutMask: outMask}
+	for o := range m.Matrix {
+		m.Matrix[o] = make([]float64, len(in))
+	}
+	for _, rt := range routes {
+		i, o := position(in, rt.From), position(out, rt.To)
+		if i < 0 {
+			return Mix{}, fmt.Errorf("position %s not in the input layout", wav.PositionName(rt.From))
+		}
+		if o < 0 {
+			return Mix{}, fmt.Errorf("position %s not in the output layout", wav.PositionName(rt.To))
+		}
+		m.Matrix[o][i] += rt.Gain
+	}
+	return m, nil
+}
+
+// Downmix returns a Mix converting the inMask channel layout to outMask.
+// Positions present in both layouts pass through, the others are folded
+// into their nearest neighbours, mostly at -3 dB. Low frequency effects
+// are dropped when the output has no LFE channel.
+func Downmix(inMask, outMask uint32) (Mix, error) {
+	var routes []Route
+	for _, from := range wav.ChannelPositions(inMask, bits.OnesCount32(inMask)) {
+		p, gain := from, 1.0
+		if base, ok := topBase[p]; ok && outMask&p == 0 {
+			p, gain = base, math.Sqrt2/2
+		}
+		routes = append(routes, foldRoutes(from, p, outMask, gain)...)
+	}
+	return MixByRole(inMask, outMask, routes...)
+}
+
+// foldRoutes returns the routes carrying input position from, placed at p
+// and scaled by gain, to the outMask layout.
+func foldRoutes(from, p, outMask uint32, gain float64) []Route {
+	if outMask&p != 0 {
+		return []Route{{From: from, To: p, Gain: gain}}
+	}
+	for _, alt := range foldRules[p] {
+		if outMask&alt.to != alt.to {
+			continue
+		}
+		var routes []Route
+		for _, to := range wav.ChannelPositions(alt.to, bits.OnesCount32(alt.to)) {
+			routes = append(routes, Route{From: from, To: to, Gain: gain * alt.gain})
+		}
+		return routes
+	}
+	return nil
+}
+
+type fold struct {
+	to   uint32 // positions the channel is spread to
+	gain float64
+}
+
+// foldRules lists, in order of preference, where a position missing from
+// the output goes.
+var foldRules = map[uint32][]fold{
+	wav.FrontLeft:          {{wav.FrontCenter, 0.5}},
+	wav.FrontRight:         {{wav.FrontCenter, 0.5}},
+	wav.FrontCenter:        {{wav.FrontLeft | wav.FrontRight, math.Sqrt2 / 2}},
+	wav.BackLeft:           {{wav.SideLeft, 1}, {wav.FrontLeft, math.Sqrt2 / 2}, {wav.FrontCenter, 0.5}},
+	wav.BackRight:          {{wav.SideRight, 1}, {wav.FrontRight, math.Sqrt2 / 2}, {wav.FrontCenter, 0.5}},
+	wav.SideLeft:           {{wav.BackLeft, 1}, {wav.FrontLeft, math.Sqrt2 / 2}, {wav.FrontCenter, 0.5}},
+	wav.SideRight:          {{wav.BackRight, 1}, {wav.FrontRight, math.Sqrt2 / 2}, {wav.FrontCenter, 0.5}},
+	wav.BackCenter:         {{wav.BackLeft | wav.BackRight, math.Sqrt2 / 2}, {wav.SideLeft | wav.SideRight, math.Sqrt2 / 2}, {wav.FrontLeft | wav.FrontRight, 0.5}, {wav.FrontCenter, 0.5}},
+	wav.FrontLeftOfCenter:  {{wav.FrontLeft, 1}, {wav.FrontCenter, 0.5}},
+	wav.FrontRightOfCenter: {{wav.FrontRight, 1}, {wav.FrontCenter, 0.5}},
+}
+
+// topBase maps the height positions to the ones they fold into.
+var topBase = map[uint32]uint32{
+	wav.TopCenter:      wav.FrontCenter,
+	wav.TopFrontLeft:   wav.FrontLeft,
+	wav.TopFrontCenter: wav.FrontCenter,
+	wav.TopFrontRight:  wav.FrontRight,
+	wav.TopBackLeft:    wav.BackLeft,
+	wav.TopBackCenter:  wav.BackCenter,
+	wav.TopBackRight:   wav.BackRight,
+}
+
+func position(positions []uint32, p uint32) int {
+	for i, q := range positions {
+		if q == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// WithMix mixes the input channels according to m before resampling.
+// The output then has len(m.Matrix) channels.
+func WithMix(m Mix) Option {
+	return func(o *options) {
+		o.mix = m.Matrix
+		o.outMask = m.OutMask
+	}
+}
+
+// checkMix validates a mixing matrix for the given number of input channels.
+func checkMix(m [][]float64, channels int) error {
+	if len(m) == 0 {
+		return errors.New("empty mixing matrix")
+	}
+	for _, row := range m {
+		if len(row) != channels {
+			return errors.New("mixing matrix doesn't match the input channels")
+		}
+	}
+	return nil
+}
+
+// mixStage returns a stage applying the matrix m to frames of channels samples.
+func mixStage(m [][]float64, channels int) stage {
+	return func(s []float64) []float64 {
+		return mixFrames(s, channels, m)
+	}
+}
+
+// mixFrames applies the matrix m to interleaved frames of channels samples.
+func mixFrames(s []float64, channels int, m [][]float64) []float64 {
+	frames := len(s) / channels
+	out := make([]float64, frames*len(m))
+	for f := 0; f < frames; f++ {
+		in := s[f*channels : (f+1)*channels]
+		for o, row := range m {
+			var v float64
+			for i, g := range row {
+				v += g * in[i]
+			}
+			out[f*len(m)+o] = v
+		}
+	}
+	return out
+}
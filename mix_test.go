@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/zaf/resample/wav"
+)
+
+const minus3dB = math.Sqrt2 / 2
+
+var DownmixTest = []struct {
+	name    string
+	in, out uint32
+	matrix  [][]float64
+}{
+	{"stereo to mono", wav.FrontLeft | wav.FrontRight, wav.FrontCenter, [][]float64{{0.5, 0.5}}},
+	{"mono to stereo", wav.FrontCenter, wav.FrontLeft | wav.FrontRight, [][]float64{{minus3dB}, {minus3dB}}},
+	{"5.1 to stereo", wav.DefaultChannelMask(6), wav.DefaultChannelMask(2), [][]float64{
+		{1, 0, minus3dB, 0, minus3dB, 0},
+		{0, 1, minus3dB, 0, 0, minus3dB},
+	}},
+	{"7.1 to 5.1", wav.DefaultChannelMask(8), wav.DefaultChannelMask(6), [][]float64{
+		{1, 0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 0, 0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 0, 0, 0, 0},
+		{0, 0, 0, 1, 0, 0, 0, 0},
+		{0, 0, 0, 0, 1, 0, 1, 0},
+		{0, 0, 0, 0, 0, 1, 0, 1},
+	}},
+	{"top to stereo", wav.TopFrontLeft, wav.DefaultChannelMask(2), [][]float64{{minus3dB}, {0}}},
+}
+
+func TestDownmix(t *testing.T) {
+	for _, tc := range DownmixTest {
+		m, err := Downmix(tc.in, tc.out)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.Matrix, tc.matrix) || m.OutMask != tc.out {
+			t.Errorf("%s: matrix %v, expecting: %v", tc.name, m.Matrix, tc.matrix)
+		}
+	}
+}
+
+func TestMixByRole(t *testing.T) {
+	stereo := wav.DefaultChannelMask(2)
+	m, err := MixByRole(stereo, stereo, Route{wav.FrontLeft, wav.FrontRight, 1}, Route{wav.FrontRight, wav.FrontLeft, 1})
+	if err != nil {
+		t.Fatal("MixByRole failed:", err)
+	}
+	if !reflect.DeepEqual(m.Matrix, [][]float64{{0, 1}, {1, 0}}) {
+		t.Errorf("Swap matrix: %v", m.Matrix)
+	}
+	if _, err = MixByRole(stereo, stereo, Route{wav.FrontCenter, wav.FrontLeft, 1}); err == nil {
+		t.Error("Route from a missing position didn't return an error")
+	}
+	if _, err = MixByRole(0, stereo); err == nil {
+		t.Error("Empty layout didn't return an error")
+	}
+}
+
+func TestWithMix(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, 8000, 16000, 2, I16, I16, MediumQ, WithMix(Mix{Matrix: [][]float64{{1}}})); err == nil {
+		t.Error("Mismatched matrix didn't return an error")
+	}
+	var out bytes.Buffer
+	res, err := New(&out, 8000, 16000, 2, I16, I16, MediumQ, WithMix(Mix{Matrix: [][]float64{{0.5, 0.5}}}))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	in := make([]byte, 4*800)
+	for i := 0; i < len(in); i += 4 {
+		binary.LittleEndian.PutUint16(in[i:], 8000)
+		binary.LittleEndian.PutUint16(in[i+2:], 0)
+	}
+	if n, err := res.Write(in); err != nil || n != len(in) {
+		t.Fatalf("Write returned %d, %v", n, err)
+	}
+	res.Close()
+	if out.Len() < 2*1500 || out.Len() > 2*1600 {
+		t.Fatalf("Output size: %d, expecting about %d", out.Len(), 2*1600)
+	}
+	mid := out.Bytes()[1600 : 1600+2]
+	if s := int16(binary.LittleEndian.Uint16(mid)); s < 3900 || s > 4100 {
+		t.Errorf("Mixed sample: %d, expecting about 4000", s)
+	}
+}
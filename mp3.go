@@ -0,0 +1,30 @@
+//go:build mp3
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// NewFromMP3 decodes the MP3 stream src and returns a Resampler configured
+// with its sampling rate. The decoder always produces 16-bit stereo, so the
+// input of the Resampler is two channels of I16, mono streams being duplicated.
+// The decoded audio is resampled to outRate and written to dst. The Resampler
+// must be closed to flush its remaining output.
+func NewFromMP3(dst io.Writer, src io.Reader, outRate float64, opts ...Option) (*Resampler, error) {
+	dec, err := mp3.NewDecoder(src)
+	if err != nil {
+		return nil, err
+	}
+	return newFromReader(dst, dec, float64(dec.SampleRate()), outRate, 2, I16, 4, opts)
+}
@@ -0,0 +1,76 @@
+//go:build mp3
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestNewFromMP3(t *testing.T) {
+	// A second of a 440 Hz sine at half scale, encoded at 128 kbps by shine.
+	for _, tc := range []struct {
+		file     string
+		channels int
+	}{
+		{"testing/sine-44.1k-2.mp3", 2},
+		{"testing/sine-22.05k-1.mp3", 1},
+	} {
+		in, err := os.ReadFile(tc.file)
+		if err != nil {
+			t.Fatal("Failed to read test data:", err)
+		}
+		var out bytes.Buffer
+		res, err := NewFromMP3(&out, bytes.NewReader(in), 16000, WithOutFormat(F32), WithDither(DitherNone))
+		if err != nil {
+			t.Fatalf("%s: NewFromMP3 failed: %v", tc.file, err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatalf("%s: Close failed: %v", tc.file, err)
+		}
+		// Always stereo.
+		if frames := out.Len() / 8; math.Abs(float64(frames-16000)) > 500 {
+			t.Errorf("%s: %d frames out, expected about 16000", tc.file, frames)
+		}
+		s := make([]float32, out.Len()/4)
+		binary.Read(&out, binary.LittleEndian, s)
+		// Half a second in the middle, of the left channel.
+		var sum float64
+		crossings := 0
+		for i := 2 * 4000; i < 2*12000; i += 2 {
+			sum += float64(s[i]) * float64(s[i])
+			if (s[i-2] < 0) != (s[i] < 0) {
+				crossings++
+			}
+		}
+		if rms := math.Sqrt(sum / 8000); math.Abs(rms-0.5/math.Sqrt2) > 0.06 {
+			t.Errorf("%s: RMS %g, expected about %g", tc.file, rms, 0.5/math.Sqrt2)
+		}
+		if crossings < 436 || crossings > 444 {
+			t.Errorf("%s: %d zero crossings, expected 440", tc.file, crossings)
+		}
+		// Mono streams are duplicated.
+		if tc.channels == 1 {
+			for i := 0; i < len(s); i += 2 {
+				if s[i] != s[i+1] {
+					t.Fatalf("%s: channels differ at frame %d", tc.file, i/2)
+				}
+			}
+		}
+	}
+	if _, err := NewFromMP3(io.Discard, bytes.NewReader(make([]byte, 100)), 16000); err == nil {
+		t.Error("NewFromMP3 of silence bytes didn't return an error")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package ogg implements writing of Ogg bitstream pages as described in RFC 3533.
+*/
+package ogg
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// Page header type flags
+	Continued     = 0x01 // page continues a packet from the previous page
+	BeginOfStream = 0x02 // first page of the logical bitstream
+	EndOfStream   = 0x04 // last page of the logical bitstream
+
+	headerSize  = 27
+	maxSegments = 255
+	// maxPageData is the largest payload a single page can carry.
+	maxPageData = maxSegments * 255
+)
+
+var crcTable = func() (t [256]uint32) {
+	for i := range t {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return
+}()
+
+// crc computes the Ogg page checksum.
+func crc(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c = c<<8 ^ crcTable[byte(c>>24)^v]
+	}
+	return c
+}
+
+// Writer writes packets of a single logical bitstream as Ogg pages.
+type Writer struct {
+	w      io.Writer
+	serial uint32 // bitstream serial number
+	seq    uint32 // page sequence number
+	closed bool   // end of stream page has been written
+}
+
+// NewWriter returns a Writer that writes pages of the logical bitstream
+// with the given serial number to w.
+func NewWriter(w io.Writer, serial uint32) *Writer {
+	return &Writer{w: w, serial: serial}
+}
+
+// WritePacket writes packet on one or more pages. The granule position is
+// set on the page where the packet ends. If last is true the page is marked
+// as the end of the stream and no more packets can be written.
+func (w *Writer) WritePacket(packet []byte, granule int64, last bool) error {
+	if w.closed {
+		return errors.New("ogg: write after end of stream")
+	}
+	var flags byte
+	if w.seq == 0 {
+		flags |= BeginOfStream
+	}
+	for {
+		data := packet
+		// A packet ending exactly on a page boundary needs a zero length
+		// segment on the following page, so only split strictly larger packets.
+		if len(data) >= maxPageData {
+			data = packet[:maxPageData]
+		}
+		packet = packet[len(data):]
+		end := len(data) < maxPageData
+		gp := int64(-1)
+		if end {
+			gp = granule
+			if last {
+				flags |= EndOfStream
+			}
+		}
+		if err := w.writePage(data, flags, gp, end); err != nil {
+			return err
+		}
+		if end {
+			break
+		}
+		flags = Continued
+	}
+	w.closed = last
+	return nil
+}
+
+// writePage writes a single page holding data. If end is true the
+// segment table terminates the packet.
+func (w *Writer) writePage(data []byte, flags byte, granule int64, end bool) error {
+	segments := len(data) / 255
+	if end {
+		segments++
+	}
+	page := make([]byte, headerSize+segments+len(data))
+	copy(page, "OggS")
+	page[4] = 0 // stream structure version
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], w.serial)
+	binary.LittleEndian.PutUint32(page[18:], w.seq)
+	page[26] = byte(segments)
+	for i := 0; i < segments; i++ {
+		page[headerSize+i] = 255
+	}
+	if end {
+		page[headerSize+segments-1] = byte(len(data) % 255)
+	}
+	copy(page[headerSize+segments:], data)
+	binary.LittleEndian.PutUint32(page[22:], crc(page))
+	w.seq++
+	_, err := w.w.Write(page)
+	return err
+}
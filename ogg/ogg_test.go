@@ -0,0 +1,113 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestCRC(t *testing.T) {
+	// CRC-32 with polynomial 0x04c11db7, zero init and no final xor.
+	if c := crc([]byte("123456789")); c != 0x89a1897f {
+		t.Fatalf("CRC: %#x, expecting: %#x", c, uint32(0x89a1897f))
+	}
+}
+
+// page is a parsed Ogg page used to check the Writer output.
+type page struct {
+	flags    byte
+	granule  int64
+	serial   uint32
+	seq      uint32
+	segments []byte
+	data     []byte
+}
+
+func parsePages(t *testing.T, b []byte) []page {
+	var pages []page
+	for len(b) > 0 {
+		if len(b) < headerSize || string(b[:4]) != "OggS" {
+			t.Fatal("Invalid page header")
+		}
+		p := page{
+			flags:   b[5],
+			granule: int64(binary.LittleEndian.Uint64(b[6:])),
+			serial:  binary.LittleEndian.Uint32(b[14:]),
+			seq:     binary.LittleEndian.Uint32(b[18:]),
+		}
+		n := int(b[26])
+		p.segments = b[headerSize : headerSize+n]
+		size := 0
+		for _, s := range p.segments {
+			size += int(s)
+		}
+		end := headerSize + n + size
+		p.data = b[headerSize+n : end]
+		raw := append([]byte{}, b[:end]...)
+		sum := binary.LittleEndian.Uint32(raw[22:])
+		binary.LittleEndian.PutUint32(raw[22:], 0)
+		if crc(raw) != sum {
+			t.Fatalf("Page %d checksum mismatch", p.seq)
+		}
+		pages = append(pages, p)
+		b = b[end:]
+	}
+	return pages
+}
+
+func TestWritePacket(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, 0x1234)
+	if err := w.WritePacket([]byte("head"), 0, false); err != nil {
+		t.Fatal("WritePacket failed:", err)
+	}
+	if err := w.WritePacket(make([]byte, 300), 960, true); err != nil {
+		t.Fatal("WritePacket failed:", err)
+	}
+	if err := w.WritePacket([]byte("late"), 1920, false); err == nil {
+		t.Fatal("WritePacket after end of stream didn't return an error")
+	}
+	pages := parsePages(t, buf.Bytes())
+	if len(pages) != 2 {
+		t.Fatalf("Pages: %d, expecting: 2", len(pages))
+	}
+	if pages[0].flags != BeginOfStream || pages[1].flags != EndOfStream {
+		t.Errorf("Page flags: %#x %#x", pages[0].flags, pages[1].flags)
+	}
+	if pages[1].granule != 960 || pages[1].serial != 0x1234 || pages[1].seq != 1 {
+		t.Errorf("Unexpected page header: %+v", pages[1])
+	}
+	if !bytes.Equal(pages[1].segments, []byte{255, 45}) {
+		t.Errorf("Segment table: %v, expecting: [255 45]", pages[1].segments)
+	}
+}
+
+func TestWriteLargePacket(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, 1)
+	packet := make([]byte, maxPageData)
+	if err := w.WritePacket(packet, 48000, false); err != nil {
+		t.Fatal("WritePacket failed:", err)
+	}
+	pages := parsePages(t, buf.Bytes())
+	if len(pages) != 2 {
+		t.Fatalf("Pages: %d, expecting: 2", len(pages))
+	}
+	if pages[0].granule != -1 || len(pages[0].data) != maxPageData {
+		t.Errorf("First page granule: %d, size: %d", pages[0].granule, len(pages[0].data))
+	}
+	if pages[1].flags != Continued || pages[1].granule != 48000 {
+		t.Errorf("Second page flags: %#x, granule: %d", pages[1].flags, pages[1].granule)
+	}
+	if !bytes.Equal(pages[1].segments, []byte{0}) {
+		t.Errorf("Segment table: %v, expecting: [0]", pages[1].segments)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Option sets an optional Resampler parameter.
+type Option func(*options)
+
+type options struct {
+	outFormat    int               // output format, -1 means same as the input
+	quality      int               // quality setting
+	mix          [][]float64       // channel mixing matrix, nil for none
+	outMask      uint32            // wav channel mask of the mixed output
+	keepChunk    func(string) bool // WAV metadata chunk filter
+	threads      int               // soxr threads, 0 for one per CPU
+	soxrFlags    soxrFlags         // extra flags of the soxr specs
+	gain         float64           // linear input gain
+	swapIn       bool              // input samples are big-endian
+	swapOut      bool              // output samples are big-endian
+	dither       int               // dither setting
+	seed         *int64            // dither random seed, nil for none
+	backend      int               // resampling library
+	fir          *firSpec          // filter of BackendFIR, nil for the quality's
+	dcCutoff     float64           // DC block cutoff in Hz, 0 for none
+	preFilters   []Filter          // filters applied to the input
+	postFilters  []Filter          // filters applied to the output
+	preProcs     []Processor       // processors applied to the input
+	postProcs    []Processor       // processors applied to the output
+	limit        float64           // limiter threshold in dBFS, 0 for none
+	variable     bool              // the output rate may change
+	sanitize     bool              // NaN and Inf float input replaced with zero
+	overflow     int               // overflow setting
+	bufFrames    int               // input frames the output buffer is allocated for
+	maxBufFrames int               // input frames the output buffer is capped to
+	memLimit     int               // bytes of buffers per Write, 0 for no limit
+	queue        int               // Writes queued by an asynchronous Resampler, 0 for synchronous ones
+}
+
+func defaultOptions() options {
+	return options{
+		outFormat: -1,
+		quality:   HighQ,
+		backend:   defaultBackend(),
+		gain:      1,
+		keepChunk: func(string) bool { return true },
+	}
+}
+
+// applyOptions returns the defaults modified by opts. An unset output
+// format is resolved to inFormat.
+func applyOptions(opts []Option, inFormat int) options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.outFormat < 0 {
+		o.outFormat = inFormat
+	}
+	return o
+}
+
+// WithOutFormat sets the output format. When not set NewFromWAV keeps
+// the input format.
+func WithOutFormat(format int) Option {
+	return func(o *options) {
+		o.outFormat = format
+	}
+}
+
+// WithQuality sets the quality setting. The default is HighQ.
+func WithQuality(quality int) Option {
+	return func(o *options) {
+		o.quality = quality
+	}
+}
+
+// WithMetadata sets the WAV metadata chunks carried over by ConvertWAV
+// and WAVMetadata.
+// keep is called with the identifier of every chunk found besides the fmt
+// and data ones and reports whether it should be kept. By default all are.
+func WithMetadata(keep func(id string) bool) Option {
+	return func(o *options) {
+		o.keepChunk = keep
+	}
+}
+
+// WithThreads sets the number of threads soxr may use. The default, 0,
+// uses one thread per CPU.
+func WithThreads(n int) Option {
+	return func(o *options) {
+		o.threads = n
+	}
+}
+
+// soxrFlags are flags ORed into the specs of a soxr resampler.
+type soxrFlags struct {
+	quality, io, runtime uint
+}
+
+// WithSoxrFlags ORs flag bits, the SOXR_ constants of soxr.h, into the
+// quality, io and runtime specs soxr is created with, for libsoxr features
+// the package doesn't cover yet. They aren't checked: flags changing the
+// data layout, such as SOXR_VR, break the Resampler. libsamplerate ignores
+// them.
+func WithSoxrFlags(quality, io, runtime uint) Option {
+	return func(o *options) {
+		o.soxrFlags = soxrFlags{quality: quality, io: io, runtime: runtime}
+	}
+}
+
+// WithBufferSize allocates the output buffer of the resampling library
+// for Writes of up to frames input frames when the Resampler is created,
+// so that latency sensitive streams don't allocate it mid-stream. The
+// buffer is reused by every Write and grows for larger ones.
+func WithBufferSize(frames int) Option {
+	return func(o *options) {
+		o.bufFrames = frames
+	}
+}
+
+// WithMaxBufferSize caps the output buffer of the resampling library to
+// the output of frames input frames. Larger Writes are resampled frames
+// at a time, and the output of Close is written in pieces that fit the
+// buffer. The default, 0, lets the buffer grow to the largest Write.
+func WithMaxBufferSize(frames int) Option {
+	return func(o *options) {
+		o.maxBufFrames = frames
+	}
+}
+
+// WithMemoryLimit bounds the buffers used to resample a Write, from the
+// conversion of its input to the output written, to about bytes, trading
+// throughput for predictable memory on small devices. Writes are then
+// resampled in pieces that fit, as with WithMaxBufferSize, and soxr uses
+// a single thread unless WithThreads says otherwise. The filter state of
+// the resampling library isn't counted, lower quality settings keep it
+// small. A limit too small for a single frame fails New.
+func WithMemoryLimit(bytes int) Option {
+	return func(o *options) {
+		o.memLimit = bytes
+	}
+}
+
+// WithGain applies a gain of dB decibels to the input. The samples are
+// scaled in double precision before being resampled and quantized to
+// the output format.
+func WithGain(dB float64) Option {
+	return func(o *options) {
+		o.gain = math.Pow(10, dB/20)
+	}
+}
+
+// WithVariableRate creates a Resampler whose output rate can be changed
+// while it runs with SetRate, for clock drift correction or varispeed
+// effects. soxr then uses its variable rate filters.
+func WithVariableRate() Option {
+	return func(o *options) {
+		o.variable = true
+	}
+}
+
+// WithByteOrder sets the byte order of the input and output samples. The
+// default, and the one used for nil arguments, is little-endian.
+func WithByteOrder(in, out binary.ByteOrder) Option {
+	return func(o *options) {
+		o.swapIn = in == binary.BigEndian
+		o.swapOut = out == binary.BigEndian
+	}
+}
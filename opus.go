@@ -0,0 +1,222 @@
+//go:build opus && cgo
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+/*
+// Link libopus using pkg-config.
+#cgo pkg-config: opus
+#include <opus.h>
+
+// opus_encoder_ctl is variadic and cannot be called directly from Go.
+static int get_lookahead(OpusEncoder *enc, opus_int32 *v) {
+	return opus_encoder_ctl(enc, OPUS_GET_LOOKAHEAD(v));
+}
+
+static int set_bitrate(OpusEncoder *enc, opus_int32 v) {
+	return opus_encoder_ctl(enc, OPUS_SET_BITRATE(v));
+}
+*/
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+
+	"github.com/zaf/resample/ogg"
+)
+
+const (
+	opusRate      = 48000 // Opus always operates at 48 kHz
+	opusFrame     = 960   // 20 ms frames
+	opusMaxPacket = 4000  // recommended maximum packet size
+)
+
+// OpusWriter encodes 48 kHz F32 PCM audio to Opus and writes it to an
+// Ogg container. It is meant to be used as the destination of a Resampler
+// with an output rate of 48000 and an F32 output format.
+type OpusWriter struct {
+	enc      *C.OpusEncoder
+	ogg      *ogg.Writer
+	channels int
+	preSkip  int       // encoder lookahead in samples
+	samples  int64     // samples per channel passed to the encoder
+	pcm      []float32 // samples waiting for a complete frame
+	partial  []byte    // bytes of an incomplete sample
+	packet   []byte    // encoder output buffer
+	pending  []byte    // last encoded packet, held back to mark the end of stream
+	granule  int64     // granule position of the pending packet
+}
+
+// NewOpusWriter returns an OpusWriter for mono or stereo audio that writes
+// to w. A bitrate of 0 lets the encoder choose.
+func NewOpusWriter(w io.Writer, channels, bitrate int) (*OpusWriter, error) {
+	if w == nil {
+		return nil, errors.New("io.Writer is nil")
+	}
+	if channels != 1 && channels != 2 {
+		return nil, errors.New("opus: only mono and stereo streams are supported")
+	}
+	var e C.int
+	enc := C.opus_encoder_create(opusRate, C.int(channels), C.OPUS_APPLICATION_AUDIO, &e)
+	if e != C.OPUS_OK {
+		return nil, errors.New(C.GoString(C.opus_strerror(e)))
+	}
+	if bitrate > 0 {
+		if e = C.set_bitrate(enc, C.opus_int32(bitrate)); e != C.OPUS_OK {
+			C.opus_encoder_destroy(enc)
+			return nil, errors.New(C.GoString(C.opus_strerror(e)))
+		}
+	}
+	var lookahead C.opus_int32
+	if e = C.get_lookahead(enc, &lookahead); e != C.OPUS_OK {
+		C.opus_encoder_destroy(enc)
+		return nil, errors.New(C.GoString(C.opus_strerror(e)))
+	}
+	o := &OpusWriter{
+		enc:      enc,
+		ogg:      ogg.NewWriter(w, rand.Uint32()),
+		channels: channels,
+		preSkip:  int(lookahead),
+		pcm:      make([]float32, 0, opusFrame*channels),
+		packet:   make([]byte, opusMaxPacket),
+	}
+	if err := o.writeHeaders(); err != nil {
+		C.opus_encoder_destroy(enc)
+		return nil, err
+	}
+	return o, nil
+}
+
+// writeHeaders writes the identification and comment header packets.
+func (o *OpusWriter) writeHeaders() error {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = byte(o.channels)
+	binary.LittleEndian.PutUint16(head[10:], uint16(o.preSkip))
+	binary.LittleEndian.PutUint32(head[12:], opusRate)
+	// Output gain and channel mapping family are left at zero.
+	if err := o.ogg.WritePacket(head, 0, false); err != nil {
+		return err
+	}
+	vendor := C.GoString(C.opus_get_version_string())
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+	return o.ogg.WritePacket(tags, 0, false)
+}
+
+// Write encodes F32 little-endian interleaved PCM data.
+func (o *OpusWriter) Write(p []byte) (int, error) {
+	if o.enc == nil {
+		return 0, errors.New("opus encoder is nil")
+	}
+	n := len(p)
+	if len(o.partial) > 0 {
+		need := 4 - len(o.partial)
+		if len(p) < need {
+			o.partial = append(o.partial, p...)
+			return n, nil
+		}
+		o.partial = append(o.partial, p[:need]...)
+		p = p[need:]
+		if err := o.push(math.Float32frombits(binary.LittleEndian.Uint32(o.partial))); err != nil {
+			return 0, err
+		}
+		o.partial = o.partial[:0]
+	}
+	for ; len(p) >= 4; p = p[4:] {
+		if err := o.push(math.Float32frombits(binary.LittleEndian.Uint32(p))); err != nil {
+			return 0, err
+		}
+	}
+	o.partial = append(o.partial, p...)
+	return n, nil
+}
+
+// push adds a sample, encoding a frame once it is complete.
+func (o *OpusWriter) push(v float32) error {
+	o.pcm = append(o.pcm, v)
+	if len(o.pcm) < cap(o.pcm) {
+		return nil
+	}
+	o.samples += opusFrame
+	return o.encode()
+}
+
+// encode encodes the buffered frame and writes out the previous packet.
+func (o *OpusWriter) encode() error {
+	n := C.opus_encode_float(o.enc, (*C.float)(&o.pcm[0]), opusFrame, (*C.uchar)(&o.packet[0]), C.opus_int32(len(o.packet)))
+	o.pcm = o.pcm[:0]
+	if n < 0 {
+		return errors.New(C.GoString(C.opus_strerror(n)))
+	}
+	if o.pending != nil {
+		if err := o.ogg.WritePacket(o.pending, o.granule, false); err != nil {
+			return err
+		}
+	}
+	o.pending = append(o.pending[:0], o.packet[:n]...)
+	o.granule = int64(o.preSkip) + o.samples
+	return nil
+}
+
+// Close encodes any buffered samples, padding the last frame with silence,
+// writes the final page and frees the encoder. It does not close the
+// underlying Writer.
+func (o *OpusWriter) Close() error {
+	if o.enc == nil {
+		return errors.New("opus encoder is nil")
+	}
+	defer func() {
+		C.opus_encoder_destroy(o.enc)
+		o.enc = nil
+	}()
+	if len(o.pcm) > 0 || o.pending == nil {
+		total := o.samples + int64(len(o.pcm)/o.channels)
+		n := len(o.pcm)
+		o.pcm = o.pcm[:cap(o.pcm)]
+		for i := n; i < len(o.pcm); i++ {
+			o.pcm[i] = 0
+		}
+		if err := o.encode(); err != nil {
+			return err
+		}
+		// The end of stream granule position trims the padding.
+		o.granule = int64(o.preSkip) + total
+	}
+	return o.ogg.WritePacket(o.pending, o.granule, true)
+}
+
+// opusDecode decodes Opus packets of channels at 48 kHz to interleaved
+// samples, for the tests to check the output of an OpusWriter.
+func opusDecode(packets [][]byte, channels int) ([]float32, error) {
+	var e C.int
+	dec := C.opus_decoder_create(opusRate, C.int(channels), &e)
+	if e != C.OPUS_OK {
+		return nil, errors.New(C.GoString(C.opus_strerror(e)))
+	}
+	defer C.opus_decoder_destroy(dec)
+	// The longest packet holds 120 ms.
+	buf := make([]float32, opusRate*120/1000*channels)
+	var pcm []float32
+	for _, p := range packets {
+		n := C.opus_decode_float(dec, (*C.uchar)(&p[0]), C.opus_int32(len(p)), (*C.float)(&buf[0]), C.int(len(buf)/channels), 0)
+		if n < 0 {
+			return nil, errors.New(C.GoString(C.opus_strerror(n)))
+		}
+		pcm = append(pcm, buf[:int(n)*channels]...)
+	}
+	return pcm, nil
+}
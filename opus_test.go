@@ -0,0 +1,169 @@
+//go:build opus && cgo
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// oggPage is a parsed Ogg page.
+type oggPage struct {
+	flags   byte
+	granule int64
+	packets [][]byte // packets ending on the page
+}
+
+// parseOgg returns the pages of the Ogg stream b, with the packets ending
+// on each of them.
+func parseOgg(t *testing.T, b []byte) []oggPage {
+	t.Helper()
+	var pages []oggPage
+	var packet []byte
+	for len(b) > 0 {
+		if len(b) < 27 || string(b[:4]) != "OggS" {
+			t.Fatalf("Invalid Ogg page at %d bytes from the end", len(b))
+		}
+		p := oggPage{flags: b[5], granule: int64(binary.LittleEndian.Uint64(b[6:]))}
+		lacing := b[27 : 27+int(b[26])]
+		data := b[27+len(lacing):]
+		for _, l := range lacing {
+			packet = append(packet, data[:l]...)
+			data = data[l:]
+			if l < 255 {
+				p.packets = append(p.packets, packet)
+				packet = nil
+			}
+		}
+		pages = append(pages, p)
+		b = data
+	}
+	return pages
+}
+
+// opusSine resamples a second of a 440 Hz sine at 16 kHz, at half scale,
+// on channels to an OpusWriter, written in chunks of chunk bytes, and
+// returns its output.
+func opusSine(t *testing.T, channels, bitrate, chunk int) []byte {
+	t.Helper()
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 16000, 16000), channels, testsignal.F64)
+	var out bytes.Buffer
+	w, err := NewOpusWriter(&out, channels, bitrate)
+	if err != nil {
+		t.Fatal("Failed to create the OpusWriter:", err)
+	}
+	var pcm bytes.Buffer
+	res, err := New(&pcm, 16000, opusRate, channels, F64, F32, HighQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	res.Write(in)
+	if err = res.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	for p := pcm.Bytes(); len(p) > 0; p = p[min(len(p), chunk):] {
+		if _, err = w.Write(p[:min(len(p), chunk)]); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	if _, err = w.Write(make([]byte, 4)); err == nil {
+		t.Error("Write after Close didn't return an error")
+	}
+	return out.Bytes()
+}
+
+func TestOpusWriter(t *testing.T) {
+	for _, channels := range []int{1, 2} {
+		pages := parseOgg(t, opusSine(t, channels, 0, 1<<20))
+		if len(pages) < 3 || pages[0].flags != 2 || len(pages[0].packets) != 1 || pages[len(pages)-1].flags&4 == 0 {
+			t.Fatalf("%d channels: %d pages, first flags %d", channels, len(pages), pages[0].flags)
+		}
+		head := pages[0].packets[0]
+		if len(head) != 19 || string(head[:8]) != "OpusHead" || head[8] != 1 || int(head[9]) != channels ||
+			binary.LittleEndian.Uint32(head[12:]) != opusRate {
+			t.Fatalf("%d channels: OpusHead %v", channels, head)
+		}
+		preSkip := int(binary.LittleEndian.Uint16(head[10:]))
+		if tags := pages[1].packets; len(tags) != 1 || !bytes.HasPrefix(tags[0], []byte("OpusTags")) {
+			t.Fatalf("%d channels: OpusTags %q", channels, tags)
+		}
+		var packets [][]byte
+		var granule int64
+		for _, p := range pages[2:] {
+			if p.granule < granule {
+				t.Errorf("%d channels: granule position %d after %d", channels, p.granule, granule)
+			}
+			granule = p.granule
+			packets = append(packets, p.packets...)
+		}
+		// The end of stream granule position trims the padding of the last frame.
+		if granule != int64(preSkip+opusRate) {
+			t.Errorf("%d channels: last granule position %d, expected %d", channels, granule, preSkip+opusRate)
+		}
+
+		pcm, err := opusDecode(packets, channels)
+		if err != nil {
+			t.Fatalf("%d channels: decoding failed: %v", channels, err)
+		}
+		if len(pcm) != len(packets)*opusFrame*channels {
+			t.Fatalf("%d channels: %d samples decoded from %d packets", channels, len(pcm), len(packets))
+		}
+		// The middle of the sine, past the encoder delay.
+		s := pcm[(preSkip+4800)*channels : (preSkip+43200)*channels]
+		var sum float64
+		crossings := 0
+		for i, v := range s {
+			sum += float64(v) * float64(v)
+			if i >= channels && (s[i-channels] < 0) != (v < 0) {
+				crossings++
+			}
+		}
+		if rms := math.Sqrt(sum / float64(len(s))); math.Abs(rms-0.5/math.Sqrt2) > 0.02 {
+			t.Errorf("%d channels: decoded RMS %g, expected %g", channels, rms, 0.5/math.Sqrt2)
+		}
+		// 440 Hz crosses zero 880 times a second, on each channel.
+		if want := 880 * 0.8 * float64(channels); math.Abs(float64(crossings)-want) > 4*float64(channels) {
+			t.Errorf("%d channels: %d zero crossings, expected %g", channels, crossings, want)
+		}
+	}
+}
+
+func TestOpusWriterChunks(t *testing.T) {
+	// Writes splitting the samples give the same packets.
+	packets := func(b []byte) [][]byte {
+		var p [][]byte
+		for _, page := range parseOgg(t, b) {
+			p = append(p, page.packets...)
+		}
+		return p[2:]
+	}
+	whole, split := packets(opusSine(t, 2, 64000, 1<<20)), packets(opusSine(t, 2, 64000, 3))
+	if !reflect.DeepEqual(whole, split) {
+		t.Error("Writes of 3 bytes changed the packets")
+	}
+	for _, channels := range []int{0, 3} {
+		if _, err := NewOpusWriter(io.Discard, channels, 0); err == nil {
+			t.Errorf("%d channels didn't return an error", channels)
+		}
+	}
+	if _, err := NewOpusWriter(nil, 1, 0); err == nil {
+		t.Error("nil Writer didn't return an error")
+	}
+}
@@ -0,0 +1,100 @@
+//go:build oto
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+)
+
+// otoContext is the oto context of the process, oto supports only one.
+var otoContext struct {
+	sync.Mutex
+	ctx      *oto.Context
+	rate     int
+	channels int
+}
+
+// NewOtoPlayer returns a Player playing the resampled input on the default
+// audio device through oto, at outputRate. oto plays mono and stereo only,
+// and a program has a single oto context: all of its Players must have the
+// output rate and channels of the first one. Other Players of the same
+// context are mixed together.
+func NewOtoPlayer(inputRate, outputRate float64, channels, inFormat, quality int, opts ...Option) (*Player, error) {
+	open := func(channels int) (io.WriteCloser, error) {
+		ctx, err := otoContextFor(int(outputRate), channels)
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		d := &otoDevice{w: pw, player: ctx.NewPlayer(pr)}
+		d.player.Play()
+		return d, nil
+	}
+	if outputRate != float64(int(outputRate)) {
+		return nil, errors.New("oto needs an integer output rate")
+	}
+	return newPlayer(open, inputRate, outputRate, channels, inFormat, quality, opts...)
+}
+
+// otoContextFor returns the oto context, created for rate and channels
+// on first use.
+func otoContextFor(rate, channels int) (*oto.Context, error) {
+	otoContext.Lock()
+	defer otoContext.Unlock()
+	if otoContext.ctx != nil {
+		if rate != otoContext.rate || channels != otoContext.channels {
+			return nil, errors.New("oto is already playing at another rate or number of channels")
+		}
+		return otoContext.ctx, nil
+	}
+	if channels != 1 && channels != 2 {
+		return nil, errors.New("oto only plays mono or stereo")
+	}
+	ctx, ready, err := oto.NewContext(&oto.NewContextOptions{SampleRate: rate, ChannelCount: channels, Format: oto.FormatFloat32LE})
+	if err != nil {
+		return nil, err
+	}
+	<-ready
+	otoContext.ctx, otoContext.rate, otoContext.channels = ctx, rate, channels
+	return ctx, nil
+}
+
+// otoDevice feeds an oto player through a pipe, so writes block while
+// the player's buffer is full.
+type otoDevice struct {
+	w      *io.PipeWriter
+	player *oto.Player
+}
+
+func (d *otoDevice) Write(p []byte) (int, error) {
+	if err := d.player.Err(); err != nil {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
+
+// Close ends the stream and waits for the player to play what it holds.
+func (d *otoDevice) Close() error {
+	d.w.Close()
+	for d.player.IsPlaying() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	err := d.player.Err()
+	if e := d.player.Close(); err == nil {
+		err = e
+	}
+	return err
+}
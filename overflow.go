@@ -0,0 +1,50 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+// Overflow settings for integer output.
+const (
+	OverflowClip     = 0 // Samples beyond full scale are hard clipped
+	OverflowSaturate = 1 // Samples near full scale are softly saturated
+	OverflowError    = 2 // Samples beyond full scale fail the Write
+)
+
+// saturateThreshold is the level in dBFS above which OverflowSaturate
+// compresses samples towards full scale.
+const saturateThreshold = -1
+
+// ErrOverflow is returned by Write and Close with OverflowError when an
+// output sample exceeds the range of the integer output format.
+var ErrOverflow = errors.New("sample exceeds full scale")
+
+// WithOverflow sets what happens to resampled samples beyond full scale
+// when converted to an I16, I32, I24In32 or G.711 output format.
+// OverflowClip, the default, hard clips them. OverflowSaturate compresses
+// the samples above -1 dBFS smoothly towards full scale, after any
+// limiter, so that none is clipped, dithered or not. OverflowError drops
+// the output of the Write and returns ErrOverflow. Clipping and the checks
+// are then done in Go, on double precision output of the resampling
+// library. Float output formats aren't affected.
+func WithOverflow(overflow int) Option {
+	return func(o *options) {
+		o.overflow = overflow
+	}
+}
+
+// saturateStage returns a stage compressing samples above saturateThreshold
+// dBFS towards the largest sample of the I16, I32 or I24In32 format, less
+// the amplitude of the dither.
+func saturateStage(format int) stage {
+	return softLimitStage(math.Pow(10, saturateThreshold/20.0), 1-math.Ldexp(1, 2-formatBits(format)))
+}
@@ -0,0 +1,62 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestWithOverflow(t *testing.T) {
+	in := testsignal.Sine(440, 1.5, 8000, 8000)
+	var out bytes.Buffer
+	for _, overflow := range []int{OverflowClip, OverflowSaturate, OverflowError} {
+		out.Reset()
+		res, err := New(&out, 8000.0, 16000.0, 1, F64, I16, MediumQ, WithOverflow(overflow), WithDitherSeed(1))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		_, err = res.Write(float64Bytes(in))
+		switch overflow {
+		case OverflowClip:
+			if err != nil || res.Clips() == 0 {
+				t.Errorf("Clipping: error %v, %d clips", err, res.Clips())
+			}
+		case OverflowSaturate:
+			var peak float64
+			for _, v := range toFloat64(out.Bytes(), I16) {
+				peak = math.Max(peak, math.Abs(v))
+			}
+			if err != nil || res.Clips() != 0 || peak >= 1 {
+				t.Errorf("Saturation: error %v, %d clips, peak %f", err, res.Clips(), peak)
+			}
+		case OverflowError:
+			if !errors.Is(err, ErrOverflow) || out.Len() != 0 {
+				t.Errorf("Overflow error: error %v, %d bytes written", err, out.Len())
+			}
+		}
+		res.Close()
+	}
+	res, err := New(io.Discard, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithOverflow(OverflowError))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Error("Overflow error for F64 output:", err)
+	}
+	res.Close()
+	if _, err = New(io.Discard, 8000.0, 16000.0, 1, F64, I16, MediumQ, WithOverflow(3)); err == nil {
+		t.Error("No error for an invalid overflow setting")
+	}
+}
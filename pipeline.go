@@ -0,0 +1,93 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "io"
+
+// Pipeline builds a Resampler from its stages, declared in a chain:
+//
+//	r, err := NewPipeline().Gain(-3).Mix(stereoToMono).Rate(48000, 16000).Format(F32, I16).Build(w)
+//
+// The stages run in a fixed order whatever the order of the calls: the
+// input is mixed, scaled by the gain and filtered before being resampled
+// and converted to the output format, as with the matching options of
+// New. A stage declared twice keeps the last settings.
+type Pipeline struct {
+	inRate, outRate     float64
+	channels            int
+	mixChannels         int // input channels of the Mix matrix
+	inFormat, outFormat int
+	quality             int
+	opts                []Option
+}
+
+// NewPipeline returns an empty Pipeline, converting I16 input at HighQ.
+func NewPipeline() *Pipeline {
+	return &Pipeline{inFormat: I16, outFormat: I16, quality: HighQ}
+}
+
+// Rate sets the input and output sampling rates.
+func (p *Pipeline) Rate(inRate, outRate float64) *Pipeline {
+	p.inRate, p.outRate = inRate, outRate
+	return p
+}
+
+// Channels sets the number of input channels. Without it they are the
+// columns of the Mix matrix.
+func (p *Pipeline) Channels(channels int) *Pipeline {
+	p.channels = channels
+	return p
+}
+
+// Format sets the input and output formats.
+func (p *Pipeline) Format(inFormat, outFormat int) *Pipeline {
+	p.inFormat, p.outFormat = inFormat, outFormat
+	return p
+}
+
+// Quality sets the quality setting.
+func (p *Pipeline) Quality(quality int) *Pipeline {
+	p.quality = quality
+	return p
+}
+
+// Gain scales the input by dB decibels, as WithGain.
+func (p *Pipeline) Gain(dB float64) *Pipeline {
+	return p.With(WithGain(dB))
+}
+
+// Mix mixes the input channels with m, as WithMix.
+func (p *Pipeline) Mix(m Mix) *Pipeline {
+	p.mixChannels = 0
+	if len(m.Matrix) > 0 {
+		p.mixChannels = len(m.Matrix[0])
+	}
+	return p.With(WithMix(m))
+}
+
+// Filter applies filters to the input before resampling, as WithPreFilter.
+func (p *Pipeline) Filter(filters ...Filter) *Pipeline {
+	return p.With(WithPreFilter(filters...))
+}
+
+// With adds options for the stages that have no method of their own.
+func (p *Pipeline) With(opts ...Option) *Pipeline {
+	p.opts = append(p.opts, opts...)
+	return p
+}
+
+// Build returns a Resampler running the stages of the Pipeline and
+// writing its output to writer. The Pipeline may be built again.
+func (p *Pipeline) Build(writer io.Writer) (*Resampler, error) {
+	channels := p.channels
+	if channels == 0 {
+		channels = p.mixChannels
+	}
+	return New(writer, p.inRate, p.outRate, channels, p.inFormat, p.outFormat, p.quality, p.opts...)
+}
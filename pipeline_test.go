@@ -0,0 +1,51 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestPipeline(t *testing.T) {
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 48000, 4800), 2, testsignal.F32)
+	stereoToMono := Mix{Matrix: [][]float64{{0.5, 0.5}}}
+	var want, got bytes.Buffer
+	res, err := New(&want, 48000, 16000, 2, F32, I16, HighQ, WithGain(-3), WithMix(stereoToMono), WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	res.Write(p)
+	res.Close()
+	res, err = NewPipeline().Gain(-3).Mix(stereoToMono).Rate(48000, 16000).Format(F32, I16).With(WithDither(DitherNone)).Build(&got)
+	if err != nil {
+		t.Fatal("Failed to build the Pipeline:", err)
+	}
+	if res.Channels() != 2 {
+		t.Errorf("%d input channels, expected those of the Mix", res.Channels())
+	}
+	if _, err = res.Write(p); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("Pipeline output differs from the one of New with the same options")
+	}
+	if _, err = NewPipeline().Format(I16, I16).Build(io.Discard); err == nil {
+		t.Error("Pipeline without rates built")
+	}
+	if _, err = NewPipeline().Rate(8000, 16000).Build(io.Discard); err == nil {
+		t.Error("Pipeline without channels built")
+	}
+}
@@ -0,0 +1,81 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// PitchShifter shifts the pitch of PCM sound data by a number of semitones
+// keeping its duration. The input is stretched by the pitch ratio with a
+// Stretcher, in double precision, then resampled back to its length by a
+// Resampler, which raises or lowers the pitch by the same ratio.
+type PitchShifter struct {
+	stretcher *Stretcher
+	resampler *Resampler
+	format    int
+	frameSize int // input frame size in bytes
+	closed    bool
+}
+
+// NewPitchShifter returns a PitchShifter of input at rate, with channels of
+// samples of format, shifting its pitch by semitones, from -24 to 24, and
+// writing the output to writer in the same format. The quality and options
+// are those of the Resampler, as for New.
+func NewPitchShifter(writer io.Writer, rate float64, channels, format int, semitones float64, quality int, opts ...Option) (*PitchShifter, error) {
+	if !(semitones >= -24 && semitones <= 24) {
+		return nil, errors.New("invalid semitones")
+	}
+	ratio := math.Exp2(semitones / 12)
+	r, err := New(writer, rate*ratio, rate, channels, F64, format, quality, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewStretcher(r, rate, channels, F64, 1/ratio)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &PitchShifter{stretcher: s, resampler: r, format: format, frameSize: r.outFrameSize * channels}, nil
+}
+
+// Write shifts the pitch of the frames of p. Trailing bytes of an
+// incomplete frame are ignored, as Write of a Resampler does.
+func (ps *PitchShifter) Write(p []byte) (int, error) {
+	if ps.closed {
+		return 0, ErrClosed
+	}
+	frames := len(p) / ps.frameSize
+	if len(p) > 0 && frames == 0 {
+		return 0, errors.New("incomplete input frame data")
+	}
+	if frames == 0 {
+		return 0, nil
+	}
+	if _, err := ps.stretcher.Write(float64Bytes(toFloat64(p[:frames*ps.frameSize], ps.format))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the rest of the output and frees the PitchShifter. Closing
+// a closed PitchShifter does nothing.
+func (ps *PitchShifter) Close() error {
+	if ps.closed {
+		return nil
+	}
+	ps.closed = true
+	err := ps.stretcher.Close()
+	if e := ps.resampler.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestPitchShifter(t *testing.T) {
+	in := testsignal.Sine(440, 0.5, 48000, 48000)
+	p := fromFloat64(in, I16)
+	for _, semitones := range []float64{-12, -5, 0, 2, 7} {
+		var out bytes.Buffer
+		ps, err := NewPitchShifter(&out, 48000, 1, I16, semitones, HighQ, WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a PitchShifter:", err)
+		}
+		for i := 0; i < len(p); i += 4096 {
+			if _, err = ps.Write(p[i:min(i+4096, len(p))]); err != nil {
+				t.Fatal("Write failed:", err)
+			}
+		}
+		if err = ps.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		got := toFloat64(out.Bytes(), I16)
+		if math.Abs(float64(len(got)-len(in))) > 48 {
+			t.Errorf("%g semitones: %d frames out, expected %d", semitones, len(got), len(in))
+		}
+		want := 440 * math.Exp2(semitones/12)
+		if f := crossings(got, 48000); math.Abs(f-want) > want*0.02 {
+			t.Errorf("%g semitones: pitch of %g Hz, expected %g Hz", semitones, f, want)
+		}
+		if _, err = ps.Write(p); err != ErrClosed {
+			t.Errorf("Write after Close returned %v", err)
+		}
+	}
+	if _, err := NewPitchShifter(io.Discard, 48000, 1, I16, 30, HighQ); err == nil {
+		t.Error("Invalid semitones didn't return an error")
+	}
+}
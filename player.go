@@ -0,0 +1,68 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+)
+
+// Player is a Resampler playing its output on an audio device. Players
+// are created by NewOtoPlayer when building with the `oto' tag and by
+// NewPortAudioPlayer when building with the `portaudio' tag.
+type Player struct {
+	*Resampler
+	device io.WriteCloser // audio output, Close waits for the playback to end
+}
+
+// newPlayer returns a Player resampling to F32 samples at outputRate for
+// the device created by open with the number of output channels.
+func newPlayer(open func(channels int) (io.WriteCloser, error), inputRate, outputRate float64, channels, inFormat, quality int, opts ...Option) (*Player, error) {
+	if channels <= 0 {
+		return nil, errors.New("invalid channels number")
+	}
+	outChannels := channels
+	if o := applyOptions(opts, inFormat); o.mix != nil {
+		outChannels = len(o.mix)
+	}
+	device, err := open(outChannels)
+	if err != nil {
+		return nil, err
+	}
+	// Devices take native little-endian samples.
+	opts = append(opts, func(o *options) { o.swapOut = false })
+	r, err := New(device, inputRate, outputRate, channels, inFormat, F32, quality, opts...)
+	if err != nil {
+		device.Close()
+		return nil, err
+	}
+	return &Player{Resampler: r, device: device}, nil
+}
+
+// Close flushes the Resampler and waits until the device has played all
+// of its output, then releases the device.
+func (p *Player) Close() error {
+	err := p.Resampler.Close()
+	if e := p.device.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Rate returns the output sampling rate of the Player, the rate the
+// device plays at.
+func (p *Player) Rate() float64 {
+	return p.outRate
+}
+
+// Reset permits reusing a Player for a new stream, once the previous one
+// has been written. The output keeps going to the audio device.
+func (p *Player) Reset() error {
+	return p.Resampler.Reset(p.device)
+}
@@ -0,0 +1,73 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeDevice records the data played and whether it was closed.
+type fakeDevice struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestPlayer(t *testing.T) {
+	dev := &fakeDevice{}
+	var opened int
+	open := func(channels int) (io.WriteCloser, error) {
+		opened = channels
+		return dev, nil
+	}
+	p, err := newPlayer(open, 8000, 16000, 2, I16, MediumQ, WithMix(Mix{Matrix: [][]float64{{0.5, 0.5}}}), WithByteOrder(nil, binary.BigEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != 1 {
+		t.Errorf("device opened with %d channels, expected 1", opened)
+	}
+	if p.Rate() != 16000 {
+		t.Errorf("rate is %g, expected 16000", p.Rate())
+	}
+	if _, err = p.Write(make([]byte, 8000*4)); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !dev.closed {
+		t.Error("device not closed")
+	}
+	if n := dev.Len() / 4; n < 15900 || n > 16100 {
+		t.Errorf("played %d F32 frames, expected about 16000", n)
+	}
+
+	dev = &fakeDevice{}
+	if _, err = newPlayer(open, 8000, 16000, 2, 99, MediumQ); err == nil {
+		t.Error("invalid format accepted")
+	}
+	if !dev.closed {
+		t.Error("device not closed after a failure")
+	}
+	fail := func(int) (io.WriteCloser, error) {
+		return nil, errors.New("no device")
+	}
+	if _, err = newPlayer(fail, 8000, 16000, 2, I16, MediumQ); err == nil {
+		t.Error("device error not returned")
+	}
+}
@@ -0,0 +1,169 @@
+//go:build portaudio
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// paFrames is the number of frames written to PortAudio at a time.
+const paFrames = 1024
+
+// NewPortAudioPlayer returns a Player playing the resampled input on the
+// default output device of PortAudio, at the default sample rate of the
+// device, returned by the Player's Rate. The output must not have more
+// channels than the device.
+func NewPortAudioPlayer(inputRate float64, channels, inFormat, quality int, opts ...Option) (*Player, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+	dev, err := portaudio.DefaultOutputDevice()
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	opened := false // the device terminates PortAudio on Close
+	open := func(channels int) (io.WriteCloser, error) {
+		if channels > dev.MaxOutputChannels {
+			return nil, errors.New("the output has more channels than " + dev.Name)
+		}
+		params := portaudio.HighLatencyParameters(nil, dev)
+		params.Output.Channels = channels
+		d := &paDevice{buf: make([]float32, paFrames*channels)}
+		stream, err := portaudio.OpenStream(params, &d.buf)
+		if err != nil {
+			return nil, err
+		}
+		if err = stream.Start(); err != nil {
+			stream.Close()
+			return nil, err
+		}
+		d.stream, opened = stream, true
+		return d, nil
+	}
+	p, err := newPlayer(open, inputRate, dev.DefaultSampleRate, channels, inFormat, quality, opts...)
+	if err != nil && !opened {
+		portaudio.Terminate()
+	}
+	return p, err
+}
+
+// paDevice writes F32 samples to a blocking PortAudio stream, a buffer
+// at a time.
+type paDevice struct {
+	stream *portaudio.Stream
+	buf    []float32 // samples of the stream buffer
+	n      int       // samples of buf filled
+}
+
+func (d *paDevice) Write(p []byte) (int, error) {
+	for i := 0; i+4 <= len(p); i += 4 {
+		d.buf[d.n] = math.Float32frombits(binary.LittleEndian.Uint32(p[i:]))
+		if d.n++; d.n == len(d.buf) {
+			if err := d.stream.Write(); err != nil {
+				return i, err
+			}
+			d.n = 0
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the partial buffer, waits for the stream to play, then
+// releases it and PortAudio.
+func (d *paDevice) Close() error {
+	var err error
+	if d.n > 0 {
+		d.buf = d.buf[:d.n]
+		err = d.stream.Write()
+	}
+	if e := d.stream.Stop(); err == nil {
+		err = e
+	}
+	if e := d.stream.Close(); err == nil {
+		err = e
+	}
+	if e := portaudio.Terminate(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// NewPortAudioRecorder returns a Recorder capturing channels from the
+// default input device of PortAudio, at the default sample rate of the
+// device, and resampling them to outputRate in outFormat.
+func NewPortAudioRecorder(outputRate float64, channels, outFormat, quality int, opts ...Option) (*Recorder, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+	dev, err := portaudio.DefaultInputDevice()
+	if err == nil && channels > dev.MaxInputChannels {
+		err = errors.New(dev.Name + " has fewer channels than requested")
+	}
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	params := portaudio.HighLatencyParameters(dev, nil)
+	params.Input.Channels = channels
+	d := &paCapture{buf: make([]float32, paFrames*channels)}
+	if d.stream, err = portaudio.OpenStream(params, &d.buf); err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	if err = d.stream.Start(); err != nil {
+		d.stream.Close()
+		portaudio.Terminate()
+		return nil, err
+	}
+	return newRecorder(d, dev.DefaultSampleRate, outputRate, channels, outFormat, quality, opts...)
+}
+
+// paCapture reads F32 samples from a blocking PortAudio stream, a buffer
+// at a time.
+type paCapture struct {
+	stream  *portaudio.Stream
+	buf     []float32 // samples of the stream buffer
+	pending []byte    // captured data not read yet
+}
+
+func (d *paCapture) Read(p []byte) (int, error) {
+	if len(d.pending) == 0 {
+		// Overflows lose samples but don't stop the capture.
+		if err := d.stream.Read(); err != nil && err != portaudio.InputOverflowed {
+			return 0, err
+		}
+		d.pending = d.pending[:0]
+		for _, v := range d.buf {
+			d.pending = binary.LittleEndian.AppendUint32(d.pending, math.Float32bits(v))
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// Close stops the stream and releases it and PortAudio.
+func (d *paCapture) Close() error {
+	err := d.stream.Stop()
+	if e := d.stream.Close(); err == nil {
+		err = e
+	}
+	if e := portaudio.Terminate(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "time"
+
+// InputPosition returns the number of input frames written to the
+// Resampler since it was created or last Reset, and their duration.
+func (r *Resampler) InputPosition() (int64, time.Duration) {
+	return r.inFrames, frameTime(r.inFrames, r.inRate)
+}
+
+// OutputPosition returns the number of frames written to the destination
+// since the Resampler was created or last Reset, and their duration.
+// The output is aligned with the input, frame n of the output being at
+// the time n/outRate of the input timeline, so the duration maps output
+// frames back to input ones. Until Close, OutputPosition lags behind
+// InputPosition by the data held in the resampler's filters.
+func (r *Resampler) OutputPosition() (int64, time.Duration) {
+	return r.outFrames, frameTime(r.outFrames, r.outRate)
+}
+
+// frameTime returns the duration of n frames at rate.
+func frameTime(n int64, rate float64) time.Duration {
+	return time.Duration(float64(n) / rate * float64(time.Second))
+}
+
+// WriteWithTime writes p as Write does, its first frame having the
+// timestamp pts. The output frames take timestamps on the same timeline,
+// returned by OutputTime. Without WriteWithTime the timeline starts at 0.
+func (r *Resampler) WriteWithTime(p []byte, pts time.Duration) (int, error) {
+	r.pts, r.ptsFrame = pts, r.inFrames
+	return r.Write(p)
+}
+
+// OutputTime returns the timestamp of the next frame written to the
+// destination, anchored to the timestamp of the last WriteWithTime. While
+// the destination's Write runs, it is the timestamp of the data written.
+func (r *Resampler) OutputTime() time.Duration {
+	return r.pts + frameTime(r.outFrames, r.outRate) - frameTime(r.ptsFrame, r.inRate)
+}
@@ -0,0 +1,59 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+// Process resamples input data from src into dst instead of writing it to
+// the destination, for use inside DSP graphs that own their buffers. It
+// consumes the whole frames of src whose output fits in dst and returns
+// the number of bytes consumed and produced. Output held back by the
+// resampling library is returned by later calls, so produced may be less
+// than the space of dst. An empty src flushes the resampler as Close does:
+// call Process with an empty src until it produces nothing to get all the
+// output. Output of a flush that doesn't fit in dst is kept for the next
+// call, or written to the destination by Close.
+func (r *Resampler) Process(dst, src []byte) (consumed, produced int, err error) {
+	if err := r.check(); err != nil {
+		return 0, 0, err
+	}
+	outFrame := r.outFrameSize * r.outChannels
+	defer func() {
+		r.outFrames += int64(produced / outFrame)
+	}()
+	produced = copy(dst, r.pending)
+	r.pending = r.pending[produced:]
+	if len(r.pending) > 0 {
+		return 0, produced, nil
+	}
+	var out []byte
+	if len(src) == 0 {
+		out, err = r.backend.flush()
+	} else {
+		inFrame := r.inFrameSize * r.channels
+		frames := min(len(src)/inFrame, int(float64((len(dst)-produced)/outFrame)*r.inRate/r.outRate))
+		if r.maxFrames > 0 {
+			frames = min(frames, r.maxFrames)
+		}
+		if frames == 0 {
+			return 0, produced, nil
+		}
+		if out, err = r.resample(src[:frames*inFrame], frames); err == nil {
+			consumed = frames * inFrame
+		}
+	}
+	if err == nil {
+		out, err = r.convert(out)
+	}
+	if err != nil {
+		return consumed, produced, err
+	}
+	n := copy(dst[produced:], out)
+	r.pending = append(r.pending[:0], out[n:]...)
+	produced += n
+	return consumed, produced, nil
+}
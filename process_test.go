@@ -0,0 +1,61 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestProcess(t *testing.T) {
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 8000, 8000), 1, testsignal.F32)
+	var want bytes.Buffer
+	res, err := New(&want, 8000.0, 11025.0, 1, F32, F32, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(in); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	res.Close()
+
+	res, err = New(io.Discard, 8000.0, 11025.0, 1, F32, F32, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	var got bytes.Buffer
+	dst := make([]byte, 4*100)
+	for src := in; ; {
+		consumed, produced, err := res.Process(dst, src)
+		if err != nil {
+			t.Fatal("Process failed:", err)
+		}
+		got.Write(dst[:produced])
+		if len(src) == 0 && produced == 0 {
+			break
+		}
+		if consumed == 0 && produced == 0 {
+			t.Fatal("Process made no progress")
+		}
+		src = src[consumed:]
+	}
+	if n, _ := res.OutputPosition(); n != int64(got.Len()/4) {
+		t.Errorf("Output position %d, expecting %d", n, got.Len()/4)
+	}
+	res.Close()
+	if want.Len() == 0 || !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("Process output of %d bytes, Write output of %d", got.Len(), want.Len())
+	}
+	if _, _, err = res.Process(dst, in); err == nil {
+		t.Error("Process after Close didn't return an error")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "errors"
+
+// Processor is a custom processing stage, such as an equalizer or an
+// automatic gain control, inserted in the write path of a Resampler.
+// Process reads the interleaved samples of in, full scale being 1, and
+// writes the result to out, which has room for len(in) samples, returning
+// the number of samples written. It may return fewer, as long as they are
+// whole frames. An error fails the Write. A Processor with a Reset method
+// has it called by the Reset of the Resampler.
+type Processor interface {
+	Process(out, in []float64) (int, error)
+}
+
+// WithPreProcessor runs processors, in order, on the input before it is
+// resampled, after any mixing, gain and filters. They see the channels of
+// the output.
+func WithPreProcessor(processors ...Processor) Option {
+	return func(o *options) {
+		o.preProcs = processors
+	}
+}
+
+// WithPostProcessor runs processors, in order, on the resampled output,
+// after any post filters and before the limiter and the conversion to the
+// output format.
+func WithPostProcessor(processors ...Processor) Option {
+	return func(o *options) {
+		o.postProcs = processors
+	}
+}
+
+// processorStages returns the stages running processors on samples of
+// frames of channels, and the resets of the processors that have one. The
+// first error of the processors is stored in failed.
+func processorStages(processors []Processor, channels int, failed *error) ([]stage, []func()) {
+	var stages []stage
+	var resets []func()
+	for _, p := range processors {
+		stages = append(stages, processorStage(p, channels, failed))
+		if r, ok := p.(interface{ Reset() }); ok {
+			resets = append(resets, r.Reset)
+		}
+	}
+	return stages, resets
+}
+
+// processorStage returns a stage running p, with an output buffer reused
+// by the calls.
+func processorStage(p Processor, channels int, failed *error) stage {
+	var out []float64
+	return func(s []float64) []float64 {
+		if *failed != nil {
+			return s
+		}
+		if cap(out) < len(s) {
+			out = make([]float64, len(s))
+		}
+		out = out[:len(s)]
+		n, err := p.Process(out, s)
+		switch {
+		case err != nil:
+			*failed = err
+			return s
+		case n < 0 || n > len(s):
+			*failed = errors.New("processor returned an invalid number of samples")
+			return s
+		case n%channels != 0:
+			*failed = errors.New("processor returned a partial frame")
+			return s
+		}
+		return out[:n]
+	}
+}
+
+// processorError returns and clears the error of the processors stored in
+// failed.
+func processorError(failed *error) error {
+	if failed == nil {
+		return nil
+	}
+	err := *failed
+	*failed = nil
+	return err
+}
@@ -0,0 +1,111 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// scaler is a Processor scaling samples, counting its resets.
+type scaler struct {
+	gain   float64
+	resets int
+}
+
+func (p *scaler) Process(out, in []float64) (int, error) {
+	for i, v := range in {
+		out[i] = v * p.gain
+	}
+	return len(in), nil
+}
+
+func (p *scaler) Reset() {
+	p.resets++
+}
+
+// processorFunc is a Processor calling a function.
+type processorFunc func(out, in []float64) (int, error)
+
+func (f processorFunc) Process(out, in []float64) (int, error) {
+	return f(out, in)
+}
+
+func TestProcessor(t *testing.T) {
+	in := testsignal.Sine(440, 0.5, 8000, 8000)
+	resample := func(opts ...Option) []float64 {
+		var out bytes.Buffer
+		res, err := New(&out, 8000.0, 16000.0, 1, F64, F64, MediumQ, opts...)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if _, err = res.Write(float64Bytes(in)); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		return toFloat64(out.Bytes(), F64)
+	}
+	want := resample(WithGain(20 * math.Log10(0.25)))
+	for _, opts := range [][]Option{
+		{WithPreProcessor(&scaler{gain: 0.5}, &scaler{gain: 0.5})},
+		{WithPreProcessor(&scaler{gain: 0.5}), WithPostProcessor(&scaler{gain: 0.5})},
+	} {
+		got := resample(opts...)
+		if len(got) != len(want) {
+			t.Fatalf("Output %d samples, expected %d", len(got), len(want))
+		}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 1e-9 {
+				t.Fatalf("Sample %d is %f, expected %f", i, got[i], want[i])
+			}
+		}
+	}
+
+	p := &scaler{gain: 1}
+	res, err := New(io.Discard, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithPostProcessor(p))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if err = res.Reset(io.Discard); err != nil || p.resets != 1 {
+		t.Errorf("Reset returned %v and reset the processor %d times", err, p.resets)
+	}
+	res.Close()
+
+	errFailed := errors.New("processor failed")
+	for _, opt := range []Option{
+		WithPreProcessor(processorFunc(func(out, in []float64) (int, error) { return 0, errFailed })),
+		WithPostProcessor(processorFunc(func(out, in []float64) (int, error) { return 0, errFailed })),
+	} {
+		res, err = New(io.Discard, 8000.0, 16000.0, 1, I16, I16, MediumQ, opt)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if _, err = res.Write(make([]byte, 1600)); err != errFailed {
+			t.Errorf("Write returned %v, expected the error of the processor", err)
+		}
+		res.Close()
+	}
+	res, err = New(io.Discard, 8000.0, 16000.0, 2, I16, I16, MediumQ, WithPreProcessor(processorFunc(func(out, in []float64) (int, error) {
+		return 1, nil
+	})))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(make([]byte, 1600)); err == nil {
+		t.Error("Partial frame of a processor didn't fail the Write")
+	}
+	res.Close()
+}
@@ -0,0 +1,118 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package quality measures the fidelity of resampled audio, to write
+regression tests of the resampling quality settings and backends.
+
+Signals are float64 samples of a single channel in the [-1, 1) range,
+such as the F64 output of a Resampler. Levels are returned in dB, higher
+being better for the SNR and the alias rejection and lower for THD+N.
+The resampler output is aligned with its input, the filter delay is
+compensated, so signals resampled back to their rate can be compared
+sample by sample. Leave out the start and end of the signals, where the
+filters settle, from the measurements.
+*/
+package quality
+
+import "math"
+
+// SNR returns the signal to noise ratio of test against the reference ref,
+// over the length of the shorter one: the level of ref over the level of
+// their difference. Identical signals give +Inf.
+func SNR(ref, test []float64) float64 {
+	n := len(ref)
+	if len(test) < n {
+		n = len(test)
+	}
+	var signal, noise float64
+	for i, v := range ref[:n] {
+		d := test[i] - v
+		signal += v * v
+		noise += d * d
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+// THDN returns the total harmonic distortion plus noise of x, a sine of
+// freq Hz sampled at rate: the level of what remains once the sine, and
+// any DC offset, fitted by least squares are removed, relative to the
+// level of x.
+func THDN(x []float64, freq, rate float64) float64 {
+	// Normal equations of the fit of a*sin + b*cos + c.
+	var m [3][4]float64
+	basis := func(i int) [3]float64 {
+		s, c := math.Sincos(2 * math.Pi * freq * float64(i) / rate)
+		return [3]float64{s, c, 1}
+	}
+	var total float64
+	for i, v := range x {
+		f := basis(i)
+		for j := range f {
+			for k := range f {
+				m[j][k] += f[j] * f[k]
+			}
+			m[j][3] += f[j] * v
+		}
+		total += v * v
+	}
+	coefs := solve(m)
+	var residual float64
+	for i, v := range x {
+		f := basis(i)
+		d := v - coefs[0]*f[0] - coefs[1]*f[1] - coefs[2]*f[2]
+		residual += d * d
+	}
+	return 10 * math.Log10(residual/total)
+}
+
+// solve solves the system of three linear equations of the augmented
+// matrix m by Gaussian elimination with partial pivoting.
+func solve(m [3][4]float64) [3]float64 {
+	for c := 0; c < 3; c++ {
+		p := c
+		for r := c + 1; r < 3; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[p][c]) {
+				p = r
+			}
+		}
+		m[c], m[p] = m[p], m[c]
+		for r := c + 1; r < 3; r++ {
+			f := m[r][c] / m[c][c]
+			for k := c; k < 4; k++ {
+				m[r][k] -= f * m[c][k]
+			}
+		}
+	}
+	var x [3]float64
+	for r := 2; r >= 0; r-- {
+		x[r] = m[r][3]
+		for k := r + 1; k < 3; k++ {
+			x[r] -= m[r][k] * x[k]
+		}
+		x[r] /= m[r][r]
+	}
+	return x
+}
+
+// AliasRejection returns how much a tone above the Nyquist frequency of
+// the output rate is attenuated by resampling, input holding the tone and
+// output the result of resampling it: the RMS level of input over that of
+// output. Anything left in the output is aliasing or noise.
+func AliasRejection(input, output []float64) float64 {
+	return rms(input) - rms(output)
+}
+
+// rms returns the RMS level of x in dB.
+func rms(x []float64) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += v * v
+	}
+	return 10 * math.Log10(sum/float64(len(x)))
+}
@@ -0,0 +1,51 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package quality
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func offset(x []float64, dc float64) []float64 {
+	for i := range x {
+		x[i] += dc
+	}
+	return x
+}
+
+func TestQuality(t *testing.T) {
+	ref := testsignal.Sine(997, 0.5, 48000, 48000)
+	// A third harmonic 40 dB below the sine and noise 71 dB below.
+	harmonic := testsignal.Sine(3*997, 0.005, 48000, len(ref))
+	rng := rand.New(rand.NewSource(1))
+	test := make([]float64, len(ref))
+	for i, v := range ref {
+		test[i] = v + harmonic[i] + 1e-4*rng.NormFloat64()
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"SNR", SNR(ref, test), 40},
+		{"THD+N", THDN(test, 997, 48000), -40},
+		{"THD+N of a pure sine with DC", THDN(offset(testsignal.Sine(1000, 0.9, 8000, 8000), 0.05), 1000, 8000), math.Inf(-1)},
+		{"Alias rejection", AliasRejection(ref, testsignal.Sine(100, 0.5e-4, 8000, 8000)), 80},
+	} {
+		if math.IsInf(tc.want, -1) && tc.got > -200 || !math.IsInf(tc.want, -1) && math.Abs(tc.got-tc.want) > 0.5 {
+			t.Errorf("%s: %.2f dB, expected %.2f dB", tc.name, tc.got, tc.want)
+		}
+	}
+	if snr := SNR(ref, ref[:100]); !math.IsInf(snr, 1) {
+		t.Errorf("SNR of identical signals: %f", snr)
+	}
+}
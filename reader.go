@@ -0,0 +1,85 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "io"
+
+// chunkFrames is the number of frames passed to the resampler per Write
+// when streaming from a Reader.
+const chunkFrames = 4096
+
+// newFromReader creates a Resampler for the given input parameters, applying
+// opts, and copies the frames read from data through it. It is the common
+// part of the container specific constructors.
+func newFromReader(dst io.Writer, data io.Reader, inRate, outRate float64, channels, inFormat, frameSize int, opts []Option) (*Resampler, error) {
+	o := applyOptions(opts, inFormat)
+	r, err := New(dst, inRate, outRate, channels, inFormat, o.outFormat, o.quality, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = CopyFrames(r, data, frameSize); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// CopyFrames copies whole frames of frameSize bytes from src to dst, such as
+// a Resampler, until src ends. Data is buffered so that every Write, including
+// the last one, carries at least 4096 frames when the input is long enough.
+// Trailing bytes of an incomplete frame are dropped.
+func CopyFrames(dst io.Writer, src io.Reader, frameSize int) error {
+	buf := make([]byte, 2*chunkFrames*frameSize)
+	half := len(buf) / 2
+	n := 0
+	for {
+		m, err := io.ReadFull(src, buf[n:])
+		n += m
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			n -= n % frameSize
+			if n == 0 {
+				return nil
+			}
+			_, err = dst.Write(buf[:n])
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = dst.Write(buf[:half]); err != nil {
+			return err
+		}
+		n = copy(buf, buf[half:n])
+	}
+}
+
+// byteSwapReader reverses the byte order of every sample read from r.
+type byteSwapReader struct {
+	r    io.Reader
+	size int // sample size in bytes
+}
+
+func (s *byteSwapReader) Read(p []byte) (int, error) {
+	if len(p) < s.size {
+		return 0, io.ErrShortBuffer
+	}
+	p = p[:len(p)-len(p)%s.size]
+	n, err := s.r.Read(p)
+	if rem := n % s.size; rem != 0 {
+		// Complete the last sample.
+		m, e := io.ReadFull(s.r, p[n:n+s.size-rem])
+		n += m
+		if e != nil {
+			n -= n % s.size
+			err = e
+		}
+	}
+	swapBytes(p[:n], s.size)
+	return n, err
+}
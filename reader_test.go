@@ -0,0 +1,68 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// writeSizes records the size of every Write.
+type writeSizes struct {
+	bytes.Buffer
+	sizes []int
+}
+
+func (w *writeSizes) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestCopyFrames(t *testing.T) {
+	const frameSize = 4
+	for _, frames := range []int{0, 10, chunkFrames, 5*chunkFrames + 100} {
+		in := make([]byte, frames*frameSize+3)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		var out writeSizes
+		// One byte per Read.
+		if err := CopyFrames(&out, iotest.OneByteReader(bytes.NewReader(in)), frameSize); err != nil {
+			t.Fatalf("%d frames: %v", frames, err)
+		}
+		if !bytes.Equal(out.Bytes(), in[:frames*frameSize]) {
+			t.Errorf("%d frames: %d bytes copied", frames, out.Len())
+		}
+		for _, n := range out.sizes {
+			if n%frameSize != 0 || frames >= chunkFrames && n < chunkFrames*frameSize {
+				t.Errorf("%d frames: Write of %d bytes", frames, n)
+			}
+		}
+	}
+
+	fail := errors.New("failure")
+	if err := CopyFrames(io.Discard, iotest.ErrReader(fail), frameSize); err != fail {
+		t.Errorf("Read error returned %v", err)
+	}
+	if err := CopyFrames(&errWriter{fail}, bytes.NewReader(make([]byte, 40)), frameSize); err != fail {
+		t.Errorf("Write error returned %v", err)
+	}
+}
+
+// errWriter fails every Write with err.
+type errWriter struct {
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
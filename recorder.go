@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+)
+
+// Recorder is an io.ReadCloser of the audio captured from a device,
+// resampled from the rate of the device to a fixed one. Recorders are
+// created by NewPortAudioRecorder when building with the `portaudio' tag.
+type Recorder struct {
+	device     io.ReadCloser // captured F32 samples at the device rate
+	deviceRate float64
+	pr         *io.PipeReader
+	stop       chan struct{} // closed by Close to end the capture
+	done       chan error    // error of the capture
+}
+
+// newRecorder starts capturing device, which delivers F32 samples at
+// deviceRate, and returns a Recorder reading them resampled to outputRate
+// in outFormat.
+func newRecorder(device io.ReadCloser, deviceRate, outputRate float64, channels, outFormat, quality int, opts ...Option) (*Recorder, error) {
+	pr, pw := io.Pipe()
+	r, err := New(pw, deviceRate, outputRate, channels, F32, outFormat, quality, opts...)
+	if err != nil {
+		device.Close()
+		return nil, err
+	}
+	rec := &Recorder{device: device, deviceRate: deviceRate, pr: pr, stop: make(chan struct{}), done: make(chan error, 1)}
+	go rec.capture(r, pw, channels*4)
+	return rec, nil
+}
+
+// capture resamples the device data until Close or a device error.
+func (rec *Recorder) capture(r *Resampler, pw *io.PipeWriter, frame int) {
+	buf := make([]byte, 32*1024/frame*frame)
+	var n int
+	var err error
+	for err == nil {
+		select {
+		case <-rec.stop:
+			err = io.EOF
+			continue
+		default:
+		}
+		var m int
+		m, err = rec.device.Read(buf[n:])
+		n += m
+		if whole := n - n%frame; whole > 0 {
+			if _, e := r.Write(buf[:whole]); e != nil && err == nil {
+				err = e
+			}
+			n = copy(buf, buf[whole:n])
+		}
+	}
+	if err == io.EOF || errors.Is(err, io.ErrClosedPipe) {
+		err = nil // stopped by Close
+	}
+	if e := rec.device.Close(); err == nil {
+		err = e
+	}
+	if e := r.Close(); err == nil && !errors.Is(e, io.ErrClosedPipe) {
+		err = e
+	}
+	pw.CloseWithError(err)
+	rec.done <- err
+}
+
+// Read reads resampled audio, blocking until the device captured it.
+// Once the capture stopped on an error it is returned after the data
+// resampled before it.
+func (rec *Recorder) Read(p []byte) (int, error) {
+	return rec.pr.Read(p)
+}
+
+// DeviceRate returns the sampling rate the device captures at.
+func (rec *Recorder) DeviceRate() float64 {
+	return rec.deviceRate
+}
+
+// Close stops the capture and releases the device. Data not read yet is
+// discarded.
+func (rec *Recorder) Close() error {
+	select {
+	case <-rec.stop:
+		return errors.New("recorder already closed")
+	default:
+	}
+	close(rec.stop)
+	rec.pr.Close()
+	return <-rec.done
+}
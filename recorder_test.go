@@ -0,0 +1,100 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeCapture is a capture device delivering data in odd sized reads,
+// then err.
+type fakeCapture struct {
+	data   []byte
+	err    error
+	closed bool
+}
+
+func (d *fakeCapture) Read(p []byte) (int, error) {
+	if len(d.data) == 0 {
+		if d.err == nil {
+			return len(p), nil // endless silence
+		}
+		return 0, d.err
+	}
+	if len(p) > 1001 {
+		p = p[:1001]
+	}
+	n := copy(p, d.data)
+	d.data = d.data[n:]
+	return n, nil
+}
+
+func (d *fakeCapture) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	dev := &fakeCapture{data: make([]byte, 48000*2*4), err: io.EOF}
+	rec, err := newRecorder(dev, 48000, 16000, 2, I16, MediumQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.DeviceRate() != 48000 {
+		t.Errorf("device rate is %g, expected 48000", rec.DeviceRate())
+	}
+	var out bytes.Buffer
+	if _, err = io.Copy(&out, rec); err != nil {
+		t.Fatal(err)
+	}
+	if n := out.Len() / 4; n < 15900 || n > 16100 {
+		t.Errorf("recorded %d frames, expected about 16000", n)
+	}
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !dev.closed {
+		t.Error("device not closed")
+	}
+	if rec.Close() == nil {
+		t.Error("second Close succeeded")
+	}
+
+	dev = &fakeCapture{}
+	if rec, err = newRecorder(dev, 48000, 16000, 2, I16, MediumQ); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadFull(rec, make([]byte, 4000)); err != nil {
+		t.Fatal(err)
+	}
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !dev.closed {
+		t.Error("device not closed when stopped")
+	}
+
+	dev = &fakeCapture{data: make([]byte, 8000), err: errors.New("unplugged")}
+	if rec, err = newRecorder(dev, 48000, 16000, 2, I16, MediumQ); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(rec); err == nil || err.Error() != "unplugged" {
+		t.Errorf("read error %v, expected the device error", err)
+	}
+	if err = rec.Close(); err == nil {
+		t.Error("Close didn't return the device error")
+	}
+
+	if _, err = newRecorder(&fakeCapture{}, 48000, 16000, 2, 99, MediumQ); err == nil {
+		t.Error("invalid format accepted")
+	}
+}
@@ -14,25 +14,80 @@ To install make sure you have libsoxr and pkg-config installed, then run:
 
 go install github.com/zaf/resample@latest
 
+When built with CGO_ENABLED=0, on 64-bit Linux, macOS and FreeBSD,
+libsoxr is instead loaded at run time, so cross-compiled programs use it
+when the host has it, and otherwise resample with the FIR backend. Set
+RESAMPLE_SOXR_LIB to its path if it isn't found by its usual names. Build
+with the `loadsoxr' tag to load it that way with cgo too, on machines
+without the libsoxr headers or pkg-config:
+
+	go install -tags loadsoxr github.com/zaf/resample@latest
+
+The libsoxr library is still needed at run time to resample with it. The
+module doesn't vendor the libsoxr sources to compile them in, the request
+for such a build option (an `embedsoxr' tag) is declined.
+
+Other builds without cgo, for WebAssembly with GOOS=js or GOOS=wasip1,
+or for Windows, have no libsoxr and resample with the FIR backend in Go,
+so that browser and WASI programs can convert microphone captures to
+16 kHz for speech recognition:
+
+	GOOS=js GOARCH=wasm go build
+
+The filter of the FIR backend and the conversions of I16 and F32 samples
+run in AVX2 and FMA assembly on amd64 processors that have them and in
+NEON assembly on arm64. Build with the `noasm' tag to use the Go loops.
+
+On Windows pkg-config isn't needed: point cgo at libsoxr with environment
+variables, the headers with CGO_CFLAGS and the library with CGO_LDFLAGS.
+Both MinGW builds (libsoxr.dll.a) and MSVC builds (soxr.lib or soxr.dll)
+are found, with soxr.dll next to the program or in the PATH at run time:
+
+	set CGO_CFLAGS=-IC:\soxr\include
+	set CGO_LDFLAGS=-LC:\soxr\lib
+	go install github.com/zaf/resample@latest
+
+Build with the `pkgconfig' tag to use pkg-config on Windows too, as in MSYS2.
+
 The package warps an io.Reader in a Resampler that resamples and
 writes all input data. Input should be RAW PCM encoded audio samples.
+A Stretcher, written to the same way, changes the tempo of the audio
+without changing its pitch, and a PitchShifter its pitch without changing
+its tempo.
+
+FLAC input can be decoded with NewFromFLAC when building with the `flac'
+build tag, and MP3 input with NewFromMP3 when building with the `mp3' tag.
+Building with the `opus' tag and cgo adds OpusWriter, which encodes 48 kHz
+output to Ogg/Opus using libopus. The `samplerate' tag, with cgo too, adds
+libsamplerate as an alternative resampling library, selected with
+WithBackend, as is the FIR backend, which resamples in Go with a filter of
+its own or of WithFIR.
+The `oto' and `portaudio' tags add NewOtoPlayer and NewPortAudioPlayer,
+which play the output on an audio device through oto or PortAudio, and
+the `portaudio' tag NewPortAudioRecorder, which reads audio captured from
+a device resampled to a fixed rate.
+
+The analysis subpackage measures the peak, RMS level and BS.1770 loudness
+of a stream, such as the output of a Resampler. The quality subpackage
+computes the SNR, THD+N and alias rejection of resampled signals, for
+regression tests of the quality settings and backends, and the testsignal
+subpackage generates deterministic sines, swept sines, noise and impulse
+trains to feed them.
+
+The beep subpackage wraps the Streamers of github.com/gopxl/beep, so beep
+applications can swap beep.Resample for libsoxr resampling.
 
 For usage details please see the code snippet in the cmd folder.
 */
 package resample
 
-/*
-// Link soxr using pkg-config.
-#cgo pkg-config: soxr
-#include <stdlib.h>
-#include <soxr.h>
-*/
-import "C"
 import (
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"runtime"
-	"unsafe"
+	"time"
 )
 
 const (
@@ -43,24 +98,70 @@ const (
 	HighQ     = 4 // High quality
 	VeryHighQ = 6 // Very high quality
 
+	// libsamplerate emulation, soxr's SOXR_LSR0Q to SOXR_LSR2Q recipes,
+	// to compare with the output of libsamplerate's sinc converters.
+	SincBestQ    = 8  // As SRC_SINC_BEST_QUALITY
+	SincMediumQ  = 9  // As SRC_SINC_MEDIUM_QUALITY
+	SincFastestQ = 10 // As SRC_SINC_FASTEST
+
 	// Input formats
 	F32 = 0 // 32-bit floating point PCM
 	F64 = 1 // 64-bit floating point PCM
 	I32 = 2 // 32-bit signed linear PCM
 	I16 = 3 // 16-bit signed linear PCM
 
+	// Companded formats, converted to and from I16 around soxr.
+	// Values 4 to 7 are taken by the soxr planar datatypes.
+	MuLaw = 8 // 8-bit G.711 µ-law
+	ALaw  = 9 // 8-bit G.711 A-law
+
+	// 24-bit samples in the upper bytes of 32-bit words, as many audio
+	// interfaces use. Input is resampled as I32, output rounded to 24 bits.
+	I24In32 = 10 // 24-bit signed linear PCM left-justified in 32 bits
+
 	byteLen = 8
+
+	// maxChannels is the largest number of channels of a Resampler.
+	maxChannels = 1024
 )
 
+// ErrClosed is returned by the methods of a Resampler called after Close.
+var ErrClosed = errors.New("resampler is closed")
+
 // Resampler resamples PCM sound data.
 type Resampler struct {
-	resampler    C.soxr_t
-	inRate       float64   // input sample rate
-	outRate      float64   // output sample rate
-	channels     int       // number of input channels
-	inFrameSize  int       // input frame size in bytes
-	outFrameSize int       // output frame size in bytes
-	destination  io.Writer // output data
+	backend      backend       // resampling library
+	inRate       float64       // input sample rate
+	outRate      float64       // output sample rate
+	channels     int           // number of input channels
+	outChannels  int           // number of output channels
+	stages       []stage       // processing done on float64 input
+	post         []stage       // processing done on float64 output
+	resets       []func()      // clear the state of the stages
+	swapIn       bool          // input samples are big-endian
+	swapOut      bool          // output samples are big-endian
+	variable     bool          // the output rate may change
+	closed       bool          // Close was called
+	overflowErr  bool          // output beyond full scale fails the Write
+	maxFrames    int           // input frames passed to the backend at a time, 0 for all
+	pending      []byte        // flushed output that didn't fit the dst of Process
+	gather       []byte        // input of WriteV
+	async        *async        // background Writes, nil for synchronous ones
+	shaper       *shaper       // dithered quantization of F64 backend output
+	inFormat     int           // input format
+	outFormat    int           // output format
+	quality      int           // quality setting
+	inFrameSize  int           // input frame size in bytes
+	outFrameSize int           // output frame size in bytes
+	backendSize  int           // backend output sample size in bytes
+	inFrames     int64         // input frames written
+	outFrames    int64         // output frames written
+	clips        int64         // output samples clipped in Go or by a closed backend
+	sanitized    *int64        // non-finite input samples replaced, nil for none
+	procErr      *error        // error of the Processor stages, nil for none
+	pts          time.Duration // timestamp of the input frame ptsFrame
+	ptsFrame     int64         // input frame of the last WriteWithTime
+	destination  io.Writer     // output data
 }
 
 var threads int
@@ -72,159 +173,595 @@ func init() {
 // New returns a pointer to a Resampler that implements an io.WriteCloser.
 // It takes as parameters the destination data Writer, the input and output
 // sampling rates, the number of channels of the input data, the input format
-// and the quality setting.
-func New(writer io.Writer, inputRate, outputRate float64, channels, inFormat, outFormat, quality int) (*Resampler, error) {
+// and the quality setting. Options other than WithOutFormat and WithQuality,
+// which the arguments already cover, may follow. Rates must be positive
+// and finite, and up to 1024 channels are supported.
+func New(writer io.Writer, inputRate, outputRate float64, channels, inFormat, outFormat, quality int, opts ...Option) (*Resampler, error) {
 	var err error
 	if writer == nil {
 		return nil, errors.New("io.Writer is nil")
 	}
-	if inputRate <= 0 || outputRate <= 0 {
+	// NaN rates fail the comparisons.
+	if !(inputRate > 0 && outputRate > 0) || math.IsInf(inputRate, 0) || math.IsInf(outputRate, 0) {
 		return nil, errors.New("invalid input or output sampling rates")
 	}
-	if channels == 0 {
+	if channels <= 0 {
 		return nil, errors.New("invalid channels number")
 	}
-	if quality < 0 || quality > 6 {
-		return nil, errors.New("invalid quality setting")
+	if channels > maxChannels {
+		return nil, fmt.Errorf("too many channels, at most %d are supported", maxChannels)
 	}
-
-	// Determine byte sizes for each format
-	sizeOf := func(format int) (int, error) {
-		switch format {
-		case F64:
-			return 8, nil
-		case F32:
-			return 4, nil
-		case I32:
-			return 4, nil
-		case I16:
-			return 2, nil
-		}
-		return 0, errors.New("invalid format setting")
+	if (quality < 0 || quality > VeryHighQ) && (quality < SincBestQ || quality > SincFastestQ) {
+		return nil, errors.New("invalid quality setting")
 	}
-
 	inSize, err := sizeOf(inFormat)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid input format %d", inFormat)
 	}
-
 	outSize, err := sizeOf(outFormat)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid output format %d", outFormat)
+	}
+	o := applyOptions(opts, inFormat)
+	if o.threads < 0 {
+		return nil, errors.New("invalid threads number")
+	}
+	if o.memLimit < 0 {
+		return nil, errors.New("invalid memory limit")
+	}
+	if o.threads == 0 && o.memLimit > 0 {
+		o.threads = 1
+	}
+	if o.threads == 0 {
+		o.threads = threads
+	}
+	if o.bufFrames < 0 || o.maxBufFrames < 0 {
+		return nil, errors.New("invalid buffer size")
+	}
+	if o.queue < 0 {
+		return nil, errors.New("invalid queue length")
+	}
+	outChannels := channels
+	var stages []stage
+	var resets []func()
+	var sanitized *int64
+	if o.sanitize && (inFormat == F32 || inFormat == F64) {
+		sanitized = new(int64)
+		stages = append(stages, sanitizeStage(sanitized))
+	}
+	if o.dcCutoff != 0 {
+		st, reset, err := dcBlockStage(o.dcCutoff, inputRate, channels)
+		if err != nil {
+			return nil, err
+		}
+		stages, resets = append(stages, st), append(resets, reset)
+	}
+	if o.mix != nil {
+		if err = checkMix(o.mix, channels); err != nil {
+			return nil, err
+		}
+		if outChannels = len(o.mix); outChannels > maxChannels {
+			return nil, fmt.Errorf("too many channels, at most %d are supported", maxChannels)
+		}
+		stages = append(stages, mixStage(o.mix, channels))
+	}
+	if o.gain != 1 {
+		stages = append(stages, gainStage(o.gain))
+	}
+	if len(o.preFilters) > 0 {
+		st, reset, err := filterStage(o.preFilters, inputRate, outChannels)
+		if err != nil {
+			return nil, err
+		}
+		stages, resets = append(stages, st), append(resets, reset)
+	}
+	var procErr *error
+	if o.preProcs != nil || o.postProcs != nil {
+		procErr = new(error)
+	}
+	if o.preProcs != nil {
+		st, reset := processorStages(o.preProcs, outChannels, procErr)
+		stages, resets = append(stages, st...), append(resets, reset...)
+	}
+	var post []stage
+	if len(o.postFilters) > 0 {
+		st, reset, err := filterStage(o.postFilters, outputRate, outChannels)
+		if err != nil {
+			return nil, err
+		}
+		post, resets = append(post, st), append(resets, reset)
+	}
+	if o.postProcs != nil {
+		st, reset := processorStages(o.postProcs, outChannels, procErr)
+		post, resets = append(post, st...), append(resets, reset...)
+	}
+	if o.limit != 0 {
+		st, err := limiterStage(o.limit)
+		if err != nil {
+			return nil, err
+		}
+		post = append(post, st)
+	}
+	intOut := outFormat == I16 || outFormat == I32 || outFormat == I24In32 || isG711(outFormat)
+	switch o.overflow {
+	case OverflowClip, OverflowError:
+	case OverflowSaturate:
+		if intOut {
+			post = append(post, saturateStage(backendFormat(outFormat)))
+		}
+	default:
+		return nil, errors.New("invalid overflow setting")
+	}
+	overflowErr := o.overflow == OverflowError && intOut
+	// Formats of the data passed to and returned by the backend.
+	backendIn, backendOut := backendFormat(inFormat), backendFormat(outFormat)
+	if backendIn == I24In32 {
+		backendIn = I32
+	}
+	if backendOut == I24In32 {
+		// Rounded to 24 bits in Go.
+		backendOut = F64
+	}
+	if stages != nil {
+		backendIn = F64
+	}
+	if post != nil || overflowErr {
+		backendOut = F64
+	}
+	switch o.dither {
+	case DitherTPDF:
+		if (o.seed != nil || o.backend != BackendSoxr) && intOut {
+			backendOut = F64
+		}
+	case DitherNone:
+	case DitherShaped, DitherShapedLight, DitherShapedF:
+		if outFormat != I16 {
+			return nil, errors.New("shaped dither needs I16 output")
+		}
+		backendOut = F64
+	default:
+		return nil, errors.New("invalid dither setting")
 	}
 
-	var soxr C.soxr_t
-	var soxErr C.soxr_error_t
-	// Setup soxr and create a stream resampler
-	ioSpec := C.soxr_io_spec(C.soxr_datatype_t(inFormat), C.soxr_datatype_t(outFormat))
-	qSpec := C.soxr_quality_spec(C.ulong(quality), 0)
-	runtimeSpec := C.soxr_runtime_spec(C.uint(threads))
-
-	soxr = C.soxr_create(C.double(inputRate), C.double(outputRate), C.uint(channels), &soxErr, &ioSpec, &qSpec, &runtimeSpec)
-	if C.GoString(soxErr) != "" && C.GoString(soxErr) != "0" {
-		err = errors.New(C.GoString(soxErr))
-		C.free(unsafe.Pointer(soxErr))
+	if o.fir != nil && o.backend != BackendFIR {
+		return nil, errors.New("FIR filter needs BackendFIR")
+	}
+	var b backend
+	switch o.backend {
+	case BackendSoxr:
+		b, err = newSoxr(inputRate, outputRate, outChannels, backendIn, backendOut, quality, o.threads, o.dither == DitherNone, o.variable, o.soxrFlags)
+	case BackendSamplerate:
+		b, err = newSamplerate(inputRate, outputRate, outChannels, backendIn, backendOut, quality)
+	case BackendFIR:
+		b, err = newFIR(inputRate, outputRate, outChannels, backendIn, backendOut, quality, o.fir)
+	default:
+		err = errors.New("invalid backend setting")
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	r := Resampler{
-		resampler:    soxr,
+		backend:      b,
 		inRate:       inputRate,
 		outRate:      outputRate,
 		channels:     channels,
+		outChannels:  outChannels,
+		stages:       stages,
+		post:         post,
+		resets:       resets,
+		sanitized:    sanitized,
+		procErr:      procErr,
+		swapIn:       o.swapIn && inSize > 1,
+		swapOut:      o.swapOut && outSize > 1,
+		variable:     o.variable,
+		overflowErr:  overflowErr,
+		maxFrames:    o.maxBufFrames,
+		inFormat:     inFormat,
+		outFormat:    outFormat,
+		quality:      quality,
 		inFrameSize:  inSize,
 		outFrameSize: outSize,
+		backendSize:  outSize,
 		destination:  writer,
 	}
-	C.free(unsafe.Pointer(soxErr))
-	return &r, err
+	if isG711(outFormat) {
+		r.backendSize = 2
+	}
+	switch {
+	case shapeCurves[o.dither] != nil:
+		r.backendSize = 8
+		r.shaper = newShaper(outChannels, 16, shapeCurves[o.dither], o.seed)
+	case backendOut == F64 && o.dither == DitherTPDF && intOut:
+		// TPDF dither done in Go, seeded, for a backend without dither or
+		// after post filters. G.711 is compressed from I16.
+		r.backendSize = 8
+		r.shaper = newShaper(outChannels, formatBits(outFormat), nil, o.seed)
+	case backendOut == F64:
+		r.backendSize = 8
+	}
+	if o.memLimit > 0 {
+		frames := o.memLimit / r.frameBytes()
+		if frames == 0 {
+			b.close()
+			return nil, errors.New("memory limit too small for a frame")
+		}
+		if o.maxBufFrames == 0 || frames < o.maxBufFrames {
+			o.maxBufFrames = frames
+		}
+		r.maxFrames = o.maxBufFrames
+	}
+	if n := o.bufFrames; n > 0 || o.maxBufFrames > 0 {
+		// With a cap the buffer is allocated now so that flush calls,
+		// which return what fits in it, stay within the cap too.
+		if n == 0 || o.maxBufFrames > 0 && n > o.maxBufFrames {
+			n = o.maxBufFrames
+		}
+		b.reserve(n)
+	}
+	if o.queue > 0 {
+		r.async = newAsync(&r, o.queue)
+	}
+	return &r, nil
 }
 
 // Reset permits reusing a Resampler rather than allocating a new one.
+// A closed Resampler can't be reset, Reset then returns ErrClosed.
 func (r *Resampler) Reset(writer io.Writer) error {
-	var err error
-	if r.resampler == nil {
-		return errors.New("soxr resampler is nil")
+	err := r.check()
+	if err != nil {
+		return err
+	}
+	if r.async != nil {
+		err = r.async.reset()
+	}
+	if e := r.flush(); err == nil {
+		err = e
 	}
-	err = r.flush()
 	r.destination = writer
-	C.soxr_clear(r.resampler)
+	r.inFrames, r.outFrames = 0, 0
+	r.clips = 0
+	if r.sanitized != nil {
+		*r.sanitized = 0
+	}
+	r.pts, r.ptsFrame = 0, 0
+	if e := r.backend.reset(); err == nil {
+		err = e
+	}
+	for _, reset := range r.resets {
+		reset()
+	}
+	if r.shaper != nil {
+		r.shaper.reset()
+	}
 	return err
 }
 
+// SetRate changes the output sampling rate of a Resampler created with
+// WithVariableRate, from the next Write on. With soxr the rate can't drop
+// below half the one the Resampler was created with. Output durations are
+// computed at the current rate.
+func (r *Resampler) SetRate(outputRate float64) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	if !r.variable {
+		return errors.New("resampler created without WithVariableRate")
+	}
+	if !(outputRate > 0) || math.IsInf(outputRate, 1) {
+		return errors.New("invalid input or output sampling rates")
+	}
+	if err := r.backend.setRatio(outputRate / r.inRate); err != nil {
+		return err
+	}
+	r.outRate = outputRate
+	return nil
+}
+
+// Clips returns the number of output samples clipped to the range of an
+// integer output format since the Resampler was created or last Reset,
+// either by the resampling library or by the conversion to the format.
+func (r *Resampler) Clips() int64 {
+	n := r.clips
+	if r.backend != nil {
+		n += r.backend.clips()
+	}
+	if r.shaper != nil {
+		n += r.shaper.clips
+	}
+	return n
+}
+
 // Close flushes, clean-ups and frees memory. Should always be called when
 // finished using the resampler. Should always be called when finished using
-// the resampler, and before we can use its output.
+// the resampler, and before we can use its output. Closing a closed
+// Resampler does nothing, its other methods then return ErrClosed.
 func (r *Resampler) Close() error {
-	var err error
-	if r.resampler == nil {
-		return errors.New("soxr resampler is nil")
+	if r.closed {
+		return nil
+	}
+	err := r.check()
+	if err != nil {
+		return err
+	}
+	if r.async != nil {
+		err = r.async.stop()
+		r.async = nil
+	}
+	if e := r.flush(); err == nil {
+		err = e
 	}
-	err = r.flush()
-	C.soxr_delete(r.resampler)
-	r.resampler = nil
+	r.clips += r.backend.clips()
+	r.backend.close()
+	r.backend = nil
+	r.closed = true
 	return err
 }
 
+// check returns ErrClosed after Close, or an error when the Resampler has
+// no resampling library, as one not created by New.
+func (r *Resampler) check() error {
+	if r.closed {
+		return ErrClosed
+	}
+	if r.backend == nil {
+		return errors.New("soxr resampler is nil")
+	}
+	return nil
+}
+
 // Write resamples PCM sound data. Writes len(p) bytes from p to
 // the underlying data stream, returns the number of bytes written
 // from p (0 <= n <= len(p)) and any error encountered that caused
-// the write to stop early.
+// the write to stop early. With WithAsync the data is queued instead.
 func (r *Resampler) Write(p []byte) (int, error) {
-	var err error
-	var i int
-	if r.resampler == nil {
-		return i, errors.New("soxr resampler is nil")
+	if r.async != nil {
+		return r.async.write(r, p)
+	}
+	return r.writeSync(p)
+}
+
+// checkWrite returns the number of input frames of p, or the error of a
+// Write of p.
+func (r *Resampler) checkWrite(p []byte) (int, error) {
+	if err := r.check(); err != nil {
+		return 0, err
 	}
 	if len(p) == 0 {
-		return i, nil
+		return 0, nil
 	}
 	framesIn := len(p) / r.inFrameSize / r.channels
 	if framesIn == 0 {
-		return i, errors.New("incomplete input frame data")
+		return 0, errors.New("incomplete input frame data")
 	}
 	framesOut := int(float64(framesIn) * (r.outRate / r.inRate))
 	if framesOut == 0 {
-		return i, errors.New("not enough input to generate output")
-	}
-	dataIn := C.CBytes(p)
-	dataOut := C.malloc(C.size_t(framesOut * r.channels * r.outFrameSize))
-	var soxErr C.soxr_error_t
-	var read, done C.size_t = 0, 0
-	soxErr = C.soxr_process(r.resampler, C.soxr_in_t(dataIn), C.size_t(framesIn), &read, C.soxr_out_t(dataOut), C.size_t(framesOut), &done)
-	if C.GoString(soxErr) != "" && C.GoString(soxErr) != "0" {
-		err = errors.New(C.GoString(soxErr))
-		goto cleanup
-	}
-	_, err = r.destination.Write(C.GoBytes(dataOut, C.int(int(done)*r.channels*r.outFrameSize)))
+		return 0, errors.New("not enough input to generate output")
+	}
+	return framesIn, nil
+}
+
+// writeSync resamples p and writes the output to the destination.
+func (r *Resampler) writeSync(p []byte) (int, error) {
+	var i int
+	n := len(p)
+	framesIn, err := r.checkWrite(p)
+	if framesIn == 0 {
+		return i, err
+	}
+	step := framesIn
+	if r.maxFrames > 0 && step > r.maxFrames {
+		step = r.maxFrames
+	}
+	size := r.channels * r.inFrameSize
+	for done := 0; done < framesIn && err == nil; done += step {
+		frames := min(step, framesIn-done)
+		err = r.write(p[done*size:(done+frames)*size], frames)
+	}
 	// In many cases the resampler will not return the full data unless we flush it. Espasially if the input chunck is small
 	// As long as we close the resampler (Close() flushes all data) we don't need to worry about short writes, unless r.destination.Write() fails
 	if err == nil {
-		i = len(p)
+		i = n
 	}
-cleanup:
-	C.free(dataIn)
-	C.free(dataOut)
-	C.free(unsafe.Pointer(soxErr))
 	return i, err
 }
 
+// frameBytes returns an estimate of the memory of the buffers used to
+// resample an input frame, from the copies of the input to the output of
+// the backend and its conversions.
+func (r *Resampler) frameBytes() int {
+	in := r.channels * r.inFrameSize
+	if r.swapIn {
+		in *= 2
+	}
+	switch {
+	case r.stages != nil:
+		in += 8*r.channels + 16*r.outChannels
+	case isG711(r.inFormat):
+		in += 2 * r.channels
+	}
+	out := r.outChannels * (r.backendSize + r.outFrameSize)
+	if r.post != nil {
+		out += 16 * r.outChannels
+	}
+	if r.backendSize == 8 && isG711(r.outFormat) {
+		// Quantized to I16 before being compressed.
+		out += 2 * r.outChannels
+	}
+	return in + int(math.Ceil(float64(out)*r.outRate/r.inRate))
+}
+
+// write resamples frames of input data p and writes the output.
+func (r *Resampler) write(p []byte, frames int) error {
+	out, err := r.resample(p, frames)
+	if err != nil {
+		return err
+	}
+	return r.output(out)
+}
+
+// resample passes frames of input data p to the backend and returns its
+// output.
+func (r *Resampler) resample(p []byte, frames int) ([]byte, error) {
+	if r.swapIn {
+		p = append([]byte(nil), p...)
+		swapBytes(p, r.inFrameSize)
+	}
+	n := frames
+	switch {
+	case r.stages != nil:
+		s := toFloat64(p, r.inFormat)
+		for _, st := range r.stages {
+			s = st(s)
+		}
+		if err := processorError(r.procErr); err != nil {
+			return nil, err
+		}
+		// Processors may drop frames.
+		n = len(s) / r.outChannels
+		p = float64Bytes(s)
+	case isG711(r.inFormat):
+		p = expandG711(p, r.inFormat)
+	}
+	var out []byte
+	if n > 0 {
+		var err error
+		if out, err = r.backend.process(p, n); err != nil {
+			return nil, err
+		}
+	}
+	r.inFrames += int64(frames)
+	return out, nil
+}
+
 // flush any pending output from the resampler. Aftter that no more input can be passed.
 func (r *Resampler) flush() error {
-	var err error
-	var done C.size_t
-	var soxErr C.soxr_error_t
-	framesOut := 4096 * 16
-	dataOut := C.malloc(C.size_t(framesOut * r.channels * r.outFrameSize))
-	// Flush any pending output by calling soxr_process with no input data.
-	soxErr = C.soxr_process(r.resampler, nil, 0, nil, C.soxr_out_t(dataOut), C.size_t(framesOut), &done)
-	if C.GoString(soxErr) != "" && C.GoString(soxErr) != "0" {
-		err = errors.New(C.GoString(soxErr))
-		goto cleanup
-	}
-	_, err = r.destination.Write(C.GoBytes(dataOut, C.int(int(done)*r.channels*r.outFrameSize)))
-cleanup:
-	C.free(dataOut)
-	C.free(unsafe.Pointer(soxErr))
+	if len(r.pending) > 0 {
+		err := r.writeOut(r.pending)
+		r.outFrames += int64(len(r.pending) / r.outFrameSize / r.outChannels)
+		r.pending = r.pending[:0]
+		if err != nil {
+			return err
+		}
+	}
+	for {
+		out, err := r.backend.flush()
+		if err != nil || len(out) == 0 {
+			return err
+		}
+		if err = r.output(out); err != nil {
+			return err
+		}
+	}
+}
+
+// output writes backend output data to the destination, converting it to
+// the output format when the backend can't produce it directly.
+func (r *Resampler) output(out []byte) error {
+	out, err := r.convert(out)
+	if err != nil {
+		return err
+	}
+	err = r.writeOut(out)
+	r.outFrames += int64(len(out) / r.outFrameSize / r.outChannels)
 	return err
 }
+
+// writeOut writes all of p to the destination, retrying short writes. A
+// destination that writes nothing without an error fails with
+// io.ErrShortWrite.
+func (r *Resampler) writeOut(p []byte) error {
+	for len(p) > 0 {
+		n, err := r.destination.Write(p)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		p = p[n:]
+	}
+	return nil
+}
+
+// convert converts backend output data to the output format.
+func (r *Resampler) convert(out []byte) ([]byte, error) {
+	if r.post != nil {
+		s := toFloat64(out, F64)
+		for _, st := range r.post {
+			s = st(s)
+		}
+		if err := processorError(r.procErr); err != nil {
+			return nil, err
+		}
+		out = float64Bytes(s)
+	}
+	if r.overflowErr && countClips(toFloat64(out, F64), backendFormat(r.outFormat)) > 0 {
+		return nil, ErrOverflow
+	}
+	switch {
+	case r.shaper != nil:
+		out = r.shaper.quantize(out)
+	case r.backendSize == 8 && r.outFormat != F64:
+		s := toFloat64(out, F64)
+		r.clips += countClips(s, backendFormat(r.outFormat))
+		out = fromFloat64(s, backendFormat(r.outFormat))
+	}
+	if isG711(r.outFormat) {
+		out = compressG711(out, r.outFormat)
+	}
+	if r.swapOut {
+		swapBytes(out, r.outFrameSize)
+	}
+	return out, nil
+}
+
+// FormatSize returns the size in bytes of a sample of format, or 0 for an
+// invalid format.
+func FormatSize(format int) int {
+	size, _ := sizeOf(format)
+	return size
+}
+
+// sizeOf returns the byte size of the samples of format.
+func sizeOf(format int) (int, error) {
+	switch format {
+	case F64:
+		return 8, nil
+	case F32:
+		return 4, nil
+	case I32, I24In32:
+		return 4, nil
+	case I16:
+		return 2, nil
+	case MuLaw, ALaw:
+		return 1, nil
+	}
+	return 0, errors.New("invalid format setting")
+}
+
+// formatBits returns the resolution in bits of the I16, I32 and I24In32
+// formats, 16 for the G.711 ones, which are compressed from I16.
+func formatBits(format int) int {
+	switch format {
+	case I32:
+		return 32
+	case I24In32:
+		return 24
+	}
+	return 16
+}
+
+// backendFormat returns the format of the data passed to or returned by
+// the backend for a format.
+func backendFormat(format int) int {
+	if isG711(format) {
+		return I16
+	}
+	return format
+}
+
+// isG711 reports whether format is a G.711 companded format.
+func isG711(format int) bool {
+	return format == MuLaw || format == ALaw
+}
@@ -10,9 +10,14 @@ package resample
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
+	"math"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/zaf/resample/testsignal"
 )
 
 var NewTest = []struct {
@@ -34,13 +39,23 @@ var NewTest = []struct {
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: LowQ, err: ""},
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: HighQ, err: ""},
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: VeryHighQ, err: ""},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: SincBestQ, err: ""},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: SincMediumQ, err: ""},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: SincFastestQ, err: ""},
 	{writer: nil, inputRate: 8000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: MediumQ, err: "io.Writer is nil"},
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 0, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid channels number"},
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 0.0, channels: 0, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid input or output sampling rates"},
 	{writer: io.Discard, inputRate: 0.0, outputRate: 8000.0, channels: 0, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid input or output sampling rates"},
-	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: 10, outFormat: 10, quality: MediumQ, err: "invalid format setting"},
-	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: 10, err: "invalid quality setting"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: -2, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid channels number"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 1 << 20, inFormat: I16, outFormat: I16, quality: MediumQ, err: "too many channels, at most 1024 are supported"},
+	{writer: io.Discard, inputRate: math.NaN(), outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid input or output sampling rates"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: -8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid input or output sampling rates"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: math.Inf(1), channels: 2, inFormat: I16, outFormat: I16, quality: MediumQ, err: "invalid input or output sampling rates"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: 11, outFormat: 11, quality: MediumQ, err: "invalid input format 11"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: 5, quality: MediumQ, err: "invalid output format 5"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: 11, err: "invalid quality setting"},
 	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: -10, err: "invalid quality setting"},
+	{writer: io.Discard, inputRate: 16000.0, outputRate: 8000.0, channels: 2, inFormat: I16, outFormat: I16, quality: 7, err: "invalid quality setting"},
 }
 
 func TestNew(t *testing.T) {
@@ -179,12 +194,22 @@ func TestClose(t *testing.T) {
 		t.Fatal("Failed to Close the Resampler:", err)
 	}
 	_, err = res.Write(WriteTest[0].testData[3].data)
-	if err == nil {
-		t.Fatal("Running Write on a closed Resampler didn't return an error.")
+	if err != ErrClosed {
+		t.Fatal("Running Write on a closed Resampler didn't return ErrClosed:", err)
 	}
 	err = res.Close()
-	if err == nil {
-		t.Fatal("Running Close on a closed Resampler didn't return an error.")
+	if err != nil {
+		t.Fatal("Running Close on a closed Resampler returned an error:", err)
+	}
+	if err = res.WriteSilence(time.Second); err != ErrClosed {
+		t.Fatal("Running WriteSilence on a closed Resampler didn't return ErrClosed:", err)
+	}
+	if _, _, err = res.Process(make([]byte, 64), nil); err != ErrClosed {
+		t.Fatal("Running Process on a closed Resampler didn't return ErrClosed:", err)
+	}
+	var zero Resampler
+	if err = zero.Close(); err == nil || err == ErrClosed {
+		t.Fatal("Running Close on a Resampler not created by New didn't fail:", err)
 	}
 }
 
@@ -202,8 +227,104 @@ func TestReset(t *testing.T) {
 		t.Fatal("Failed to Close the Resampler:", err)
 	}
 	err = res.Reset(io.Discard)
-	if err == nil {
-		t.Fatal("Running Reset on a closed Resampler didn't return an error.")
+	if err != ErrClosed {
+		t.Fatal("Running Reset on a closed Resampler didn't return ErrClosed:", err)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	var out bytes.Buffer
+	res, err := New(&out, 16000.0, 8000.0, 2, I16, F32, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(make([]byte, 16000*2*2)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if n, d := res.InputPosition(); n != 16000 || d != time.Second {
+		t.Errorf("Input position: %d frames, %s", n, d)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to Close the Resampler:", err)
+	}
+	n, d := res.OutputPosition()
+	if n != int64(out.Len()/2/4) || n < 7990 || n > 8000 {
+		t.Errorf("Output position: %d frames for %d bytes", n, out.Len())
+	}
+	if want := time.Duration(n) * time.Second / 8000; d != want {
+		t.Errorf("Output position: %s, expected %s", d, want)
+	}
+}
+
+// writerFunc is an io.Writer calling a function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestShortWrite(t *testing.T) {
+	in := make([]byte, 8000*2)
+	for i := range in {
+		in[i] = byte(i * 7)
+	}
+	var want, got bytes.Buffer
+	for _, w := range []io.Writer{&want, writerFunc(func(p []byte) (int, error) {
+		return got.Write(p[:min(len(p), 7)])
+	})} {
+		res, err := New(w, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if _, err = res.Write(in); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("Short writes output %d bytes, expected %d", got.Len(), want.Len())
+	}
+	res, err := New(writerFunc(func([]byte) (int, error) { return 0, nil }), 8000.0, 16000.0, 1, I16, I16, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(in); err != io.ErrShortWrite {
+		t.Errorf("Write to a stalled writer returned: %v", err)
+	}
+	res.Close()
+}
+
+func TestWriteWithTime(t *testing.T) {
+	var res *Resampler
+	var stamps []time.Duration
+	var frames int64
+	w := writerFunc(func(p []byte) (int, error) {
+		stamps = append(stamps, res.OutputTime())
+		if want := 10*time.Second + time.Duration(frames)*time.Second/8000; len(p) > 0 && res.OutputTime() != want {
+			t.Errorf("Output at frame %d stamped %s, expected %s", frames, res.OutputTime(), want)
+		}
+		frames += int64(len(p) / 2)
+		return len(p), nil
+	})
+	res, err := New(w, 16000.0, 8000.0, 1, I16, I16, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	for _, pts := range []time.Duration{10 * time.Second, 10*time.Second + 500*time.Millisecond} {
+		if _, err = res.WriteWithTime(make([]byte, 8000*2), pts); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to Close the Resampler:", err)
+	}
+	if len(stamps) == 0 || stamps[0] != 10*time.Second {
+		t.Errorf("Output timestamps: %v", stamps)
+	}
+	if d := res.OutputTime() - 11*time.Second; d < -2*time.Millisecond || d > 0 {
+		t.Errorf("Output ends at %s, expected 11s", res.OutputTime())
 	}
 }
 
@@ -230,6 +351,45 @@ var BenchData = []struct {
 	{"16bit 1 ch 16->8  VeryHigh", "testing/piano-16k-16-1.wav", 16000.0, 8000.0, 1, I16, I16, VeryHighQ},
 }
 
+func TestClips(t *testing.T) {
+	in := make([]byte, 4*1000)
+	for i := 0; i < len(in); i += 4 {
+		binary.LittleEndian.PutUint32(in[i:], math.Float32bits(1.5))
+	}
+	for _, tc := range []struct {
+		name   string
+		format int
+		opts   []Option
+		clips  bool
+	}{
+		{"library", I16, nil, true},
+		{"go", I16, []Option{WithGain(1)}, true},
+		{"shaped", I16, []Option{WithDither(DitherShaped)}, true},
+		{"i32", I32, []Option{WithDither(DitherNone), WithGain(1)}, true},
+		{"fir", I16, []Option{WithBackend(BackendFIR), WithDither(DitherNone)}, true},
+		{"float", F32, nil, false},
+	} {
+		res, err := New(io.Discard, 8000, 16000, 1, F32, tc.format, MediumQ, tc.opts...)
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if _, err = res.Write(in); err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if err = res.Reset(io.Discard); err != nil {
+			t.Fatal(tc.name, err)
+		}
+		if n := res.Clips(); n != 0 {
+			t.Errorf("%s: %d clips after Reset", tc.name, n)
+		}
+		res.Write(in)
+		res.Close()
+		if n := res.Clips(); tc.clips && (n < 1900 || n > 2000) || !tc.clips && n != 0 {
+			t.Errorf("%s: %d clipped samples", tc.name, n)
+		}
+	}
+}
+
 func BenchmarkResampling(b *testing.B) {
 	for _, bd := range BenchData {
 		b.Run(bd.name, func(b *testing.B) {
@@ -253,3 +413,153 @@ func BenchmarkResampling(b *testing.B) {
 		})
 	}
 }
+
+func TestWithThreads(t *testing.T) {
+	if _, err := New(io.Discard, 16000.0, 8000.0, 1, I16, I16, MediumQ, WithThreads(-1)); err == nil || err.Error() != "invalid threads number" {
+		t.Errorf("Expecting: invalid threads number got: %v", err)
+	}
+	res, err := New(io.Discard, 16000.0, 8000.0, 1, I16, I16, MediumQ, WithThreads(1))
+	if err != nil {
+		t.Fatal("Failed to create a single threaded Resampler:", err)
+	}
+	if _, err = res.Write(make([]byte, 2048)); err != nil {
+		t.Error("Write failed:", err)
+	}
+	res.Close()
+}
+
+func TestWithBufferSize(t *testing.T) {
+	if _, err := New(io.Discard, 8000.0, 16000.0, 1, F32, F32, MediumQ, WithMaxBufferSize(-1)); err == nil {
+		t.Error("No error for a negative buffer size")
+	}
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 8000, 8000), 1, testsignal.F32)
+	var out [2]bytes.Buffer
+	for i, opt := range []Option{WithBufferSize(4000), WithMaxBufferSize(300)} {
+		res, err := New(&out[i], 8000.0, 16000.0, 1, F32, F32, MediumQ, opt)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		for j := 0; j < len(p); j += len(p) / 2 {
+			if _, err = res.Write(p[j : j+len(p)/2]); err != nil {
+				t.Fatal("Write failed:", err)
+			}
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+	}
+	if out[0].Len() == 0 || !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+		t.Errorf("Output of %d bytes with a preallocated buffer, %d with a capped one", out[0].Len(), out[1].Len())
+	}
+}
+
+func TestWithMemoryLimit(t *testing.T) {
+	for _, limit := range []int{-1, 4} {
+		if _, err := New(io.Discard, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithMemoryLimit(limit)); err == nil {
+			t.Errorf("No error for a memory limit of %d bytes", limit)
+		}
+	}
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.25, 8000, 40000), 1, testsignal.I16)
+	var out [2]bytes.Buffer
+	for i, limit := range []int{0, 16 << 10} {
+		res, err := New(&out[i], 8000.0, 16000.0, 1, I16, I16, MediumQ, WithMemoryLimit(limit), WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if limit > 0 && (res.maxFrames == 0 || res.maxFrames*res.frameBytes() > limit) {
+			t.Errorf("%d frames per Write of %d bytes for a %d bytes limit", res.maxFrames, res.frameBytes(), limit)
+		}
+		if _, err = res.Write(in); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+	}
+	if out[0].Len() == 0 || !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+		t.Errorf("Output of %d bytes without a limit, %d with one", out[0].Len(), out[1].Len())
+	}
+}
+
+func TestWithGain(t *testing.T) {
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithGain(-20*math.Log10(2)))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	in := make([]byte, 2*800)
+	for i := 0; i < len(in); i += 2 {
+		binary.LittleEndian.PutUint16(in[i:], 8000)
+	}
+	res.Write(in)
+	res.Close()
+	if out.Len() < 1600 {
+		t.Fatalf("Output size: %d, expecting about 3200", out.Len())
+	}
+	if s := int16(binary.LittleEndian.Uint16(out.Bytes()[1600:])); s < 3900 || s > 4100 {
+		t.Errorf("Sample: %d, expecting about 4000", s)
+	}
+}
+
+func TestWithByteOrder(t *testing.T) {
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.3, 8000, 1000), 1, testsignal.I16)
+	resample := func(data []byte, opts ...Option) []byte {
+		var out bytes.Buffer
+		res, err := New(&out, 8000.0, 16000.0, 1, I16, I16, MediumQ, append(opts, WithDither(DitherNone))...)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		res.Write(data)
+		res.Close()
+		return out.Bytes()
+	}
+	little := resample(in)
+	swapped := append([]byte(nil), in...)
+	swapBytes(swapped, 2)
+	big := resample(swapped, WithByteOrder(binary.BigEndian, binary.BigEndian))
+	swapBytes(big, 2)
+	if !bytes.Equal(little, big) {
+		t.Error("Big-endian resampling doesn't match the little-endian one")
+	}
+	if swapped[0] != in[1] || swapped[1] != in[0] {
+		t.Error("Write modified its input")
+	}
+}
+
+func TestI24In32(t *testing.T) {
+	x := testsignal.Sine(1000, 0.9, 48000, 4800)
+	in := make([]byte, 4*len(x))
+	for i, v := range x {
+		binary.LittleEndian.PutUint32(in[4*i:], uint32(int32(math.Round(v*(1<<23)))<<8))
+	}
+	resample := func(inFormat, outFormat int, opts ...Option) []byte {
+		var out bytes.Buffer
+		res, err := New(&out, 48000.0, 44100.0, 1, inFormat, outFormat, MediumQ, opts...)
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if _, err = res.Write(in); err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		return out.Bytes()
+	}
+	want := resample(I32, I32, WithDither(DitherNone))
+	for _, dither := range []int{DitherNone, DitherTPDF} {
+		got := resample(I24In32, I24In32, WithDither(dither))
+		if len(got) != len(want) {
+			t.Fatalf("Output %d bytes, expected %d", len(got), len(want))
+		}
+		for i := 0; i < len(got); i += 4 {
+			v, w := int32(binary.LittleEndian.Uint32(got[i:])), int32(binary.LittleEndian.Uint32(want[i:]))
+			if v&0xff != 0 {
+				t.Fatalf("Sample %d isn't left-justified: %#x", i/4, v)
+			}
+			if d := int64(v) - int64(w); d > 2<<8 || d < -2<<8 {
+				t.Fatalf("Sample %d is %d, expected about %d", i/4, v, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// responseFrames is the number of input frames of the impulse run through
+// the resampler by FilterResponse, the impulse being in the middle.
+const responseFrames = 1 << 14
+
+// Response is the measured filter response of a resampling configuration.
+type Response struct {
+	Rate      float64   // sampling rate of the impulse response, the output rate
+	Impulse   []float64 // impulse response, without its silent ends
+	Frequency []float64 // frequency in Hz of each Magnitude value, up to Rate/2
+	Magnitude []float64 // magnitude response in dB, 0 dB being unity gain
+}
+
+// FilterResponse runs a unit impulse through a mono Resampler converting
+// from inputRate to outputRate with the quality setting and options, and
+// returns its impulse response and the magnitude response derived from it
+// by FFT. The response is scaled so that a passband gain of 1 reads 0 dB.
+func FilterResponse(inputRate, outputRate float64, quality int, opts ...Option) (*Response, error) {
+	var out bytes.Buffer
+	r, err := New(&out, inputRate, outputRate, 1, F64, F64, quality, opts...)
+	if err != nil {
+		return nil, err
+	}
+	in := make([]float64, responseFrames)
+	in[responseFrames/2] = 1
+	_, err = r.Write(float64Bytes(in))
+	if e := r.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := toFloat64(out.Bytes(), F64)
+	var peak float64
+	for _, v := range h {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	// Trim the ends below the resolution of F64 relative to the peak.
+	start, end := 0, len(h)
+	for start < end && math.Abs(h[start]) <= peak*1e-15 {
+		start++
+	}
+	for end > start && math.Abs(h[end-1]) <= peak*1e-15 {
+		end--
+	}
+	resp := &Response{Rate: outputRate, Impulse: h[start:end]}
+	n := 1
+	for n < len(resp.Impulse) {
+		n <<= 1
+	}
+	x := make([]complex128, n)
+	for i, v := range resp.Impulse {
+		// An impulse of 1 is spread over outputRate/inputRate output samples.
+		x[i] = complex(v*inputRate/outputRate, 0)
+	}
+	fft(x)
+	for i := 0; i <= n/2; i++ {
+		resp.Frequency = append(resp.Frequency, float64(i)*outputRate/float64(n))
+		resp.Magnitude = append(resp.Magnitude, 20*math.Log10(cmplx.Abs(x[i])))
+	}
+	return resp, nil
+}
+
+// fft computes in place the discrete Fourier transform of x, whose length
+// must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	if n < 2 {
+		return
+	}
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range x {
+		if j := int(bits.Reverse64(uint64(i)) >> shift); i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, -2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFilterResponse(t *testing.T) {
+	resp, err := FilterResponse(8000.0, 16000.0, HighQ)
+	if err != nil {
+		t.Fatal("FilterResponse failed:", err)
+	}
+	if resp.Rate != 16000 || len(resp.Impulse) == 0 || len(resp.Frequency) != len(resp.Magnitude) {
+		t.Fatalf("Response of %g Hz with %d impulse samples, %d frequencies and %d magnitudes",
+			resp.Rate, len(resp.Impulse), len(resp.Frequency), len(resp.Magnitude))
+	}
+	last := len(resp.Magnitude) - 1
+	if resp.Frequency[0] != 0 || resp.Frequency[last] != 8000 {
+		t.Errorf("Frequencies from %g to %g Hz", resp.Frequency[0], resp.Frequency[last])
+	}
+	if math.Abs(resp.Magnitude[0]) > 0.1 {
+		t.Errorf("DC gain of %.2f dB", resp.Magnitude[0])
+	}
+	// Upsampled images above the input Nyquist frequency are filtered out.
+	if resp.Magnitude[last] > -20 {
+		t.Errorf("Gain of %.2f dB at the output Nyquist frequency", resp.Magnitude[last])
+	}
+	if _, err = FilterResponse(8000.0, 16000.0, 11); err == nil {
+		t.Error("No error for an invalid quality")
+	}
+}
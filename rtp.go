@@ -0,0 +1,98 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Packetizer cuts the data written to it, such as the output of a
+// Resampler, into payloads of a fixed number of frames for RTP. Each
+// payload is passed to a send function with its RTP timestamp, which
+// counts frames at the sampling rate of the data: 160 frame payloads of
+// 8 kHz audio are 20 ms packets whose timestamps advance by 160.
+type Packetizer struct {
+	send    func(payload []byte, timestamp uint32) error
+	format  int
+	sample  int    // sample size in bytes
+	size    int    // payload size in bytes
+	buf     []byte // payload being filled
+	frames  uint32 // frames per payload
+	ts      uint32 // timestamp of the payload being filled
+	written int    // bytes of buf filled
+}
+
+// NewPacketizer returns a Packetizer of data of format with channels
+// channels, cutting it into payloads of frames frames passed to send. The
+// first payload has the timestamp start, which RTP wants random. The
+// payload passed to send is reused afterwards, send must copy it to keep it.
+func NewPacketizer(send func(payload []byte, timestamp uint32) error, format, channels, frames int, start uint32) (*Packetizer, error) {
+	if send == nil {
+		return nil, errors.New("send function is nil")
+	}
+	size, err := sizeOf(format)
+	if err != nil {
+		return nil, err
+	}
+	if channels <= 0 {
+		return nil, errors.New("invalid channels number")
+	}
+	if frames <= 0 {
+		return nil, errors.New("invalid payload size")
+	}
+	p := &Packetizer{send: send, format: format, sample: size, size: frames * channels * size, frames: uint32(frames), ts: start}
+	p.buf = make([]byte, p.size)
+	return p, nil
+}
+
+// Write adds b to the payloads, sending each one once it is full.
+func (p *Packetizer) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		m := copy(p.buf[p.written:], b)
+		p.written += m
+		b = b[m:]
+		if p.written == p.size {
+			if err := p.flush(); err != nil {
+				return n - len(b), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush sends the payload and starts the next one.
+func (p *Packetizer) flush() error {
+	err := p.send(p.buf, p.ts)
+	p.ts += p.frames // wraps around as RTP timestamps do
+	p.written = 0
+	return err
+}
+
+// Timestamp returns the RTP timestamp of the next payload.
+func (p *Packetizer) Timestamp() uint32 {
+	return p.ts
+}
+
+// Close sends the last payload, completed with silence, if any data is
+// left. It doesn't close the underlying Resampler, which should be closed
+// first so its remaining output is sent.
+func (p *Packetizer) Close() error {
+	if p.written == 0 {
+		return nil
+	}
+	// Rounded up to whole samples, the end of an incomplete one is zeroed.
+	var silence bytes.Buffer
+	if err := WriteSilence(&silence, p.format, 1, int64((p.size-p.written+p.sample-1)/p.sample)); err != nil {
+		return err
+	}
+	copy(p.buf[p.written:], silence.Bytes())
+	return p.flush()
+}
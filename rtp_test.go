@@ -0,0 +1,55 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPacketizer(t *testing.T) {
+	var payloads [][]byte
+	var stamps []uint32
+	send := func(payload []byte, ts uint32) error {
+		payloads = append(payloads, append([]byte(nil), payload...))
+		stamps = append(stamps, ts)
+		return nil
+	}
+	p, err := NewPacketizer(send, MuLaw, 1, 160, 1<<32-100)
+	if err != nil {
+		t.Fatal("NewPacketizer failed:", err)
+	}
+	res, err := New(p, 16000.0, 8000.0, 1, I16, MuLaw, MediumQ, WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	// 50 ms of output: two full payloads and one padded with silence.
+	res.Write(make([]byte, 800*2))
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to Close the Resampler:", err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to Close the Packetizer:", err)
+	}
+	if len(payloads) != 3 {
+		t.Fatalf("%d payloads sent", len(payloads))
+	}
+	for i, pl := range payloads {
+		if len(pl) != 160 || !bytes.Equal(pl, bytes.Repeat([]byte{0xff}, 160)) {
+			t.Errorf("Payload %d: % x", i, pl)
+		}
+	}
+	// Timestamps wrap around.
+	if stamps[0] != 1<<32-100 || stamps[1] != 60 || stamps[2] != 220 || p.Timestamp() != 380 {
+		t.Errorf("Timestamps: %v, next %d", stamps, p.Timestamp())
+	}
+	if _, err = NewPacketizer(send, I16, 1, 0, 0); err == nil {
+		t.Error("No error for empty payloads")
+	}
+}
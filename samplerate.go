@@ -0,0 +1,170 @@
+//go:build samplerate && cgo
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+/*
+// Link libsamplerate using pkg-config, or by name on Windows as soxr is.
+#cgo !windows pkg-config: samplerate
+#cgo windows,pkgconfig pkg-config: samplerate
+#cgo windows,!pkgconfig LDFLAGS: -lsamplerate
+#include <stdlib.h>
+#include <samplerate.h>
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// srcBackend resamples with libsamplerate, which works on float samples.
+type srcBackend struct {
+	state     *C.SRC_STATE
+	ratio     float64
+	channels  int
+	inFormat  int   // format of the input data
+	outFormat int   // format of the output data
+	clipped   int64 // output samples clipped to an integer format
+	// C buffers of the input and output samples, reused by the calls.
+	// libsamplerate's SRC_DATA can't point to Go memory.
+	in, out []C.float
+}
+
+// srcConverter returns the libsamplerate converter matching a quality setting.
+func srcConverter(quality int) C.int {
+	switch {
+	case quality == SincMediumQ:
+		return C.SRC_SINC_MEDIUM_QUALITY
+	case quality == SincFastestQ:
+		return C.SRC_SINC_FASTEST
+	case quality == Quick:
+		return C.SRC_LINEAR
+	case quality <= LowQ:
+		return C.SRC_SINC_FASTEST
+	case quality <= MediumQ:
+		return C.SRC_SINC_MEDIUM_QUALITY
+	}
+	return C.SRC_SINC_BEST_QUALITY
+}
+
+func newSamplerate(inRate, outRate float64, channels, inFormat, outFormat, quality int) (backend, error) {
+	var e C.int
+	state := C.src_new(srcConverter(quality), C.int(channels), &e)
+	if state == nil {
+		return nil, errors.New(C.GoString(C.src_strerror(e)))
+	}
+	return &srcBackend{state: state, ratio: outRate / inRate, channels: channels, inFormat: inFormat, outFormat: outFormat}, nil
+}
+
+func (s *srcBackend) process(p []byte, frames int) ([]byte, error) {
+	return s.run(p, frames, false)
+}
+
+func (s *srcBackend) flush() ([]byte, error) {
+	return s.run(nil, 0, true)
+}
+
+func (s *srcBackend) reserve(frames int) {
+	s.in = growFloats(s.in, frames*s.channels+1)
+	s.out = growFloats(s.out, (int(float64(frames)*s.ratio)+256)*s.channels)
+}
+
+// growFloats returns a C buffer of at least n samples, replacing buf when
+// it's too small.
+func growFloats(buf []C.float, n int) []C.float {
+	if len(buf) >= n {
+		return buf
+	}
+	freeFloats(buf)
+	p := (*C.float)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.float(0)))))
+	return unsafe.Slice(p, n)
+}
+
+// freeFloats frees a C buffer of growFloats.
+func freeFloats(buf []C.float) {
+	if buf != nil {
+		C.free(unsafe.Pointer(&buf[0]))
+	}
+}
+
+// delay returns 0, libsamplerate doesn't report its latency.
+func (s *srcBackend) delay() float64 {
+	return 0
+}
+
+func (s *srcBackend) setRatio(ratio float64) error {
+	if e := C.src_set_ratio(s.state, C.double(ratio)); e != 0 {
+		return errors.New(C.GoString(C.src_strerror(e)))
+	}
+	s.ratio = ratio
+	return nil
+}
+
+func (s *srcBackend) clips() int64 {
+	return s.clipped
+}
+
+// run passes frames of input data p to libsamplerate. When last is set it
+// flushes as much output as fits in the output buffer.
+func (s *srcBackend) run(p []byte, frames int, last bool) ([]byte, error) {
+	in := toFloat64(p, s.inFormat)[:frames*s.channels]
+	s.in = growFloats(s.in, len(in)+1)
+	s.out = growFloats(s.out, (int(float64(frames)*s.ratio)+256)*s.channels)
+	inBuf, outBuf, dataOut := s.in, s.out, &s.out[0]
+	outFrames := len(outBuf) / s.channels
+	for i, v := range in {
+		inBuf[i] = C.float(v)
+	}
+	var out []float64
+	used := 0
+	for {
+		data := C.SRC_DATA{
+			data_in:       (*C.float)(unsafe.Pointer(&inBuf[used*s.channels])),
+			data_out:      dataOut,
+			input_frames:  C.long(frames - used),
+			output_frames: C.long(outFrames),
+			src_ratio:     C.double(s.ratio),
+		}
+		if last {
+			data.end_of_input = 1
+		}
+		if e := C.src_process(s.state, &data); e != 0 {
+			return nil, errors.New(C.GoString(C.src_strerror(e)))
+		}
+		used += int(data.input_frames_used)
+		gen := int(data.output_frames_gen)
+		for _, v := range outBuf[:gen*s.channels] {
+			out = append(out, float64(v))
+		}
+		// Stop once the input is consumed and the output buffer wasn't
+		// filled, or after a flush call filled it.
+		if used == frames && (gen == 0 || gen < outFrames || last) {
+			break
+		}
+	}
+	s.clipped += countClips(out, s.outFormat)
+	return fromFloat64(out, s.outFormat), nil
+}
+
+func (s *srcBackend) reset() error {
+	s.clipped = 0
+	if e := C.src_reset(s.state); e != 0 {
+		return errors.New(C.GoString(C.src_strerror(e)))
+	}
+	return nil
+}
+
+func (s *srcBackend) close() {
+	C.src_delete(s.state)
+	s.state = nil
+	freeFloats(s.in)
+	freeFloats(s.out)
+	s.in, s.out = nil, nil
+}
@@ -0,0 +1,17 @@
+//go:build !samplerate || !cgo
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "errors"
+
+func newSamplerate(inRate, outRate float64, channels, inFormat, outFormat, quality int) (backend, error) {
+	return nil, errors.New("libsamplerate support needs cgo and the samplerate build tag")
+}
@@ -0,0 +1,72 @@
+//go:build samplerate && cgo
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestSamplerate(t *testing.T) {
+	const frames = 8000
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 8000, frames), 1, testsignal.I16)
+	for _, format := range []int{I16, I32, F32, F64, MuLaw} {
+		var out bytes.Buffer
+		res, err := New(&out, 8000, 16000, 1, I16, format, MediumQ, WithBackend(BackendSamplerate), WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		for p := in; len(p) > 0; p = p[1000:] {
+			if _, err = res.Write(p[:1000]); err != nil {
+				t.Fatal("Write failed:", err)
+			}
+		}
+		if err = res.Reset(&out); err != nil {
+			t.Fatal("Reset failed:", err)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		size := map[int]int{I16: 2, I32: 4, F32: 4, F64: 8, MuLaw: 1}[format]
+		if n := out.Len() / size; math.Abs(float64(n-2*frames)) > 100 {
+			t.Errorf("Format %d: %d frames out, expecting about %d", format, n, 2*frames)
+		}
+	}
+}
+
+func TestSamplerateBackend(t *testing.T) {
+	b, err := newSamplerate(8000, 16000, 1, F64, F64, MediumQ)
+	if err != nil {
+		t.Fatal("Failed to create the backend:", err)
+	}
+	testBackend(t, b)
+}
+
+func TestSamplerateClips(t *testing.T) {
+	in := make([]byte, 4*1000)
+	for i := 0; i < len(in); i += 4 {
+		binary.LittleEndian.PutUint32(in[i:], math.Float32bits(1.5))
+	}
+	res, err := New(io.Discard, 8000, 16000, 1, F32, I16, MediumQ, WithBackend(BackendSamplerate), WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	res.Write(in)
+	res.Close()
+	if n := res.Clips(); n < 1900 || n > 2000 {
+		t.Errorf("%d clipped samples", n)
+	}
+}
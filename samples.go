@@ -0,0 +1,142 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/zaf/resample/internal/simd"
+)
+
+// Sample conversion for the processing done in Go before data reaches
+// soxr. Samples are handled as float64 in the [-1, 1) range and handed
+// to soxr as F64.
+
+// stage is a processing step applied to interleaved float64 input samples.
+// It may work in place and returns the processed samples.
+type stage func(s []float64) []float64
+
+// gainStage returns a stage scaling samples by gain.
+func gainStage(gain float64) stage {
+	return func(s []float64) []float64 {
+		for i := range s {
+			s[i] *= gain
+		}
+		return s
+	}
+}
+
+// toFloat64 decodes little-endian samples of format to float64.
+func toFloat64(p []byte, format int) []float64 {
+	var s []float64
+	switch format {
+	case F64:
+		s = make([]float64, len(p)/8)
+		for i := range s {
+			s[i] = math.Float64frombits(binary.LittleEndian.Uint64(p[8*i:]))
+		}
+	case F32:
+		s = make([]float64, len(p)/4)
+		simd.F32ToFloat64(s, p)
+	case I32, I24In32:
+		s = make([]float64, len(p)/4)
+		for i := range s {
+			s[i] = float64(int32(binary.LittleEndian.Uint32(p[4*i:]))) / (1 << 31)
+		}
+	case I16:
+		s = make([]float64, len(p)/2)
+		simd.I16ToFloat64(s, p)
+	case MuLaw:
+		s = make([]float64, len(p))
+		for i, b := range p {
+			s[i] = float64(ulawTable[b]) / (1 << 15)
+		}
+	case ALaw:
+		s = make([]float64, len(p))
+		for i, b := range p {
+			s[i] = float64(alawTable[b]) / (1 << 15)
+		}
+	}
+	return s
+}
+
+// fromFloat64 encodes samples as little-endian data of format, rounding
+// and clipping integer samples.
+func fromFloat64(s []float64, format int) []byte {
+	var p []byte
+	switch format {
+	case F64:
+		p = float64Bytes(s)
+	case F32:
+		p = make([]byte, 4*len(s))
+		simd.Float64ToF32(p, s)
+	case I32:
+		p = make([]byte, 4*len(s))
+		for i, v := range s {
+			v = math.Max(-1<<31, math.Min(1<<31-1, math.Round(v*(1<<31))))
+			binary.LittleEndian.PutUint32(p[4*i:], uint32(int32(v)))
+		}
+	case I24In32:
+		p = make([]byte, 4*len(s))
+		for i, v := range s {
+			v = math.Max(-1<<23, math.Min(1<<23-1, math.Round(v*(1<<23))))
+			binary.LittleEndian.PutUint32(p[4*i:], uint32(int32(v)<<8))
+		}
+	case I16:
+		p = make([]byte, 2*len(s))
+		for i, v := range s {
+			v = math.Max(-1<<15, math.Min(1<<15-1, math.Round(v*(1<<15))))
+			binary.LittleEndian.PutUint16(p[2*i:], uint16(int16(v)))
+		}
+	}
+	return p
+}
+
+// countClips returns the number of samples that fromFloat64 clips to the
+// range of format.
+func countClips(s []float64, format int) int64 {
+	var scale float64
+	switch format {
+	case I32:
+		scale = 1 << 31
+	case I24In32:
+		scale = 1 << 23
+	case I16:
+		scale = 1 << 15
+	default:
+		return 0
+	}
+	var n int64
+	for _, v := range s {
+		if v = math.Round(v * scale); v > scale-1 || v < -scale {
+			n++
+		}
+	}
+	return n
+}
+
+// float64Bytes encodes samples as little-endian F64 data.
+func float64Bytes(s []float64) []byte {
+	p := make([]byte, 8*len(s))
+	for i, v := range s {
+		binary.LittleEndian.PutUint64(p[8*i:], math.Float64bits(v))
+	}
+	return p
+}
+
+// swapBytes reverses in place the byte order of the samples of size bytes in p.
+func swapBytes(p []byte, size int) {
+	for i := 0; i+size <= len(p); i += size {
+		b := p[i : i+size]
+		for j, k := 0, size-1; j < k; j, k = j+1, k-1 {
+			b[j], b[k] = b[k], b[j]
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "math"
+
+// WithSanitizeFloats replaces NaN and infinite F32 or F64 input samples
+// with zero before they are resampled. A single NaN, as an upstream
+// decoder may produce, would otherwise spread through the filter state
+// and turn the following output into full scale noise. The samples
+// replaced are counted by Sanitized. Integer and G.711 input is left as is.
+func WithSanitizeFloats() Option {
+	return func(o *options) {
+		o.sanitize = true
+	}
+}
+
+// Sanitized returns the number of NaN and infinite input samples replaced
+// with zero since the Resampler was created or last Reset, always 0
+// without WithSanitizeFloats.
+func (r *Resampler) Sanitized() int64 {
+	if r.sanitized == nil {
+		return 0
+	}
+	return *r.sanitized
+}
+
+// sanitizeStage returns a stage replacing non-finite samples with zero,
+// adding their number to count.
+func sanitizeStage(count *int64) stage {
+	return func(s []float64) []float64 {
+		for i, v := range s {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				s[i] = 0
+				*count++
+			}
+		}
+		return s
+	}
+}
@@ -0,0 +1,53 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestWithSanitizeFloats(t *testing.T) {
+	in := testsignal.Sine(440, 0.5, 8000, 8000)
+	in[100], in[2000], in[4000] = math.NaN(), math.Inf(1), math.Inf(-1)
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, F64, F64, MediumQ, WithSanitizeFloats())
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if _, err = res.Write(float64Bytes(in)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if n := res.Sanitized(); n != 3 {
+		t.Errorf("Sanitized %d samples, expected 3", n)
+	}
+	for i, v := range toFloat64(out.Bytes(), F64) {
+		if math.IsNaN(v) || math.Abs(v) > 1 {
+			t.Fatalf("Output sample %d is %f", i, v)
+		}
+	}
+	if err = res.Reset(&out); err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+	if n := res.Sanitized(); n != 0 {
+		t.Errorf("Sanitized %d samples after Reset", n)
+	}
+	res.Close()
+	res, err = New(&out, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithSanitizeFloats())
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if res.stages != nil {
+		t.Error("Integer input converted to be sanitized")
+	}
+	res.Close()
+}
@@ -0,0 +1,190 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+)
+
+// primeFrames is the number of input frames resampled ahead of a seek
+// position, at output rates of the input rate or more, so the filter
+// state is settled once the position is reached.
+const primeFrames = 1024
+
+// ReadSeeker reads a RAW PCM source resampled, and seeks in the resampled
+// timeline. Seeking moves the source before the mapped input position and
+// resamples from there, dropping the output until the position, so the
+// data read is the one a sequential read returns.
+type ReadSeeker struct {
+	src      io.ReadSeeker
+	base     int64 // offset of the first frame in src
+	create   func() (*Resampler, error)
+	r        *Resampler // nil once flushed
+	inRate   float64
+	outRate  float64
+	inFrame  int64 // input frame size in bytes
+	outFrame int64 // output frame size in bytes
+	step     int64 // input frames between positions mapping to whole output frames
+	prime    int64 // input frames resampled ahead of a seek position
+	buf      []byte
+	out      bytes.Buffer // resampled data not read yet
+	skip     int64        // output bytes to drop after a seek
+	pos      int64        // output offset
+	drained  bool         // the source is drained and the resampler flushed
+}
+
+// NewReadSeeker returns a ReadSeeker of src, whose frames start at its
+// current offset, resampled as New does. Offsets of Seek and Read are in
+// bytes of output data.
+func NewReadSeeker(src io.ReadSeeker, inputRate, outputRate float64, channels, inFormat, outFormat, quality int, opts ...Option) (*ReadSeeker, error) {
+	if src == nil {
+		return nil, errors.New("io.ReadSeeker is nil")
+	}
+	base, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ReadSeeker{src: src, base: base, inRate: inputRate, outRate: outputRate, step: 1}
+	rs.create = func() (*Resampler, error) {
+		return New(&rs.out, inputRate, outputRate, channels, inFormat, outFormat, quality, opts...)
+	}
+	if rs.r, err = rs.create(); err != nil {
+		return nil, err
+	}
+	rs.inFrame = int64(rs.r.inFrameSize * rs.r.channels)
+	rs.outFrame = int64(rs.r.outFrameSize * rs.r.outChannels)
+	rs.buf = make([]byte, chunkFrames*rs.inFrame)
+	if in, out := int64(inputRate), int64(outputRate); float64(in) == inputRate && float64(out) == outputRate {
+		rs.step = in / gcd(in, out)
+	}
+	rs.prime = primeFrames
+	if inputRate > outputRate {
+		rs.prime = int64(math.Ceil(primeFrames * inputRate / outputRate))
+	}
+	return rs, nil
+}
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Read reads resampled data into p.
+func (rs *ReadSeeker) Read(p []byte) (int, error) {
+	if rs.create == nil {
+		return 0, errors.New("read seeker is closed")
+	}
+	for {
+		if rs.skip > 0 {
+			n := int64(rs.out.Len())
+			if n > rs.skip {
+				n = rs.skip
+			}
+			rs.out.Next(int(n))
+			rs.skip -= n
+		}
+		if rs.skip == 0 && rs.out.Len() > 0 {
+			break
+		}
+		if rs.drained {
+			return 0, io.EOF
+		}
+		if err := rs.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n, _ := rs.out.Read(p)
+	rs.pos += int64(n)
+	return n, nil
+}
+
+// fill resamples the next chunk of the source, flushing the resampler
+// at the end of the source.
+func (rs *ReadSeeker) fill() error {
+	n, err := io.ReadFull(rs.src, rs.buf)
+	n -= n % int(rs.inFrame)
+	// A chunk too short for an output frame can only be the last one.
+	if frames := n / int(rs.inFrame); frames > 0 && int(float64(frames)*rs.outRate/rs.inRate) > 0 {
+		if _, e := rs.r.Write(rs.buf[:n]); e != nil {
+			return e
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = rs.r.Close()
+		rs.r, rs.drained = nil, true
+	}
+	return err
+}
+
+// Seek sets the offset of the next Read in the resampled data. The end
+// is computed from the size of the source, it is the length of the
+// output when rounded from the input length.
+func (rs *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if rs.create == nil {
+		return 0, errors.New("read seeker is closed")
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rs.pos
+	case io.SeekEnd:
+		end, err := rs.src.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		frames := (end - rs.base) / rs.inFrame
+		offset += int64(math.Round(float64(frames)*rs.outRate/rs.inRate)) * rs.outFrame
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	in := int64(float64(offset/rs.outFrame) * rs.inRate / rs.outRate)
+	start := in - rs.prime
+	if start < 0 {
+		start = 0
+	}
+	start -= start % rs.step
+	if _, err := rs.src.Seek(rs.base+start*rs.inFrame, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var err error
+	if rs.r != nil {
+		err = rs.r.Reset(&rs.out)
+	} else {
+		rs.r, err = rs.create()
+	}
+	if err != nil {
+		return 0, err
+	}
+	rs.out.Reset()
+	rs.skip = offset - int64(math.Round(float64(start)*rs.outRate/rs.inRate))*rs.outFrame
+	rs.pos, rs.drained = offset, false
+	return offset, nil
+}
+
+// Close frees the resampler. The source is left open.
+func (rs *ReadSeeker) Close() error {
+	if rs.create == nil {
+		return errors.New("read seeker is closed")
+	}
+	rs.create = nil
+	if rs.r != nil {
+		rs.r.Close()
+		rs.r = nil
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestReadSeeker(t *testing.T) {
+	const frames = 20000
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.3, 44100, frames), 1, testsignal.I16)
+	src := append(make([]byte, 4), p...)
+	r := bytes.NewReader(src)
+	r.Seek(4, io.SeekStart) // the frames follow a header
+	rs, err := NewReadSeeker(r, 44100, 48000, 1, I16, I16, MediumQ, WithDither(DitherNone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := io.ReadAll(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ref) / 2; math.Abs(float64(n)-frames*48000/44100.0) > 20 {
+		t.Errorf("read %d frames, expected about %d", n, frames*48000/44100)
+	}
+	check := func(offset int64, whence int, want int64) {
+		t.Helper()
+		pos, err := rs.Seek(offset, whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos != want {
+			t.Fatalf("Seek(%d, %d) = %d, expected %d", offset, whence, pos, want)
+		}
+		got := make([]byte, 200)
+		if _, err = io.ReadFull(rs, got); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(got); i += 2 {
+			a := int16(binary.LittleEndian.Uint16(got[i:]))
+			b := int16(binary.LittleEndian.Uint16(ref[pos+int64(i):]))
+			if d := int(a) - int(b); d > 2 || d < -2 {
+				t.Fatalf("sample at %d after Seek(%d, %d) is %d, expected %d", pos+int64(i), offset, whence, a, b)
+			}
+		}
+	}
+	check(0, io.SeekStart, 0)
+	check(10001*2, io.SeekStart, 10001*2)
+	check(-3000, io.SeekCurrent, 10001*2+200-3000)
+	end := int64(math.Round(frames*48000/44100.0)) * 2
+	check(-1000, io.SeekEnd, end-1000)
+	check(501, io.SeekStart, 501) // within a frame
+	if _, err = rs.Seek(-1, io.SeekStart); err == nil {
+		t.Error("negative position accepted")
+	}
+	if _, err = rs.Seek(end+100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := rs.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("read %d, %v past the end", n, err)
+	}
+	if err = rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rs.Read(make([]byte, 10)); err == nil {
+		t.Error("read after Close")
+	}
+}
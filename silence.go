@@ -0,0 +1,78 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// silenceChunk is the number of frames of silence written at once.
+const silenceChunk = 4096
+
+// WriteSilence writes frames frames of digital silence of the given format
+// and number of channels to w. Silence is 0 in the linear formats, 0xff in
+// MuLaw and 0xd5 in ALaw.
+func WriteSilence(w io.Writer, format, channels int, frames int64) error {
+	if channels <= 0 {
+		return errors.New("invalid channels number")
+	}
+	if frames < 0 {
+		return errors.New("invalid number of frames")
+	}
+	var b byte
+	size := 1
+	switch format {
+	case F64:
+		size = 8
+	case F32, I32, I24In32:
+		size = 4
+	case I16:
+		size = 2
+	case MuLaw:
+		b = 0xff
+	case ALaw:
+		b = 0xd5
+	default:
+		return errors.New("invalid format setting")
+	}
+	frameSize := int64(size * channels)
+	n := frames
+	if n >= 2*silenceChunk {
+		n = 2*silenceChunk - 1
+	}
+	buf := bytes.Repeat([]byte{b}, int(n*frameSize))
+	for frames > 0 {
+		// The last write takes the rest, so it isn't too short to resample.
+		n := int64(silenceChunk)
+		if frames < 2*silenceChunk {
+			n = frames
+		}
+		if _, err := w.Write(buf[:n*frameSize]); err != nil {
+			return err
+		}
+		frames -= n
+	}
+	return nil
+}
+
+// WriteSilence writes d of digital silence through the Resampler, in its
+// input format, rounded to whole input frames.
+func (r *Resampler) WriteSilence(d time.Duration) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	if d < 0 {
+		return errors.New("invalid duration")
+	}
+	return WriteSilence(r, r.inFormat, r.channels, int64(math.Round(d.Seconds()*r.inRate)))
+}
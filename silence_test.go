@@ -0,0 +1,57 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteSilence(t *testing.T) {
+	for _, tc := range []struct {
+		format, channels int
+		frames           int64
+		b                byte
+		size             int
+	}{
+		{I16, 2, 100, 0, 4},
+		{F64, 1, 10000, 0, 8},
+		{MuLaw, 1, 8192, 0xff, 1},
+		{ALaw, 2, 3, 0xd5, 2},
+		{F32, 1, 0, 0, 4},
+		{I24In32, 2, 5, 0, 8},
+	} {
+		var buf bytes.Buffer
+		if err := WriteSilence(&buf, tc.format, tc.channels, tc.frames); err != nil {
+			t.Fatal("WriteSilence failed:", err)
+		}
+		if !bytes.Equal(buf.Bytes(), bytes.Repeat([]byte{tc.b}, int(tc.frames)*tc.size)) {
+			t.Errorf("Format %d: wrong silence of %d bytes", tc.format, buf.Len())
+		}
+	}
+	if err := WriteSilence(&bytes.Buffer{}, 11, 1, 1); err == nil {
+		t.Error("No error for an invalid format")
+	}
+	var out bytes.Buffer
+	res, err := New(&out, 8000.0, 16000.0, 1, MuLaw, I16, MediumQ, WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Resampler:", err)
+	}
+	if err = res.WriteSilence(2 * time.Second); err != nil {
+		t.Fatal("WriteSilence failed:", err)
+	}
+	res.Close()
+	if n, _ := res.InputPosition(); n != 16000 {
+		t.Errorf("Wrote %d frames of silence, expected 16000", n)
+	}
+	if bytes.Count(out.Bytes(), []byte{0}) != out.Len() {
+		t.Error("Resampled silence isn't silent")
+	}
+}
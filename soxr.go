@@ -0,0 +1,166 @@
+//go:build !loadsoxr
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+/*
+// Link soxr using pkg-config. On Windows, unless built with the pkgconfig
+// tag, it is linked by name and found through CGO_CFLAGS and CGO_LDFLAGS:
+// -lsoxr matches the MinGW libsoxr.dll.a, the MSVC soxr.lib import library
+// or soxr.dll itself. soxr uses the default C calling convention, so DLLs
+// built by either compiler work.
+#cgo !windows pkg-config: soxr
+#cgo windows,pkgconfig pkg-config: soxr
+#cgo windows,!pkgconfig LDFLAGS: -lsoxr
+#include <stdlib.h>
+#include <soxr.h>
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// defaultBackend returns the resampling library used unless WithBackend is
+// set.
+func defaultBackend() int {
+	return BackendSoxr
+}
+
+// soxrBackend resamples with libsoxr. The F32, F64, I32 and I16 formats
+// are the soxr interleaved datatypes.
+type soxrBackend struct {
+	soxr     C.soxr_t
+	ratio    float64 // output to input rate ratio
+	channels int
+	outSize  int    // output sample size in bytes
+	out      []byte // output buffer, reused by the calls
+}
+
+// newSoxr returns a soxr backend. A variable rate one accepts setRatio,
+// down to half the output to input ratio it is created with.
+func newSoxr(inRate, outRate float64, channels, inFormat, outFormat, quality, threads int, noDither, variable bool, extra soxrFlags) (backend, error) {
+	var soxErr C.soxr_error_t
+	// Setup soxr and create a stream resampler
+	ioSpec := C.soxr_io_spec(C.soxr_datatype_t(inFormat), C.soxr_datatype_t(outFormat))
+	ioSpec.flags |= C.ulong(extra.io)
+	if noDither {
+		ioSpec.flags |= C.SOXR_NO_DITHER
+	}
+	flags := C.ulong(extra.quality)
+	maxRatio := 1.0
+	if variable {
+		// The rates given to soxr_create set the largest input to output ratio.
+		flags, maxRatio = flags|C.SOXR_VR, 2
+	}
+	qSpec := C.soxr_quality_spec(C.ulong(quality), flags)
+	runtimeSpec := C.soxr_runtime_spec(C.uint(threads))
+	runtimeSpec.flags |= C.ulong(extra.runtime)
+	soxr := C.soxr_create(C.double(inRate*maxRatio), C.double(outRate), C.uint(channels), &soxErr, &ioSpec, &qSpec, &runtimeSpec)
+	if err := soxrError(soxErr); err != nil {
+		return nil, err
+	}
+	outSize := map[int]int{F32: 4, F64: 8, I32: 4, I16: 2}[outFormat]
+	s := &soxrBackend{soxr: soxr, ratio: outRate / inRate, channels: channels, outSize: outSize}
+	if variable {
+		if err := s.setRatio(s.ratio); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// soxrError returns the error of a soxr call, if any, and frees it.
+func soxrError(soxErr C.soxr_error_t) error {
+	var err error
+	if C.GoString(soxErr) != "" && C.GoString(soxErr) != "0" {
+		err = errors.New(C.GoString(soxErr))
+	}
+	C.free(unsafe.Pointer(soxErr))
+	return err
+}
+
+func (s *soxrBackend) process(p []byte, frames int) ([]byte, error) {
+	framesOut := max(int(float64(frames)*s.ratio), 1)
+	s.out = growBuffer(s.out, framesOut*s.channels*s.outSize)
+	// soxr doesn't keep the buffers, they can be Go memory.
+	var read, done C.size_t = 0, 0
+	soxErr := C.soxr_process(s.soxr, C.soxr_in_t(unsafe.Pointer(&p[0])), C.size_t(frames), &read, C.soxr_out_t(unsafe.Pointer(&s.out[0])), C.size_t(framesOut), &done)
+	if err := soxrError(soxErr); err != nil {
+		return nil, err
+	}
+	return s.out[:int(done)*s.channels*s.outSize], nil
+}
+
+func (s *soxrBackend) flush() ([]byte, error) {
+	var done C.size_t
+	framesOut := flushFrames
+	if s.out != nil {
+		framesOut = cap(s.out) / (s.channels * s.outSize)
+	}
+	s.out = growBuffer(s.out, framesOut*s.channels*s.outSize)
+	// Flush any pending output by calling soxr_process with no input data.
+	soxErr := C.soxr_process(s.soxr, nil, 0, nil, C.soxr_out_t(unsafe.Pointer(&s.out[0])), C.size_t(framesOut), &done)
+	if err := soxrError(soxErr); err != nil {
+		return nil, err
+	}
+	return s.out[:int(done)*s.channels*s.outSize], nil
+}
+
+func (s *soxrBackend) reserve(frames int) {
+	s.out = growBuffer(s.out, max(int(float64(frames)*s.ratio), 1)*s.channels*s.outSize)
+}
+
+func (s *soxrBackend) delay() float64 {
+	return float64(C.soxr_delay(s.soxr))
+}
+
+func (s *soxrBackend) clips() int64 {
+	if n := C.soxr_num_clips(s.soxr); n != nil {
+		return int64(*n)
+	}
+	return 0
+}
+
+func (s *soxrBackend) setRatio(ratio float64) error {
+	if ratio <= 0 {
+		return errors.New("invalid resampling ratio")
+	}
+	// soxr only changes the ratio of resamplers created for variable rate.
+	if err := soxrError(C.soxr_set_io_ratio(s.soxr, C.double(1/ratio), 0)); err != nil {
+		return err
+	}
+	s.ratio = ratio
+	return nil
+}
+
+func (s *soxrBackend) reset() error {
+	return soxrError(C.soxr_clear(s.soxr))
+}
+
+func (s *soxrBackend) close() {
+	C.soxr_delete(s.soxr)
+	s.soxr = nil
+}
+
+// cSoxrQuality returns the quality spec soxr_quality_spec computes for a
+// recipe and flags, which the tests compare soxrQuality with.
+func cSoxrQuality(recipe, flags uint64) soxrQualitySpec {
+	q := C.soxr_quality_spec(C.ulong(recipe), C.ulong(flags))
+	return soxrQualitySpec{
+		precision:     float64(q.precision),
+		phaseResponse: float64(q.phase_response),
+		passbandEnd:   float64(q.passband_end),
+		stopbandBegin: float64(q.stopband_begin),
+		e:             unsafe.Pointer(q.e),
+		flags:         uint64(q.flags),
+	}
+}
@@ -0,0 +1,27 @@
+//go:build (!cgo || loadsoxr) && !((linux || darwin || freebsd) && (amd64 || arm64))
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+// Without cgo, or with the loadsoxr tag, on the systems purego can't load
+// libsoxr on, such as WebAssembly or Windows, libsoxr isn't available and the Go FIR backend
+// is the default.
+
+import "errors"
+
+// defaultBackend returns the resampling library used unless WithBackend is
+// set.
+func defaultBackend() int {
+	return BackendFIR
+}
+
+func newSoxr(inRate, outRate float64, channels, inFormat, outFormat, quality, threads int, noDither, variable bool, extra soxrFlags) (backend, error) {
+	return nil, errors.New("libsoxr can't be loaded on this system, use BackendFIR")
+}
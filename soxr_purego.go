@@ -0,0 +1,239 @@
+//go:build (!cgo || loadsoxr) && (linux || darwin || freebsd) && (amd64 || arm64)
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+// Without cgo, or with the loadsoxr tag, libsoxr is loaded at run time
+// with purego, so binaries cross-compiled with CGO_ENABLED=0 still use it
+// when the target host has it, and cgo builds don't need its headers. The library is looked up by its usual names, or at the path set in
+// the RESAMPLE_SOXR_LIB environment variable.
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// defaultBackend returns the resampling library used unless WithBackend is
+// set, the FIR backend when libsoxr can't be loaded.
+func defaultBackend() int {
+	if loadSoxr() != nil {
+		return BackendFIR
+	}
+	return BackendSoxr
+}
+
+// soxrNames are the names libsoxr is looked up by on each system.
+var soxrNames = map[string][]string{
+	"darwin":  {"libsoxr.0.dylib", "/opt/homebrew/lib/libsoxr.0.dylib", "/usr/local/lib/libsoxr.0.dylib"},
+	"freebsd": {"libsoxr.so.0", "/usr/local/lib/libsoxr.so.0"},
+	"linux":   {"libsoxr.so.0", "libsoxr.so"},
+}
+
+// The soxr io and runtime spec structs, for 64-bit systems where unsigned
+// long has 64 bits. The quality spec is computed in soxr_quality.go.
+type soxrIOSpec struct {
+	itype, otype uint32
+	scale        float64
+	e            unsafe.Pointer
+	flags        uint64
+}
+
+type soxrRuntimeSpec struct {
+	log2MinDFTSize, log2LargeDFTSize, coefSizeKbytes, numThreads uint32
+	e                                                            unsafe.Pointer
+	flags                                                        uint64
+}
+
+const soxrNoDither = 8 // SOXR_NO_DITHER io flag
+
+// soxrLib holds the libsoxr functions, loaded once.
+var soxrLib struct {
+	once       sync.Once
+	err        error
+	create     func(inRate, outRate float64, channels uint32, err **byte, io *soxrIOSpec, q *soxrQualitySpec, rt *soxrRuntimeSpec) uintptr
+	process    func(soxr uintptr, in unsafe.Pointer, inLen uintptr, inDone *uintptr, out unsafe.Pointer, outLen uintptr, outDone *uintptr) string
+	clear      func(soxr uintptr) string
+	delete     func(soxr uintptr)
+	delay      func(soxr uintptr) float64
+	numClips   func(soxr uintptr) *uintptr
+	setIORatio func(soxr uintptr, ratio float64, slewLen uintptr) string
+}
+
+// loadSoxr loads libsoxr and its functions.
+func loadSoxr() error {
+	soxrLib.once.Do(func() {
+		names := soxrNames[runtime.GOOS]
+		if path := os.Getenv("RESAMPLE_SOXR_LIB"); path != "" {
+			names = []string{path}
+		}
+		var lib uintptr
+		for _, name := range names {
+			if lib, soxrLib.err = purego.Dlopen(name, purego.RTLD_NOW|purego.RTLD_GLOBAL); soxrLib.err == nil {
+				break
+			}
+		}
+		if soxrLib.err != nil {
+			soxrLib.err = errors.New("libsoxr not found: " + soxrLib.err.Error())
+			return
+		}
+		// Look the functions up first, RegisterLibFunc panics on a missing one.
+		for _, fn := range []struct {
+			ptr  interface{}
+			name string
+		}{
+			{&soxrLib.create, "soxr_create"},
+			{&soxrLib.process, "soxr_process"},
+			{&soxrLib.clear, "soxr_clear"},
+			{&soxrLib.delete, "soxr_delete"},
+			{&soxrLib.delay, "soxr_delay"},
+			{&soxrLib.numClips, "soxr_num_clips"},
+			{&soxrLib.setIORatio, "soxr_set_io_ratio"},
+		} {
+			sym, err := purego.Dlsym(lib, fn.name)
+			if err != nil {
+				purego.Dlclose(lib)
+				soxrLib.err = errors.New("libsoxr not found: " + err.Error())
+				return
+			}
+			purego.RegisterFunc(fn.ptr, sym)
+		}
+	})
+	return soxrLib.err
+}
+
+// cString returns the C string at p.
+func cString(p *byte) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for *(*byte)(unsafe.Add(unsafe.Pointer(p), n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice(p, n))
+}
+
+// soxrError returns the error of a soxr call, if any.
+func soxrError(e string) error {
+	if e != "" {
+		return errors.New(e)
+	}
+	return nil
+}
+
+// soxrBackend resamples with libsoxr. The F32, F64, I32 and I16 formats
+// are the soxr interleaved datatypes.
+type soxrBackend struct {
+	soxr     uintptr
+	ratio    float64 // output to input rate ratio
+	channels int
+	outSize  int    // output sample size in bytes
+	out      []byte // output buffer, reused by the calls
+}
+
+// newSoxr returns a soxr backend. A variable rate one accepts setRatio,
+// down to half the output to input ratio it is created with.
+func newSoxr(inRate, outRate float64, channels, inFormat, outFormat, quality, threads int, noDither, variable bool, extra soxrFlags) (backend, error) {
+	if err := loadSoxr(); err != nil {
+		return nil, err
+	}
+	ioSpec := soxrIOSpec{itype: uint32(inFormat), otype: uint32(outFormat), scale: 1, flags: uint64(extra.io)}
+	if noDither {
+		ioSpec.flags |= soxrNoDither
+	}
+	flags := uint64(extra.quality)
+	maxRatio := 1.0
+	if variable {
+		// The rates given to soxr_create set the largest input to output ratio.
+		flags, maxRatio = flags|soxrVR, 2
+	}
+	qSpec := soxrQuality(uint64(quality), flags)
+	runtimeSpec := soxrRuntimeSpec{log2MinDFTSize: 10, log2LargeDFTSize: 17, coefSizeKbytes: 400, numThreads: uint32(threads), flags: uint64(extra.runtime)}
+	var e *byte
+	soxr := soxrLib.create(inRate*maxRatio, outRate, uint32(channels), &e, &ioSpec, &qSpec, &runtimeSpec)
+	if err := soxrError(cString(e)); err != nil {
+		return nil, err
+	}
+	outSize := map[int]int{F32: 4, F64: 8, I32: 4, I16: 2}[outFormat]
+	s := &soxrBackend{soxr: soxr, ratio: outRate / inRate, channels: channels, outSize: outSize}
+	if variable {
+		if err := s.setRatio(s.ratio); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *soxrBackend) process(p []byte, frames int) ([]byte, error) {
+	framesOut := max(int(float64(frames)*s.ratio), 1)
+	s.out = growBuffer(s.out, framesOut*s.channels*s.outSize)
+	var read, done uintptr
+	err := soxrError(soxrLib.process(s.soxr, unsafe.Pointer(&p[0]), uintptr(frames), &read, unsafe.Pointer(&s.out[0]), uintptr(framesOut), &done))
+	if err != nil {
+		return nil, err
+	}
+	return s.out[:int(done)*s.channels*s.outSize], nil
+}
+
+func (s *soxrBackend) flush() ([]byte, error) {
+	framesOut := flushFrames
+	if s.out != nil {
+		framesOut = cap(s.out) / (s.channels * s.outSize)
+	}
+	s.out = growBuffer(s.out, framesOut*s.channels*s.outSize)
+	var done uintptr
+	// Flush any pending output by calling soxr_process with no input data.
+	if err := soxrError(soxrLib.process(s.soxr, nil, 0, nil, unsafe.Pointer(&s.out[0]), uintptr(framesOut), &done)); err != nil {
+		return nil, err
+	}
+	return s.out[:int(done)*s.channels*s.outSize], nil
+}
+
+func (s *soxrBackend) reserve(frames int) {
+	s.out = growBuffer(s.out, max(int(float64(frames)*s.ratio), 1)*s.channels*s.outSize)
+}
+
+func (s *soxrBackend) delay() float64 {
+	return soxrLib.delay(s.soxr)
+}
+
+func (s *soxrBackend) clips() int64 {
+	if n := soxrLib.numClips(s.soxr); n != nil {
+		return int64(*n)
+	}
+	return 0
+}
+
+func (s *soxrBackend) setRatio(ratio float64) error {
+	if ratio <= 0 {
+		return errors.New("invalid resampling ratio")
+	}
+	// soxr only changes the ratio of resamplers created for variable rate.
+	if err := soxrError(soxrLib.setIORatio(s.soxr, 1/ratio, 0)); err != nil {
+		return err
+	}
+	s.ratio = ratio
+	return nil
+}
+
+func (s *soxrBackend) reset() error {
+	return soxrError(soxrLib.clear(s.soxr))
+}
+
+func (s *soxrBackend) close() {
+	soxrLib.delete(s.soxr)
+	s.soxr = 0
+}
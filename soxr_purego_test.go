@@ -0,0 +1,60 @@
+//go:build (!cgo || loadsoxr) && (linux || darwin || freebsd) && (amd64 || arm64)
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestLoadSoxr loads libsoxr from the paths of RESAMPLE_SOXR_LIB that
+// aren't libsoxr, in a new process each since it is loaded once.
+func TestLoadSoxr(t *testing.T) {
+	if os.Getenv("RESAMPLE_TEST_LOAD") != "" {
+		err := loadSoxr()
+		if err == nil || !strings.HasPrefix(err.Error(), "libsoxr not found") {
+			t.Fatalf("Loading %s returned %v", os.Getenv("RESAMPLE_SOXR_LIB"), err)
+		}
+		if _, err = New(io.Discard, 8000, 16000, 1, I16, I16, MediumQ, WithBackend(BackendSoxr)); err == nil {
+			t.Error("New without libsoxr didn't return an error")
+		}
+		if _, err = New(io.Discard, 8000, 16000, 1, I16, I16, MediumQ, WithBackend(BackendFIR)); err != nil {
+			t.Error("New with the FIR backend failed:", err)
+		}
+		// The FIR backend is the default without libsoxr.
+		if b := defaultBackend(); b != BackendFIR {
+			t.Errorf("Default backend %d without libsoxr", b)
+		}
+		if _, err = New(io.Discard, 8000, 16000, 1, I16, I16, MediumQ); err != nil {
+			t.Error("New with the default backend failed:", err)
+		}
+		return
+	}
+	libs := []string{"/nonexistent/libsoxr.so.0"}
+	// A library without the soxr functions.
+	switch runtime.GOOS {
+	case "linux":
+		libs = append(libs, "libm.so.6")
+	case "darwin":
+		libs = append(libs, "/usr/lib/libSystem.B.dylib")
+	}
+	for _, lib := range libs {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLoadSoxr$")
+		cmd.Env = append(os.Environ(), "RESAMPLE_TEST_LOAD=1", "RESAMPLE_SOXR_LIB="+lib)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s: %v\n%s", lib, err, out)
+		}
+	}
+}
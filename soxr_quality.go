@@ -0,0 +1,79 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"math"
+	"unsafe"
+)
+
+// soxrQualitySpec is soxr_quality_spec_t, laid out as on 64-bit systems
+// where unsigned long has 64 bits.
+type soxrQualitySpec struct {
+	precision, phaseResponse, passbandEnd, stopbandBegin float64
+	e                                                    unsafe.Pointer
+	flags                                                uint64
+}
+
+// The soxr quality flags and recipe bits.
+const (
+	soxrRolloffMedium = 1       // SOXR_ROLLOFF_MEDIUM
+	soxrRolloffNone   = 2       // SOXR_ROLLOFF_NONE
+	soxrRolloffLSR2Q  = 3       // SOXR_ROLLOFF_LSR2Q
+	soxrVR            = 32      // SOXR_VR
+	soxrPromoteToLQ   = 64      // SOXR_PROMOTE_TO_LQ
+	soxrResetClear    = 1 << 31 // RESET_ON_CLEAR
+	soxrSteepFilter   = 0x40    // SOXR_STEEP_FILTER recipe bit
+)
+
+// soxrQuality returns the quality spec of a recipe, one of the quality
+// settings with the phase response and steep filter bits, and flags,
+// computed as soxr_quality_spec does, since purego can't return structs.
+func soxrQuality(recipe, flags uint64) soxrQualitySpec {
+	quality := recipe & 0xf
+	q := soxrQualitySpec{
+		phaseResponse: []float64{50, 25, 100, 0}[(recipe&0x30)>>4],
+		stopbandBegin: 1,
+		flags:         flags,
+	}
+	if quality < SincBestQ {
+		q.flags |= soxrResetClear
+	}
+	switch {
+	case quality == Quick:
+		q.precision = 0
+	case quality <= 3:
+		q.precision = 16
+	case quality < SincBestQ:
+		q.precision = float64(4 + quality*4)
+	default:
+		q.precision = float64(55 - quality*4)
+	}
+	rej := q.precision * (math.Log10(2) * 20)
+	to3dB := (1.6e-6*rej-7.5e-4)*rej + 0.646
+	switch {
+	case quality == LowQ:
+		q.passbandEnd = 1385.0 / 2048
+	case quality < SincBestQ:
+		q.passbandEnd = 1 - 0.05/to3dB
+	default:
+		// The libsamplerate emulation recipes.
+		q.passbandEnd = float64([]float32{.931, .832, .663}[quality-SincBestQ])
+	}
+	switch {
+	case quality <= MediumQ:
+		q.flags = q.flags&^soxrRolloffNone | soxrRolloffMedium
+	case quality == SincFastestQ:
+		q.flags = q.flags&^soxrRolloffNone | soxrRolloffLSR2Q | soxrPromoteToLQ
+	}
+	if recipe&soxrSteepFilter != 0 {
+		q.passbandEnd = 1 - 0.01/to3dB
+	}
+	return q
+}
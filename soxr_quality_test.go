@@ -0,0 +1,37 @@
+//go:build cgo && !loadsoxr
+
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSoxrQuality checks the quality specs computed without cgo against
+// soxr_quality_spec, for every quality setting, phase response, steep
+// filter bit and combination of the flags below SOXR_PROMOTE_TO_LQ, with
+// SOXR_VR.
+func TestSoxrQuality(t *testing.T) {
+	qualities := []uint64{Quick, LowQ, MediumQ, 3, HighQ, 5, VeryHighQ, 7, SincBestQ, SincMediumQ, SincFastestQ}
+	for _, quality := range qualities {
+		for _, bits := range []uint64{0, 0x10, 0x20, 0x30, soxrSteepFilter, soxrSteepFilter | 0x30} {
+			recipe := quality | bits
+			for flags := uint64(0); flags < soxrPromoteToLQ*2; flags++ {
+				got, want := soxrQuality(recipe, flags), cSoxrQuality(recipe, flags)
+				if got.precision != want.precision || got.phaseResponse != want.phaseResponse ||
+					math.Abs(got.passbandEnd-want.passbandEnd) > 1e-15 || got.stopbandBegin != want.stopbandBegin ||
+					got.e != want.e || got.flags != want.flags {
+					t.Fatalf("Recipe %#x, flags %#x: spec %+v, libsoxr %+v", recipe, flags, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+)
+
+// Splitter resamples each channel of interleaved input on its own, with a
+// mono Resampler per channel writing to a destination of its own, for
+// channels that go different ways such as the legs of a call.
+type Splitter struct {
+	channels []*Resampler
+	size     int // input sample size in bytes
+}
+
+// NewSplitter returns a Splitter of input with one channel per writer,
+// resampling channel c to writers[c]. The other parameters and the options
+// are those of New, applied to each mono Resampler.
+func NewSplitter(writers []io.Writer, inputRate, outputRate float64, inFormat, outFormat, quality int, opts ...Option) (*Splitter, error) {
+	if len(writers) == 0 {
+		return nil, errors.New("invalid channels number")
+	}
+	size, err := sizeOf(inFormat)
+	if err != nil {
+		return nil, err
+	}
+	s := &Splitter{size: size}
+	for _, w := range writers {
+		r, err := New(w, inputRate, outputRate, 1, inFormat, outFormat, quality, opts...)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.channels = append(s.channels, r)
+	}
+	return s, nil
+}
+
+// Channel returns the Resampler of channel c, to read its position.
+func (s *Splitter) Channel(c int) *Resampler {
+	return s.channels[c]
+}
+
+// Write de-interleaves the frames of p and resamples each channel. Trailing
+// bytes of an incomplete frame are ignored, as Write of a Resampler does.
+func (s *Splitter) Write(p []byte) (int, error) {
+	frame := s.size * len(s.channels)
+	frames := len(p) / frame
+	if len(p) > 0 && frames == 0 {
+		return 0, errors.New("incomplete input frame data")
+	}
+	buf := make([]byte, frames*s.size)
+	for c, r := range s.channels {
+		for i := 0; i < frames; i++ {
+			copy(buf[i*s.size:(i+1)*s.size], p[i*frame+c*s.size:])
+		}
+		if _, err := r.Write(buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes and closes the Resampler of every channel, returning the
+// first error.
+func (s *Splitter) Close() error {
+	var err error
+	for _, r := range s.channels {
+		if e := r.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
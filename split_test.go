@@ -0,0 +1,57 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestSplitter(t *testing.T) {
+	in := make([]byte, 8000*2*2)
+	mono := [2][]byte{make([]byte, 8000*2), make([]byte, 8000*2)}
+	for i := 0; i < 8000; i++ {
+		for c := range mono {
+			v := uint16(int16((i%100)*(c*2-1)) * 100)
+			binary.LittleEndian.PutUint16(in[4*i+2*c:], v)
+			binary.LittleEndian.PutUint16(mono[c][2*i:], v)
+		}
+	}
+	var out [2]bytes.Buffer
+	s, err := NewSplitter([]io.Writer{&out[0], &out[1]}, 8000.0, 16000.0, I16, I16, MediumQ, WithDither(DitherNone))
+	if err != nil {
+		t.Fatal("Failed to create a Splitter:", err)
+	}
+	if _, err = s.Write(in); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	for c := range mono {
+		var want bytes.Buffer
+		res, err := New(&want, 8000.0, 16000.0, 1, I16, I16, MediumQ, WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		res.Write(mono[c])
+		res.Close()
+		if !bytes.Equal(out[c].Bytes(), want.Bytes()) {
+			t.Errorf("Channel %d differs from resampling it alone", c)
+		}
+		if n, _ := s.Channel(c).InputPosition(); n != 8000 {
+			t.Errorf("Channel %d: %d input frames", c, n)
+		}
+	}
+	if _, err = NewSplitter(nil, 8000.0, 16000.0, I16, I16, MediumQ); err == nil {
+		t.Error("No error without writers")
+	}
+}
@@ -0,0 +1,240 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// Stretcher changes the tempo of PCM sound data without changing its
+// pitch, the duration of the output being that of the input divided by
+// the tempo. It implements WSOLA: overlapping windows of the input are
+// taken at the tempo's pace and added back at the original one, each
+// window shifted by up to 10 ms to continue the waveform of the previous
+// one. It writes in the input format and can feed a Resampler, or be fed
+// by one, to change both the tempo and the rate.
+type Stretcher struct {
+	destination io.Writer
+	channels    int
+	format      int
+	frameSize   int       // input frame size in bytes
+	tempo       float64   // input frames per output frame
+	hop         int       // output frames per window, half its length
+	delta       int       // frames a window may be shifted by
+	window      []float64 // Hann window of 2*hop frames
+	in          []float64 // buffered input frames, interleaved
+	inStart     int64     // input frame of in[0]
+	inFrames    int64     // input frames written
+	pos         float64   // input frame of the next window before its shift
+	prev        int64     // input frame of the last window, -1 before the first
+	tail        []float64 // second half of the last window, to overlap
+	outFrames   int64     // output frames written
+	expected    float64   // output frames of the input written
+	closed      bool
+}
+
+// NewStretcher returns a Stretcher of input at rate, with channels of
+// samples of format, changing its tempo by tempo, from 0.25 to 4, and
+// writing the output to writer in the same format.
+func NewStretcher(writer io.Writer, rate float64, channels, format int, tempo float64) (*Stretcher, error) {
+	if writer == nil {
+		return nil, errors.New("io.Writer is nil")
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, errors.New("invalid sampling rate")
+	}
+	if channels <= 0 || channels > maxChannels {
+		return nil, errors.New("invalid channels number")
+	}
+	size, err := sizeOf(format)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stretcher{
+		destination: writer,
+		channels:    channels,
+		format:      format,
+		frameSize:   size * channels,
+		hop:         max(int(math.Round(rate*0.015)), 1),
+		delta:       int(math.Round(rate * 0.01)),
+	}
+	if err = s.SetTempo(tempo); err != nil {
+		return nil, err
+	}
+	n := 2 * s.hop
+	s.window = make([]float64, n)
+	for i := range s.window {
+		// Periodic, so that windows overlapping by half add up to 1.
+		s.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	s.reset()
+	return s, nil
+}
+
+// SetTempo changes the tempo of the following Writes.
+func (s *Stretcher) SetTempo(tempo float64) error {
+	if !(tempo >= 0.25 && tempo <= 4) {
+		return errors.New("invalid tempo")
+	}
+	s.tempo = tempo
+	return nil
+}
+
+// Write stretches the frames of p. Trailing bytes of an incomplete frame
+// are ignored, as Write of a Resampler does. Output is written as windows
+// are completed, lagging the input by about 40 ms, until Close.
+func (s *Stretcher) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, ErrClosed
+	}
+	frames := len(p) / s.frameSize
+	if len(p) > 0 && frames == 0 {
+		return 0, errors.New("incomplete input frame data")
+	}
+	if frames == 0 {
+		return 0, nil
+	}
+	s.in = append(s.in, toFloat64(p[:frames*s.frameSize], s.format)...)
+	s.inFrames += int64(frames)
+	s.expected += float64(frames) / s.tempo
+	if err := s.output(s.stretch(s.inFrames)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close stretches the rest of the input, writes it and frees the
+// Stretcher. Closing a closed Stretcher does nothing.
+func (s *Stretcher) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	// Silence lets the last windows be taken.
+	s.in = append(s.in, make([]float64, (2*s.hop+s.delta)*s.channels)...)
+	out := append(s.stretch(s.inFrames), s.tail...)
+	n := int64(math.Round(s.expected)) - s.outFrames
+	if int64(len(out)/s.channels) < n {
+		out = append(out, make([]float64, int(n)*s.channels-len(out))...)
+	}
+	err := s.output(out[:max(n, 0)*int64(s.channels)])
+	s.in, s.tail = nil, nil
+	return err
+}
+
+// Reset writes the rest of the output as Close does and permits reusing
+// the Stretcher for a new stream written to writer.
+func (s *Stretcher) Reset(writer io.Writer) error {
+	if s.closed {
+		return ErrClosed
+	}
+	err := s.Close()
+	s.closed = false
+	s.destination = writer
+	s.reset()
+	return err
+}
+
+// reset clears the stream state.
+func (s *Stretcher) reset() {
+	s.in = s.in[:0]
+	s.inStart, s.inFrames, s.outFrames = 0, 0, 0
+	s.pos, s.prev, s.expected = 0, -1, 0
+	s.tail = make([]float64, s.hop*s.channels)
+}
+
+// stretch overlaps the windows whose nominal position is before end that
+// the buffered input allows, and returns the output frames completed.
+func (s *Stretcher) stretch(end int64) []float64 {
+	c, n := s.channels, 2*s.hop
+	var out []float64
+	for {
+		nominal := int64(math.Round(s.pos))
+		avail := s.inStart + int64(len(s.in)/c)
+		if nominal >= end || nominal+int64(s.delta+n) > avail {
+			break
+		}
+		at := nominal
+		if s.prev >= 0 {
+			at = s.search(nominal)
+		}
+		x := s.in[(at-s.inStart)*int64(c):]
+		for i := 0; i < s.hop; i++ {
+			w := s.window[i]
+			if s.prev < 0 {
+				// The first window doesn't fade in.
+				w = 1
+			}
+			for ch := 0; ch < c; ch++ {
+				out = append(out, s.tail[i*c+ch]+w*x[i*c+ch])
+			}
+		}
+		for i := s.hop; i < n; i++ {
+			for ch := 0; ch < c; ch++ {
+				s.tail[(i-s.hop)*c+ch] = s.window[i] * x[i*c+ch]
+			}
+		}
+		s.prev = at
+		s.pos += float64(s.hop) * s.tempo
+		// Drop the input no longer needed by the next window.
+		keep := min(at+int64(s.hop), int64(math.Round(s.pos))-int64(s.delta))
+		if drop := keep - s.inStart; drop > 0 {
+			s.in = s.in[:copy(s.in, s.in[drop*int64(c):])]
+			s.inStart = keep
+		}
+	}
+	return out
+}
+
+// search returns the input frame, within delta of nominal, of the window
+// whose first half best matches the continuation of the previous window.
+func (s *Stretcher) search(nominal int64) int64 {
+	c := s.channels
+	template := s.in[(s.prev+int64(s.hop)-s.inStart)*int64(c):][:s.hop*c]
+	best, bestScore := nominal, math.Inf(-1)
+	for at := max(nominal-int64(s.delta), s.inStart); at <= nominal+int64(s.delta); at++ {
+		x := s.in[(at-s.inStart)*int64(c):][:s.hop*c]
+		var dot, energy float64
+		for i, v := range x {
+			dot += template[i] * v
+			energy += v * v
+		}
+		if score := dot / math.Sqrt(energy+1e-12); score > bestScore {
+			best, bestScore = at, score
+		}
+	}
+	return best
+}
+
+// output writes stretched frames to the destination in the format.
+func (s *Stretcher) output(out []float64) error {
+	if len(out) == 0 {
+		return nil
+	}
+	var p []byte
+	if isG711(s.format) {
+		p = compressG711(fromFloat64(out, I16), s.format)
+	} else {
+		p = fromFloat64(out, s.format)
+	}
+	s.outFrames += int64(len(out) / s.channels)
+	for len(p) > 0 {
+		n, err := s.destination.Write(p)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		p = p[n:]
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+// crossings returns the number of rising zero crossings of s per second at rate.
+func crossings(s []float64, rate float64) float64 {
+	n := 0
+	for i := 1; i < len(s); i++ {
+		if s[i-1] < 0 && s[i] >= 0 {
+			n++
+		}
+	}
+	return float64(n) / (float64(len(s)) / rate)
+}
+
+func TestStretcher(t *testing.T) {
+	p, _ := testsignal.Encode(testsignal.Sine(440, 0.5, 16000, 16000), 2, testsignal.I16)
+	for _, tempo := range []float64{0.5, 0.8, 1, 1.25, 2} {
+		var out bytes.Buffer
+		s, err := NewStretcher(&out, 16000, 2, I16, tempo)
+		if err != nil {
+			t.Fatal("Failed to create a Stretcher:", err)
+		}
+		for i := 0; i < len(p); i += 1000 {
+			if _, err = s.Write(p[i:min(i+1000, len(p))]); err != nil {
+				t.Fatal("Write failed:", err)
+			}
+		}
+		if err = s.Close(); err != nil {
+			t.Fatal("Close failed:", err)
+		}
+		got := toFloat64(out.Bytes(), I16)
+		if want := int(math.Round(16000 / tempo)); len(got)/2 != want {
+			t.Errorf("Tempo %g: %d frames out, expected %d", tempo, len(got)/2, want)
+		}
+		left := make([]float64, len(got)/2)
+		for i := range left {
+			left[i] = got[2*i]
+		}
+		if f := crossings(left, 16000); math.Abs(f-440) > 10 {
+			t.Errorf("Tempo %g: pitch of %g Hz, expected 440 Hz", tempo, f)
+		}
+		var peak float64
+		for _, v := range got[:len(got)-1000] {
+			peak = math.Max(peak, math.Abs(v))
+		}
+		if peak < 0.45 || peak > 0.55 {
+			t.Errorf("Tempo %g: peak %g, expected 0.5", tempo, peak)
+		}
+		if _, err = s.Write(p); err != ErrClosed {
+			t.Errorf("Write after Close returned %v", err)
+		}
+	}
+	if _, err := NewStretcher(io.Discard, 16000, 1, I16, 8); err == nil {
+		t.Error("Invalid tempo didn't return an error")
+	}
+	if _, err := NewStretcher(io.Discard, 16000, 1, 11, 1); err == nil {
+		t.Error("Invalid format didn't return an error")
+	}
+}
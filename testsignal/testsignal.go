@@ -0,0 +1,153 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package testsignal generates deterministic test signals: sines, swept sines,
+white and pink noise and impulse trains, at any sampling rate.
+
+Generators return n float64 samples of a single channel in the [-1, 1)
+range. Encode turns them into PCM data of the resample package formats,
+ready to be written to a Resampler. Noise is seeded, so a signal is the
+same on every run.
+*/
+package testsignal
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+)
+
+const (
+	// Sample formats, with the values of the resample package formats.
+	// Samples are little-endian.
+	F32 = 0 // 32-bit floating point PCM
+	F64 = 1 // 64-bit floating point PCM
+	I32 = 2 // 32-bit signed linear PCM
+	I16 = 3 // 16-bit signed linear PCM
+)
+
+// Sine returns a sine of freq Hz and peak amplitude amp sampled at rate.
+func Sine(freq, amp, rate float64, n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/rate)
+	}
+	return x
+}
+
+// Chirp returns a sine of peak amplitude amp sampled at rate, swept
+// exponentially from the frequency from to the frequency to over the n
+// samples, spending the same time in each octave.
+func Chirp(from, to, amp, rate float64, n int) []float64 {
+	if from == to {
+		return Sine(from, amp, rate, n)
+	}
+	x := make([]float64, n)
+	d := float64(n) / rate
+	k := math.Log(to / from)
+	for i := range x {
+		t := float64(i) / rate
+		// The phase is the integral of the frequency from*(to/from)^(t/d).
+		phase := 2 * math.Pi * from * d / k * (math.Exp(t/d*k) - 1)
+		x[i] = amp * math.Sin(phase)
+	}
+	return x
+}
+
+// WhiteNoise returns uniformly distributed white noise of peak amplitude
+// amp, from the random numbers of seed.
+func WhiteNoise(amp float64, n int, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = amp * (2*rng.Float64() - 1)
+	}
+	return x
+}
+
+// PinkNoise returns pink noise, falling by 3 dB per octave, of peak
+// amplitude amp, from the random numbers of seed. White noise is filtered
+// with the refined filter of Paul Kellet.
+func PinkNoise(amp float64, n int, seed int64) []float64 {
+	x := WhiteNoise(1, n, seed)
+	var b [7]float64
+	var peak float64
+	for i, w := range x {
+		b[0] = 0.99886*b[0] + w*0.0555179
+		b[1] = 0.99332*b[1] + w*0.0750759
+		b[2] = 0.96900*b[2] + w*0.1538520
+		b[3] = 0.86650*b[3] + w*0.3104856
+		b[4] = 0.55000*b[4] + w*0.5329522
+		b[5] = -0.7616*b[5] - w*0.0168980
+		x[i] = b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + w*0.5362
+		b[6] = w * 0.115926
+		peak = math.Max(peak, math.Abs(x[i]))
+	}
+	if peak > 0 {
+		for i := range x {
+			x[i] *= amp / peak
+		}
+	}
+	return x
+}
+
+// Impulses returns a train of impulses of amplitude amp repeating freq
+// times per second at rate, the first one on the first sample. Each
+// impulse falls on the sample nearest to its time.
+func Impulses(freq, amp, rate float64, n int) []float64 {
+	x := make([]float64, n)
+	for k := 0; ; k++ {
+		i := int(math.Round(float64(k) * rate / freq))
+		if i >= n {
+			break
+		}
+		x[i] = amp
+	}
+	return x
+}
+
+// Encode returns the samples of x as PCM data of format, with each sample
+// repeated in channels channels. Integer samples are rounded and clipped.
+func Encode(x []float64, channels, format int) ([]byte, error) {
+	if channels <= 0 {
+		return nil, errors.New("invalid channels number")
+	}
+	var size int
+	switch format {
+	case F64:
+		size = 8
+	case F32, I32:
+		size = 4
+	case I16:
+		size = 2
+	default:
+		return nil, errors.New("invalid format setting")
+	}
+	p := make([]byte, len(x)*channels*size)
+	for i, v := range x {
+		s := p[i*channels*size:]
+		switch format {
+		case F64:
+			binary.LittleEndian.PutUint64(s, math.Float64bits(v))
+		case F32:
+			binary.LittleEndian.PutUint32(s, math.Float32bits(float32(v)))
+		case I32:
+			v = math.Max(-1<<31, math.Min(1<<31-1, math.Round(v*(1<<31))))
+			binary.LittleEndian.PutUint32(s, uint32(int32(v)))
+		case I16:
+			v = math.Max(-1<<15, math.Min(1<<15-1, math.Round(v*(1<<15))))
+			binary.LittleEndian.PutUint16(s, uint16(int16(v)))
+		}
+		for c := 1; c < channels; c++ {
+			copy(s[c*size:(c+1)*size], s[:size])
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,117 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package testsignal
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// crossings returns the number of rising zero crossings of x.
+func crossings(x []float64) int {
+	var n int
+	for i := 1; i < len(x); i++ {
+		if x[i-1] < 0 && x[i] >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// roughness returns the power of the differences of successive samples of
+// x relative to the power of x, 2 for white noise and less for pink noise.
+func roughness(x []float64) float64 {
+	var d, p float64
+	for i := 1; i < len(x); i++ {
+		d += (x[i] - x[i-1]) * (x[i] - x[i-1])
+		p += x[i] * x[i]
+	}
+	return d / p
+}
+
+func peak(x []float64) float64 {
+	var p float64
+	for _, v := range x {
+		p = math.Max(p, math.Abs(v))
+	}
+	return p
+}
+
+func TestGenerators(t *testing.T) {
+	if n := crossings(Sine(1000, 0.5, 44100, 44100)); n < 999 || n > 1000 {
+		t.Errorf("Sine: %d cycles in a second", n)
+	}
+	// 20 Hz to 20 kHz in 10 s, about an octave per second: 20 / ln 2 cycles
+	// in the first second, 10000 / ln 2 in the last.
+	chirp := Chirp(20, 20000, 0.5, 48000, 480000)
+	if first, last := crossings(chirp[:48000]), crossings(chirp[9*48000:]); first < 28 || first > 29 || last < 14400 || last > 14460 {
+		t.Errorf("Chirp: %d cycles in the first second, %d in the last", first, last)
+	}
+	if p := peak(chirp); p > 0.5 {
+		t.Errorf("Chirp peak: %f", p)
+	}
+	white, pink := WhiteNoise(0.25, 48000, 1), PinkNoise(0.25, 48000, 1)
+	if !equal(white, WhiteNoise(0.25, 48000, 1)) || !equal(pink, PinkNoise(0.25, 48000, 1)) {
+		t.Error("Noise isn't the same for the same seed")
+	}
+	if equal(white, WhiteNoise(0.25, 48000, 2)) {
+		t.Error("Noise is the same for different seeds")
+	}
+	if p := peak(white); p > 0.25 || p < 0.24 {
+		t.Errorf("White noise peak: %f", p)
+	}
+	if p := peak(pink); p != 0.25 {
+		t.Errorf("Pink noise peak: %f", p)
+	}
+	if r := roughness(white); math.Abs(r-2) > 0.05 {
+		t.Errorf("White noise roughness: %f", r)
+	}
+	if r := roughness(pink); r > 0.5 {
+		t.Errorf("Pink noise roughness: %f", r)
+	}
+	imp := Impulses(3, 1, 10, 10)
+	if !equal(imp, []float64{1, 0, 0, 1, 0, 0, 0, 1, 0, 0}) {
+		t.Errorf("Impulses: %v", imp)
+	}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEncode(t *testing.T) {
+	x := []float64{0.5, -1, 1}
+	for _, tc := range []struct {
+		format int
+		want   []byte
+	}{
+		{I16, []byte{0x00, 0x40, 0x00, 0x40, 0x00, 0x80, 0x00, 0x80, 0xff, 0x7f, 0xff, 0x7f}},
+		{F32, []byte{0, 0, 0, 0x3f, 0, 0, 0, 0x3f, 0, 0, 0x80, 0xbf, 0, 0, 0x80, 0xbf, 0, 0, 0x80, 0x3f, 0, 0, 0x80, 0x3f}},
+	} {
+		p, err := Encode(x, 2, tc.format)
+		if err != nil || !bytes.Equal(p, tc.want) {
+			t.Errorf("Format %d: % x, %v", tc.format, p, err)
+		}
+	}
+	if p, _ := Encode(x, 1, F64); len(p) != 24 {
+		t.Errorf("F64: %d bytes", len(p))
+	}
+	if _, err := Encode(x, 1, 8); err == nil {
+		t.Error("No error for an invalid format")
+	}
+}
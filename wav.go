@@ -0,0 +1,198 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/zaf/resample/wav"
+)
+
+// NewFromWAV reads the WAV or Wave64 header from src and returns a Resampler
+// configured with the channels, sample format and sampling rate found in it.
+// The sample data of the data chunk is then resampled to outRate and
+// written to dst, IMA ADPCM data being decoded to I16 first.
+// The Resampler must be closed to flush its remaining output.
+func NewFromWAV(dst io.Writer, src io.Reader, outRate float64, opts ...Option) (*Resampler, error) {
+	h, err := wav.ReadHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	return newFromWAVHeader(dst, src, h, outRate, opts)
+}
+
+// ConvertWAV resamples the WAV or Wave64 file read from src to outRate and
+// writes the result to dst in the same container. The channel mask and
+// the metadata chunks, such as LIST, iXML or axml, are carried over, cue
+// points and the bext time reference rescaled to the new rate. Chunks
+// following the data chunk are only preserved when dst is an io.WriteSeeker.
+// WithMetadata selects the chunks to keep.
+func ConvertWAV(dst io.Writer, src io.Reader, outRate float64, opts ...Option) error {
+	h, err := wav.ReadHeader(src)
+	if err != nil {
+		return err
+	}
+	inFormat, err := WAVSampleFormat(h.Format)
+	if err != nil {
+		return err
+	}
+	o := applyOptions(opts, inFormat)
+	channels, mask := h.Channels, h.ChannelMask
+	if o.mix != nil {
+		channels, mask = len(o.mix), o.outMask
+	}
+	f, err := WAVFormat(o.outFormat, channels, outRate)
+	if err != nil {
+		return err
+	}
+	f.ChannelMask = mask
+	ratio := outRate / float64(h.SampleRate)
+	newWriter := wav.NewWriter
+	readChunks := wav.ReadChunks
+	if h.W64 {
+		newWriter = wav.NewW64Writer
+		readChunks = wav.ReadW64Chunks
+	}
+	w, err := newWriter(dst, f, wavMetadata(h.Chunks, ratio, o.keepChunk)...)
+	if err != nil {
+		return err
+	}
+	r, err := newFromWAVHeader(w, src, h, outRate, opts)
+	if err != nil {
+		return err
+	}
+	if err = r.Close(); err != nil {
+		return err
+	}
+	// Metadata may follow the sample data. Errors here only mean there is
+	// nothing more to carry over.
+	if _, err = io.CopyN(io.Discard, src, h.DataPad()); err == nil {
+		trailer, _ := readChunks(src)
+		w.Trailer = wavMetadata(trailer, ratio, o.keepChunk)
+	}
+	return w.Close()
+}
+
+// WAVMetadata returns the metadata chunks of a WAV or Wave64 file, as
+// found in its header or following its data, to be written to a version
+// of it resampled by ratio, the output rate over the input rate. Cue
+// points and the bext time reference are rescaled, fact and smpl chunks
+// dropped. WithMetadata selects the chunks to keep, other options are ignored.
+func WAVMetadata(chunks []wav.Chunk, ratio float64, opts ...Option) []wav.Chunk {
+	return wavMetadata(chunks, ratio, applyOptions(opts, 0).keepChunk)
+}
+
+// newFromWAVHeader resamples the data chunk described by h.
+func newFromWAVHeader(dst io.Writer, src io.Reader, h *wav.Header, outRate float64, opts []Option) (*Resampler, error) {
+	inFormat, err := WAVSampleFormat(h.Format)
+	if err != nil {
+		return nil, err
+	}
+	data := io.LimitReader(src, h.DataSize)
+	frameSize := h.BlockAlign
+	if h.Tag == wav.FormatIMAADPCM {
+		if data, err = wav.NewIMAReader(data, h.Format); err != nil {
+			return nil, err
+		}
+		frameSize = 2 * h.Channels
+	}
+	return newFromReader(dst, data, float64(h.SampleRate), outRate, h.Channels, inFormat, frameSize, opts)
+}
+
+// WAVSampleFormat returns the Resampler format of the samples of a WAV
+// format, I16 for IMA ADPCM, which NewFromWAV decodes.
+func WAVSampleFormat(f wav.Format) (int, error) {
+	switch {
+	case f.Tag == wav.FormatPCM && f.BitsPerSample == 16:
+		return I16, nil
+	case f.Tag == wav.FormatPCM && f.BitsPerSample == 32:
+		return I32, nil
+	case f.Tag == wav.FormatFloat && f.BitsPerSample == 32:
+		return F32, nil
+	case f.Tag == wav.FormatFloat && f.BitsPerSample == 64:
+		return F64, nil
+	case f.Tag == wav.FormatMuLaw && f.BitsPerSample == 8:
+		return MuLaw, nil
+	case f.Tag == wav.FormatALaw && f.BitsPerSample == 8:
+		return ALaw, nil
+	case f.Tag == wav.FormatIMAADPCM && f.BitsPerSample == 4:
+		// Decoded to 16-bit linear PCM
+		return I16, nil
+	}
+	return 0, errors.New("unsupported WAV sample format")
+}
+
+// WAVFormat returns the WAV format of channels of samples of a Resampler
+// format at rate, I24In32 samples being stored in 32 bits.
+func WAVFormat(format, channels int, rate float64) (wav.Format, error) {
+	f := wav.Format{Channels: channels, SampleRate: int(math.Round(rate))}
+	switch format {
+	case I16:
+		f.Tag, f.BitsPerSample = wav.FormatPCM, 16
+	case I32, I24In32:
+		f.Tag, f.BitsPerSample = wav.FormatPCM, 32
+	case F32:
+		f.Tag, f.BitsPerSample = wav.FormatFloat, 32
+	case F64:
+		f.Tag, f.BitsPerSample = wav.FormatFloat, 64
+	case MuLaw:
+		f.Tag, f.BitsPerSample = wav.FormatMuLaw, 8
+	case ALaw:
+		f.Tag, f.BitsPerSample = wav.FormatALaw, 8
+	default:
+		return f, errors.New("invalid format setting")
+	}
+	f.BlockAlign = channels * f.BitsPerSample / 8
+	f.ByteRate = f.SampleRate * f.BlockAlign
+	return f, nil
+}
+
+// bextTimeReference is the offset of the 64-bit sample count time
+// reference in a Broadcast Wave bext chunk.
+const bextTimeReference = 338
+
+// wavMetadata returns the chunks worth keeping after resampling by ratio,
+// as selected by keep. Cue point positions and the bext time reference
+// are rescaled, fact and smpl chunks, describing the old sample data, are
+// dropped and everything else is copied as it is.
+func wavMetadata(chunks []wav.Chunk, ratio float64, keep func(id string) bool) []wav.Chunk {
+	var kept []wav.Chunk
+	for _, c := range chunks {
+		if !keep(c.ID) {
+			continue
+		}
+		switch c.ID {
+		case "fact", "smpl":
+		case "cue ":
+			points, err := wav.ParseCue(c.Data)
+			if err != nil {
+				continue
+			}
+			for i := range points {
+				points[i].Position = uint32(math.Round(float64(points[i].Position) * ratio))
+				points[i].SampleOffset = uint32(math.Round(float64(points[i].SampleOffset) * ratio))
+			}
+			kept = append(kept, wav.CueChunk(points))
+		case "bext":
+			if len(c.Data) < bextTimeReference+8 {
+				continue
+			}
+			b := append([]byte(nil), c.Data...)
+			t := binary.LittleEndian.Uint64(b[bextTimeReference:])
+			binary.LittleEndian.PutUint64(b[bextTimeReference:], uint64(math.Round(float64(t)*ratio)))
+			kept = append(kept, wav.Chunk{ID: c.ID, Data: b})
+		default:
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
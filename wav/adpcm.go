@@ -0,0 +1,159 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var imaIndexTable = [16]int{
+	-1, -1, -1, -1, 2, 4, 6, 8,
+	-1, -1, -1, -1, 2, 4, 6, 8,
+}
+
+var imaStepTable = [89]int{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17,
+	19, 21, 23, 25, 28, 31, 34, 37, 41, 45,
+	50, 55, 60, 66, 73, 80, 88, 97, 107, 118,
+	130, 143, 157, 173, 190, 209, 230, 253, 279, 307,
+	337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+	876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358,
+	5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899,
+	15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794, 32767,
+}
+
+// imaDecoder holds the per channel IMA ADPCM state.
+type imaDecoder struct {
+	predictor int
+	index     int
+}
+
+// decode expands a 4-bit code to a 16-bit sample.
+func (d *imaDecoder) decode(code byte) int16 {
+	step := imaStepTable[d.index]
+	diff := step >> 3
+	if code&1 != 0 {
+		diff += step >> 2
+	}
+	if code&2 != 0 {
+		diff += step >> 1
+	}
+	if code&4 != 0 {
+		diff += step
+	}
+	if code&8 != 0 {
+		d.predictor -= diff
+	} else {
+		d.predictor += diff
+	}
+	if d.predictor > 32767 {
+		d.predictor = 32767
+	} else if d.predictor < -32768 {
+		d.predictor = -32768
+	}
+	d.index += imaIndexTable[code&0x0f]
+	if d.index < 0 {
+		d.index = 0
+	} else if d.index > 88 {
+		d.index = 88
+	}
+	return int16(d.predictor)
+}
+
+// imaReader decodes IMA ADPCM blocks into interleaved 16-bit PCM.
+type imaReader struct {
+	r        io.Reader
+	channels int
+	block    []byte // encoded block
+	out      []byte // decoded block
+	buf      []byte // decoded data not yet read
+	state    []imaDecoder
+}
+
+// NewIMAReader returns a Reader that decodes the IMA/DVI ADPCM data of r,
+// laid out in blocks as described by f, to 16-bit little-endian linear PCM.
+func NewIMAReader(r io.Reader, f Format) (io.Reader, error) {
+	if f.Tag != FormatIMAADPCM || f.BitsPerSample != 4 {
+		return nil, errors.New("wav: not an IMA ADPCM format")
+	}
+	if f.Channels == 0 || f.BlockAlign <= 4*f.Channels || (f.BlockAlign-4*f.Channels)%(4*f.Channels) != 0 {
+		return nil, ErrBadFormat
+	}
+	return &imaReader{
+		r:        r,
+		channels: f.Channels,
+		block:    make([]byte, f.BlockAlign),
+		out:      make([]byte, 2*f.Channels*f.SamplesPerBlock()),
+		state:    make([]imaDecoder, f.Channels),
+	}, nil
+}
+
+// SamplesPerBlock returns the number of samples per channel in an IMA ADPCM block.
+func (f Format) SamplesPerBlock() int {
+	if f.Channels == 0 {
+		return 0
+	}
+	return (f.BlockAlign-4*f.Channels)*2/f.Channels + 1
+}
+
+func (d *imaReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if err := d.decodeBlock(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// decodeBlock reads and decodes the next block. A truncated final
+// block is decoded up to its last complete group of samples.
+func (d *imaReader) decodeBlock() error {
+	n, err := io.ReadFull(d.r, d.block)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	head := 4 * d.channels
+	if n < head {
+		return io.EOF
+	}
+	groups := (n - head) / head
+	out := d.out[:2*d.channels*(1+8*groups)]
+	// Block header: the first sample and the step index of each channel.
+	for c := range d.state {
+		h := d.block[4*c:]
+		d.state[c].predictor = int(int16(binary.LittleEndian.Uint16(h)))
+		d.state[c].index = int(h[2])
+		if d.state[c].index > 88 {
+			return errors.New("wav: invalid IMA ADPCM step index")
+		}
+		binary.LittleEndian.PutUint16(out[2*c:], uint16(h[0])|uint16(h[1])<<8)
+	}
+	// Each group holds 4 bytes, 8 samples low nibble first, per channel.
+	data := d.block[head:]
+	for g := 0; g < groups; g++ {
+		for c := range d.state {
+			codes := data[(g*d.channels+c)*4:][:4]
+			for i := 0; i < 8; i++ {
+				code := codes[i/2] >> (4 * uint(i&1))
+				frame := 1 + g*8 + i
+				binary.LittleEndian.PutUint16(out[2*(frame*d.channels+c):], uint16(d.state[c].decode(code&0x0f)))
+			}
+		}
+	}
+	d.buf = out
+	return nil
+}
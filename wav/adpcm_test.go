@@ -0,0 +1,70 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// A stereo block with a single group of 8 samples per channel.
+var imaBlock = []byte{
+	100, 0, 10, 0, // channel 0 header: predictor 100, step index 10
+	0x38, 0xff, 0, 0, // channel 1 header: predictor -200, step index 0
+	0x71, 0x80, 0x3f, 0x9a, // channel 0 samples
+	0x07, 0x70, 0xff, 0x00, // channel 1 samples
+}
+
+var imaDecoded = []int16{100, -200, 106, -189, 137, -187, 141, -186, 137, -163, 81, -215, 138, -327, 101, -311, 82, -297}
+
+var imaFormat = Format{Tag: FormatIMAADPCM, Channels: 2, SampleRate: 8000, BlockAlign: 16, BitsPerSample: 4}
+
+func TestIMAReader(t *testing.T) {
+	if n := imaFormat.SamplesPerBlock(); n != 9 {
+		t.Fatalf("Samples per block: %d, expecting: 9", n)
+	}
+	// Two blocks, the second one truncated to its header.
+	data := append(append([]byte{}, imaBlock...), imaBlock[:8]...)
+	r, err := NewIMAReader(bytes.NewReader(data), imaFormat)
+	if err != nil {
+		t.Fatal("NewIMAReader failed:", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal("Decoding failed:", err)
+	}
+	expected := append(append([]int16{}, imaDecoded...), imaDecoded[:2]...)
+	if len(out) != 2*len(expected) {
+		t.Fatalf("Decoded size: %d, expecting: %d", len(out), 2*len(expected))
+	}
+	for i, v := range expected {
+		if s := int16(binary.LittleEndian.Uint16(out[2*i:])); s != v {
+			t.Errorf("Sample %d: %d, expecting: %d", i, s, v)
+		}
+	}
+}
+
+func TestIMAReaderErrors(t *testing.T) {
+	if _, err := NewIMAReader(bytes.NewReader(nil), Format{Tag: FormatPCM, Channels: 1, BlockAlign: 2, BitsPerSample: 16}); err == nil {
+		t.Error("NewIMAReader didn't return an error for PCM data")
+	}
+	bad := imaFormat
+	bad.BlockAlign = 10
+	if _, err := NewIMAReader(bytes.NewReader(nil), bad); err != ErrBadFormat {
+		t.Errorf("Error: %v, expecting: %v", err, ErrBadFormat)
+	}
+	block := append([]byte{}, imaBlock...)
+	block[2] = 89
+	r, _ := NewIMAReader(bytes.NewReader(block), imaFormat)
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("Invalid step index didn't return an error")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import "math/bits"
+
+// Speaker positions of a WAVE_FORMAT_EXTENSIBLE channel mask. Channels are
+// stored in the order of the bits set in the mask.
+const (
+	FrontLeft          = 1 << iota // FL
+	FrontRight                     // FR
+	FrontCenter                    // FC
+	LowFrequency                   // LFE
+	BackLeft                       // BL
+	BackRight                      // BR
+	FrontLeftOfCenter              // FLC
+	FrontRightOfCenter             // FRC
+	BackCenter                     // BC
+	SideLeft                       // SL
+	SideRight                      // SR
+	TopCenter                      // TC
+	TopFrontLeft                   // TFL
+	TopFrontCenter                 // TFC
+	TopFrontRight                  // TFR
+	TopBackLeft                    // TBL
+	TopBackCenter                  // TBC
+	TopBackRight                   // TBR
+)
+
+var positionNames = []string{"FL", "FR", "FC", "LFE", "BL", "BR", "FLC", "FRC", "BC", "SL", "SR", "TC", "TFL", "TFC", "TFR", "TBL", "TBC", "TBR"}
+
+// PositionName returns the short name of a single speaker position, e.g. "FL".
+func PositionName(position uint32) string {
+	if bits.OnesCount32(position) != 1 || bits.TrailingZeros32(position) >= len(positionNames) {
+		return "?"
+	}
+	return positionNames[bits.TrailingZeros32(position)]
+}
+
+// DefaultChannelMask returns the customary speaker layout for a number of
+// channels, or 0 when there is none.
+func DefaultChannelMask(channels int) uint32 {
+	switch channels {
+	case 1:
+		return FrontCenter
+	case 2:
+		return FrontLeft | FrontRight
+	case 3:
+		return FrontLeft | FrontRight | FrontCenter
+	case 4:
+		return FrontLeft | FrontRight | BackLeft | BackRight
+	case 5:
+		return FrontLeft | FrontRight | FrontCenter | BackLeft | BackRight
+	case 6:
+		return FrontLeft | FrontRight | FrontCenter | LowFrequency | BackLeft | BackRight
+	case 8:
+		return FrontLeft | FrontRight | FrontCenter | LowFrequency | BackLeft | BackRight | SideLeft | SideRight
+	}
+	return 0
+}
+
+// Mask returns the channel mask of the format, falling back to the
+// default layout for its number of channels when the file has none.
+func (f Format) Mask() uint32 {
+	if f.ChannelMask != 0 {
+		return f.ChannelMask
+	}
+	return DefaultChannelMask(f.Channels)
+}
+
+// Positions returns the speaker position of each channel of the format.
+// Channels beyond those described by the mask have position 0.
+func (f Format) Positions() []uint32 {
+	return ChannelPositions(f.Mask(), f.Channels)
+}
+
+// ChannelPositions returns the speaker position of each of the channels laid
+// out according to mask.
+func ChannelPositions(mask uint32, channels int) []uint32 {
+	p := make([]uint32, channels)
+	for i := range p {
+		if mask == 0 {
+			break
+		}
+		p[i] = mask & -mask
+		mask &^= p[i]
+	}
+	return p
+}
@@ -0,0 +1,60 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var PositionsTest = []struct {
+	name      string
+	format    Format
+	positions []uint32
+}{
+	{"mono", Format{Channels: 1}, []uint32{FrontCenter}},
+	{"stereo", Format{Channels: 2}, []uint32{FrontLeft, FrontRight}},
+	{"5.1", Format{Channels: 6}, []uint32{FrontLeft, FrontRight, FrontCenter, LowFrequency, BackLeft, BackRight}},
+	{"mask", Format{Channels: 3, ChannelMask: FrontLeft | FrontRight | LowFrequency}, []uint32{FrontLeft, FrontRight, LowFrequency}},
+	{"short mask", Format{Channels: 3, ChannelMask: FrontCenter}, []uint32{FrontCenter, 0, 0}},
+	{"no layout", Format{Channels: 7}, make([]uint32, 7)},
+}
+
+func TestPositions(t *testing.T) {
+	for _, tc := range PositionsTest {
+		if p := tc.format.Positions(); !reflect.DeepEqual(p, tc.positions) {
+			t.Errorf("%s: positions %v, expecting: %v", tc.name, p, tc.positions)
+		}
+	}
+	if n := PositionName(LowFrequency); n != "LFE" {
+		t.Errorf("PositionName: %s, expecting: LFE", n)
+	}
+	if n := PositionName(FrontLeft | FrontRight); n != "?" {
+		t.Errorf("PositionName of a mask: %s, expecting: ?", n)
+	}
+}
+
+func TestChannelMaskRoundTrip(t *testing.T) {
+	f := Format{Tag: FormatPCM, Channels: 6, SampleRate: 48000, BitsPerSample: 16, ChannelMask: DefaultChannelMask(6)}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, f)
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	w.Write(make([]byte, 24))
+	w.Close()
+	h, err := ReadHeader(&buf)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.Tag != FormatPCM || h.ChannelMask != f.ChannelMask || h.BlockAlign != 12 {
+		t.Errorf("Format: %+v", h.Format)
+	}
+}
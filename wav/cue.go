@@ -0,0 +1,67 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const cuePointSize = 24
+
+// CuePoint is an entry of a cue chunk, marking a position in the audio data.
+type CuePoint struct {
+	ID           uint32 // unique identifier, referenced by adtl labels
+	Position     uint32 // sample frame position in playback order
+	DataChunkID  string // chunk holding the cue point, normally "data"
+	ChunkStart   uint32 // offset of that chunk in a wavl list
+	BlockStart   uint32 // offset of the block holding the cue point
+	SampleOffset uint32 // sample frame offset of the cue point
+}
+
+// ParseCue parses the payload of a cue chunk.
+func ParseCue(b []byte) ([]CuePoint, error) {
+	if len(b) < 4 {
+		return nil, errors.New("wav: malformed cue chunk")
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	if n > len(b)/cuePointSize {
+		return nil, errors.New("wav: malformed cue chunk")
+	}
+	points := make([]CuePoint, n)
+	for i := range points {
+		p := b[i*cuePointSize:]
+		points[i] = CuePoint{
+			ID:           binary.LittleEndian.Uint32(p[0:]),
+			Position:     binary.LittleEndian.Uint32(p[4:]),
+			DataChunkID:  string(p[8:12]),
+			ChunkStart:   binary.LittleEndian.Uint32(p[12:]),
+			BlockStart:   binary.LittleEndian.Uint32(p[16:]),
+			SampleOffset: binary.LittleEndian.Uint32(p[20:]),
+		}
+	}
+	return points, nil
+}
+
+// CueChunk returns a cue chunk holding points.
+func CueChunk(points []CuePoint) Chunk {
+	b := make([]byte, 4+len(points)*cuePointSize)
+	binary.LittleEndian.PutUint32(b, uint32(len(points)))
+	for i, c := range points {
+		p := b[4+i*cuePointSize:]
+		binary.LittleEndian.PutUint32(p[0:], c.ID)
+		binary.LittleEndian.PutUint32(p[4:], c.Position)
+		copy(p[8:12], c.DataChunkID)
+		binary.LittleEndian.PutUint32(p[12:], c.ChunkStart)
+		binary.LittleEndian.PutUint32(p[16:], c.BlockStart)
+		binary.LittleEndian.PutUint32(p[20:], c.SampleOffset)
+	}
+	return Chunk{ID: "cue ", Data: b}
+}
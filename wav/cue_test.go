@@ -0,0 +1,35 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCue(t *testing.T) {
+	points := []CuePoint{
+		{ID: 1, Position: 100, DataChunkID: "data", SampleOffset: 100},
+		{ID: 2, Position: 48000, DataChunkID: "data", SampleOffset: 48000},
+	}
+	c := CueChunk(points)
+	if c.ID != "cue " || len(c.Data) != 4+2*cuePointSize {
+		t.Fatalf("Unexpected cue chunk: %+v", c)
+	}
+	parsed, err := ParseCue(c.Data)
+	if err != nil {
+		t.Fatal("ParseCue failed:", err)
+	}
+	if !reflect.DeepEqual(parsed, points) {
+		t.Errorf("Cue points: %+v, expecting: %+v", parsed, points)
+	}
+	if _, err = ParseCue(c.Data[:30]); err == nil {
+		t.Error("ParseCue didn't return an error for a truncated chunk")
+	}
+}
@@ -0,0 +1,60 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"io"
+)
+
+// Sony Wave64 identifies chunks with GUIDs and uses 64-bit sizes that
+// include the 24 byte chunk header. Payloads are aligned to 8 bytes.
+
+const w64ChunkHeader = 24
+
+var (
+	// Suffix of the GUIDs built from a RIFF chunk identifier.
+	w64Suffix = []byte{0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a}
+	w64Riff   = []byte{'r', 'i', 'f', 'f', 0x2e, 0x91, 0xcf, 0x11, 0xa5, 0xd6, 0x28, 0xdb, 0x04, 0xc1, 0x00, 0x00}
+	w64List   = []byte{'l', 'i', 's', 't', 0x2f, 0x91, 0xcf, 0x11, 0xa5, 0xd6, 0x28, 0xdb, 0x04, 0xc1, 0x00, 0x00}
+	w64Wave   = append([]byte("wave"), w64Suffix...)
+)
+
+// readW64Header checks the rest of the Wave64 file header, given its first 12 bytes.
+func readW64Header(r io.Reader, start []byte) error {
+	b := make([]byte, 40)
+	copy(b, start)
+	if _, err := io.ReadFull(r, b[len(start):]); err != nil {
+		return ErrNotWAV
+	}
+	if !bytes.Equal(b[:16], w64Riff) || !bytes.Equal(b[24:], w64Wave) {
+		return ErrNotWAV
+	}
+	return nil
+}
+
+// guidID returns the RIFF identifier of a Wave64 chunk GUID, or an empty
+// string for GUIDs without one.
+func guidID(guid []byte) string {
+	switch {
+	case bytes.Equal(guid[4:], w64Suffix):
+		return string(guid[:4])
+	case bytes.Equal(guid, w64List):
+		return "LIST"
+	}
+	return ""
+}
+
+// idGUID returns the Wave64 chunk GUID of a RIFF identifier.
+func idGUID(id string) []byte {
+	if id == "LIST" {
+		return w64List
+	}
+	return append([]byte(id), w64Suffix...)
+}
@@ -0,0 +1,71 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestW64(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.w64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info := Chunk{ID: "LIST", Data: []byte("INFOINAM\x03\x00\x00\x00abc")}
+	w, err := NewW64Writer(f, testFormat, info, Chunk{ID: "iXML", Data: []byte("<x/>")})
+	if err != nil {
+		t.Fatal("NewW64Writer failed:", err)
+	}
+	w.Trailer = []Chunk{{ID: "cue ", Data: []byte{0, 0, 0, 0}}}
+	if _, err = w.Write(make([]byte, 1002)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	f.Seek(0, io.SeekStart)
+	h, err := ReadHeader(f)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if !h.W64 || h.Format != testFormat || h.DataSize != 1002 || h.DataPad() != 6 {
+		t.Errorf("Header: %+v", h)
+	}
+	if !reflect.DeepEqual(h.Chunks, []Chunk{info, {ID: "iXML", Data: []byte("<x/>")}}) {
+		t.Errorf("Chunks: %+v", h.Chunks)
+	}
+	f.Seek(h.DataSize+h.DataPad(), io.SeekCurrent)
+	trailer, err := ReadW64Chunks(f)
+	if err != nil {
+		t.Fatal("ReadW64Chunks failed:", err)
+	}
+	if !reflect.DeepEqual(trailer, w.Trailer) {
+		t.Errorf("Trailer: %+v, expecting: %+v", trailer, w.Trailer)
+	}
+}
+
+func TestW64Errors(t *testing.T) {
+	riff := append(append([]byte{}, w64Riff...), make([]byte, 8)...)
+	bad := append(append([]byte{}, riff...), []byte("WAVE")...)
+	if _, err := ReadHeader(bytes.NewReader(bad)); err != ErrNotWAV {
+		t.Errorf("Error: %v, expecting: %v", err, ErrNotWAV)
+	}
+	// A chunk size smaller than the chunk header
+	small := append(append([]byte{}, riff...), w64Wave...)
+	small = append(append(small, idGUID("fmt ")...), 8, 0, 0, 0, 0, 0, 0, 0)
+	if _, err := ReadHeader(bytes.NewReader(small)); err != ErrBadChunk {
+		t.Errorf("Error: %v, expecting: %v", err, ErrBadChunk)
+	}
+}
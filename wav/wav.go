@@ -0,0 +1,280 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+/*
+Package wav implements reading and writing of RIFF/WAVE and Sony Wave64 files.
+
+ReadHeader consumes everything up to the start of the sample data,
+leaving the reader positioned at the first byte of PCM audio.
+Writer produces files with a fmt chunk, optional metadata chunks and
+a data chunk.
+*/
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// Audio format tags
+	FormatPCM        = 0x0001 // Linear PCM
+	FormatFloat      = 0x0003 // IEEE floating point PCM
+	FormatALaw       = 0x0006 // G.711 A-law
+	FormatMuLaw      = 0x0007 // G.711 µ-law
+	FormatIMAADPCM   = 0x0011 // IMA/DVI ADPCM
+	FormatExtensible = 0xFFFE // WAVE_FORMAT_EXTENSIBLE, the real tag is in the sub-format GUID
+)
+
+var (
+	// ErrNotWAV is returned when the input is not a RIFF/WAVE stream.
+	ErrNotWAV = errors.New("wav: not a RIFF/WAVE file")
+	// ErrNoFormat is returned when the data chunk precedes the fmt chunk.
+	ErrNoFormat = errors.New("wav: missing fmt chunk")
+	// ErrBadFormat is returned for malformed fmt chunks.
+	ErrBadFormat = errors.New("wav: malformed fmt chunk")
+	// ErrBadChunk is returned for chunks with an invalid size.
+	ErrBadChunk = errors.New("wav: invalid chunk size")
+)
+
+// maxChunkSize limits the size of metadata chunks kept in memory.
+const maxChunkSize = 1 << 30
+
+// Format describes the layout of the audio samples as defined in the fmt chunk.
+type Format struct {
+	Tag           uint16 // audio format tag, resolved from the sub-format for extensible files
+	Channels      int    // number of interleaved channels
+	SampleRate    int    // sampling rate in Hz
+	ByteRate      int    // average bytes per second
+	BlockAlign    int    // size of a frame in bytes
+	BitsPerSample int    // bits per sample
+	ChannelMask   uint32 // speaker positions of extensible files, 0 if not set
+}
+
+// Chunk is a RIFF chunk.
+type Chunk struct {
+	ID   string // four character chunk identifier
+	Data []byte // chunk payload, without the pad byte
+}
+
+// Header holds the parsed header of a WAV file.
+type Header struct {
+	Format
+	DataSize int64   // size of the data chunk in bytes
+	Chunks   []Chunk // chunks preceding the data chunk, other than fmt and padding
+	W64      bool    // the container is Sony Wave64 rather than RIFF
+}
+
+// Frames returns the number of sample frames in the data chunk.
+func (h *Header) Frames() int64 {
+	if h.BlockAlign == 0 {
+		return 0
+	}
+	return h.DataSize / int64(h.BlockAlign)
+}
+
+// DataPad returns the number of padding bytes following the data chunk payload.
+func (h *Header) DataPad() int64 {
+	return padding(h.DataSize, h.W64)
+}
+
+// ReadHeader parses a WAV or Wave64 header from r. On success r is positioned
+// at the beginning of the data chunk payload. JUNK and PAD chunks are skipped,
+// other chunks are collected in the Header. Wave64 chunks are mapped onto
+// their RIFF identifiers, those without one are skipped.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotWAV
+		}
+		return nil, err
+	}
+	var h Header
+	switch {
+	case string(riff[0:4]) == "RIFF" && string(riff[8:12]) == "WAVE":
+	case string(riff[0:4]) == "riff":
+		if err := readW64Header(r, riff[:]); err != nil {
+			return nil, err
+		}
+		h.W64 = true
+	default:
+		return nil, ErrNotWAV
+	}
+	var haveFmt bool
+	for {
+		id, size, err := readChunkHeader(r, h.W64)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case "fmt ":
+			c, err := readChunk(r, id, size, h.W64)
+			if err != nil {
+				return nil, err
+			}
+			if err = parseFormat(c.Data, &h.Format); err != nil {
+				return nil, err
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return nil, ErrNoFormat
+			}
+			h.DataSize = size
+			return &h, nil
+		case "", "JUNK", "PAD ":
+			if err = skip(r, size+padding(size, h.W64)); err != nil {
+				return nil, err
+			}
+		default:
+			c, err := readChunk(r, id, size, h.W64)
+			if err != nil {
+				return nil, err
+			}
+			h.Chunks = append(h.Chunks, c)
+		}
+	}
+}
+
+// ReadChunks reads RIFF chunks from r until the end of the input. It can be
+// used to get the chunks following the data chunk, once its payload and pad
+// byte have been consumed.
+func ReadChunks(r io.Reader) ([]Chunk, error) {
+	return readChunks(r, false)
+}
+
+// ReadW64Chunks is the Wave64 counterpart of ReadChunks.
+func ReadW64Chunks(r io.Reader) ([]Chunk, error) {
+	return readChunks(r, true)
+}
+
+func readChunks(r io.Reader, w64 bool) ([]Chunk, error) {
+	var chunks []Chunk
+	for {
+		id, size, err := readChunkHeader(r, w64)
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		if id == "" {
+			if err = skip(r, size+padding(size, w64)); err != nil {
+				return chunks, err
+			}
+			continue
+		}
+		c, err := readChunk(r, id, size, w64)
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+// readChunk reads a chunk payload of the given size and its padding.
+func readChunk(r io.Reader, id string, size int64, w64 bool) (Chunk, error) {
+	if size > maxChunkSize {
+		return Chunk{}, errors.New("wav: chunk too large")
+	}
+	// The payload is read as it arrives, a header declaring a large chunk
+	// doesn't allocate its size up front.
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, r, size); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Chunk{}, err
+	}
+	// Tolerate missing padding at the end of the file.
+	if pad := padding(size, w64); pad > 0 {
+		if _, err := io.CopyN(io.Discard, r, pad); err != nil && err != io.EOF {
+			return Chunk{}, err
+		}
+	}
+	return Chunk{ID: id, Data: b.Bytes()}, nil
+}
+
+// readChunkHeader reads a chunk identifier and its payload size.
+// io.EOF is returned only when no input is left. Wave64 chunks
+// without a RIFF identifier are returned with an empty one.
+func readChunkHeader(r io.Reader, w64 bool) (string, int64, error) {
+	if !w64 {
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return "", 0, err
+		}
+		return string(b[0:4]), int64(binary.LittleEndian.Uint32(b[4:8])), nil
+	}
+	var b [w64ChunkHeader]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return "", 0, err
+	}
+	size := binary.LittleEndian.Uint64(b[16:])
+	if size < w64ChunkHeader {
+		return "", 0, ErrBadChunk
+	}
+	if size > math.MaxInt64 {
+		// Streamed data of unknown size
+		return guidID(b[:16]), math.MaxInt64, nil
+	}
+	return guidID(b[:16]), int64(size) - w64ChunkHeader, nil
+}
+
+// padding returns the number of bytes aligning a payload of size bytes.
+func padding(size int64, w64 bool) int64 {
+	if w64 {
+		return (8 - size%8) % 8
+	}
+	return size & 1
+}
+
+// parseFormat parses a fmt chunk payload into f.
+func parseFormat(b []byte, f *Format) error {
+	if len(b) < 16 {
+		return ErrBadFormat
+	}
+	f.Tag = binary.LittleEndian.Uint16(b[0:2])
+	f.Channels = int(binary.LittleEndian.Uint16(b[2:4]))
+	f.SampleRate = int(binary.LittleEndian.Uint32(b[4:8]))
+	f.ByteRate = int(binary.LittleEndian.Uint32(b[8:12]))
+	f.BlockAlign = int(binary.LittleEndian.Uint16(b[12:14]))
+	f.BitsPerSample = int(binary.LittleEndian.Uint16(b[14:16]))
+	if f.Tag == FormatExtensible {
+		// cbSize(2) validBits(2) channelMask(4) subFormat GUID(16)
+		if len(b) < 40 {
+			return ErrBadFormat
+		}
+		f.ChannelMask = binary.LittleEndian.Uint32(b[20:24])
+		f.Tag = binary.LittleEndian.Uint16(b[24:26])
+	}
+	if f.Channels == 0 || f.SampleRate == 0 || f.BlockAlign == 0 {
+		return ErrBadFormat
+	}
+	return nil
+}
+
+// skip discards n bytes from r, seeking when possible.
+func skip(r io.Reader, n int64) error {
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+)
+
+var HeaderTest = []struct {
+	file       string
+	tag        uint16
+	channels   int
+	sampleRate int
+	bits       int
+	dataSize   int64
+}{
+	{"../testing/piano-16k-16-1.wav", FormatPCM, 1, 16000, 16, 201808},
+	{"../testing/piano-16k-16-2.wav", FormatPCM, 2, 16000, 16, 403616},
+	{"../testing/piano-44.1k-16-2.wav", FormatPCM, 2, 44100, 16, 1112468},
+	{"../testing/piano-44.1k-32f-2.wav", FormatFloat, 2, 44100, 32, 2224936},
+	{"../testing/piano-48k-16-2.wav", FormatPCM, 2, 48000, 16, 1210848},
+}
+
+func TestReadHeader(t *testing.T) {
+	for _, tc := range HeaderTest {
+		f, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatal("Failed to open test data:", err)
+		}
+		h, err := ReadHeader(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: ReadHeader failed: %s", tc.file, err)
+		}
+		if h.Tag != tc.tag || h.Channels != tc.channels || h.SampleRate != tc.sampleRate || h.BitsPerSample != tc.bits {
+			t.Errorf("%s: unexpected format: %+v", tc.file, h.Format)
+		}
+		if h.DataSize != tc.dataSize {
+			t.Errorf("%s: data size: %d, expecting: %d", tc.file, h.DataSize, tc.dataSize)
+		}
+	}
+}
+
+func TestReadHeaderStream(t *testing.T) {
+	// A non seekable reader must be positioned at the start of the PCM data.
+	data, err := os.ReadFile("../testing/piano-44.1k-32f-2.wav")
+	if err != nil {
+		t.Fatal("Failed to read test data:", err)
+	}
+	r := io.MultiReader(bytes.NewReader(data))
+	h, err := ReadHeader(r)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	rest, _ := io.ReadAll(r)
+	if int64(len(rest)) < h.DataSize {
+		t.Fatalf("Remaining data: %d, expecting at least: %d", len(rest), h.DataSize)
+	}
+	if !bytes.Equal(rest[:h.DataSize], data[len(data)-len(rest):][:h.DataSize]) {
+		t.Fatal("Reader not positioned at the data chunk")
+	}
+}
+
+var BadHeaderTest = []struct {
+	name string
+	data []byte
+	err  error
+}{
+	{"empty", []byte{}, ErrNotWAV},
+	{"not riff", []byte("RIFX\x00\x00\x00\x00WAVE"), ErrNotWAV},
+	{"not wave", []byte("RIFF\x00\x00\x00\x00AVI "), ErrNotWAV},
+	{"truncated", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), io.ErrUnexpectedEOF},
+	{"no fmt", []byte("RIFF\x00\x00\x00\x00WAVEdata\x00\x00\x00\x00"), ErrNoFormat},
+	{"huge LIST", []byte("RIFF\x00\x00\x00\x00WAVELIST\x00\x00\x00\x40INFO"), io.ErrUnexpectedEOF},
+	{"short fmt", []byte("RIFF\x00\x00\x00\x00WAVEfmt \x02\x00\x00\x00\x01\x00"), ErrBadFormat},
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	for _, tc := range BadHeaderTest {
+		_, err := ReadHeader(bytes.NewReader(tc.data))
+		if err != tc.err {
+			t.Errorf("%s: error: %v, expecting: %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestReadHeaderChunkAlloc(t *testing.T) {
+	// A header declaring a 1 GiB chunk allocates what it sends, not what it
+	// declares.
+	data := []byte("RIFF\x00\x00\x00\x00WAVELIST\x00\x00\x00\x40INFO")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := ReadHeader(bytes.NewReader(data)); err != io.ErrUnexpectedEOF {
+		t.Errorf("Error: %v, expecting: %v", err, io.ErrUnexpectedEOF)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("%d bytes allocated", n)
+	}
+}
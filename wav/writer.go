@@ -0,0 +1,204 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// subFormatSuffix follows the format tag in WAVE_FORMAT_EXTENSIBLE sub-format GUIDs.
+var subFormatSuffix = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+// Writer writes sample data to a WAV or Wave64 file.
+type Writer struct {
+	w        io.Writer
+	seeker   io.WriteSeeker // set when the sizes can be filled in on Close
+	start    int64          // offset of the file in the seeker
+	w64      bool           // write a Wave64 file
+	dataSize int64          // sample bytes written
+	dataOff  int64          // offset of the data chunk size field
+	closed   bool
+	// Trailer holds chunks to be written after the data chunk on Close.
+	// Trailing chunks are only written when the destination is seekable.
+	Trailer []Chunk
+}
+
+// NewWriter writes a WAV header describing f, followed by chunks, to w and
+// returns a Writer for the sample data. A WAVE_FORMAT_EXTENSIBLE header is
+// written when f has a channel mask. If w is an io.WriteSeeker the chunk
+// sizes are filled in on Close, otherwise they are set to the maximum value
+// as is customary for streamed WAV data.
+func NewWriter(w io.Writer, f Format, chunks ...Chunk) (*Writer, error) {
+	return newWriter(w, f, false, chunks)
+}
+
+// NewW64Writer is like NewWriter but writes a Sony Wave64 file, which
+// is not limited to 4 GB of data.
+func NewW64Writer(w io.Writer, f Format, chunks ...Chunk) (*Writer, error) {
+	return newWriter(w, f, true, chunks)
+}
+
+func newWriter(w io.Writer, f Format, w64 bool, chunks []Chunk) (*Writer, error) {
+	if w == nil {
+		return nil, errors.New("io.Writer is nil")
+	}
+	if f.Channels <= 0 || f.SampleRate <= 0 || f.BitsPerSample <= 0 {
+		return nil, ErrBadFormat
+	}
+	if f.BlockAlign == 0 {
+		f.BlockAlign = f.Channels * ((f.BitsPerSample + 7) / 8)
+	}
+	if f.ByteRate == 0 {
+		f.ByteRate = f.SampleRate * f.BlockAlign
+	}
+	wr := &Writer{w: w, w64: w64}
+	if s, ok := w.(io.WriteSeeker); ok {
+		if off, err := s.Seek(0, io.SeekCurrent); err == nil {
+			wr.seeker = s
+			wr.start = off
+		}
+	}
+	var b []byte
+	if w64 {
+		b = append(b, w64Riff...)
+		b = binary.LittleEndian.AppendUint64(b, math.MaxUint64)
+		b = append(b, w64Wave...)
+	} else {
+		b = append(b, "RIFF"...)
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+		b = append(b, "WAVE"...)
+	}
+	tag := f.Tag
+	if f.ChannelMask != 0 {
+		tag = FormatExtensible
+	}
+	var fmtData []byte
+	fmtData = binary.LittleEndian.AppendUint16(fmtData, tag)
+	fmtData = binary.LittleEndian.AppendUint16(fmtData, uint16(f.Channels))
+	fmtData = binary.LittleEndian.AppendUint32(fmtData, uint32(f.SampleRate))
+	fmtData = binary.LittleEndian.AppendUint32(fmtData, uint32(f.ByteRate))
+	fmtData = binary.LittleEndian.AppendUint16(fmtData, uint16(f.BlockAlign))
+	fmtData = binary.LittleEndian.AppendUint16(fmtData, uint16(f.BitsPerSample))
+	switch {
+	case tag == FormatExtensible:
+		// cbSize, valid bits, channel mask and sub-format GUID
+		fmtData = binary.LittleEndian.AppendUint16(fmtData, 22)
+		fmtData = binary.LittleEndian.AppendUint16(fmtData, uint16(f.BitsPerSample))
+		fmtData = binary.LittleEndian.AppendUint32(fmtData, f.ChannelMask)
+		fmtData = binary.LittleEndian.AppendUint16(fmtData, f.Tag)
+		fmtData = append(fmtData, subFormatSuffix...)
+	case tag != FormatPCM:
+		// cbSize is required for non PCM formats
+		fmtData = binary.LittleEndian.AppendUint16(fmtData, 0)
+	}
+	b = wr.appendChunk(b, Chunk{ID: "fmt ", Data: fmtData})
+	for _, c := range chunks {
+		b = wr.appendChunk(b, c)
+	}
+	if w64 {
+		b = append(b, idGUID("data")...)
+		b = binary.LittleEndian.AppendUint64(b, math.MaxUint64)
+		wr.dataOff = int64(len(b) - 8)
+	} else {
+		b = append(b, "data"...)
+		b = binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+		wr.dataOff = int64(len(b) - 4)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return wr, nil
+}
+
+// Write writes sample data.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("wav: write on closed Writer")
+	}
+	n, err := w.w.Write(p)
+	w.dataSize += int64(n)
+	return n, err
+}
+
+// Close terminates the data chunk, writes the trailing chunks and fills in
+// the chunk sizes when the destination is seekable. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.seeker == nil {
+		return nil
+	}
+	b := make([]byte, padding(w.dataSize, w.w64))
+	for _, c := range w.Trailer {
+		b = w.appendChunk(b, c)
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+	end, err := w.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if w.w64 {
+		// Wave64 sizes include the chunk headers.
+		err = w.patch(16, end-w.start)
+		if err == nil {
+			err = w.patch(w.dataOff, w.dataSize+w64ChunkHeader)
+		}
+	} else {
+		err = w.patch(4, end-w.start-8)
+		if err == nil {
+			err = w.patch(w.dataOff, w.dataSize)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// patch writes a chunk size at offset off of the file. RIFF sizes that
+// don't fit in the 32-bit field are saturated.
+func (w *Writer) patch(off, size int64) error {
+	if _, err := w.seeker.Seek(w.start+off, io.SeekStart); err != nil {
+		return err
+	}
+	var b []byte
+	if w.w64 {
+		b = binary.LittleEndian.AppendUint64(b, uint64(size))
+	} else {
+		if size > math.MaxUint32 {
+			size = math.MaxUint32
+		}
+		b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	}
+	_, err := w.seeker.Write(b)
+	return err
+}
+
+// appendChunk appends a chunk and its padding to b.
+func (w *Writer) appendChunk(b []byte, c Chunk) []byte {
+	size := int64(len(c.Data))
+	if w.w64 {
+		b = append(b, idGUID(c.ID)...)
+		b = binary.LittleEndian.AppendUint64(b, uint64(size+w64ChunkHeader))
+	} else {
+		b = append(b, c.ID...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	}
+	b = append(b, c.Data...)
+	return append(b, make([]byte, padding(size, w.w64))...)
+}
@@ -0,0 +1,89 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testFormat = Format{Tag: FormatPCM, Channels: 1, SampleRate: 8000, ByteRate: 16000, BlockAlign: 2, BitsPerSample: 16}
+
+func TestWriter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info := Chunk{ID: "LIST", Data: []byte("INFOINAM\x03\x00\x00\x00abc")}
+	w, err := NewWriter(f, testFormat, info)
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	w.Trailer = []Chunk{{ID: "note", Data: []byte("xyz")}}
+	if _, err = w.Write(make([]byte, 1000)); err != nil {
+		t.Fatal("Write failed:", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+	if _, err = w.Write([]byte{0}); err == nil {
+		t.Error("Write on closed Writer didn't return an error")
+	}
+	f.Seek(0, io.SeekStart)
+	data, _ := io.ReadAll(f)
+	if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size: %d, expecting: %d", size, len(data)-8)
+	}
+	r := bytes.NewReader(data)
+	h, err := ReadHeader(r)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	if h.Format != testFormat || h.DataSize != 1000 {
+		t.Errorf("Header: %+v", h)
+	}
+	if !reflect.DeepEqual(h.Chunks, []Chunk{info}) {
+		t.Errorf("Chunks: %+v, expecting: %+v", h.Chunks, []Chunk{info})
+	}
+	r.Seek(h.DataSize, io.SeekCurrent)
+	trailer, err := ReadChunks(r)
+	if err != nil {
+		t.Fatal("ReadChunks failed:", err)
+	}
+	if !reflect.DeepEqual(trailer, w.Trailer) {
+		t.Errorf("Trailer: %+v, expecting: %+v", trailer, w.Trailer)
+	}
+}
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Format{Tag: FormatFloat, Channels: 2, SampleRate: 48000, BitsPerSample: 32})
+	if err != nil {
+		t.Fatal("NewWriter failed:", err)
+	}
+	w.Write(make([]byte, 16))
+	w.Close()
+	h, err := ReadHeader(&buf)
+	if err != nil {
+		t.Fatal("ReadHeader failed:", err)
+	}
+	// Sizes can't be filled in on a stream
+	if h.DataSize != 0xffffffff || h.BlockAlign != 8 || h.ByteRate != 384000 {
+		t.Errorf("Header: %+v", h)
+	}
+	if _, err = NewWriter(&buf, Format{}); err != ErrBadFormat {
+		t.Errorf("Error: %v, expecting: %v", err, ErrBadFormat)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zaf/resample/wav"
+)
+
+var WAVTest = []struct {
+	file       string
+	outputRate float64
+	opts       []Option
+	frameSize  int
+	inFrames   int
+}{
+	{"testing/piano-16k-16-1.wav", 8000.0, nil, 2, 100904},
+	{"testing/piano-16k-16-2.wav", 8000.0, []Option{WithQuality(MediumQ)}, 4, 100904},
+	{"testing/piano-44.1k-32f-2.wav", 22050.0, nil, 8, 278117},
+	{"testing/piano-44.1k-32f-2.wav", 22050.0, []Option{WithOutFormat(I16)}, 4, 278117},
+}
+
+func TestNewFromWAV(t *testing.T) {
+	for _, td := range WAVTest {
+		input, err := os.Open(td.file)
+		if err != nil {
+			t.Fatal("Failed to open test data:", err)
+		}
+		var out bytes.Buffer
+		res, err := NewFromWAV(&out, input, td.outputRate, td.opts...)
+		input.Close()
+		if err != nil {
+			t.Fatalf("%s: NewFromWAV failed: %s", td.file, err)
+		}
+		err = res.Close()
+		if err != nil {
+			t.Fatal("Failed to close Resampler:", err)
+		}
+		if out.Len()%td.frameSize != 0 {
+			t.Errorf("%s: output is not frame aligned: %d bytes", td.file, out.Len())
+		}
+		// Allow for rounding of odd input lengths
+		if frames := out.Len() / td.frameSize; frames < td.inFrames/2 || frames > td.inFrames/2+1 {
+			t.Errorf("%s: output frames: %d, expecting: %d", td.file, frames, td.inFrames/2)
+		}
+	}
+}
+
+func TestNewFromWAVErrors(t *testing.T) {
+	_, err := NewFromWAV(&bytes.Buffer{}, bytes.NewReader([]byte("not a wav file")), 8000.0)
+	if err == nil {
+		t.Fatal("NewFromWAV didn't return an error for invalid input")
+	}
+	_, err = NewFromWAV(nil, bytes.NewReader([]byte("not a wav file")), 0)
+	if err == nil {
+		t.Fatal("NewFromWAV didn't return an error for invalid parameters")
+	}
+}
+
+func TestNewFromWAVADPCM(t *testing.T) {
+	// Mono IMA ADPCM, 36 byte blocks holding 65 samples each.
+	const blocks = 10
+	var in bytes.Buffer
+	in.WriteString("RIFF")
+	binary.Write(&in, binary.LittleEndian, uint32(4+28+8+blocks*36))
+	in.WriteString("WAVEfmt ")
+	binary.Write(&in, binary.LittleEndian, []uint32{20, 0x00010011, 8000, 4055})
+	binary.Write(&in, binary.LittleEndian, []uint16{36, 4, 2, 65})
+	in.WriteString("data")
+	binary.Write(&in, binary.LittleEndian, uint32(blocks*36))
+	in.Write(make([]byte, blocks*36))
+
+	var out bytes.Buffer
+	res, err := NewFromWAV(&out, &in, 16000.0)
+	if err != nil {
+		t.Fatal("NewFromWAV failed:", err)
+	}
+	if err = res.Close(); err != nil {
+		t.Fatal("Failed to close Resampler:", err)
+	}
+	if out.Len() != blocks*65*2*2 {
+		t.Errorf("Output size: %d, expecting: %d", out.Len(), blocks*65*2*2)
+	}
+}
+
+func TestConvertWAV(t *testing.T) {
+	// 16 kHz mono input with a cue point, LIST chunks before and after the data
+	// and a fact chunk that must not be carried over.
+	info := wav.Chunk{ID: "LIST", Data: []byte("INFOINAM\x05\x00\x00\x00piano\x00")}
+	labels := wav.Chunk{ID: "LIST", Data: []byte("adtllabl\x06\x00\x00\x00\x01\x00\x00\x00a\x00")}
+	cue := wav.CueChunk([]wav.CuePoint{{ID: 1, Position: 16000, DataChunkID: "data", SampleOffset: 16000}})
+	dir := t.TempDir()
+	in, err := os.Create(filepath.Join(dir, "in.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	w, err := wav.NewWriter(in, wav.Format{Tag: wav.FormatPCM, Channels: 1, SampleRate: 16000, BitsPerSample: 16},
+		cue, info, wav.Chunk{ID: "fact", Data: []byte{0x80, 0x3e, 0, 0}})
+	if err != nil {
+		t.Fatal("Failed to create WAV writer:", err)
+	}
+	w.Trailer = []wav.Chunk{labels}
+	w.Write(make([]byte, 64000))
+	if err = w.Close(); err != nil {
+		t.Fatal("Failed to write test input:", err)
+	}
+	in.Seek(0, io.SeekStart)
+
+	f, err := os.Create(filepath.Join(dir, "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = ConvertWAV(f, in, 8000.0); err != nil {
+		t.Fatal("ConvertWAV failed:", err)
+	}
+	f.Seek(0, io.SeekStart)
+	h, err := wav.ReadHeader(f)
+	if err != nil {
+		t.Fatal("Failed to parse output:", err)
+	}
+	if h.SampleRate != 8000 || h.Tag != wav.FormatPCM || h.BitsPerSample != 16 || h.DataSize != 32000 {
+		t.Errorf("Unexpected output header: %+v", h)
+	}
+	if len(h.Chunks) != 2 || h.Chunks[0].ID != "cue " || !reflect.DeepEqual(h.Chunks[1], info) {
+		t.Fatalf("Unexpected output chunks: %+v", h.Chunks)
+	}
+	points, err := wav.ParseCue(h.Chunks[0].Data)
+	if err != nil || len(points) != 1 || points[0].Position != 8000 || points[0].SampleOffset != 8000 {
+		t.Errorf("Cue points not rescaled: %+v", points)
+	}
+	f.Seek(h.DataSize, io.SeekCurrent)
+	trailer, err := wav.ReadChunks(f)
+	if err != nil || !reflect.DeepEqual(trailer, []wav.Chunk{labels}) {
+		t.Errorf("Trailing chunks: %+v, error: %v", trailer, err)
+	}
+}
+
+func TestConvertW64(t *testing.T) {
+	dir := t.TempDir()
+	in, err := os.Create(filepath.Join(dir, "in.w64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	w, err := wav.NewW64Writer(in, wav.Format{Tag: wav.FormatFloat, Channels: 2, SampleRate: 48000, BitsPerSample: 32})
+	if err != nil {
+		t.Fatal("Failed to create Wave64 writer:", err)
+	}
+	w.Write(make([]byte, 48000*8))
+	w.Close()
+	in.Seek(0, io.SeekStart)
+
+	var out bytes.Buffer
+	if err = ConvertWAV(&out, in, 16000.0, WithOutFormat(I16)); err != nil {
+		t.Fatal("ConvertWAV failed:", err)
+	}
+	h, err := wav.ReadHeader(&out)
+	if err != nil {
+		t.Fatal("Failed to parse output:", err)
+	}
+	if !h.W64 || h.SampleRate != 16000 || h.Tag != wav.FormatPCM || h.BitsPerSample != 16 || out.Len() != 16000*4 {
+		t.Errorf("Unexpected output: %+v, data: %d bytes", h, out.Len())
+	}
+}
+
+func TestWAVMetadata(t *testing.T) {
+	bext := make([]byte, bextTimeReference+10)
+	binary.LittleEndian.PutUint64(bext[bextTimeReference:], 48000)
+	chunks := []wav.Chunk{
+		{ID: "iXML", Data: []byte("<BWFXML/>")},
+		{ID: "fact", Data: []byte{1, 0, 0, 0}},
+		{ID: "bext", Data: bext},
+		{ID: "axml", Data: []byte("<x/>")},
+		{ID: "abcd", Data: []byte{1, 2, 3}},
+	}
+	kept := wavMetadata(chunks, 0.5, defaultOptions().keepChunk)
+	if len(kept) != 4 {
+		t.Fatalf("Kept %d chunks, expecting: 4", len(kept))
+	}
+	for i, id := range []string{"iXML", "bext", "axml", "abcd"} {
+		if kept[i].ID != id {
+			t.Errorf("Chunk %d: %s, expecting: %s", i, kept[i].ID, id)
+		}
+	}
+	if ref := binary.LittleEndian.Uint64(kept[1].Data[bextTimeReference:]); ref != 24000 {
+		t.Errorf("bext time reference: %d, expecting: 24000", ref)
+	}
+	if ref := binary.LittleEndian.Uint64(bext[bextTimeReference:]); ref != 48000 {
+		t.Error("Input bext chunk was modified")
+	}
+	var o options
+	WithMetadata(func(id string) bool { return id == "iXML" })(&o)
+	if kept = wavMetadata(chunks, 0.5, o.keepChunk); len(kept) != 1 || kept[0].ID != "iXML" {
+		t.Errorf("Filtered chunks: %+v", kept)
+	}
+	kept = WAVMetadata(chunks, 2, WithMetadata(func(id string) bool { return id == "bext" }))
+	if len(kept) != 1 || binary.LittleEndian.Uint64(kept[0].Data[bextTimeReference:]) != 96000 {
+		t.Errorf("WAVMetadata chunks: %+v", kept)
+	}
+}
+
+func TestWAVFormat(t *testing.T) {
+	for _, format := range []int{I16, I32, F32, F64, MuLaw, ALaw} {
+		f, err := WAVFormat(format, 2, 44100)
+		if err != nil {
+			t.Fatalf("WAVFormat(%d) failed: %v", format, err)
+		}
+		if f.BlockAlign != 2*FormatSize(format) || f.ByteRate != 44100*f.BlockAlign {
+			t.Errorf("Format %d: block align %d, byte rate %d", format, f.BlockAlign, f.ByteRate)
+		}
+		if back, err := WAVSampleFormat(f); err != nil || back != format {
+			t.Errorf("Format %d read back as %d, %v", format, back, err)
+		}
+	}
+	if f, err := WAVFormat(I24In32, 1, 48000); err != nil || f.Tag != wav.FormatPCM || f.BitsPerSample != 32 {
+		t.Errorf("I24In32 WAV format %+v, %v", f, err)
+	}
+	if _, err := WAVFormat(11, 1, 48000); err == nil {
+		t.Error("Invalid format didn't return an error")
+	}
+	if _, err := WAVSampleFormat(wav.Format{Tag: wav.FormatPCM, BitsPerSample: 24}); err == nil {
+		t.Error("24-bit WAV format didn't return an error")
+	}
+}
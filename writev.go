@@ -0,0 +1,27 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import "net"
+
+// WriteV resamples the data of bufs, such as the payloads of packets, as
+// a single Write of their concatenation, frames possibly spanning buffers.
+// The buffers are gathered in a buffer of the Resampler, reused by every
+// call, and passed to the resampling library at once. It returns the
+// number of bytes of bufs written.
+func (r *Resampler) WriteV(bufs net.Buffers) (int, error) {
+	if len(bufs) == 1 {
+		return r.Write(bufs[0])
+	}
+	r.gather = r.gather[:0]
+	for _, b := range bufs {
+		r.gather = append(r.gather, b...)
+	}
+	return r.Write(r.gather)
+}
@@ -0,0 +1,45 @@
+/*
+	Copyright (C) 2016 - 2024, Lefteris Zafiris <zaf@fastmail.com>
+
+	This program is free software, distributed under the terms of
+	the BSD 3-Clause License. See the LICENSE file
+	at the top of the source tree.
+*/
+
+package resample
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/zaf/resample/testsignal"
+)
+
+func TestWriteV(t *testing.T) {
+	in, _ := testsignal.Encode(testsignal.Sine(440, 0.25, 8000, 4000), 2, testsignal.I16)
+	var out [2]bytes.Buffer
+	for i := range out {
+		res, err := New(&out[i], 8000.0, 16000.0, 2, I16, I16, MediumQ, WithDither(DitherNone))
+		if err != nil {
+			t.Fatal("Failed to create a Resampler:", err)
+		}
+		if i == 0 {
+			_, err = res.Write(in)
+		} else {
+			// Packets splitting frames and samples.
+			var n int
+			n, err = res.WriteV(net.Buffers{in[:3], in[3:1001], in[1001:1001], in[1001:]})
+			if n != len(in) {
+				t.Errorf("WriteV wrote %d bytes, expecting %d", n, len(in))
+			}
+		}
+		if err != nil {
+			t.Fatal("Write failed:", err)
+		}
+		res.Close()
+	}
+	if out[0].Len() == 0 || !bytes.Equal(out[0].Bytes(), out[1].Bytes()) {
+		t.Errorf("WriteV output of %d bytes, Write output of %d", out[1].Len(), out[0].Len())
+	}
+}